@@ -0,0 +1,476 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+	"github.com/tsny/shopsync/pkg/wpimg"
+)
+
+// adminUser is the HTTP basic auth user for /admin; the password comes from
+// ADMIN_PASSWORD. Under serve -api-keys an admin-scope key also works as
+// the password, with any user name.
+const adminUser = "admin"
+
+// admin is the curation UI mounted under /admin by serve -admin. It needs a
+// writable store.
+type admin struct {
+	store    *showstore.Store
+	loc      *time.Location
+	password string   // may be empty with keys
+	keys     *apiKeys // nil unless -api-keys
+}
+
+func (a *admin) register(mux *http.ServeMux) {
+	h := http.NewServeMux()
+	h.HandleFunc("GET /admin/{$}", a.handleList)
+	h.HandleFunc("GET /admin/shows/{uid}", a.handleEdit)
+	h.HandleFunc("POST /admin/shows/{uid}", a.handleSave)
+	h.HandleFunc("POST /admin/shows/{uid}/image", a.handleRefetchImage)
+	h.HandleFunc("POST /admin/shows/{uid}/enrich", a.handleEnrich)
+	h.HandleFunc("POST /admin/shows/{uid}/attendance", a.handleAttendance)
+	h.HandleFunc("GET /admin/review", a.handleReviewList)
+	h.HandleFunc("POST /admin/review/{uid}", a.handleReview)
+	mux.Handle("/admin/", a.auth(http.NewCrossOriginProtection().Handler(h)))
+}
+
+func (a *admin) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if ok && a.password != "" && user == adminUser && subtle.ConstantTimeCompare([]byte(pass), []byte(a.password)) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if a.keys != nil && requestAPIKey(r) != "" {
+			k, status, err := a.keys.check(r, showstore.ScopeAdmin)
+			if err == nil {
+				slog.Info("admin access", "key", k.Name, "method", r.Method, "path", r.URL.Path)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if status == http.StatusInternalServerError {
+				a.fail(w, r, err)
+				return
+			}
+			if status == http.StatusForbidden {
+				http.Error(w, err.Error(), status)
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="shopsync admin"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// handleList shows upcoming shows; ?unmatched=1 keeps only those without
+// teams.
+func (a *admin) handleList(w http.ResponseWriter, r *http.Request) {
+	shows, _, err := a.store.ListShows(r.Context(), showstore.ShowFilter{From: time.Now().Add(-6 * time.Hour), Limit: maxPageSize})
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	unmatchedOnly := r.URL.Query().Get("unmatched") != ""
+	var rows []icalplayers.Event
+	unmatched := 0
+	for _, s := range shows {
+		if len(nonEmpty(s.Teams)) == 0 {
+			unmatched++
+		} else if unmatchedOnly {
+			continue
+		}
+		rows = append(rows, s)
+	}
+	a.render(w, "list", map[string]any{
+		"Shows": rows, "Total": len(shows), "Unmatched": unmatched, "UnmatchedOnly": unmatchedOnly,
+		"Flash": r.URL.Query().Get("msg"),
+	})
+}
+
+func (a *admin) handleEdit(w http.ResponseWriter, r *http.Request) {
+	show, err := a.store.GetShow(r.Context(), r.PathValue("uid"))
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	if show == nil {
+		http.NotFound(w, r)
+		return
+	}
+	teams, err := a.store.GetAllTeams(r.Context())
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	counts, err := a.store.GetAttendance(r.Context(), show.UID)
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	a.render(w, "edit", map[string]any{
+		"Show": show, "Teams": teams, "Counts": counts, "Flash": r.URL.Query().Get("msg"),
+		"TeamText": strings.Join(nonEmpty(show.Teams), "\n"), "PlayerText": strings.Join(nonEmpty(show.Players), "\n"),
+	})
+}
+
+// handleSave replaces a show's teams and players. Teams are entered one per
+// line by name, alias or ID. The form carries the version it was rendered
+// from, so a sync that lands in between isn't silently overwritten.
+func (a *admin) handleSave(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	version, err := strconv.ParseInt(r.PostForm.Get("version"), 10, 64)
+	if err != nil || version < 1 {
+		http.Error(w, "missing or invalid version", http.StatusBadRequest)
+		return
+	}
+	all, err := a.store.GetAllTeams(r.Context())
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	var names, ids []string
+	for _, want := range formLines(r.PostForm.Get("teams")) {
+		t := lookupTeam(want, all)
+		if t == nil {
+			a.redirect(w, r, "/admin/shows/"+uid, fmt.Sprintf("Unknown team %q; nothing saved.", want))
+			return
+		}
+		names, ids = append(names, t.Name), append(ids, t.ID)
+	}
+	players := formLines(r.PostForm.Get("players"))
+	err = a.store.UpdatePlayersAndTeams(r.Context(), uid, version, players, names, ids)
+	if errors.Is(err, showstore.ErrVersionConflict) {
+		a.redirect(w, r, "/admin/shows/"+uid, "The show changed since this page loaded; review and save again.")
+		return
+	}
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	// Staff reviewed both lists, so syncs treat both as hand edits.
+	if err := a.store.MarkEdited(r.Context(), uid, showstore.FieldPlayers, showstore.FieldTeams); err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	slog.Info("admin updated show", "uid", uid, "teams", names, "players", len(players))
+	a.redirect(w, r, "/admin/shows/"+uid, "Saved.")
+}
+
+// handleRefetchImage looks the post image up again from the show's page.
+func (a *admin) handleRefetchImage(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
+	show, err := a.store.GetShow(r.Context(), uid)
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	if show == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if show.URL == "" {
+		a.redirect(w, r, "/admin/shows/"+uid, "This show has no page URL to fetch an image from.")
+		return
+	}
+	res, err := wpimg.Fetch(r.Context(), show.URL)
+	if err != nil {
+		a.redirect(w, r, "/admin/shows/"+uid, "Image fetch failed: "+err.Error())
+		return
+	}
+	if res.ImageURL == "" {
+		a.redirect(w, r, "/admin/shows/"+uid, "No post image found on the show page.")
+		return
+	}
+	if err := a.store.UpdateShowImageURL(r.Context(), uid, res.ImageURL); err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	slog.Info("admin refetched image", "uid", uid, "image", res.ImageURL)
+	a.redirect(w, r, "/admin/shows/"+uid, "Image updated.")
+}
+
+// enrichResult is the JSON answer of POST /admin/shows/{uid}/enrich.
+type enrichResult struct {
+	UID     string        `json:"uid"`
+	DryRun  bool          `json:"dryRun"`
+	Changes []fieldChange `json:"changes"`
+	// Kept are fields that changed but were edited by hand, so were left
+	// alone (pass force=1 to overwrite them).
+	Kept     []fieldChange `json:"kept,omitempty"`
+	Warnings []string      `json:"warnings,omitempty"`
+}
+
+// handleEnrich re-runs image fetching and player and team inference for
+// one show, as a sync would, and answers with the before/after diff.
+// Form values: dry_run=1 only reports, force=1 also replaces hand-edited
+// fields. Browsers (Accept: text/html) are sent back to the edit page.
+func (a *admin) handleEnrich(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dryRun, force := r.Form.Get("dry_run") != "", r.Form.Get("force") != ""
+	ctx := r.Context()
+	old, err := a.store.GetShow(ctx, uid)
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	if old == nil {
+		http.NotFound(w, r)
+		return
+	}
+	teams, err := a.store.GetAllTeams(ctx)
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	rosters, err := a.store.GetRosters(ctx)
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	edits, err := a.store.GetEdits(ctx, uid)
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	res := enrichResult{UID: uid, DryRun: dryRun, Changes: []fieldChange{}}
+
+	e, err := reprocessShow(*old, teams, rosters, nil, a.loc, true)
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	image := old.PostImageURL
+	if old.URL == "" {
+		res.Warnings = append(res.Warnings, "no page URL to fetch an image from")
+	} else if got, err := wpimg.Fetch(ctx, old.URL); err != nil {
+		res.Warnings = append(res.Warnings, "image fetch failed: "+err.Error())
+	} else if got.ImageURL == "" {
+		res.Warnings = append(res.Warnings, "no post image found on the show page")
+	} else {
+		image = got.ImageURL
+	}
+
+	edited := func(field string) bool { return !force && slices.Contains(edits.Fields, field) }
+	var writePlayers bool
+	for _, fc := range derivedFieldChanges(*old, e) {
+		field := fc.Field
+		if field == "team_ids" {
+			field = showstore.FieldTeams
+		}
+		if edited(field) {
+			res.Kept = append(res.Kept, fc)
+			continue
+		}
+		res.Changes = append(res.Changes, fc)
+		writePlayers = true
+	}
+	if edited(showstore.FieldPlayers) {
+		e.Players = old.Players
+	}
+	if edited(showstore.FieldTeams) {
+		e.Teams, e.TeamIDs = old.Teams, old.TeamIDs
+	}
+	writeImage := false
+	if image != old.PostImageURL {
+		fc := fieldChange{showstore.FieldImage, old.PostImageURL, image}
+		if edited(showstore.FieldImage) {
+			res.Kept = append(res.Kept, fc)
+		} else {
+			res.Changes = append(res.Changes, fc)
+			writeImage = true
+		}
+	}
+
+	if !dryRun {
+		if writePlayers {
+			err := a.store.UpdatePlayersAndTeams(ctx, uid, old.Version, e.Players, e.Teams, e.TeamIDs)
+			if errors.Is(err, showstore.ErrVersionConflict) {
+				a.enrichDone(w, r, http.StatusConflict, res, "The show changed while it was re-enriched; try again.")
+				return
+			}
+			if err != nil {
+				a.fail(w, r, err)
+				return
+			}
+		}
+		if writeImage {
+			if err := a.store.UpdateShowImageURL(ctx, uid, image); err != nil {
+				a.fail(w, r, err)
+				return
+			}
+		}
+		slog.Info("admin re-enriched show", "uid", uid, "changes", len(res.Changes), "kept", len(res.Kept))
+	}
+	msg := fmt.Sprintf("Re-enriched: %d changes.", len(res.Changes))
+	if dryRun {
+		msg = fmt.Sprintf("Re-enriching would make %d changes.", len(res.Changes))
+	}
+	if len(res.Kept) > 0 {
+		msg += fmt.Sprintf(" %d hand-edited fields kept.", len(res.Kept))
+	}
+	for _, warn := range res.Warnings {
+		msg += " Warning: " + warn + "."
+	}
+	a.enrichDone(w, r, http.StatusOK, res, msg)
+}
+
+// enrichDone answers handleEnrich: JSON for API clients, a redirect with
+// msg for the edit page's form.
+func (a *admin) enrichDone(w http.ResponseWriter, r *http.Request, status int, res enrichResult, msg string) {
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		a.redirect(w, r, "/admin/shows/"+res.UID, msg)
+		return
+	}
+	if status != http.StatusOK {
+		writeError(w, status, errors.New(msg))
+		return
+	}
+	writeJSON(w, status, res)
+}
+
+func (a *admin) redirect(w http.ResponseWriter, r *http.Request, path, msg string) {
+	http.Redirect(w, r, path+"?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+func (a *admin) fail(w http.ResponseWriter, r *http.Request, err error) {
+	slog.Error("admin request failed", "method", r.Method, "path", r.URL.Path, "err", err)
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
+
+func (a *admin) render(w http.ResponseWriter, name string, data map[string]any) {
+	data["Loc"] = a.loc
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := adminTemplates.ExecuteTemplate(w, name, data); err != nil {
+		slog.Error("admin template failed", "template", name, "err", err)
+	}
+}
+
+// formLines splits a textarea into trimmed, non-empty lines.
+func formLines(s string) []string {
+	var out []string
+	for _, ln := range strings.Split(s, "\n") {
+		if ln = strings.TrimSpace(ln); ln != "" {
+			out = append(out, ln)
+		}
+	}
+	return out
+}
+
+var adminTemplates = template.Must(template.New("admin").Funcs(template.FuncMap{
+	"join": strings.Join,
+	"percent": func(f float64) string {
+		return fmt.Sprintf("%.0f%%", f*100)
+	},
+	"when": func(t *time.Time, loc *time.Location) string {
+		if t == nil {
+			return ""
+		}
+		return t.In(loc).Format("Mon Jan 2, 3:04 PM")
+	},
+}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>shopsync admin</title>
+<style>
+body { font-family: -apple-system, Helvetica, Arial, sans-serif; margin: 24px; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+td, th { text-align: left; padding: 6px 8px; border-bottom: 1px solid #eee; vertical-align: top; }
+.unmatched { background: #fff4e5; }
+.flash { padding: 8px 12px; background: #eef6ff; border-radius: 4px; }
+textarea { width: 100%; font: inherit; }
+img.thumb { width: 64px; border-radius: 4px; }
+</style></head>
+<body>
+<p><a href="/admin/">Upcoming shows</a> · <a href="/admin/?unmatched=1">Unmatched only</a> · <a href="/admin/review">Review queue</a></p>
+{{if .Flash}}<p class="flash">{{.Flash}}</p>{{end}}
+{{end}}
+
+{{define "list"}}{{template "head" .}}
+<h1>Upcoming shows</h1>
+<p>{{.Total}} shows, {{.Unmatched}} with no team.</p>
+<table>
+<tr><th></th><th>When</th><th>Show</th><th>Teams</th><th>Players</th></tr>
+{{range .Shows}}
+<tr{{if not .Teams}} class="unmatched"{{end}}>
+<td>{{if .PostImageURL}}<img class="thumb" src="{{.PostImageURL}}" alt="">{{end}}</td>
+<td>{{when .Start $.Loc}}</td>
+<td><a href="/admin/shows/{{.UID}}">{{.Summary}}</a></td>
+<td>{{if .Teams}}{{join .Teams ", "}}{{else}}<em>unmatched</em>{{end}}</td>
+<td>{{join .Players ", "}}</td>
+</tr>
+{{end}}
+</table>
+</body></html>
+{{end}}
+
+{{define "review"}}{{template "head" .}}
+<h1>Team review queue</h1>
+<p>{{len .Items}} {{.Status}} shows that matched no team. Approving or reassigning one saves the name its description uses as a team alias, so shows like it match on their own.</p>
+<table>
+<tr><th>When</th><th>Show</th><th>Suggested teams</th><th>Reassign</th><th></th></tr>
+{{range $it := .Items}}
+<tr>
+<td>{{when $it.Start $.Loc}}</td>
+<td><a href="/admin/shows/{{$it.UID}}">{{$it.Summary}}</a><br><small>{{percent $it.Confidence}} confidence</small></td>
+<td>{{range $it.Suggestions}}
+<form method="post" action="/admin/review/{{$it.UID}}"><input type="hidden" name="action" value="approve"><input type="hidden" name="teams" value="{{.TeamID}}">
+<button>{{.Team}}</button> <small>as “{{.Text}}”, {{percent .Score}}</small></form>
+{{else}}<em>none</em>{{end}}</td>
+<td><form method="post" action="/admin/review/{{$it.UID}}"><input type="hidden" name="action" value="reassign">
+<input name="teams" placeholder="team name, alias or ID"> <input name="alias" placeholder="as written in the description"> <button>Reassign</button></form></td>
+<td><form method="post" action="/admin/review/{{$it.UID}}"><input type="hidden" name="action" value="dismiss"><button>Not a team show</button></form></td>
+</tr>
+{{end}}
+</table>
+</body></html>
+{{end}}
+
+{{define "edit"}}{{template "head" .}}
+{{with .Show}}
+<h1>{{.Summary}}</h1>
+<p>{{when .Start $.Loc}}{{if .URL}} · <a href="{{.URL}}">show page</a>{{end}} · <code>{{.UID}}</code></p>
+{{if .PostImageURL}}<p><img src="{{.PostImageURL}}" alt="" width="240"></p>{{end}}
+<form method="post" action="/admin/shows/{{.UID}}/image"><button>Re-fetch image</button></form>
+<form method="post" action="/admin/shows/{{.UID}}/enrich"><button>Re-enrich</button> <small>fetch the image and infer players and teams again; hand edits are kept</small></form>
+<form method="post" action="/admin/shows/{{.UID}}">
+<input type="hidden" name="version" value="{{.Version}}">
+<h2>Teams</h2>
+<p>One per line: team name, alias or ID.</p>
+<textarea name="teams" rows="4">{{$.TeamText}}</textarea>
+<h2>Players</h2>
+<p>One per line.</p>
+<textarea name="players" rows="8">{{$.PlayerText}}</textarea>
+<p><button>Save</button></p>
+</form>
+<h2>Attendance</h2>
+<form method="post" action="/admin/shows/{{.UID}}/attendance">
+<label>Attendance <input type="number" name="attendance" min="0" value="{{with $.Counts}}{{with .Attendance}}{{.}}{{end}}{{end}}"></label>
+<label>Capacity <input type="number" name="capacity" min="0" value="{{with $.Counts}}{{with .Capacity}}{{.}}{{end}}{{end}}"></label>
+<button>Save counts</button> <small>blank leaves a count as it is</small>
+</form>
+<h2>Description</h2>
+<pre style="white-space: pre-wrap;">{{.Description}}</pre>
+{{end}}
+<details><summary>All teams</summary><ul>{{range .Teams}}<li>{{.Name}}</li>{{end}}</ul></details>
+</body></html>
+{{end}}
+`))
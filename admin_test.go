@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// testStore opens SHOPSYNC_TEST_DATABASE_URL with the tables in a
+// throwaway schema, dropped when the test ends.
+func testStore(t *testing.T) *showstore.Store {
+	t.Helper()
+	dbURL := os.Getenv("SHOPSYNC_TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("SHOPSYNC_TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	schema := fmt.Sprintf("shopsync_test_%d", time.Now().UnixNano())
+	s, err := showstore.Open(ctx, dbURL, showstore.Schema(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+		conn, err := pgx.Connect(ctx, dbURL)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close(ctx)
+		_, _ = conn.Exec(ctx, "DROP SCHEMA IF EXISTS "+pgx.Identifier{schema}.Sanitize()+" CASCADE")
+	})
+	if err := s.EnsureTeamTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// A re-enrich that finds new players must keep hand-edited teams,
+// show_teams rows included.
+func TestEnrichKeepsEditedTeams(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+	team, err := store.CreateTeam(ctx, "Mainstage Ensemble "+time.Now().Format("150405.000000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2030, 1, 4, 20, 0, 0, 0, time.UTC)
+	show := icalplayers.Event{
+		UID:         "enrich-1",
+		Summary:     "Friday Night Harold",
+		Description: "Cast: Maya Ortiz, Devon Clarke, Priya Raman",
+		Start:       &start,
+		Teams:       []string{team.Name},
+		TeamIDs:     []string{team.ID},
+	}
+	if err := store.Upsert(ctx, show); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.MarkEdited(ctx, show.UID, showstore.FieldTeams); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &admin{store: store, loc: time.UTC}
+	r := httptest.NewRequest("POST", "/admin/shows/"+show.UID+"/enrich", nil)
+	r.SetPathValue("uid", show.UID)
+	w := httptest.NewRecorder()
+	a.handleEnrich(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+
+	got, err := store.GetShow(ctx, show.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Players) == 0 {
+		t.Error("players weren't inferred")
+	}
+	if !slices.Equal(got.Teams, show.Teams) || !slices.Equal(got.TeamIDs, show.TeamIDs) {
+		t.Errorf("teams %v %v, want %v %v", got.Teams, got.TeamIDs, show.Teams, show.TeamIDs)
+	}
+	byTeam, _, err := store.ListShows(ctx, showstore.ShowFilter{TeamID: team.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byTeam) != 1 || byTeam[0].UID != show.UID {
+		t.Errorf("the team's shows are %v, want the re-enriched one", byTeam)
+	}
+
+	// A second pass finds nothing to change: the stored team IDs match.
+	w = httptest.NewRecorder()
+	a.handleEnrich(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"changes":[]`) || strings.Contains(body, "team_ids") {
+		t.Errorf("second re-enrich reported changes: %s", body)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/airtable"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// Field names publish-airtable expects in the target table.
+const (
+	airtableUID       = "UID"        // single line text; the merge key
+	airtableName      = "Name"       // single line text
+	airtableStart     = "Start"      // date with time
+	airtableTeams     = "Teams"      // multiple select
+	airtablePlayers   = "Players"    // long text, one per line
+	airtableLink      = "Link"       // URL
+	airtablePoster    = "Poster"     // attachment
+	airtablePosterURL = "Poster URL" // URL; the source of Poster
+)
+
+// runPublishAirtable upserts upcoming stored shows into an Airtable table.
+// Rows are matched on UID and never deleted. Airtable re-downloads
+// attachments on every write, so Poster is only sent when Poster URL
+// changes.
+func runPublishAirtable(args []string) {
+	fs := flag.NewFlagSet("publish-airtable", flag.ExitOnError)
+	base := fs.String("base", os.Getenv("AIRTABLE_BASE_ID"), "Airtable base ID (default $AIRTABLE_BASE_ID)")
+	defaultTable := os.Getenv("AIRTABLE_TABLE")
+	if defaultTable == "" {
+		defaultTable = "Shows"
+	}
+	table := fs.String("table", defaultTable, "Table name or ID (default $AIRTABLE_TABLE, else Shows)")
+	token := fs.String("token", os.Getenv("AIRTABLE_TOKEN"), "Personal access token with data.records read/write on the base (default $AIRTABLE_TOKEN)")
+	days := fs.Int("days", 90, "Publish shows starting within this many days")
+	dryRun := fs.Bool("dry-run", true, "If set, only log what would change in Airtable")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if *base == "" || *table == "" || *token == "" {
+		fmt.Fprintln(os.Stderr, "-base, -table and -token are required")
+		os.Exit(exitUsage)
+	}
+	if *days < 1 {
+		fmt.Fprintln(os.Stderr, "-days must be at least 1")
+		os.Exit(exitUsage)
+	}
+
+	ctx := context.Background()
+	store := openStore(ctx, showstore.ReadOnly())
+	defer store.Close()
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		exitErr(err)
+	}
+	y, m, d := time.Now().In(loc).Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	shows, _, err := store.ListShows(ctx, showstore.ShowFilter{From: from, To: from.AddDate(0, 0, *days)})
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+
+	client := airtable.New(*token, *base, *table)
+	existing, err := client.List(ctx, airtableUID, airtableName, airtableStart, airtableTeams, airtablePlayers, airtableLink, airtablePosterURL)
+	if err != nil {
+		exitErr(withCode(exitNetwork, err))
+	}
+	byUID := map[string]map[string]any{}
+	for _, r := range existing {
+		if uid, _ := r.Fields[airtableUID].(string); uid != "" {
+			byUID[uid] = r.Fields
+		}
+	}
+
+	var writes []airtable.Record
+	var created, updated, unchanged int
+	for _, s := range shows {
+		if s.Start == nil {
+			continue
+		}
+		fields := airtableFields(s)
+		old, exists := byUID[s.UID]
+		if exists && airtableCurrent(old, fields) {
+			unchanged++
+			continue
+		}
+		if !exists || old[airtablePosterURL] != fields[airtablePosterURL] {
+			fields[airtablePoster] = []map[string]string{}
+			if s.PostImageURL != "" {
+				fields[airtablePoster] = []map[string]string{{"url": s.PostImageURL}}
+			}
+		}
+		log := eventLogger(s)
+		if exists {
+			updated++
+			log.Debug("Airtable row out of date")
+		} else {
+			created++
+			log.Debug("Airtable row missing")
+		}
+		writes = append(writes, airtable.Record{Fields: fields})
+	}
+	if *dryRun {
+		slog.Info("dry run; not writing to Airtable", "would_create", created, "would_update", updated, "unchanged", unchanged)
+		return
+	}
+	n, err := client.Upsert(ctx, writes, airtableUID)
+	slog.Info("published to Airtable", "created", created, "updated", updated, "unchanged", unchanged, "written", n, "of", len(writes))
+	if err != nil {
+		exitErr(withCode(exitNetwork, err))
+	}
+}
+
+func airtableFields(s icalplayers.Event) map[string]any {
+	teams := nonEmpty(s.Teams)
+	if teams == nil {
+		teams = []string{}
+	}
+	return map[string]any{
+		airtableUID:       s.UID,
+		airtableName:      s.Summary,
+		airtableStart:     s.Start.UTC().Format(time.RFC3339),
+		airtableTeams:     teams,
+		airtablePlayers:   strings.Join(nonEmpty(s.Players), "\n"),
+		airtableLink:      s.URL,
+		airtablePosterURL: s.PostImageURL,
+	}
+}
+
+// airtableCurrent compares a listed row with the fields we'd write. Empty
+// cells are absent from Airtable's response, so missing means "".
+func airtableCurrent(old, want map[string]any) bool {
+	str := func(k string) string { v, _ := old[k].(string); return v }
+	for _, k := range []string{airtableName, airtablePlayers, airtableLink, airtablePosterURL} {
+		if str(k) != want[k] {
+			return false
+		}
+	}
+	t, err := time.Parse(time.RFC3339, str(airtableStart))
+	if err != nil || t.UTC().Format(time.RFC3339) != want[airtableStart] {
+		return false
+	}
+	var teams []string
+	if list, ok := old[airtableTeams].([]any); ok {
+		for _, v := range list {
+			if s, ok := v.(string); ok {
+				teams = append(teams, s)
+			}
+		}
+	}
+	return teamsEqualSorted(teams, want[airtableTeams].([]string))
+}
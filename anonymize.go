@@ -0,0 +1,84 @@
+package main
+
+import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// emailRe matches an email address, with the mailto: ICS organizers carry.
+var emailRe = regexp.MustCompile(`(?i)(?:mailto:)?[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`)
+
+// redactedEmail replaces every email address in an anonymized export.
+const redactedEmail = "redacted@example.invalid"
+
+// anonymizer swaps performer names for pseudonyms like "Player 3f9a0c1e"
+// and redacts email addresses, so an export keeps its shape (the same
+// performer gets the same pseudonym in every show) without naming anyone.
+// Pseudonyms are keyed HMACs: without the key they can't be reversed by
+// hashing a list of likely names.
+type anonymizer struct {
+	key []byte
+}
+
+// newAnonymizer keys pseudonyms with key, or a random key when it is
+// empty, in which case they only hold within one export.
+func newAnonymizer(key string) *anonymizer {
+	if key != "" {
+		return &anonymizer{key: []byte(key)}
+	}
+	b := make([]byte, 32)
+	rand.Read(b)
+	return &anonymizer{key: b}
+}
+
+func (a *anonymizer) pseudonym(name string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(strings.ToLower(strings.Join(strings.Fields(name), " "))))
+	return "Player " + hex.EncodeToString(mac.Sum(nil)[:4])
+}
+
+// events returns copies of events with players pseudonymized, their names
+// replaced wherever they appear in the description, the page lineup
+// rebuilt from the pseudonyms, and email addresses redacted. Names that
+// inference missed can still appear in descriptions.
+func (a *anonymizer) events(events []icalplayers.Event) []icalplayers.Event {
+	out := make([]icalplayers.Event, len(events))
+	for i, e := range events {
+		out[i] = a.event(e)
+	}
+	return out
+}
+
+func (a *anonymizer) event(e icalplayers.Event) icalplayers.Event {
+	names := slices.Clone(e.Players)
+	// Longest first, so "Ann Lee" is replaced before "Ann".
+	slices.SortFunc(names, func(x, y string) int { return cmp.Compare(len(y), len(x)) })
+	players := make([]string, len(e.Players))
+	for i, p := range e.Players {
+		players[i] = a.pseudonym(p)
+	}
+	e.Players = players
+	for _, n := range names {
+		if strings.TrimSpace(n) == "" {
+			continue
+		}
+		re := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(n) + `\b`)
+		e.Description = re.ReplaceAllLiteralString(e.Description, a.pseudonym(n))
+	}
+	e.Description = emailRe.ReplaceAllLiteralString(e.Description, redactedEmail)
+	e.Organizer = emailRe.ReplaceAllLiteralString(e.Organizer, redactedEmail)
+	if e.Details != nil && e.Details.Lineup != "" {
+		d := *e.Details
+		d.Lineup = cmp.Or(strings.Join(players, ", "), "[redacted]")
+		e.Details = &d
+	}
+	return e
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// runKeys manages API keys for serve -api-keys:
+//
+//	shopsync keys add -name NAME [-scope read|admin]
+//	shopsync keys list
+//	shopsync keys revoke -name NAME
+func runKeys(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: shopsync keys add|list|revoke [flags]")
+		os.Exit(exitUsage)
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet("keys "+cmd, flag.ExitOnError)
+	name := fs.String("name", "", "Key holder's name (add, revoke)")
+	scope := fs.String("scope", showstore.ScopeRead, "What the key may do (add): read for the JSON API, admin for that and /admin")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args[1:])
+	logOpts.setup()
+
+	ctx := context.Background()
+	switch cmd {
+	case "add", "revoke":
+		if *name == "" {
+			fmt.Fprintf(os.Stderr, "keys %s needs -name\n", cmd)
+			os.Exit(exitUsage)
+		}
+		if cmd == "add" && *scope != showstore.ScopeRead && *scope != showstore.ScopeAdmin {
+			exitErr(withCode(exitUsage, fmt.Errorf("invalid -scope %q (want read or admin)", *scope)))
+		}
+	case "list":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown keys command %q\n", cmd)
+		os.Exit(exitUsage)
+	}
+	var opts []showstore.Option
+	if cmd == "list" {
+		opts = append(opts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, opts...)
+	defer store.Close()
+
+	switch cmd {
+	case "add":
+		key, err := store.CreateAPIKey(ctx, *name, *scope)
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		slog.Info("issued API key", "name", *name, "scope", *scope)
+		fmt.Println(key)
+	case "revoke":
+		n, err := store.RevokeAPIKeys(ctx, *name)
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		if n == 0 {
+			exitErr(fmt.Errorf("no live keys for %q", *name))
+		}
+		slog.Info("revoked API keys", "name", *name, "count", n)
+	case "list":
+		keys, err := store.ListAPIKeys(ctx)
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tSCOPE\tCREATED\tREVOKED")
+		for _, k := range keys {
+			revoked := "-"
+			if k.RevokedAt != nil {
+				revoked = k.RevokedAt.Format(time.DateTime)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", k.Name, k.Scope, k.CreatedAt.Format(time.DateTime), revoked)
+		}
+		tw.Flush()
+	}
+}
+
+// apiKeyCacheTTL is how long a key lookup is reused, so a busy client
+// doesn't cost a query per request. Revocations take up to this long.
+const apiKeyCacheTTL = 30 * time.Second
+
+// apiKeys checks the keys presented to serve -api-keys.
+type apiKeys struct {
+	store *showstore.Store
+
+	mu    sync.Mutex
+	cache map[string]cachedKey // by key
+}
+
+type cachedKey struct {
+	key *showstore.APIKey // nil for unknown or revoked
+	at  time.Time
+}
+
+func newAPIKeys(store *showstore.Store) *apiKeys {
+	return &apiKeys{store: store, cache: map[string]cachedKey{}}
+}
+
+var errNoAPIKey = errors.New("API key required")
+
+// requestAPIKey returns the key on r, from "Authorization: Bearer <key>",
+// X-API-Key, or the basic auth password (for /admin in a browser).
+func requestAPIKey(r *http.Request) string {
+	if k, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(k)
+	}
+	if k := r.Header.Get("X-API-Key"); k != "" {
+		return k
+	}
+	if _, pass, ok := r.BasicAuth(); ok {
+		return pass
+	}
+	return ""
+}
+
+// check returns the live key r presents, or a status and error.
+func (a *apiKeys) check(r *http.Request, scope string) (*showstore.APIKey, int, error) {
+	given := requestAPIKey(r)
+	if given == "" {
+		return nil, http.StatusUnauthorized, errNoAPIKey
+	}
+	k, err := a.lookup(r.Context(), given)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if k == nil {
+		return nil, http.StatusUnauthorized, errors.New("invalid or revoked API key")
+	}
+	if !k.Allows(scope) {
+		return nil, http.StatusForbidden, fmt.Errorf("API key %q has %s scope, this needs %s", k.Name, k.Scope, scope)
+	}
+	return k, 0, nil
+}
+
+func (a *apiKeys) lookup(ctx context.Context, given string) (*showstore.APIKey, error) {
+	a.mu.Lock()
+	c, ok := a.cache[given]
+	a.mu.Unlock()
+	if ok && time.Since(c.at) < apiKeyCacheTTL {
+		return c.key, nil
+	}
+	k, err := a.store.LookupAPIKey(ctx, given)
+	if err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for g, c := range a.cache {
+		if time.Since(c.at) >= apiKeyCacheTTL {
+			delete(a.cache, g)
+		}
+	}
+	a.cache[given] = cachedKey{key: k, at: time.Now()}
+	return k, nil
+}
+
+// require wraps next so it only runs for requests with a key covering
+// scope.
+func (a *apiKeys) require(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		k, status, err := a.check(r, scope)
+		if err != nil {
+			if status == http.StatusInternalServerError {
+				slog.Error("looking up API key", "err", err)
+				err = errors.New("internal error")
+			}
+			if status == http.StatusUnauthorized {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="shopsync"`)
+			}
+			writeError(w, status, err)
+			return
+		}
+		slog.Debug("API key accepted", "name", k.Name, "scope", k.Scope, "path", r.URL.Path)
+		next(w, r)
+	}
+}
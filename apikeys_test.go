@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+func TestAPIKeyScopes(t *testing.T) {
+	a := newAPIKeys(nil)
+	// Seed the cache so no lookup reaches the (absent) store.
+	now := time.Now()
+	a.cache["ssk_read"] = cachedKey{key: &showstore.APIKey{Name: "site", Scope: showstore.ScopeRead}, at: now}
+	a.cache["ssk_admin"] = cachedKey{key: &showstore.APIKey{Name: "staff", Scope: showstore.ScopeAdmin}, at: now}
+	a.cache["ssk_revoked"] = cachedKey{at: now} // what lookup caches for either
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	bearer := func(k string) func(*http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+k) }
+	}
+	tests := []struct {
+		name  string
+		scope string
+		auth  func(*http.Request)
+		want  int
+	}{
+		{"no key", showstore.ScopeRead, func(*http.Request) {}, http.StatusUnauthorized},
+		{"unknown or revoked key", showstore.ScopeRead, bearer("ssk_revoked"), http.StatusUnauthorized},
+		{"read key, read scope", showstore.ScopeRead, bearer("ssk_read"), http.StatusOK},
+		{"read key, admin scope", showstore.ScopeAdmin, bearer("ssk_read"), http.StatusForbidden},
+		{"admin key, read scope", showstore.ScopeRead, bearer("ssk_admin"), http.StatusOK},
+		{"admin key, admin scope", showstore.ScopeAdmin, bearer("ssk_admin"), http.StatusOK},
+		{"X-API-Key header", showstore.ScopeRead, func(r *http.Request) { r.Header.Set("X-API-Key", "ssk_read") }, http.StatusOK},
+		{"basic auth password", showstore.ScopeAdmin, func(r *http.Request) { r.SetBasicAuth("admin", "ssk_admin") }, http.StatusOK},
+		{"basic auth, read key", showstore.ScopeAdmin, func(r *http.Request) { r.SetBasicAuth("admin", "ssk_read") }, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/shows", nil)
+			tt.auth(r)
+			w := httptest.NewRecorder()
+			a.require(tt.scope, ok)(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if challenge := w.Header().Get("WWW-Authenticate"); (tt.want == http.StatusUnauthorized) != (challenge != "") {
+				t.Errorf("WWW-Authenticate %q with status %d", challenge, w.Code)
+			}
+		})
+	}
+}
+
+// Keys are looked up by hash, and a revoked key stops working once the
+// cached lookup expires.
+func TestAPIKeyRevoke(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+	key, err := store.CreateAPIKey(ctx, "site", showstore.ScopeRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newAPIKeys(store)
+	check := func(key string) int {
+		r := httptest.NewRequest("GET", "/shows", nil)
+		r.Header.Set("Authorization", "Bearer "+key)
+		_, status, _ := a.check(r, showstore.ScopeRead)
+		return status
+	}
+	if status := check(key); status != 0 {
+		t.Fatalf("new key: status %d", status)
+	}
+	if status := check(key + "x"); status != http.StatusUnauthorized {
+		t.Errorf("unknown key: status %d, want 401", status)
+	}
+	if _, err := store.RevokeAPIKeys(ctx, "site"); err != nil {
+		t.Fatal(err)
+	}
+	a.cache = map[string]cachedKey{}
+	if status := check(key); status != http.StatusUnauthorized {
+		t.Errorf("revoked key: status %d, want 401", status)
+	}
+}
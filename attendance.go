@@ -0,0 +1,278 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// countFlag is an optional non-negative head count; nil until set.
+type countFlag struct{ n *int }
+
+func (c *countFlag) String() string {
+	if c.n == nil {
+		return ""
+	}
+	return strconv.Itoa(*c.n)
+}
+
+func (c *countFlag) Set(s string) error {
+	n, err := parseCount(s)
+	if err != nil {
+		return err
+	}
+	c.n = n
+	return nil
+}
+
+// parseCount parses a head count; "" is unknown (nil).
+func parseCount(s string) (*int, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid count %q: want a whole number, 0 or more", s)
+	}
+	return &n, nil
+}
+
+// runAttendance records and lists house counts:
+//
+//	shopsync attendance set -show UID [-count N] [-capacity N]
+//	shopsync attendance list [-days 30]
+func runAttendance(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: shopsync attendance set|list [flags]")
+		os.Exit(exitUsage)
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet("attendance "+cmd, flag.ExitOnError)
+	uid := fs.String("show", "", "UID of the show (set)")
+	var count, capacity countFlag
+	fs.Var(&count, "count", "How many people came (set)")
+	fs.Var(&capacity, "capacity", "How many seats the room had (set)")
+	days := fs.Int("days", 30, "List shows from this many days back (list)")
+	output := fs.String("output", "text", "Output format: text or json (list)")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args[1:])
+	logOpts.setup()
+
+	switch cmd {
+	case "set":
+		if *uid == "" {
+			exitErr(withCode(exitUsage, errors.New("attendance set needs -show")))
+		}
+		if count.n == nil && capacity.n == nil {
+			exitErr(withCode(exitUsage, errors.New("attendance set needs -count, -capacity or both")))
+		}
+	case "list":
+		if *days < 1 {
+			exitErr(withCode(exitUsage, errors.New("-days must be at least 1")))
+		}
+		if !validOutput(*output) {
+			exitErr(withCode(exitUsage, fmt.Errorf("invalid -output %q (want text or json)", *output)))
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown attendance command %q\n", cmd)
+		os.Exit(exitUsage)
+	}
+	ctx := context.Background()
+	var opts []showstore.Option
+	if cmd == "list" {
+		opts = append(opts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, opts...)
+	defer store.Close()
+
+	switch cmd {
+	case "set":
+		found, err := store.SetAttendance(ctx, *uid, capacity.n, count.n)
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		if !found {
+			exitErr(fmt.Errorf("no show with uid %q", *uid))
+		}
+		slog.Info("recorded attendance", "uid", *uid, "count", count.String(), "capacity", capacity.String())
+	case "list":
+		now := time.Now()
+		recs, err := store.ListAttendance(ctx, now.AddDate(0, 0, -*days), now)
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		if *output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(append([]showstore.ShowAttendance{}, recs...)); err != nil {
+				exitErr(err)
+			}
+			return
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "DATE\tSHOW\tATTENDANCE\tCAPACITY\tUID")
+		for _, a := range recs {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", inOutputTZ(*a.Start).Format("2006-01-02 15:04"), truncateStr(a.Summary, 40), countString(a.Attendance), countString(a.Capacity), a.UID)
+		}
+		tw.Flush()
+	}
+}
+
+func countString(n *int) string {
+	if n == nil {
+		return "-"
+	}
+	return strconv.Itoa(*n)
+}
+
+// attendanceTotal sums the recorded house counts of a team or series.
+type attendanceTotal struct {
+	Name       string `json:"name"`
+	Shows      int    `json:"shows"`
+	Attendance int    `json:"attendance"`
+	Average    int    `json:"average"` // per show, rounded
+	// FillRate is attendance over capacity, in percent, for the shows
+	// with both recorded; nil when none have a capacity.
+	FillRate *int `json:"fillRate,omitempty"`
+}
+
+// attendanceBy totals recs by the names key returns for each (a show with
+// several teams counts toward each), busiest first.
+func attendanceBy(recs []showstore.ShowAttendance, key func(showstore.ShowAttendance) []string) []attendanceTotal {
+	type acc struct {
+		attendanceTotal
+		seated, seats int
+	}
+	byName := map[string]*acc{}
+	for _, a := range recs {
+		if a.Attendance == nil {
+			continue
+		}
+		for _, name := range key(a) {
+			t := byName[name]
+			if t == nil {
+				t = &acc{attendanceTotal: attendanceTotal{Name: name}}
+				byName[name] = t
+			}
+			t.Shows++
+			t.Attendance += *a.Attendance
+			if a.Capacity != nil && *a.Capacity > 0 {
+				t.seated += *a.Attendance
+				t.seats += *a.Capacity
+			}
+		}
+	}
+	out := make([]attendanceTotal, 0, len(byName))
+	for _, t := range byName {
+		t.Average = (t.Attendance + t.Shows/2) / t.Shows
+		if t.seats > 0 {
+			rate := (100*t.seated + t.seats/2) / t.seats
+			t.FillRate = &rate
+		}
+		out = append(out, t.attendanceTotal)
+	}
+	slices.SortFunc(out, func(a, b attendanceTotal) int {
+		return cmp.Or(cmp.Compare(b.Attendance, a.Attendance), cmp.Compare(a.Name, b.Name))
+	})
+	return out
+}
+
+// attendanceTeams keys a record by its teams; shows without one count as
+// "(no team)".
+func attendanceTeams(a showstore.ShowAttendance) []string {
+	if len(a.Teams) == 0 {
+		return []string{"(no team)"}
+	}
+	return a.Teams
+}
+
+// attendanceSeries groups records into series: shows whose summaries
+// normalize alike (normalizeSummary), named after the latest one's
+// summary. recs must be oldest first, as ListAttendance returns them.
+func attendanceSeries(recs []showstore.ShowAttendance) func(showstore.ShowAttendance) []string {
+	names := map[string]string{}
+	for _, a := range recs {
+		names[normalizeSummary(a.Summary)] = a.Summary
+	}
+	return func(a showstore.ShowAttendance) []string {
+		return []string{names[normalizeSummary(a.Summary)]}
+	}
+}
+
+// attendanceRequest is the body of POST /admin/shows/{uid}/attendance.
+// A missing or null field leaves the stored value alone.
+type attendanceRequest struct {
+	Capacity   *int `json:"capacity"`
+	Attendance *int `json:"attendance"`
+}
+
+// handleAttendance records a show's capacity and attendance, from JSON or
+// the edit page's form (empty fields are left alone), and answers with
+// the stored counts; browsers go back to the edit page.
+func (a *admin) handleAttendance(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
+	var req attendanceRequest
+	html := strings.Contains(r.Header.Get("Accept"), "text/html")
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %w", err))
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var err error
+		if req.Capacity, err = parseCount(r.PostForm.Get("capacity")); err == nil {
+			req.Attendance, err = parseCount(r.PostForm.Get("attendance"))
+		}
+		if err != nil {
+			if html {
+				a.redirect(w, r, "/admin/shows/"+uid, err.Error()+"; nothing saved.")
+				return
+			}
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	for _, n := range []*int{req.Capacity, req.Attendance} {
+		if n != nil && *n < 0 {
+			writeError(w, http.StatusBadRequest, errors.New("counts must not be negative"))
+			return
+		}
+	}
+	found, err := a.store.SetAttendance(r.Context(), uid, req.Capacity, req.Attendance)
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	slog.Info("admin recorded attendance", "uid", uid, "attendance", countString(req.Attendance), "capacity", countString(req.Capacity))
+	if html {
+		a.redirect(w, r, "/admin/shows/"+uid, "Attendance saved.")
+		return
+	}
+	rec, err := a.store.GetAttendance(r.Context(), uid)
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
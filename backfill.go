@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+	"github.com/tsny/shopsync/pkg/wpevents"
+)
+
+// runBackfillImages looks up post images for stored shows that have none and
+// writes only post_image_url, leaving the rest of each row alone.
+func runBackfillImages(args []string) {
+	fs := flag.NewFlagSet("backfill-images", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", true, "If set, only report the images that would be stored")
+	workers := fs.Int("image-concurrency", icalplayers.DefaultImageWorkers, "Number of event pages fetched in parallel")
+	rate := fs.Float64("rate-limit", icalplayers.DefaultImageRate, "Max event page fetches per second (0 = unlimited)")
+	quiet := fs.Bool("quiet", false, "Hide progress output")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if *workers < 1 {
+		fmt.Fprintf(os.Stderr, "-image-concurrency must be at least 1, got %d\n", *workers)
+		os.Exit(exitUsage)
+	}
+	if *rate < 0 {
+		fmt.Fprintf(os.Stderr, "-rate-limit must not be negative, got %g\n", *rate)
+		os.Exit(exitUsage)
+	}
+
+	ctx := context.Background()
+	var opts []showstore.Option
+	if *dryRun {
+		opts = append(opts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, opts...)
+	defer store.Close()
+
+	missing, err := store.GetShowsWithoutImageURL(ctx)
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	var events []icalplayers.Event
+	for _, m := range missing {
+		if m.URL == "" {
+			slog.Debug("show has no event URL; skipping", "uid", m.UID, "summary", m.Summary)
+			continue
+		}
+		events = append(events, icalplayers.Event{UID: m.UID, Summary: m.Summary, URL: m.URL})
+	}
+	slog.Info("shows missing images", "count", len(missing), "with_url", len(events))
+	if len(events) == 0 {
+		return
+	}
+
+	prog := newProgress(*quiet)
+	icalplayers.EnrichImages(ctx, events, icalplayers.WithImageWorkers(*workers), icalplayers.WithImageRate(*rate),
+		icalplayers.WithImageProgress(func(done, total int) { prog.update("fetch", done, total) }))
+	prog.finish()
+
+	var found, updated, failed int
+	for _, e := range events {
+		if e.PostImageURL == "" {
+			continue
+		}
+		found++
+		img := wpevents.RewriteCdnCgiURL(e.PostImageURL)
+		log := eventLogger(e).With("image", img)
+		if *dryRun {
+			log.Info("dry run; would set post image")
+			continue
+		}
+		if err := store.UpdateShowImageURL(ctx, e.UID, img); err != nil {
+			log.Error("could not update post image", "err", err)
+			failed++
+			continue
+		}
+		log.Debug("set post image")
+		updated++
+	}
+	slog.Info("backfill finished", "looked_up", len(events), "found", found, "updated", updated, "failed", failed, "dry_run", *dryRun)
+	if failed > 0 {
+		exitErr(withCode(exitPartial, fmt.Errorf("%d of %d image updates failed", failed, found)))
+	}
+}
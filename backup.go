@@ -0,0 +1,365 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// backupFormat is the archive layout version written to the manifest.
+const backupFormat = 1
+
+// restoreBatch is how many rows restore inserts per statement.
+const restoreBatch = 500
+
+// backupManifest is manifest.json, the first entry of a backup archive.
+// The rest are tables/<table>.jsonl (one row_to_json object per line, in
+// showstore.BackupTables order) and images/<file> for downloaded posters.
+type backupManifest struct {
+	Format        int            `json:"format"`
+	SchemaVersion int            `json:"schemaVersion"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	Version       string         `json:"version"`
+	Tables        map[string]int `json:"tables"`
+	Images        int            `json:"images"`
+}
+
+func runBackup(args []string) {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := fs.String("out", "", "Write the archive to this file instead of stdout")
+	imagesDir := fs.String("images-dir", "", "Directory of downloaded post images to include (skipped if empty)")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+
+	ctx := context.Background()
+	store := openStore(ctx, showstore.ReadOnly())
+	defer store.Close()
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			exitErr(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	m, err := writeBackup(ctx, store, w, *imagesDir)
+	if err != nil {
+		if *out != "" {
+			os.Remove(*out)
+		}
+		exitErr(err)
+	}
+	slog.Info("wrote backup", "shows", m.Tables["shows"], "teams", m.Tables["Team"], "images", m.Images, "path", cmp.Or(*out, "stdout"))
+}
+
+// writeBackup dumps every table to a temporary file first, so the manifest
+// can lead the archive with row counts and each tar entry knows its size.
+func writeBackup(ctx context.Context, store *showstore.Store, w io.Writer, imagesDir string) (*backupManifest, error) {
+	tmp, err := os.MkdirTemp("", "shopsync-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	m := &backupManifest{
+		Format:        backupFormat,
+		SchemaVersion: showstore.SchemaVersion,
+		CreatedAt:     time.Now().UTC(),
+		Version:       version,
+		Tables:        map[string]int{},
+	}
+	for _, table := range showstore.BackupTables {
+		n, err := dumpTable(ctx, store, table, filepath.Join(tmp, table+".jsonl"))
+		if err != nil {
+			return nil, dbErr(fmt.Errorf("dumping %s: %w", table, err))
+		}
+		m.Tables[table] = n
+	}
+	var images []string
+	if imagesDir != "" {
+		entries, err := os.ReadDir(imagesDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.Type().IsRegular() {
+				images = append(images, e.Name())
+			}
+		}
+		m.Images = len(images)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	hdr := &tar.Header{Name: "manifest.json", Mode: 0o644, Size: int64(len(manifest)), ModTime: m.CreatedAt}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return nil, err
+	}
+	for _, table := range showstore.BackupTables {
+		if err := addTarFile(tw, filepath.Join(tmp, table+".jsonl"), "tables/"+table+".jsonl"); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range images {
+		if err := addTarFile(tw, filepath.Join(imagesDir, name), "images/"+name); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return m, gz.Close()
+}
+
+func dumpTable(ctx context.Context, store *showstore.Store, table, file string) (int, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	bw := bufio.NewWriter(f)
+	n := 0
+	err = store.DumpTable(ctx, table, func(row json.RawMessage) error {
+		n++
+		if _, err := bw.Write(row); err != nil {
+			return err
+		}
+		return bw.WriteByte('\n')
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	return n, f.Close()
+}
+
+func addTarFile(tw *tar.Writer, file, name string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: fi.Size(), ModTime: fi.ModTime()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+func runRestore(args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", true, "If set, only read the archive and report what would be restored")
+	imagesDir := fs.String("images-dir", "", "Directory to extract the archive's post images into (skipped if empty)")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: shopsync restore [flags] <archive|->")
+		os.Exit(exitUsage)
+	}
+
+	var r io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			exitErr(err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	ctx := context.Background()
+	var restorer *showstore.Restorer
+	if !*dryRun {
+		store := openStore(ctx)
+		defer store.Close()
+		if err := store.EnsureTeamTable(ctx); err != nil {
+			exitErr(dbErr(err))
+		}
+		if err := store.Migrate(ctx); err != nil {
+			exitErr(dbErr(err))
+		}
+		var err error
+		if restorer, err = store.BeginRestore(ctx); err != nil {
+			if errors.Is(err, showstore.ErrNotEmpty) {
+				exitErr(fmt.Errorf("%w; restore only fills an empty database", err))
+			}
+			exitErr(dbErr(err))
+		}
+		defer restorer.Rollback(ctx)
+	}
+
+	m, counts, images, err := readBackup(ctx, r, restorer, *imagesDir)
+	if err != nil {
+		exitErr(err)
+	}
+	if restorer != nil {
+		if err := restorer.Commit(ctx); err != nil {
+			exitErr(dbErr(err))
+		}
+	}
+	for _, table := range showstore.BackupTables {
+		if n, ok := counts[table]; ok {
+			fmt.Printf("%-17s %d\n", table, n)
+		}
+	}
+	if *imagesDir != "" {
+		fmt.Printf("%-17s %d\n", "images", images)
+	}
+	msg := "restored backup"
+	if *dryRun {
+		msg = "dry run; would restore backup"
+	}
+	slog.Info(msg, "created", m.CreatedAt.Format(time.RFC3339), "schema", m.SchemaVersion, "shows", counts["shows"])
+}
+
+// readBackup walks the archive, inserting each table's rows through
+// restorer (counting only when it is nil) and extracting images into
+// imagesDir when set. The manifest must come first: an archive from a
+// newer schema than this binary knows would silently lose columns.
+func readBackup(ctx context.Context, r io.Reader, restorer *showstore.Restorer, imagesDir string) (*backupManifest, map[string]int, int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("not a backup archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != "manifest.json" {
+		return nil, nil, 0, errors.New("not a backup archive: manifest.json is missing")
+	}
+	var m backupManifest
+	if err := json.NewDecoder(tr).Decode(&m); err != nil {
+		return nil, nil, 0, fmt.Errorf("reading manifest: %w", err)
+	}
+	if m.Format != backupFormat {
+		return nil, nil, 0, fmt.Errorf("unsupported backup format %d (want %d)", m.Format, backupFormat)
+	}
+	if m.SchemaVersion > showstore.SchemaVersion {
+		return nil, nil, 0, fmt.Errorf("backup is from schema v%d, newer than this build's v%d; upgrade shopsync first", m.SchemaVersion, showstore.SchemaVersion)
+	}
+	if imagesDir != "" && restorer != nil {
+		if err := os.MkdirAll(imagesDir, 0o755); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+
+	counts := map[string]int{}
+	images := 0
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		switch dir, name := path.Split(hdr.Name); dir {
+		case "tables/":
+			table := strings.TrimSuffix(name, ".jsonl")
+			n, err := restoreTable(ctx, tr, restorer, table)
+			if err != nil {
+				return nil, nil, 0, dbErr(fmt.Errorf("restoring %s: %w", table, err))
+			}
+			counts[table] = n
+		case "images/":
+			if imagesDir == "" || name == "" || hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			images++
+			if restorer == nil {
+				continue
+			}
+			if err := extractImage(tr, filepath.Join(imagesDir, name), hdr.ModTime); err != nil {
+				return nil, nil, 0, err
+			}
+		default:
+			slog.Warn("skipping unknown archive entry", "name", hdr.Name)
+		}
+	}
+	return &m, counts, images, nil
+}
+
+// restoreTable inserts a tables/*.jsonl entry in batches of restoreBatch
+// rows and returns how many rows it held.
+func restoreTable(ctx context.Context, r io.Reader, restorer *showstore.Restorer, table string) (int, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 64<<20)
+	var batch []json.RawMessage
+	n := 0
+	flush := func() error {
+		if restorer != nil && len(batch) > 0 {
+			if _, err := restorer.Insert(ctx, table, batch); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return n, fmt.Errorf("line %d is not JSON", n+1)
+		}
+		batch = append(batch, json.RawMessage(append([]byte(nil), line...)))
+		n++
+		if len(batch) == restoreBatch {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return n, err
+	}
+	return n, flush()
+}
+
+func extractImage(r io.Reader, file string, modTime time.Time) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(file, modTime, modTime)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// maxBlackoutDays bounds one blackout range or calendar event, so a typo'd
+// year doesn't black out a decade.
+const maxBlackoutDays = 366
+
+// blackouts are the days the venue is dark (holidays, closures), keyed by
+// venue date ("2006-01-02"), with why, e.g. "Thanksgiving".
+type blackouts map[string]string
+
+// blackoutShow is a show on a blackout date.
+type blackoutShow struct {
+	UID     string    `json:"uid"`
+	Summary string    `json:"summary"`
+	Start   time.Time `json:"start"`
+	Reason  string    `json:"reason"`
+}
+
+var blackoutDateRe = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})(?:\.\.(\d{4}-\d{2}-\d{2}))?\s*(?:=\s*(.*))?$`)
+
+// loadBlackouts reads -blackout values: a date or range of dates in venue
+// time with an optional reason ("2026-11-26=Thanksgiving",
+// "2026-12-24..2026-12-26=Winter break"), or an .ics file or URL whose
+// events black out the days they cover, named by their summaries.
+// Recurring events aren't expanded; holiday feeds list each year anyway.
+func loadBlackouts(ctx context.Context, specs []string, loc *time.Location) (blackouts, error) {
+	b := blackouts{}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if m := blackoutDateRe.FindStringSubmatch(spec); m != nil {
+			from, err := time.ParseInLocation(time.DateOnly, m[1], loc)
+			if err != nil {
+				return nil, fmt.Errorf("blackout %q: %w", spec, err)
+			}
+			to := from
+			if m[2] != "" {
+				if to, err = time.ParseInLocation(time.DateOnly, m[2], loc); err != nil {
+					return nil, fmt.Errorf("blackout %q: %w", spec, err)
+				}
+			}
+			if err := b.add(from, to, m[3]); err != nil {
+				return nil, fmt.Errorf("blackout %q: %w", spec, err)
+			}
+			continue
+		}
+		if err := b.addCalendar(ctx, spec, loc); err != nil {
+			return nil, fmt.Errorf("blackout calendar %s: %w", spec, err)
+		}
+	}
+	return b, nil
+}
+
+// add blacks out the days from through to, inclusive. The first reason
+// given for a day wins.
+func (b blackouts) add(from, to time.Time, reason string) error {
+	if reason = strings.TrimSpace(reason); reason == "" {
+		reason = "blackout"
+	}
+	if to.Before(from) {
+		return errors.New("ends before it starts")
+	}
+	for d, n := from, 0; !d.After(to); d, n = d.AddDate(0, 0, 1), n+1 {
+		if n == maxBlackoutDays {
+			return fmt.Errorf("longer than %d days", maxBlackoutDays)
+		}
+		if _, ok := b[d.Format(time.DateOnly)]; !ok {
+			b[d.Format(time.DateOnly)] = reason
+		}
+	}
+	return nil
+}
+
+// addCalendar blacks out the days covered by the events of the calendar
+// at src. All-day events (DTSTART;VALUE=DATE) end the day before DTEND, as
+// RFC 5545 has it; timed ones cover the venue dates they touch. Cancelled
+// events are skipped.
+func (b blackouts) addCalendar(ctx context.Context, src string, loc *time.Location) error {
+	data, err := readBlackoutCalendar(ctx, src)
+	if err != nil {
+		return err
+	}
+	cal, err := ics.ParseCalendar(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %w", icalplayers.ErrParse, err)
+	}
+	for _, ve := range cal.Events() {
+		if p := ve.GetProperty(ics.ComponentPropertyStatus); p != nil && strings.EqualFold(p.Value, "CANCELLED") {
+			continue
+		}
+		summary := ""
+		if p := ve.GetProperty(ics.ComponentPropertySummary); p != nil {
+			summary = p.Value
+		}
+		start := ve.GetProperty(ics.ComponentPropertyDtStart)
+		if start == nil {
+			continue
+		}
+		var from, to time.Time
+		if len(start.Value) == len("20060102") {
+			if from, err = time.ParseInLocation("20060102", start.Value, loc); err != nil {
+				return fmt.Errorf("%q: DTSTART %q: %w", summary, start.Value, err)
+			}
+			to = from
+			if end := ve.GetProperty(ics.ComponentPropertyDtEnd); end != nil {
+				if t, err := time.ParseInLocation("20060102", end.Value, loc); err == nil && t.After(from) {
+					to = t.AddDate(0, 0, -1)
+				}
+			}
+		} else {
+			s, err := ve.GetStartAt()
+			if err != nil {
+				return fmt.Errorf("%q: %w", summary, err)
+			}
+			s = s.In(loc)
+			from = time.Date(s.Year(), s.Month(), s.Day(), 0, 0, 0, 0, loc)
+			to = from
+			if e, err := ve.GetEndAt(); err == nil && e.After(s) {
+				e = e.Add(-time.Nanosecond).In(loc)
+				to = time.Date(e.Year(), e.Month(), e.Day(), 0, 0, 0, 0, loc)
+			}
+		}
+		if err := b.add(from, to, summary); err != nil {
+			return fmt.Errorf("%q: %w", summary, err)
+		}
+	}
+	return nil
+}
+
+// readBlackoutCalendar returns the file or http(s) document at src.
+func readBlackoutCalendar(ctx context.Context, src string) ([]byte, error) {
+	if !isURL(src) {
+		return os.ReadFile(src)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpclient.Default.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+}
+
+// showsOnBlackouts lists the events starting, in venue time, on a
+// blackout date.
+func showsOnBlackouts(events []icalplayers.Event, b blackouts, loc *time.Location) []blackoutShow {
+	var out []blackoutShow
+	if len(b) == 0 {
+		return out
+	}
+	for _, e := range events {
+		if e.Start == nil {
+			continue
+		}
+		if reason, ok := b[e.Start.In(loc).Format(time.DateOnly)]; ok {
+			out = append(out, blackoutShow{UID: e.UID, Summary: e.Summary, Start: inOutputTZ(*e.Start), Reason: reason})
+		}
+	}
+	slices.SortFunc(out, func(a, b blackoutShow) int { return a.Start.Compare(b.Start) })
+	return out
+}
+
+// blackoutFindings turns shows on blackout dates into lint warnings: the
+// venue may mean to play a holiday, but usually forgot to cancel.
+func blackoutFindings(shows []blackoutShow, loc *time.Location) []lintFinding {
+	out := make([]lintFinding, 0, len(shows))
+	for _, s := range shows {
+		out = append(out, lintFinding{Severity: "warning", Check: "blackout", UID: s.UID, Summary: s.Summary,
+			Message: fmt.Sprintf("starts %s, a blackout date (%s)", s.Start.In(displayLoc(loc)).Format("Mon Jan 2 3:04 PM"), s.Reason)})
+	}
+	return out
+}
+
+// blackoutStage re-reads -blackout on every sync, like -overrides, and
+// lists the shows landing on a blackout date in the report. It never stops
+// the sync, and an unreadable blackout calendar is only a warning.
+func blackoutStage(specs []string) stage {
+	return stage{name: "blackouts", run: func(ctx context.Context, st *syncState) error {
+		loc, err := time.LoadLocation(venueTimezone)
+		if err != nil {
+			return err
+		}
+		b, err := loadBlackouts(ctx, specs, loc)
+		if err != nil {
+			slog.Warn("could not read blackout dates; not checking them", "err", err)
+			st.report.warn("%v", err)
+			return nil
+		}
+		st.report.Blackouts = showsOnBlackouts(st.events, b, loc)
+		for _, s := range st.report.Blackouts {
+			slog.Warn("show on a blackout date", "uid", s.UID, "summary", s.Summary, "start", s.Start, "reason", s.Reason)
+		}
+		return nil
+	}}
+}
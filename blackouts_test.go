@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+func TestLoadBlackouts(t *testing.T) {
+	loc, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal := filepath.Join(t.TempDir(), "holidays.ics")
+	err = os.WriteFile(cal, []byte("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n"+
+		// All-day: DTEND is the day after the last one.
+		"BEGIN:VEVENT\r\nUID:1\r\nSUMMARY:Thanksgiving\r\nDTSTART;VALUE=DATE:20261126\r\nDTEND;VALUE=DATE:20261128\r\nEND:VEVENT\r\n"+
+		// Timed, past midnight venue time: both dates.
+		"BEGIN:VEVENT\r\nUID:2\r\nSUMMARY:Party\r\nDTSTART:20261231T230000Z\r\nDTEND:20270101T070000Z\r\nEND:VEVENT\r\n"+
+		// Timed, ending exactly at midnight venue time: one date.
+		"BEGIN:VEVENT\r\nUID:3\r\nSUMMARY:Gala\r\nDTSTART:20261010T000000Z\r\nDTEND:20261010T050000Z\r\nEND:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\nUID:4\r\nSUMMARY:Called off\r\nSTATUS:CANCELLED\r\nDTSTART;VALUE=DATE:20261201\r\nEND:VEVENT\r\n"+
+		"END:VCALENDAR\r\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		specs []string
+		want  blackouts
+	}{
+		{"one day", []string{"2026-11-26=Thanksgiving"}, blackouts{"2026-11-26": "Thanksgiving"}},
+		{"no reason", []string{" 2026-11-26 "}, blackouts{"2026-11-26": "blackout"}},
+		{"range is inclusive", []string{"2026-12-24..2026-12-26 = Winter break"},
+			blackouts{"2026-12-24": "Winter break", "2026-12-25": "Winter break", "2026-12-26": "Winter break"}},
+		{"one-day range", []string{"2026-12-24..2026-12-24"}, blackouts{"2026-12-24": "blackout"}},
+		{"first reason wins", []string{"2026-12-25=Christmas", "2026-12-24..2026-12-26=Winter break"},
+			blackouts{"2026-12-24": "Winter break", "2026-12-25": "Christmas", "2026-12-26": "Winter break"}},
+		{"calendar", []string{cal}, blackouts{
+			"2026-11-26": "Thanksgiving", "2026-11-27": "Thanksgiving",
+			"2026-12-31": "Party", "2027-01-01": "Party",
+			"2026-10-09": "Gala",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadBlackouts(context.Background(), tt.specs, loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"2026-12-26..2026-12-24", "2026-01-01..2027-01-02", "2026-02-30", "no-such-file.ics"} {
+		if _, err := loadBlackouts(context.Background(), []string{bad}, loc); err == nil {
+			t.Errorf("loadBlackouts(%q) succeeded, want an error", bad)
+		}
+	}
+	if _, err := loadBlackouts(context.Background(), []string{"2026-01-01..2027-01-01"}, loc); err != nil {
+		t.Errorf("a %d-day range: %v", maxBlackoutDays, err)
+	}
+}
+
+func TestShowsOnBlackouts(t *testing.T) {
+	loc, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := blackouts{"2026-11-26": "Thanksgiving"}
+	show := func(uid string, t time.Time) icalplayers.Event {
+		return icalplayers.Event{UID: uid, Summary: uid, Start: &t}
+	}
+	events := []icalplayers.Event{
+		show("late", time.Date(2026, 11, 26, 23, 59, 0, 0, loc)),
+		show("day before", time.Date(2026, 11, 25, 23, 59, 0, 0, loc)),
+		show("midnight after", time.Date(2026, 11, 27, 0, 0, 0, 0, loc)),
+		show("early", time.Date(2026, 11, 26, 0, 0, 0, 0, loc)),
+		// The 27th in UTC, still the 26th at the venue.
+		show("utc", time.Date(2026, 11, 27, 3, 0, 0, 0, time.UTC)),
+		{UID: "undated"},
+	}
+	var got []string
+	for _, s := range showsOnBlackouts(events, b, loc) {
+		if s.Reason != "Thanksgiving" {
+			t.Errorf("%s: reason %q", s.UID, s.Reason)
+		}
+		got = append(got, s.UID)
+	}
+	if want := []string{"early", "utc", "late"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := showsOnBlackouts(events, blackouts{}, loc); len(got) != 0 {
+		t.Errorf("no blackouts flagged %v", got)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/daterange"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+const (
+	// boardKeep is how long after its start a show stays on the board.
+	boardKeep = 2 * time.Hour
+	// boardRefresh is how often the board page reloads its shows.
+	boardRefresh = time.Minute
+	// boardPosterWidth is the width the board asks /images for.
+	boardPosterWidth = 800
+)
+
+// boardShow is one show on the lobby board.
+type boardShow struct {
+	UID       string    `json:"uid"`
+	Summary   string    `json:"summary"`
+	Start     time.Time `json:"start"`
+	Time      string    `json:"time"` // e.g. "8:00 PM", venue time
+	Teams     []string  `json:"teams,omitempty"`
+	Players   []string  `json:"players,omitempty"`
+	PosterURL string    `json:"posterUrl,omitempty"` // through /images, scaled for a TV
+	Started   bool      `json:"started"`
+}
+
+// boardData is the body of GET /board.
+type boardData struct {
+	Title       string      `json:"title"`
+	Date        string      `json:"date"` // e.g. "Friday, March 6"
+	Now         time.Time   `json:"now"`
+	Shows       []boardShow `json:"shows"`
+	RefreshSecs int         `json:"refreshSeconds"`
+}
+
+// handleBoard serves tonight's shows for the lobby TV: shows starting
+// today in venue time, each kept until boardKeep after it starts (past
+// midnight too). It is an auto-refreshing HTML page with posters and
+// countdowns, or JSON with ?format=json or Accept: application/json.
+func (s *server) handleBoard(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "application/json") {
+		format = "json"
+	}
+	if format != "" && format != "json" && format != "html" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid format %q (want html or json)", format))
+		return
+	}
+	now := time.Now().In(s.loc)
+	today, err := daterange.Parse("today", now)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	// Just after midnight, the late shows of the night before still count.
+	from := today.Start
+	if early := now.Add(-boardKeep); early.Before(from) {
+		from = early
+	}
+	shows, _, err := s.store.ListShows(r.Context(), showstore.ShowFilter{From: from, To: today.End, Types: []string{icalplayers.TypeShow}})
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	loc := displayLoc(s.loc)
+	data := boardData{Title: calendarName, Date: venueLocale.LongDate(now.In(loc)), Now: now, Shows: []boardShow{}, RefreshSecs: int(boardRefresh.Seconds())}
+	for _, e := range shows {
+		if e.Start == nil || now.Sub(*e.Start) > boardKeep {
+			continue
+		}
+		data.Shows = append(data.Shows, boardShowOf(e, now, loc))
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	if format == "json" {
+		writeJSON(w, http.StatusOK, data)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := boardTemplate.Execute(w, data); err != nil {
+		slog.Error("board template failed", "err", err)
+	}
+}
+
+func boardShowOf(e icalplayers.Event, now time.Time, loc *time.Location) boardShow {
+	b := boardShow{
+		UID: e.UID, Summary: e.Summary, Start: e.Start.In(loc), Time: venueLocale.Time(e.Start.In(loc)),
+		Teams: nonEmpty(e.Teams), Players: nonEmpty(e.Players), Started: !now.Before(*e.Start),
+	}
+	if e.PostImageURL != "" {
+		b.PosterURL = "/images/" + url.PathEscape(e.UID) + "?w=" + strconv.Itoa(boardPosterWidth)
+	}
+	return b
+}
+
+// boardTemplate is the TV page. It re-fetches ?format=json every
+// refreshSeconds rather than reloading, so the screen never flashes
+// blank, and ticks the countdowns every second.
+var boardTemplate = template.Must(template.New("board").Funcs(template.FuncMap{"join": strings.Join}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} tonight</title>
+<style>
+html, body { margin: 0; height: 100%; background: #111; color: #f4f4f4; font-family: -apple-system, Helvetica, Arial, sans-serif; overflow: hidden; }
+header { display: flex; justify-content: space-between; align-items: baseline; padding: 2vh 3vw; }
+header h1 { margin: 0; font-size: 5vh; }
+header .date { font-size: 3vh; color: #aaa; }
+#shows { display: flex; gap: 2vw; padding: 0 3vw; height: 80vh; }
+.show { flex: 1; display: flex; flex-direction: column; background: #1d1d1d; border-radius: 1vh; overflow: hidden; max-width: 40vw; }
+.show img { width: 100%; height: 50vh; object-fit: cover; }
+.show .info { padding: 2vh 1.5vw; }
+.show h2 { margin: 0 0 1vh; font-size: 3.6vh; }
+.time { font-size: 3vh; color: #E4572E; font-weight: bold; }
+.countdown { font-size: 2.6vh; color: #ccc; margin-top: .5vh; }
+.teams { font-size: 2.4vh; margin-top: 1vh; }
+.players { font-size: 2vh; color: #aaa; margin-top: .5vh; }
+.empty { font-size: 4vh; color: #aaa; padding: 10vh 3vw; }
+</style>
+</head>
+<body>
+<header><h1>Tonight at {{.Title}}</h1><span class="date" id="date">{{.Date}}</span></header>
+<div id="shows">
+{{range .Shows}}<div class="show">
+{{if .PosterURL}}<img src="{{.PosterURL}}" alt="">{{end}}
+<div class="info"><h2>{{.Summary}}</h2><div class="time">{{.Time}}</div>
+<div class="countdown" data-start="{{.Start.Format "2006-01-02T15:04:05Z07:00"}}"></div>
+{{if .Teams}}<div class="teams">{{join .Teams " · "}}</div>{{end}}
+{{if .Players}}<div class="players">{{join .Players ", "}}</div>{{end}}
+</div></div>
+{{else}}<div class="empty">No more shows tonight.</div>{{end}}
+</div>
+<script>
+const refreshMs = {{.RefreshSecs}} * 1000;
+function esc(s) { const d = document.createElement("div"); d.textContent = s; return d.innerHTML.replace(/"/g, "&quot;"); }
+function tick() {
+  for (const el of document.querySelectorAll(".countdown")) {
+    const mins = Math.round((Date.parse(el.dataset.start) - Date.now()) / 60000);
+    el.textContent = mins > 60 ? "Starts in " + Math.floor(mins / 60) + "h " + (mins % 60) + "m"
+      : mins > 0 ? "Starts in " + mins + " min" : "Now playing";
+  }
+}
+function render(board) {
+  document.getElementById("date").textContent = board.date;
+  const shows = document.getElementById("shows");
+  if (!board.shows.length) { shows.innerHTML = '<div class="empty">No more shows tonight.</div>'; return; }
+  shows.innerHTML = board.shows.map(s => '<div class="show">' +
+    (s.posterUrl ? '<img src="' + esc(s.posterUrl) + '" alt="">' : "") +
+    '<div class="info"><h2>' + esc(s.summary) + '</h2><div class="time">' + esc(s.time) + '</div>' +
+    '<div class="countdown" data-start="' + esc(s.start) + '"></div>' +
+    (s.teams ? '<div class="teams">' + esc(s.teams.join(" · ")) + '</div>' : "") +
+    (s.players ? '<div class="players">' + esc(s.players.join(", ")) + '</div>' : "") +
+    '</div></div>').join("");
+  tick();
+}
+async function refresh() {
+  try {
+    const resp = await fetch(location.pathname + "?format=json", {cache: "no-store"});
+    if (resp.ok) render(await resp.json());
+  } catch (e) { /* keep showing the last board */ }
+}
+tick();
+setInterval(tick, 1000);
+setInterval(refresh, refreshMs);
+</script>
+</body>
+</html>
+`))
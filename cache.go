@@ -0,0 +1,177 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/httpclient"
+)
+
+// runCache inspects and purges the HTTP response cache (see -cache-dir).
+func runCache(args []string) {
+	cmd := "stats"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "stats":
+		runCacheStats(args)
+	case "purge":
+		runCachePurge(args)
+	default:
+		fmt.Fprintln(os.Stderr, "usage: shopsync cache [stats|purge] [flags]")
+		os.Exit(exitUsage)
+	}
+}
+
+// runCacheStats prints the cache's size by kind and by host.
+func runCacheStats(args []string) {
+	fs := flag.NewFlagSet("cache stats", flag.ExitOnError)
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	dir := cacheDirOrExit(logOpts)
+
+	entries, err := httpclient.Entries(dir)
+	if err != nil {
+		exitErr(err)
+	}
+	printCacheStats(os.Stdout, dir, entries)
+}
+
+// runCachePurge deletes cached responses matching every given filter. With
+// no filter it purges nothing unless -all is set.
+func runCachePurge(args []string) {
+	fs := flag.NewFlagSet("cache purge", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", true, "If set, only list what would be purged")
+	olderThan := fs.Duration("older-than", 0, "Purge responses fetched longer ago than this, e.g. 72h")
+	var hosts stringList
+	fs.Var(&hosts, "host", "Purge responses from this host (as listed by 'cache stats'). Repeatable")
+	kind := fs.String("kind", "", "Purge only this kind: feed, page, image or other")
+	all := fs.Bool("all", false, "Purge everything")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if *olderThan < 0 {
+		exitErr(withCode(exitUsage, errors.New("-older-than must not be negative")))
+	}
+	if *kind != "" && !slices.Contains([]string{httpclient.KindFeed, httpclient.KindPage, httpclient.KindImage, httpclient.KindOther}, *kind) {
+		exitErr(withCode(exitUsage, fmt.Errorf("invalid -kind %q (want feed, page, image or other)", *kind)))
+	}
+	filtered := *olderThan > 0 || len(hosts) > 0 || *kind != ""
+	if filtered == *all {
+		exitErr(withCode(exitUsage, errors.New("give -older-than, -host or -kind, or -all")))
+	}
+	dir := cacheDirOrExit(logOpts)
+
+	entries, err := httpclient.Entries(dir)
+	if err != nil {
+		exitErr(err)
+	}
+	cutoff := time.Now().Add(-*olderThan)
+	var n, failed int
+	var size int64
+	for _, e := range entries {
+		if *olderThan > 0 && e.StoredAt.After(cutoff) ||
+			len(hosts) > 0 && !slices.Contains(hosts, e.Host) ||
+			*kind != "" && e.Kind != *kind {
+			continue
+		}
+		if *dryRun {
+			slog.Info("dry run; would purge", "url", e.URL, "kind", e.Kind, "age", time.Since(e.StoredAt).Round(time.Second))
+		} else if err := httpclient.Remove(e); err != nil {
+			slog.Error("could not purge", "url", e.URL, "err", err)
+			failed++
+			continue
+		}
+		n++
+		size += e.Size
+	}
+	if *dryRun {
+		fmt.Printf("Would purge %d of %d cached responses (%s); run with -dry-run=false to delete them.\n", n, len(entries), formatBytes(size))
+		return
+	}
+	fmt.Printf("Purged %d of %d cached responses (%s).\n", n, len(entries), formatBytes(size))
+	if failed > 0 {
+		exitErr(withCode(exitPartial, fmt.Errorf("%d cached responses could not be deleted", failed)))
+	}
+}
+
+func cacheDirOrExit(o *logOptions) string {
+	if o.cacheDir == "" {
+		exitErr(withCode(exitUsage, errors.New("no cache directory: set -cache-dir")))
+	}
+	return o.cacheDir
+}
+
+type cacheTotal struct {
+	name   string
+	n      int
+	size   int64
+	oldest time.Time
+}
+
+// printCacheStats writes entry counts, sizes and the oldest fetch per kind
+// and per host.
+func printCacheStats(w io.Writer, dir string, entries []httpclient.Entry) {
+	var total int64
+	kinds, hosts := map[string]*cacheTotal{}, map[string]*cacheTotal{}
+	add := func(m map[string]*cacheTotal, name string, e httpclient.Entry) {
+		t := m[name]
+		if t == nil {
+			t = &cacheTotal{name: name}
+			m[name] = t
+		}
+		t.n++
+		t.size += e.Size
+		if t.oldest.IsZero() || e.StoredAt.Before(t.oldest) {
+			t.oldest = e.StoredAt
+		}
+	}
+	for _, e := range entries {
+		total += e.Size
+		add(kinds, e.Kind, e)
+		add(hosts, e.Host, e)
+	}
+	fmt.Fprintf(w, "%s: %d cached responses, %s\n", dir, len(entries), formatBytes(total))
+	if len(entries) == 0 {
+		return
+	}
+	for _, group := range []struct {
+		title string
+		m     map[string]*cacheTotal
+	}{{"KIND", kinds}, {"HOST", hosts}} {
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "%s\tENTRIES\tSIZE\tOLDEST\n", group.title)
+		rows := make([]*cacheTotal, 0, len(group.m))
+		for _, t := range group.m {
+			rows = append(rows, t)
+		}
+		slices.SortFunc(rows, func(a, b *cacheTotal) int { return cmp.Or(cmp.Compare(b.size, a.size), cmp.Compare(a.name, b.name)) })
+		for _, t := range rows {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", t.name, t.n, formatBytes(t.size), formatTime(&t.oldest))
+		}
+		tw.Flush()
+	}
+}
+
+// formatBytes renders n in B, KiB or MiB.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// defaultCaptionTemplate is the promo post captions use without
+// -template. Blank lines left by missing fields are squeezed out.
+const defaultCaptionTemplate = `{{.Summary}}
+{{.When}}{{with .Location}} at {{.}}{{end}}
+
+{{with .Teams}}Featuring {{list .}}{{end}}
+{{with .Players}}With {{list .}}{{end}}
+{{with .Price}}Tickets: {{.}}{{end}}{{with .URL}}
+{{.}}{{end}}
+
+{{range .TeamTags}}{{.}} {{end}}#improv #comedy
+`
+
+// captionData is what caption templates execute with: the show plus
+// ready-made pieces.
+type captionData struct {
+	icalplayers.Event
+	When     string   // e.g. "Friday, March 6 at 8:00 PM", in venue (or -tz) time and venueLocale
+	Price    string   // from the event page, when known
+	TeamTags []string // a hashtag per team
+}
+
+var captionFuncs = func() template.FuncMap {
+	f := template.FuncMap(localeFuncs())
+	f["join"], f["list"] = strings.Join, humanList
+	return f
+}()
+
+// loadCaptionTemplate parses the template file at path, or the default
+// when path is empty.
+func loadCaptionTemplate(path string) (*template.Template, error) {
+	text := defaultCaptionTemplate
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		text = string(b)
+	}
+	return template.New("caption").Funcs(captionFuncs).Parse(text)
+}
+
+// caption renders e's promo text with t.
+func caption(t *template.Template, e icalplayers.Event, loc *time.Location) (string, error) {
+	e.Players, e.Teams = nonEmpty(e.Players), nonEmpty(e.Teams)
+	d := captionData{Event: e}
+	if e.Start != nil {
+		t := e.Start.In(loc)
+		d.When = venueLocale.LongDate(t) + " " + venueLocale.phrase("at") + " " + venueLocale.Time(t)
+	}
+	if e.Details != nil {
+		d.Price = e.Details.Price
+	}
+	for _, team := range e.Teams {
+		if tag := hashtag(team); tag != "" {
+			d.TeamTags = append(d.TeamTags, tag)
+		}
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, d); err != nil {
+		return "", err
+	}
+	return squeezeBlankLines(buf.String()), nil
+}
+
+// hashtag turns a team name into a CamelCase hashtag: "The Big Team!"
+// becomes "#TheBigTeam".
+func hashtag(name string) string {
+	var b strings.Builder
+	for _, w := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		r := []rune(w)
+		b.WriteRune(unicode.ToUpper(r[0]))
+		b.WriteString(string(r[1:]))
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "#" + b.String()
+}
+
+// humanList joins items as "A, B and C".
+func humanList(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// squeezeBlankLines trims trailing spaces, drops runs of blank lines down
+// to one and trims the ends.
+func squeezeBlankLines(s string) string {
+	var out []string
+	for _, ln := range strings.Split(s, "\n") {
+		ln = strings.TrimRight(ln, " \t")
+		if ln == "" && (len(out) == 0 || out[len(out)-1] == "") {
+			continue
+		}
+		out = append(out, ln)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n")) + "\n"
+}
+
+// runCaptions prints a ready-to-post promo caption for each upcoming show.
+func runCaptions(args []string) {
+	fs := flag.NewFlagSet("captions", flag.ExitOnError)
+	days := fs.Int("days", 7, "Write captions for shows starting within this many days")
+	tmplFile := fs.String("template", "", "Go text/template file for the caption (default: built-in; fields as captionData: .Summary, .When, .Teams, .Players, .Price, .URL, .TeamTags, ...)")
+	output := fs.String("output", "text", "Output format: text or json")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if *days < 1 {
+		exitErr(withCode(exitUsage, errors.New("-days must be at least 1")))
+	}
+	if !validOutput(*output) {
+		exitErr(withCode(exitUsage, fmt.Errorf("invalid -output %q (want text or json)", *output)))
+	}
+	tmpl, err := loadCaptionTemplate(*tmplFile)
+	if err != nil {
+		exitErr(withCode(exitUsage, fmt.Errorf("-template: %w", err)))
+	}
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		exitErr(err)
+	}
+	ctx := context.Background()
+	store := openStore(ctx, showstore.ReadOnly())
+	defer store.Close()
+
+	now := time.Now()
+	shows, _, err := store.ListShows(ctx, showstore.ShowFilter{From: now, To: now.AddDate(0, 0, *days)})
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	type captionJSON struct {
+		UID     string     `json:"uid"`
+		Summary string     `json:"summary"`
+		Start   *time.Time `json:"start,omitempty"`
+		Caption string     `json:"caption"`
+	}
+	out := []captionJSON{}
+	for i, e := range shows {
+		text, err := caption(tmpl, e, displayLoc(loc))
+		if err != nil {
+			exitErr(fmt.Errorf("%s: %w", e.UID, err))
+		}
+		if *output == "json" {
+			out = append(out, captionJSON{UID: e.UID, Summary: e.Summary, Start: localizeEvent(e).Start, Caption: text})
+			continue
+		}
+		if i > 0 {
+			fmt.Println("----")
+		}
+		fmt.Print(text)
+	}
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			exitErr(err)
+		}
+	}
+}
+
+// handleCaption serves a show's caption as plain text.
+func (s *server) handleCaption(w http.ResponseWriter, r *http.Request) {
+	e, err := s.store.GetShow(r.Context(), r.PathValue("uid"))
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	if e == nil {
+		writeError(w, http.StatusNotFound, errors.New("show not found"))
+		return
+	}
+	text, err := caption(s.captions, *e, displayLoc(s.loc))
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(text))
+}
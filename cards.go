@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	_ "golang.org/x/image/webp"
+
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// Open Graph's recommended image size.
+const (
+	cardWidth  = 1200
+	cardHeight = 630
+	cardMargin = 60
+)
+
+// runCards composes a share card PNG per upcoming show: the poster as a
+// darkened background with the show name, date and teams on top. Posters
+// are read from -images-dir (named like their URL's last path segment, as
+// prune expects) and fetched when missing there. Cards go to <dir>/cards,
+// which prune leaves alone.
+func runCards(args []string) {
+	fs := flag.NewFlagSet("cards", flag.ExitOnError)
+	imagesDir := fs.String("images-dir", "", "Directory of downloaded post images; cards are written to its cards/ subdirectory")
+	outDir := fs.String("out", "", "Write cards here instead of <images-dir>/cards")
+	days := fs.Int("days", 60, "Make cards for shows starting within this many days")
+	force := fs.Bool("force", false, "Redraw cards even when they are newer than the show")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if *imagesDir == "" && *outDir == "" {
+		fmt.Fprintln(os.Stderr, "-images-dir or -out is required")
+		os.Exit(exitUsage)
+	}
+	if *outDir == "" {
+		*outDir = filepath.Join(*imagesDir, "cards")
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		exitErr(err)
+	}
+	faces, err := newCardFaces()
+	if err != nil {
+		exitErr(err)
+	}
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		exitErr(err)
+	}
+	ctx := context.Background()
+	store := openStore(ctx, showstore.ReadOnly())
+	defer store.Close()
+
+	now := time.Now()
+	shows, _, err := store.ListShows(ctx, showstore.ShowFilter{From: now, To: now.AddDate(0, 0, *days)})
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	client := httpclient.Default
+	var made, skipped, failed int
+	for _, s := range shows {
+		if s.Start == nil {
+			continue
+		}
+		dst := filepath.Join(*outDir, uidFileName(s.UID)+".png")
+		if !*force && cardFresh(dst, s) {
+			skipped++
+			continue
+		}
+		log := eventLogger(s)
+		poster := loadPoster(ctx, client, *imagesDir, s.PostImageURL)
+		if poster == nil && s.PostImageURL != "" {
+			log.Warn("poster unavailable; drawing plain card", "url", s.PostImageURL)
+		}
+		if err := writePNG(dst, drawCard(poster, s, loc, faces)); err != nil {
+			log.Error("could not write card", "path", dst, "err", err)
+			failed++
+			continue
+		}
+		log.Debug("wrote card", "path", dst)
+		made++
+	}
+	slog.Info("share cards done", "made", made, "up_to_date", skipped, "failed", failed, "dir", *outDir)
+	if failed > 0 {
+		os.Exit(exitPartial)
+	}
+}
+
+// uidFileName turns a show UID, which may contain any characters, into a
+// stable file name.
+func uidFileName(uid string) string {
+	sum := sha256.Sum256([]byte(uid))
+	return hex.EncodeToString(sum[:16])
+}
+
+// cardFresh reports whether the card at p was drawn after the show last
+// changed.
+func cardFresh(p string, s icalplayers.Event) bool {
+	fi, err := os.Stat(p)
+	return err == nil && s.UpdatedAt != nil && fi.ModTime().After(*s.UpdatedAt)
+}
+
+// loadPoster decodes the show's poster from dir, falling back to fetching
+// the URL. It returns nil when there is no usable image.
+func loadPoster(ctx context.Context, client *http.Client, dir, u string) image.Image {
+	if u == "" {
+		return nil
+	}
+	if dir != "" {
+		name := path.Base(strings.SplitN(u, "?", 2)[0])
+		if f, err := os.Open(filepath.Join(dir, name)); err == nil {
+			defer f.Close()
+			if img, _, err := image.Decode(f); err == nil {
+				return img
+			}
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", "shopsync/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	img, _, err := image.Decode(resp.Body)
+	if err != nil {
+		return nil
+	}
+	return img
+}
+
+type cardFaces struct {
+	title, date, teams font.Face
+}
+
+func newCardFaces() (cardFaces, error) {
+	bold, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return cardFaces{}, err
+	}
+	regular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return cardFaces{}, err
+	}
+	face := func(f *opentype.Font, size float64) font.Face {
+		fc, _ := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+		return fc
+	}
+	return cardFaces{title: face(bold, 64), date: face(regular, 36), teams: face(bold, 32)}, nil
+}
+
+// drawCard composes the card. The poster is scaled to cover the card and
+// a bottom-weighted shade keeps the text readable on any artwork.
+func drawCard(poster image.Image, s icalplayers.Event, loc *time.Location, faces cardFaces) *image.RGBA {
+	card := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	draw.Draw(card, card.Bounds(), image.NewUniform(color.RGBA{24, 24, 32, 255}), image.Point{}, draw.Src)
+	if poster != nil {
+		xdraw.CatmullRom.Scale(card, card.Bounds(), poster, coverRect(poster.Bounds(), cardWidth, cardHeight), draw.Src, nil)
+	}
+	for y := 0; y < cardHeight; y++ {
+		// From 25% black at the top to 85% at the bottom.
+		a := uint8(64 + 153*y/cardHeight)
+		draw.Draw(card, image.Rect(0, y, cardWidth, y+1), image.NewUniform(color.RGBA{0, 0, 0, a}), image.Point{}, draw.Over)
+	}
+
+	white := image.NewUniform(color.White)
+	muted := image.NewUniform(color.RGBA{220, 220, 220, 255})
+	width := cardWidth - 2*cardMargin
+	var lines []cardLine
+	for _, l := range wrapText(faces.title, s.Summary, width, 2) {
+		lines = append(lines, cardLine{l, faces.title, white})
+	}
+	lines = append(lines, cardLine{s.Start.In(loc).Format("Monday, January 2 · 3:04 PM"), faces.date, muted})
+	if teams := nonEmpty(s.Teams); len(teams) > 0 {
+		for _, l := range wrapText(faces.teams, strings.Join(teams, " · "), width, 2) {
+			lines = append(lines, cardLine{l, faces.teams, white})
+		}
+	}
+
+	// Stack lines upward from the bottom margin.
+	y := cardHeight - cardMargin
+	for i := len(lines) - 1; i >= 0; i-- {
+		l := lines[i]
+		d := &font.Drawer{Dst: card, Src: l.color, Face: l.face, Dot: fixed.P(cardMargin, y)}
+		d.DrawString(l.text)
+		y -= l.face.Metrics().Height.Ceil() + 8
+	}
+	return card
+}
+
+type cardLine struct {
+	text  string
+	face  font.Face
+	color image.Image
+}
+
+// coverRect is the centred part of src with the card's aspect ratio.
+func coverRect(src image.Rectangle, w, h int) image.Rectangle {
+	sw, sh := src.Dx(), src.Dy()
+	if sw*h > sh*w { // wider than the card: crop the sides
+		cw := sh * w / h
+		x := src.Min.X + (sw-cw)/2
+		return image.Rect(x, src.Min.Y, x+cw, src.Max.Y)
+	}
+	ch := sw * h / w
+	y := src.Min.Y + (sh-ch)/2
+	return image.Rect(src.Min.X, y, src.Max.X, y+ch)
+}
+
+// wrapText breaks s into at most maxLines lines no wider than width,
+// ending the last with an ellipsis if text was cut.
+func wrapText(face font.Face, s string, width, maxLines int) []string {
+	fits := func(t string) bool { return font.MeasureString(face, t).Ceil() <= width }
+	var lines []string
+	cur := ""
+	words := strings.Fields(s)
+	for i, w := range words {
+		next := strings.TrimSpace(cur + " " + w)
+		if fits(next) || cur == "" {
+			cur = next
+			continue
+		}
+		lines = append(lines, cur)
+		cur = w
+		if len(lines) == maxLines {
+			cur = ""
+			lines[maxLines-1] = ellipsize(face, lines[maxLines-1]+" "+strings.Join(words[i:], " "), width)
+			break
+		}
+	}
+	if cur != "" {
+		lines = append(lines, ellipsize(face, cur, width))
+	}
+	return lines
+}
+
+func ellipsize(face font.Face, s string, width int) string {
+	if font.MeasureString(face, s).Ceil() <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && font.MeasureString(face, string(r)+"…").Ceil() > width {
+		r = r[:len(r)-1]
+	}
+	return strings.TrimSpace(string(r)) + "…"
+}
+
+func writePNG(p string, img image.Image) error {
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// eventTypeRule classifies events as Type when their summary has one of
+// Keywords (whole words, any case) or the source files them under one of
+// Categories (any case). A venue profile's event_types replaces
+// defaultEventTypeRules; the first rule that matches wins and events no
+// rule matches are shows.
+type eventTypeRule struct {
+	Type       string   `yaml:"type"`
+	Keywords   []string `yaml:"keywords"`
+	Categories []string `yaml:"categories"`
+
+	re *regexp.Regexp // Keywords, compiled by compileEventTypeRules
+}
+
+// defaultEventTypeRules suit The Improv Shop's feeds. Showcases come first:
+// a "Level 3 Showcase" is a show put on by a class.
+var defaultEventTypeRules = []eventTypeRule{
+	{Type: icalplayers.TypeShow, Keywords: []string{"showcase", "grad show", "graduation show"}},
+	{Type: icalplayers.TypeWorkshop, Keywords: []string{"workshop", "workshops", "intensive", "masterclass", "master class", "bootcamp", "boot camp"}, Categories: []string{"workshop", "workshops"}},
+	{Type: icalplayers.TypeClass, Keywords: []string{"class", "classes", "course", "level 1", "level 2", "level 3", "level 4", "level 5", "improv 101", "improv 201", "drop-in"}, Categories: []string{"class", "classes", "education", "training"}},
+}
+
+// eventTypeRules are the rules in use: the defaults, or the venue's.
+var eventTypeRules = mustCompileEventTypeRules(defaultEventTypeRules)
+
+// compileEventTypeRules checks each rule's type and compiles its keywords.
+func compileEventTypeRules(rules []eventTypeRule) ([]eventTypeRule, error) {
+	out := make([]eventTypeRule, 0, len(rules))
+	for i, r := range rules {
+		if !slices.Contains(icalplayers.EventTypes, r.Type) {
+			return nil, fmt.Errorf("event_types rule %d: type %q is not one of %s", i+1, r.Type, strings.Join(icalplayers.EventTypes, ", "))
+		}
+		var words []string
+		for _, k := range r.Keywords {
+			if k = strings.TrimSpace(k); k != "" {
+				words = append(words, regexp.QuoteMeta(k))
+			}
+		}
+		if len(words) > 0 {
+			r.re = regexp.MustCompile(`(?i)(?:^|[^\pL\pN])(?:` + strings.Join(words, "|") + `)(?:$|[^\pL\pN])`)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func mustCompileEventTypeRules(rules []eventTypeRule) []eventTypeRule {
+	out, err := compileEventTypeRules(rules)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// classifyEvent returns e's type under eventTypeRules, and the rule's
+// reason for it ("" for an unmatched show).
+func classifyEvent(e icalplayers.Event) (string, string) {
+	for _, r := range eventTypeRules {
+		if r.re != nil {
+			if m := r.re.FindString(e.Summary); m != "" {
+				return r.Type, fmt.Sprintf("summary has %q", strings.TrimFunc(m, func(c rune) bool { return !isWordRune(c) }))
+			}
+		}
+		for _, c := range e.Categories {
+			if slices.ContainsFunc(r.Categories, func(want string) bool { return strings.EqualFold(strings.TrimSpace(want), strings.TrimSpace(c)) }) {
+				return r.Type, fmt.Sprintf("category %q", c)
+			}
+		}
+	}
+	return icalplayers.TypeShow, ""
+}
+
+func isWordRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsNumber(c)
+}
+
+// parseEventTypes reads -type values and ?type= lists: comma-separated
+// event types.
+func parseEventTypes(values []string) ([]string, error) {
+	var out []string
+	for _, v := range values {
+		for _, t := range strings.Split(v, ",") {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if t == "" {
+				continue
+			}
+			if !slices.Contains(icalplayers.EventTypes, t) {
+				return nil, fmt.Errorf("invalid event type %q (want %s)", t, strings.Join(icalplayers.EventTypes, ", "))
+			}
+			if !slices.Contains(out, t) {
+				out = append(out, t)
+			}
+		}
+	}
+	return out, nil
+}
+
+// isShow reports whether e is a show; unclassified events are.
+func isShow(e icalplayers.Event) bool {
+	return e.EventType == "" || e.EventType == icalplayers.TypeShow
+}
+
+// typesStage keeps only events of the -type types. It runs after match,
+// which classifies them.
+func typesStage(types []string) stage {
+	kept := 0 // over all batches
+	return stage{name: "types", run: func(_ context.Context, st *syncState) error {
+		before := len(st.events)
+		st.events = slices.DeleteFunc(st.events, func(e icalplayers.Event) bool {
+			t := e.EventType
+			if t == "" {
+				t = icalplayers.TypeShow
+			}
+			return !slices.Contains(types, t)
+		})
+		kept += len(st.events)
+		slog.Info("filtered by event type", "types", types, "kept", len(st.events), "dropped", before-len(st.events))
+		if kept == 0 && !st.more {
+			st.report.warn("no events of type %s", strings.Join(types, ", "))
+		}
+		if len(st.events) == 0 {
+			st.done = true
+		}
+		return nil
+	}}
+}
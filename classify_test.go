@@ -0,0 +1,96 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+func TestClassifyEvent(t *testing.T) {
+	tests := []struct {
+		summary    string
+		categories []string
+		want       string
+	}{
+		{"Friday Night Harold", nil, icalplayers.TypeShow},
+		{"Level 3 Class", nil, icalplayers.TypeClass},
+		{"IMPROV 101 (Tuesdays)", nil, icalplayers.TypeClass},
+		{"Sunday Drop-In", nil, icalplayers.TypeClass},
+		{"Character Intensive", nil, icalplayers.TypeWorkshop},
+		{"Weekend Boot Camp", nil, icalplayers.TypeWorkshop},
+
+		// Showcases are checked first: a class's showcase is a show.
+		{"Level 3 Showcase", nil, icalplayers.TypeShow},
+		{"Improv 201 Grad Show", nil, icalplayers.TypeShow},
+		{"Level 2 showcase", []string{"Classes"}, icalplayers.TypeShow},
+		// Workshops come before classes.
+		{"Masterclass: Scene Work Class", nil, icalplayers.TypeWorkshop},
+		{"Master Class", nil, icalplayers.TypeWorkshop},
+
+		// Keywords are whole words.
+		{"Classic Comedy Night", nil, icalplayers.TypeShow},
+		{"Workshopped Sketches", nil, icalplayers.TypeShow},
+		{"First-Class Improv", nil, icalplayers.TypeClass},
+
+		// Categories, in any case, when no keyword matched.
+		{"Long Form Foundations", []string{"Education"}, icalplayers.TypeClass},
+		{"Scene Study", []string{" workshops "}, icalplayers.TypeWorkshop},
+		{"Harold Night", []string{"Shows", "Comedy"}, icalplayers.TypeShow},
+		// A workshop category beats a later rule's keyword.
+		{"Level 4", []string{"Workshop"}, icalplayers.TypeWorkshop},
+	}
+	for _, tt := range tests {
+		got, why := classifyEvent(icalplayers.Event{Summary: tt.summary, Categories: tt.categories})
+		if got != tt.want {
+			t.Errorf("classifyEvent(%q, %v) = %s (%s), want %s", tt.summary, tt.categories, got, why, tt.want)
+		}
+	}
+	if _, why := classifyEvent(icalplayers.Event{Summary: "Friday Night Harold"}); why != "" {
+		t.Errorf("an unmatched show has reason %q", why)
+	}
+	// The reason quotes the leftmost keyword as the summary writes it.
+	if _, why := classifyEvent(icalplayers.Event{Summary: "LEVEL 3 Class!"}); why != `summary has "LEVEL 3"` {
+		t.Errorf("reason %q, want the keyword as written", why)
+	}
+}
+
+func TestCompileEventTypeRules(t *testing.T) {
+	defer func(rules []eventTypeRule) { eventTypeRules = rules }(eventTypeRules)
+
+	rules, err := compileEventTypeRules([]eventTypeRule{
+		{Type: icalplayers.TypeClass, Keywords: []string{"jam", " "}},
+		{Type: icalplayers.TypeWorkshop, Keywords: []string{"c++ (advanced)"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventTypeRules = rules
+	for summary, want := range map[string]string{
+		"Thursday Jam":         icalplayers.TypeClass,
+		"Jam Workshop":         icalplayers.TypeClass, // the venue's rules replace the defaults, in order
+		"C++ (Advanced) night": icalplayers.TypeWorkshop,
+		"Level 3 Class":        icalplayers.TypeShow,
+	} {
+		if got, _ := classifyEvent(icalplayers.Event{Summary: summary}); got != want {
+			t.Errorf("classifyEvent(%q) = %s, want %s", summary, got, want)
+		}
+	}
+
+	if _, err := compileEventTypeRules([]eventTypeRule{{Type: "lecture", Keywords: []string{"talk"}}}); err == nil {
+		t.Error("a rule with an unknown type compiled")
+	}
+}
+
+func TestParseEventTypes(t *testing.T) {
+	got, err := parseEventTypes([]string{"Show, class", "show", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{icalplayers.TypeShow, icalplayers.TypeClass}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := parseEventTypes([]string{"show,lecture"}); err == nil {
+		t.Error("an unknown type parsed")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+	"github.com/tsny/shopsync/pkg/wpimg"
+)
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("out", "", "Write JSON to this file instead of stdout")
+	schema := fs.Bool("schema", false, "Print the JSON Schema of the export format instead of exporting")
+	validate := fs.Bool("validate", false, "Check the export against the JSON Schema before writing it")
+	fromFlag := fs.String("from", "", "Only export shows starting on or after this date (YYYY-MM-DD or a phrase, as in ingest)")
+	toFlag := fs.String("to", "", "Only export shows starting on or before this date (YYYY-MM-DD or a phrase, as in ingest)")
+	anonymize := fs.Bool("anonymize", false, "Replace player names with pseudonyms and redact email addresses, for sharing the data outside the theater")
+	anonymizeKey := fs.String("anonymize-key", "", "Key for -anonymize pseudonyms; the same key gives the same pseudonyms across exports (random if empty)")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	from, to, err := dateWindow(*fromFlag, *toFlag)
+	if err != nil {
+		exitErr(withCode(exitUsage, err))
+	}
+
+	if *schema {
+		os.Stdout.Write(icalplayers.EventSchema())
+		return
+	}
+
+	ctx := context.Background()
+	store := openStore(ctx, showstore.ReadOnly())
+	defer store.Close()
+
+	shows, err := store.GetAllShows(ctx)
+	if err != nil {
+		exitErr(err)
+	}
+	if !from.IsZero() || !to.IsZero() {
+		shows = filterByWindow(shows, from, to)
+	}
+	if *anonymize {
+		shows = newAnonymizer(*anonymizeKey).events(shows)
+	}
+	b := icalplayers.JSON(localizeEvents(shows))
+	if *validate {
+		if err := icalplayers.ValidateJSON(b); err != nil {
+			exitErr(fmt.Errorf("export does not match event schema v%d: %w", icalplayers.EventSchemaVersion, err))
+		}
+		slog.Debug("export matches event schema", "version", icalplayers.EventSchemaVersion)
+	}
+	if *out == "" {
+		os.Stdout.Write(append(b, '\n'))
+		return
+	}
+	if err := os.WriteFile(*out, append(b, '\n'), 0o644); err != nil {
+		exitErr(err)
+	}
+	slog.Info("wrote shows", "count", len(shows), "path", *out)
+}
+
+func runImage(args []string) {
+	fs := flag.NewFlagSet("image", flag.ExitOnError)
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: shopsync image <post-url>")
+		os.Exit(exitUsage)
+	}
+	// e.g. https://theimprovshop.com/show/teams-level-2-student-showcase-16/
+	res, err := wpimg.Fetch(context.Background(), fs.Arg(0))
+	if err != nil {
+		exitErr(err)
+	}
+	fmt.Println("Fetched image:", res.ImageURL)
+}
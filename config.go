@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+	"github.com/tsny/shopsync/pkg/wpimg"
+	"go.yaml.in/yaml/v3"
+)
+
+// defaultConfigFile is read when -config isn't given; a missing default
+// file just means there are no venue profiles.
+const defaultConfigFile = "shopsync.yaml"
+
+// shopsyncConfig is the config file: named venue profiles, one of which is
+// used when -venue isn't given, and the sinks real syncs fan out to.
+type shopsyncConfig struct {
+	Default string                   `yaml:"default"`
+	Venues  map[string]*venueProfile `yaml:"venues"`
+	Sinks   []sinkConfig             `yaml:"sinks"`
+}
+
+// venueProfile holds one theater's quirks. Settings left out keep the
+// built-in Improv Shop behaviour.
+type venueProfile struct {
+	// Timezone is the venue's IANA zone: dates on the command line are
+	// read in it and local-time defaults come from it.
+	Timezone string `yaml:"timezone"`
+	// DBSchema keeps the venue's shows in their own Postgres schema of the
+	// shared database (see showstore.Schema).
+	DBSchema string `yaml:"db_schema"`
+	// CalendarPage is scraped for a Google Calendar feed when ingest has
+	// no source flags.
+	CalendarPage string `yaml:"calendar_page"`
+	// ImageSelector finds the post image on an event page.
+	ImageSelector string `yaml:"image_selector"`
+	// DetailSelectors find price, doors, ages and lineup on an event page
+	// (see wpimg.DetailSelectors).
+	DetailSelectors map[string]string `yaml:"detail_selectors"`
+	// CuePattern recognises "Cast: A, B" lines (see
+	// icalplayers.SetCuePattern).
+	CuePattern string `yaml:"cue_pattern"`
+	// EventTypes replace the rules telling classes and workshops from
+	// shows (see defaultEventTypeRules).
+	EventTypes []eventTypeRule `yaml:"event_types"`
+	// Locale sets the language and layouts of dates people read (see
+	// localeConfig).
+	Locale *localeConfig `yaml:"locale"`
+	// Flags are defaults for any command's flags, e.g. wp, src or
+	// url-pattern. A list sets a repeatable flag several times.
+	Flags map[string]flagValues `yaml:"flags"`
+	// Sinks replace the file's top-level sinks for this venue.
+	Sinks []sinkConfig `yaml:"sinks"`
+}
+
+// flagValues reads a YAML scalar or list of scalars.
+type flagValues []string
+
+func (v *flagValues) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.SequenceNode {
+		var list []string
+		if err := n.Decode(&list); err != nil {
+			return err
+		}
+		*v = list
+		return nil
+	}
+	var s string
+	if err := n.Decode(&s); err != nil {
+		return err
+	}
+	*v = flagValues{s}
+	return nil
+}
+
+// activeVenue is the name of the profile in use; "" without one.
+var activeVenue string
+
+// venueSchema is the active profile's DBSchema, used by openStore.
+var venueSchema string
+
+// addConfigFlags registers -config and -venue; addLogFlags calls it so
+// every command has them.
+func addConfigFlags(fs *flag.FlagSet) {
+	fs.String("config", defaultConfigFile, "Config file of venue profiles")
+	fs.String("venue", "", "Venue profile from -config to use (default: the file's default)")
+}
+
+// loadConfig reads path. A missing file is an empty config unless
+// required, i.e. the path was chosen explicitly.
+func loadConfig(path string, required bool) (*shopsyncConfig, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return &shopsyncConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c shopsyncConfig
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if c.Default != "" && c.Venues[c.Default] == nil {
+		return nil, fmt.Errorf("%s: default venue %q has no profile", path, c.Default)
+	}
+	return &c, nil
+}
+
+// applyVenue loads the profile picked by fs's -config and -venue, sets the
+// flags it has defaults for that weren't given explicitly, and applies its
+// other settings process-wide.
+func applyVenue(fs *flag.FlagSet, explicit map[string]bool) error {
+	cf, vf := fs.Lookup("config"), fs.Lookup("venue")
+	if cf == nil || vf == nil {
+		return nil
+	}
+	cfg, err := loadConfig(cf.Value.String(), explicit["config"])
+	if err != nil {
+		return err
+	}
+	configSinks = cfg.Sinks
+	name := vf.Value.String()
+	if name == "" {
+		name = cfg.Default
+	}
+	if name == "" {
+		return nil
+	}
+	p := cfg.Venues[name]
+	if p == nil {
+		names := make([]string, 0, len(cfg.Venues))
+		for n := range cfg.Venues {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return fmt.Errorf("unknown venue %q (profiles in %s: %s)", name, cf.Value.String(), strings.Join(names, ", "))
+	}
+
+	// Only flags this command has; a profile serves every command.
+	for flagName, vals := range p.Flags {
+		f := fs.Lookup(flagName)
+		if f == nil || explicit[flagName] || commandLineOnly[flagName] {
+			continue
+		}
+		for _, v := range vals {
+			if err := fs.Set(flagName, v); err != nil {
+				return fmt.Errorf("venue %s: flag %s=%q: %w", name, flagName, v, err)
+			}
+		}
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("venue %s: timezone: %w", name, err)
+		}
+		venueTimezone = p.Timezone
+	}
+	if p.CuePattern != "" {
+		if err := icalplayers.SetCuePattern(p.CuePattern); err != nil {
+			return fmt.Errorf("venue %s: cue_pattern: %w", name, err)
+		}
+	}
+	if p.EventTypes != nil {
+		rules, err := compileEventTypeRules(p.EventTypes)
+		if err != nil {
+			return fmt.Errorf("venue %s: %w", name, err)
+		}
+		eventTypeRules = rules
+	}
+	if p.Locale != nil {
+		l, err := newLocale(*p.Locale)
+		if err != nil {
+			return fmt.Errorf("venue %s: %w", name, err)
+		}
+		venueLocale = l
+	}
+	if p.ImageSelector != "" {
+		wpimg.PostImageSelector = p.ImageSelector
+	}
+	for field, sel := range p.DetailSelectors {
+		if err := wpimg.SetDetailSelector(field, sel); err != nil {
+			return fmt.Errorf("venue %s: detail_selectors: %w", name, err)
+		}
+	}
+	if p.CalendarPage != "" {
+		calendarPageURL = p.CalendarPage
+	}
+	if p.Sinks != nil {
+		configSinks = p.Sinks
+	}
+	activeVenue, venueSchema = name, p.DBSchema
+	return nil
+}
+
+// storeOptions adds the active venue's schema to opts.
+func storeOptions(opts []showstore.Option) []showstore.Option {
+	if venueSchema != "" {
+		opts = append(opts, showstore.Schema(venueSchema))
+	}
+	return opts
+}
+
+// syncLockKey is the advisory lock a sync holds: one per venue schema, so
+// venues sharing a database don't block each other.
+func syncLockKey() int64 {
+	if venueSchema == "" {
+		return showstore.SyncLockKey
+	}
+	h := fnv.New64a()
+	h.Write([]byte(venueSchema))
+	return showstore.SyncLockKey ^ int64(h.Sum64())
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// corsMaxAge is how long, in seconds, browsers may cache a preflight.
+const corsMaxAge = "600"
+
+// corsOrigins lets pages on other origins (the venue's website) call the
+// read-only endpoints from the browser. The list comes from -cors-origin;
+// "*" allows any origin. /admin is never shared: it has its own
+// cross-origin protection.
+type corsOrigins []string
+
+func (c corsOrigins) allows(origin string) bool {
+	return slices.Contains(c, "*") || slices.Contains(c, origin)
+}
+
+// handler answers preflights itself and adds the Access-Control headers to
+// other requests from allowed origins. Requests from other origins get
+// none, so browsers block them.
+func (c corsOrigins) handler(next http.Handler) http.Handler {
+	if len(c) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/admin/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h := w.Header()
+		h.Add("Vary", "Origin") // so a CDN keeps one copy per origin
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !c.allows(origin) {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if slices.Contains(c, "*") {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if preflight {
+			h.Set("Access-Control-Allow-Methods", "GET, HEAD")
+			h.Set("Access-Control-Allow-Headers", "Authorization, X-API-Key, If-None-Match")
+			h.Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h.Set("Access-Control-Expose-Headers", "ETag")
+		next.ServeHTTP(w, r)
+	})
+}
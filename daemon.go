@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/robfig/cron/v3"
+)
+
+func runDaemon(args []string) {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	var opts ingestOptions
+	opts.register(fs)
+	interval := fs.Duration("interval", time.Hour, "Time between syncs (ignored when -schedule is set)")
+	jitter := fs.Duration("jitter", 5*time.Minute, "Random delay up to this long added to each wait")
+	schedule := fs.String("schedule", "", `Cron expression for sync times, e.g. "0 6,16 * * *" (overrides -interval), evaluated in -tz or the venue's timezone`)
+	httpAddr := fs.String("http-addr", ":9090", "Address for /metrics, /healthz and /readyz; empty disables the listener")
+	freshness := fs.Duration("freshness", 0, "Report not ready when the last successful sync is older than this (default 3x -interval; 0 with -schedule disables)")
+	syncToken := fs.String("sync-token", os.Getenv("SYNC_TOKEN"), "Enable POST /sync on -http-addr, authenticated with this bearer token, to start a sync immediately (default $SYNC_TOKEN)")
+	s3Snapshot := fs.String("s3-snapshot", "", "After each successful sync, upload all shows as JSON to this s3://bucket/prefix (timestamped key plus latest.json)")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if err := opts.validate(); err != nil {
+		exitErr(err)
+	}
+
+	tz := venueTimezone
+	if logOpts.tz != "" {
+		tz = logOpts.tz
+	}
+	next, err := nextRunFunc(*schedule, tz, *interval)
+	if err != nil {
+		exitErr(err)
+	}
+	for _, src := range opts.srcs {
+		if src == "-" {
+			exitErr(fmt.Errorf("daemon cannot read -src from stdin"))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store := openStore(ctx)
+	defer store.Close()
+
+	if *freshness == 0 && *schedule == "" {
+		*freshness = 3 * *interval
+	}
+	clock := newSyncClock()
+	trigger := newSyncTrigger(*syncToken, store)
+	if *syncToken != "" && *httpAddr == "" {
+		exitErr(withCode(exitUsage, errors.New("-sync-token needs -http-addr")))
+	}
+	if *httpAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metricsHandler())
+		(&healthChecks{store: store, freshness: *freshness, lastSync: clock.get}).register(mux)
+		if *syncToken != "" {
+			trigger.register(mux)
+		}
+		ops := serveOps(*httpAddr, mux)
+		defer ops.Close()
+	}
+
+	var snap *snapshotter
+	if *s3Snapshot != "" {
+		if opts.dryRun {
+			slog.Warn("-s3-snapshot is ignored on dry runs")
+		} else if snap, err = newSnapshotter(ctx, *s3Snapshot); err != nil {
+			exitErr(withCode(exitUsage, err))
+		}
+	}
+
+	in := &ingester{store: store, opts: opts, feedCache: map[string]*feedCacheEntry{}, notifiers: opts.notifiers(), sinks: configuredSinks()}
+	slog.Info("daemon started", append(currentBuildInfo().logAttrs(), "interval", *interval, "schedule", *schedule, "tz", tz, "venue", activeVenue, "jitter", *jitter, "dry_run", opts.dryRun)...)
+	for {
+		ok := syncOnce(ctx, in)
+		in.resync, in.idempotencyKeys = false, nil
+		trigger.finish()
+		if ok {
+			clock.mark()
+			if snap != nil {
+				if err := snap.upload(ctx, store, time.Now()); err != nil {
+					slog.Error("snapshot upload failed", "err", err)
+					reportError(err, sentry.LevelError, map[string]string{"kind": "snapshot"})
+				}
+			}
+		}
+		wait := time.Until(next(time.Now())) + randDuration(*jitter)
+		slog.Info("next sync scheduled", "in", wait.Round(time.Second), "at", time.Now().Add(wait).Format(time.RFC3339))
+		select {
+		case <-ctx.Done():
+			slog.Info("daemon stopping")
+			return
+		case <-time.After(wait):
+		case <-trigger.ch:
+			in.resync, in.idempotencyKeys = true, trigger.start()
+			slog.Info("starting requested sync", "idempotency_keys", in.idempotencyKeys)
+		}
+	}
+}
+
+// syncOnce runs one ingest pass, logs its summary and reports whether it
+// succeeded. Errors are logged, not fatal, so a flaky feed host doesn't take
+// the daemon down.
+func syncOnce(ctx context.Context, in *ingester) bool {
+	start := time.Now()
+	report, err := in.runRecorded(ctx)
+	attrs := []any{
+		"duration", time.Since(start).Round(time.Millisecond),
+		"parsed", report.EventsParsed,
+		"unmatched", len(report.UnmatchedEvents),
+		"upserted", report.Rows.Upserted,
+		"inserted", report.Rows.Inserted,
+		"updated", report.Rows.Updated,
+		"unchanged", report.Rows.Unchanged,
+		"failed", report.Rows.Failed,
+		"warnings", len(report.Warnings),
+		"overlaps", len(report.Overlaps),
+		"blackouts", len(report.Blackouts),
+		"skipped", report.Skipped,
+	}
+	if err != nil {
+		slog.Error("sync run failed", append(attrs, "err", err)...)
+		return false
+	}
+	slog.Info("sync run finished", attrs...)
+	return true
+}
+
+// nextRunFunc returns a function giving the next sync time after now, either
+// from a standard five-field cron expression evaluated in tz or, when expr is
+// empty, a fixed interval.
+func nextRunFunc(expr, tz string, interval time.Duration) (func(time.Time) time.Time, error) {
+	if expr == "" {
+		if interval <= 0 {
+			return nil, fmt.Errorf("-interval must be positive, got %s", interval)
+		}
+		return func(now time.Time) time.Time { return now.Add(interval) }, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -tz: %w", err)
+	}
+	sched, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -schedule: %w", err)
+	}
+	return func(now time.Time) time.Time { return sched.Next(now.In(loc)) }, nil
+}
+
+func randDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return rand.N(max)
+}
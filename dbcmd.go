@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+func runDB(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: shopsync db <migrate|drop|recreate> [flags]")
+		os.Exit(exitUsage)
+	}
+	ctx := context.Background()
+	switch args[0] {
+	case "migrate":
+		fs := flag.NewFlagSet("db migrate", flag.ExitOnError)
+		logOpts := addLogFlags(fs)
+		parseFlags(fs, args[1:])
+		logOpts.setup()
+		store := openStore(ctx)
+		defer store.Close()
+		if err := store.Migrate(ctx); err != nil {
+			exitErr(err)
+		}
+		slog.Info("schema is up to date")
+	case "drop", "recreate":
+		recreate := args[0] == "recreate"
+		fs := flag.NewFlagSet("db "+args[0], flag.ExitOnError)
+		dryRun := fs.Bool("dry-run", true, "If set, only report what would be dropped")
+		yes := fs.Bool("yes", false, "Skip the interactive confirmation prompt")
+		force := fs.Bool("force", false, "Allow dropping a database that looks like production")
+		logOpts := addLogFlags(fs)
+		parseFlags(fs, args[1:])
+		logOpts.setup()
+		if *dryRun {
+			slog.Info("dry run; would drop tables", "tables", strings.Join(showstore.DropTables, ", "), "recreate", recreate)
+			return
+		}
+		store := openStore(ctx)
+		defer store.Close()
+		if err := confirmDestructive(store, *yes, *force); err != nil {
+			exitErr(err)
+		}
+		if err := store.Drop(ctx); err != nil {
+			exitErr(err)
+		}
+		slog.Info("dropped tables", "tables", strings.Join(showstore.DropTables, ", "))
+		if recreate {
+			if err := store.Migrate(ctx); err != nil {
+				exitErr(err)
+			}
+			slog.Info("recreated schema")
+		}
+	default:
+		exitErr(fmt.Errorf("unknown db command %q", args[0]))
+	}
+}
+
+// confirmDestructive guards table drops. A target that looks like production
+// needs force; every target needs the operator to type the database name
+// unless yes is set.
+func confirmDestructive(store *showstore.Store, yes, force bool) error {
+	host, db := store.Target()
+	if looksLikeProduction(host, db) && !force {
+		return fmt.Errorf("refusing to drop %s on %s: it looks like production (pass -force to override)", db, host)
+	}
+	if yes {
+		return nil
+	}
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return errors.New("stdin is not a terminal; pass -yes to confirm")
+	}
+	fmt.Fprintf(os.Stderr, "This will DROP the tables %s in %q on %s.\nType the database name to confirm: ", strings.Join(showstore.DropTables, ", "), db, host)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("read confirmation: %w", err)
+	}
+	if strings.TrimSpace(line) != db {
+		return errors.New("confirmation did not match; nothing dropped")
+	}
+	return nil
+}
+
+// looksLikeProduction treats anything that isn't on a loopback address and
+// doesn't say dev/test/staging/local in its host or database name as
+// production. SHOPSYNC_ENV=production forces the answer.
+func looksLikeProduction(host, db string) bool {
+	if strings.EqualFold(os.Getenv("SHOPSYNC_ENV"), "production") {
+		return true
+	}
+	if host == "" || host == "localhost" || strings.HasPrefix(host, "/") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return false
+	}
+	target := strings.ToLower(host + " " + db)
+	for _, marker := range []string{"dev", "test", "staging", "local"} {
+		if strings.Contains(target, marker) {
+			return false
+		}
+	}
+	return true
+}
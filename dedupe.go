@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// runDedupe finds stored shows that are the same show under different UIDs
+// and merges each group into its richest member.
+func runDedupe(args []string) {
+	fs := flag.NewFlagSet("dedupe", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", true, "If set, only print the duplicate groups and proposed merges")
+	yes := fs.Bool("yes", false, "Merge every group without asking")
+	logOpts := addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: shopsync dedupe [flags]")
+		fmt.Fprintln(fs.Output(), "\nShows are duplicates when their summaries match ignoring case and punctuation and they start within 12 hours of each other. A feed that still lists a merged-away UID will store it again on the next sync.")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	logOpts.setup()
+
+	ctx := context.Background()
+	var opts []showstore.Option
+	if *dryRun {
+		opts = append(opts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, opts...)
+	defer store.Close()
+
+	groups, err := store.FindDuplicates(ctx)
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	if len(groups) == 0 {
+		fmt.Println("No duplicate shows.")
+		return
+	}
+	var in *bufio.Reader
+	if !*dryRun && !*yes {
+		if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+			exitErr(withCode(exitUsage, errors.New("stdin is not a terminal; pass -yes to merge without asking")))
+		}
+		in = bufio.NewReader(os.Stdin)
+	}
+
+	var merged, skipped, failed int
+groups:
+	for i, g := range groups {
+		keep, drop := showstore.MergeDuplicates(g)
+		printDuplicateGroup(os.Stdout, i+1, g, keep)
+		if *dryRun {
+			continue
+		}
+		if in != nil {
+			fmt.Fprintf(os.Stderr, "Merge into %s? [y/N/q] ", keep.UID)
+			line, err := in.ReadString('\n')
+			if err != nil && line == "" {
+				exitErr(fmt.Errorf("read answer: %w", err))
+			}
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case "y", "yes":
+			case "q", "quit":
+				skipped += len(groups) - i
+				break groups
+			default:
+				skipped++
+				continue
+			}
+		}
+		err := store.MergeShows(ctx, keep, drop)
+		switch {
+		case errors.Is(err, showstore.ErrVersionConflict):
+			slog.Warn("show changed while deduping; skipped", "uid", keep.UID)
+			failed++
+		case err != nil:
+			slog.Error("merge failed", "uid", keep.UID, "err", err)
+			failed++
+		default:
+			slog.Info("merged duplicates", "uid", keep.UID, "dropped", drop)
+			merged++
+		}
+	}
+
+	if *dryRun {
+		fmt.Printf("%d duplicate groups; run with -dry-run=false to merge them.\n", len(groups))
+		return
+	}
+	fmt.Printf("%d duplicate groups: merged %d, skipped %d, failed %d.\n", len(groups), merged, skipped, failed)
+	if failed > 0 {
+		os.Exit(exitPartial)
+	}
+}
+
+// printDuplicateGroup lists a group with the show that would survive first
+// and the merged values it would end up with.
+func printDuplicateGroup(w io.Writer, n int, group []icalplayers.Event, keep icalplayers.Event) {
+	fmt.Fprintf(w, "Group %d: %s\n", n, group[0].Summary)
+	for _, e := range group {
+		mark := "drop"
+		if e.UID == keep.UID {
+			mark = "KEEP"
+		}
+		image := "no"
+		if e.PostImageURL != "" {
+			image = "yes"
+		}
+		fmt.Fprintf(w, "  %s  %s  %s  players %d, teams %d, image %s, description %d chars\n",
+			mark, formatTime(e.Start), e.UID, len(e.Players), len(e.TeamIDs), image, len(e.Description))
+	}
+	fmt.Fprintf(w, "  => players: %s\n     teams:   %s\n", joinList(keep.Players), joinList(keep.Teams))
+	if keep.PostImageURL != "" {
+		fmt.Fprintf(w, "     image:   %s\n", keep.PostImageURL)
+	}
+	fmt.Fprintln(w)
+}
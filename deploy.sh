@@ -55,7 +55,7 @@ gcloud run jobs deploy "$JOB_NAME" \
   --region "$REGION" \
   --project "$PROJECT" \
   --set-secrets "DATABASE_URL=${SECRET_NAME}:latest" \
-  --args="-wp,$WP_URL,-dry-run=false" \
+  --args="ingest,-wp,$WP_URL,-dry-run=false" \
   --max-retries 3 \
   --task-timeout 5m
 
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// defaultNameDictFile is the CSV form of the name dictionary, read with
+// icalplayers.LoadNameDict.
+const defaultNameDictFile = "names.csv"
+
+func runDict(args []string) {
+	if len(args) == 0 || args[0] != "build" {
+		fmt.Fprintln(os.Stderr, "usage: shopsync dict build [flags]")
+		os.Exit(exitUsage)
+	}
+	runDictBuild(args[1:])
+}
+
+// runDictBuild adds confirmed players to the name dictionary: everyone on a
+// scraped roster, and names stored shows list often enough, or that staff
+// typed in by hand, to trust. Existing entries are kept.
+func runDictBuild(args []string) {
+	fs := flag.NewFlagSet("dict build", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", true, "If set, print the names that would be added instead of writing them")
+	csvPath := fs.String("csv", defaultNameDictFile, "CSV name dictionary to update (first,last,full rows); empty to skip")
+	toDB := fs.Bool("db", true, "Also update the name_dict table")
+	minShows := fs.Int("min-shows", 3, "Shows a name from show descriptions must appear in before it is trusted; hand-edited lineups always count")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if *minShows < 1 {
+		exitErr(withCode(exitUsage, fmt.Errorf("-min-shows must be at least 1, got %d", *minShows)))
+	}
+	if *csvPath == "" && !*toDB {
+		exitErr(withCode(exitUsage, errors.New("nothing to update: -csv is empty and -db=false")))
+	}
+
+	ctx := context.Background()
+	var opts []showstore.Option
+	if *dryRun {
+		opts = append(opts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, opts...)
+	defer store.Close()
+
+	teams, err := store.GetAllTeams(ctx)
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	players, err := store.ListPlayers(ctx)
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	mentions, err := store.PlayerMentions(ctx)
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	entries := confirmedNames(players, mentions, teams, *minShows)
+	slog.Info("mined names", "roster_players", len(players), "show_names", len(mentions), "confirmed", len(entries))
+
+	var rows [][]string
+	known := icalplayers.NewNameDict()
+	if *csvPath != "" {
+		if rows, err = readNameDictCSV(*csvPath); err != nil {
+			exitErr(err)
+		}
+		for _, r := range rows {
+			if len(r) > 2 {
+				known.Add(r[2])
+			}
+		}
+	}
+	inDB := icalplayers.NewNameDict()
+	if *toDB {
+		if inDB, err = store.GetNameDict(ctx); err != nil {
+			exitErr(dbErr(err))
+		}
+	}
+	var newCSV, newDB []showstore.DictEntry
+	for _, e := range entries {
+		key := strings.ToLower(strings.Join(strings.Fields(e.Name), " "))
+		if _, ok := known.Full[key]; *csvPath != "" && !ok {
+			newCSV = append(newCSV, e)
+		}
+		if _, ok := inDB.Full[key]; *toDB && !ok {
+			newDB = append(newDB, e)
+		}
+	}
+
+	if *dryRun {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tSOURCE\tSHOWS\tNEW IN")
+		for _, e := range entries {
+			var where []string
+			if slices.Contains(newCSV, e) {
+				where = append(where, *csvPath)
+			}
+			if slices.Contains(newDB, e) {
+				where = append(where, "db")
+			}
+			if len(where) > 0 {
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", e.Name, e.Source, e.Shows, strings.Join(where, ", "))
+			}
+		}
+		tw.Flush()
+		slog.Info("dry run; dictionary not updated", "new_csv", len(newCSV), "new_db", len(newDB))
+		return
+	}
+
+	if *csvPath != "" && len(newCSV) > 0 {
+		for _, e := range newCSV {
+			parts := strings.Fields(e.Name)
+			rows = append(rows, []string{parts[0], parts[len(parts)-1], strings.Join(parts, " ")})
+		}
+		if err := writeNameDictCSV(*csvPath, rows); err != nil {
+			exitErr(err)
+		}
+	}
+	slog.Info("updated name dictionary CSV", "path", *csvPath, "added", len(newCSV))
+	if *toDB {
+		// Every entry, not just new ones, so show counts stay current.
+		added, err := store.UpsertNameDict(ctx, entries)
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		slog.Info("updated name_dict table", "added", added, "entries", len(entries))
+	}
+}
+
+// confirmedNames returns the full names worth adding to the dictionary,
+// sorted: every roster player, plus show names that read as a full name,
+// aren't a team, and either appear in at least minShows shows or were
+// entered by hand.
+func confirmedNames(players []showstore.Player, mentions []showstore.PlayerMention, teams []showstore.Team, minShows int) []showstore.DictEntry {
+	type tally struct {
+		name   string
+		best   int // shows under the most common spelling
+		shows  int
+		edited bool
+	}
+	byKey := map[string]*tally{}
+	for _, m := range mentions {
+		key := strings.ToLower(strings.Join(strings.Fields(m.Name), " "))
+		t := byKey[key]
+		if t == nil {
+			t = &tally{}
+			byKey[key] = t
+		}
+		if m.Shows > t.best {
+			t.name, t.best = strings.Join(strings.Fields(m.Name), " "), m.Shows
+		}
+		t.shows += m.Shows
+		t.edited = t.edited || m.Edited
+	}
+
+	var out []showstore.DictEntry
+	for _, p := range players {
+		key := strings.ToLower(strings.Join(strings.Fields(p.Name), " "))
+		e := showstore.DictEntry{Name: p.Name, Source: showstore.DictSourceRoster}
+		if t := byKey[key]; t != nil {
+			e.Shows = t.shows
+			delete(byKey, key)
+		}
+		out = append(out, e)
+	}
+	for _, t := range byKey {
+		if t.shows < minShows && !t.edited {
+			continue
+		}
+		if !strings.Contains(t.name, " ") || !icalplayers.LooksLikeName(t.name) || lookupTeam(t.name, teams) != nil {
+			continue
+		}
+		out = append(out, showstore.DictEntry{Name: t.name, Source: showstore.DictSourceShows, Shows: t.shows})
+	}
+	slices.SortFunc(out, func(a, b showstore.DictEntry) int {
+		return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return out
+}
+
+// readNameDictCSV returns the rows of a name dictionary CSV, or none if the
+// file doesn't exist yet.
+func readNameDictCSV(path string) ([][]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	var rows [][]string
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		rows = append(rows, rec)
+	}
+}
+
+func writeNameDictCSV(path string, rows [][]string) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// digestNight is one evening's shows in the digest.
+type digestNight struct {
+	Date  time.Time
+	Shows []icalplayers.Event
+}
+
+// digestChange is one recorded schedule change in the digest.
+type digestChange struct {
+	Summary string
+	Start   *time.Time // when the show is now
+	What    string     // e.g. "moved from Fri Oct 17, 7:30 PM", in venueLocale
+}
+
+// smtpConfig is read from SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASSWORD
+// and SMTP_FROM.
+type smtpConfig struct {
+	host, port, user, password, from string
+}
+
+func smtpConfigFromEnv() (smtpConfig, error) {
+	c := smtpConfig{
+		host:     os.Getenv("SMTP_HOST"),
+		port:     os.Getenv("SMTP_PORT"),
+		user:     os.Getenv("SMTP_USER"),
+		password: os.Getenv("SMTP_PASSWORD"),
+		from:     os.Getenv("SMTP_FROM"),
+	}
+	if c.port == "" {
+		c.port = "587"
+	}
+	if c.from == "" {
+		c.from = c.user
+	}
+	if c.host == "" || c.from == "" {
+		return c, errors.New("SMTP_HOST and SMTP_FROM (or SMTP_USER) must be set to send the digest")
+	}
+	return c, nil
+}
+
+func runDigest(args []string) {
+	fs := flag.NewFlagSet("digest", flag.ExitOnError)
+	var to stringList
+	fs.Var(&to, "to", "Recipient address. Repeatable; defaults to the comma-separated $DIGEST_TO")
+	days := fs.Int("days", 7, "Number of days ahead to include")
+	dryRun := fs.Bool("dry-run", true, "If set, print the email HTML to stdout instead of sending it")
+	changesSince := fs.Duration("changes", 7*24*time.Hour, "List start time and venue changes recorded this far back, for shows still to come (0 leaves them out)")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if len(to) == 0 {
+		for _, a := range strings.Split(os.Getenv("DIGEST_TO"), ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				to = append(to, a)
+			}
+		}
+	}
+	if *days < 1 {
+		fmt.Fprintln(os.Stderr, "-days must be at least 1")
+		os.Exit(exitUsage)
+	}
+	if !*dryRun && len(to) == 0 {
+		fmt.Fprintln(os.Stderr, "no recipients: pass -to or set DIGEST_TO")
+		os.Exit(exitUsage)
+	}
+
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		exitErr(err)
+	}
+	ctx := context.Background()
+	store := openStore(ctx, showstore.ReadOnly())
+	defer store.Close()
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	shows, _, err := store.ListShows(ctx, showstore.ShowFilter{From: now, To: today.AddDate(0, 0, *days)})
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	nights := groupByNight(shows, displayLoc(loc))
+	var changes []digestChange
+	if *changesSince > 0 {
+		recorded, err := store.ScheduleChangesSince(ctx, now.Add(-*changesSince))
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		changes = digestChanges(recorded, displayLoc(loc))
+	}
+	subject := fmt.Sprintf("Shows this week: %s – %s", venueLocale.ShortDate(today), venueLocale.ShortDate(today.AddDate(0, 0, *days-1)))
+
+	var html bytes.Buffer
+	if err := digestTemplate.Execute(&html, map[string]any{"Subject": subject, "Nights": nights, "Count": len(shows), "Changes": changes}); err != nil {
+		exitErr(err)
+	}
+	if *dryRun {
+		os.Stdout.Write(html.Bytes())
+		slog.Info("dry run; not sending digest", "shows", len(shows), "recipients", len(to))
+		return
+	}
+
+	cfg, err := smtpConfigFromEnv()
+	if err != nil {
+		exitErr(err)
+	}
+	msg, err := buildDigestMessage(cfg.from, to, subject, digestText(nights, changes), html.String())
+	if err != nil {
+		exitErr(err)
+	}
+	var auth smtp.Auth
+	if cfg.user != "" {
+		auth = smtp.PlainAuth("", cfg.user, cfg.password, cfg.host)
+	}
+	if err := smtp.SendMail(net.JoinHostPort(cfg.host, cfg.port), auth, cfg.from, to, msg); err != nil {
+		exitErr(withCode(exitNetwork, fmt.Errorf("send digest: %w", err)))
+	}
+	slog.Info("sent digest", "shows", len(shows), "recipients", len(to))
+}
+
+// groupByNight buckets shows by their venue-local date, keeping start order.
+func groupByNight(shows []icalplayers.Event, loc *time.Location) []digestNight {
+	var out []digestNight
+	for _, e := range shows {
+		if e.Start == nil {
+			continue
+		}
+		t := e.Start.In(loc)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		if len(out) == 0 || !out[len(out)-1].Date.Equal(day) {
+			out = append(out, digestNight{Date: day})
+		}
+		e.Start = &t
+		out[len(out)-1].Shows = append(out[len(out)-1].Shows, e)
+	}
+	return out
+}
+
+// digestChanges describes recorded schedule changes in loc.
+func digestChanges(recorded []showstore.ScheduleChange, loc *time.Location) []digestChange {
+	var out []digestChange
+	for _, c := range recorded {
+		d := digestChange{Summary: c.Summary}
+		if c.Start != nil {
+			t := c.Start.In(loc)
+			d.Start = &t
+		}
+		switch c.Field {
+		case "start":
+			d.What = "moved from " + orNone(c.Old)
+			if t, err := time.Parse("2006-01-02 15:04 MST", c.Old); err == nil {
+				d.What = "moved from " + venueLocale.DateTime(t.In(loc))
+			}
+		case "location":
+			d.What = fmt.Sprintf("venue changed from %s to %s", orNone(c.Old), orNone(c.New))
+		default:
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// digestText is the plain-text alternative for mail clients without HTML.
+func digestText(nights []digestNight, changes []digestChange) string {
+	var b strings.Builder
+	if len(changes) > 0 {
+		b.WriteString("SCHEDULE CHANGES\n")
+		for _, c := range changes {
+			fmt.Fprintf(&b, "  %s", c.Summary)
+			if c.Start != nil {
+				fmt.Fprintf(&b, ", now %s", venueLocale.DateTime(*c.Start))
+			}
+			fmt.Fprintf(&b, ": %s\n", c.What)
+		}
+		b.WriteString("\n")
+	}
+	if len(nights) == 0 {
+		b.WriteString("No shows on the calendar this week.\n")
+		return b.String()
+	}
+	for _, n := range nights {
+		fmt.Fprintf(&b, "%s\n", venueLocale.LongDate(n.Date))
+		for _, e := range n.Shows {
+			fmt.Fprintf(&b, "  %s  %s", venueLocale.Time(*e.Start), e.Summary)
+			if len(e.Teams) > 0 {
+				fmt.Fprintf(&b, " (%s)", strings.Join(e.Teams, ", "))
+			}
+			if e.URL != "" {
+				fmt.Fprintf(&b, "\n           %s", e.URL)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// buildDigestMessage assembles a multipart/alternative RFC 5322 message.
+func buildDigestMessage(from string, to []string, subject, text, html string) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ ctype, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {part.ctype}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mimeEncodeHeader(subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// mimeEncodeHeader Q-encodes s if it isn't plain ASCII.
+func mimeEncodeHeader(s string) string {
+	for _, r := range s {
+		if r > 127 {
+			return mime.QEncoding.Encode("utf-8", s)
+		}
+	}
+	return s
+}
+
+var digestTemplate = template.Must(template.New("digest").Funcs(localeFuncs()).Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Subject}}</title></head>
+<body style="font-family: -apple-system, Helvetica, Arial, sans-serif; max-width: 640px; margin: 0 auto; color: #222;">
+<h1 style="font-size: 22px;">{{.Subject}}</h1>
+{{if .Changes}}
+<div style="border: 2px solid #c0392b; border-radius: 4px; padding: 8px 12px; margin-bottom: 16px;">
+<h2 style="font-size: 16px; color: #c0392b; margin: 0 0 8px;">Schedule changes</h2>
+{{range .Changes}}<div><strong>{{.Summary}}</strong>{{if .Start}}, now {{dateTime .Start}}{{end}}: {{.What}}</div>
+{{end}}</div>
+{{end}}
+{{if not .Nights}}<p>No shows on the calendar this week.</p>{{end}}
+{{range .Nights}}
+<h2 style="font-size: 18px; border-bottom: 1px solid #ddd; padding-bottom: 4px;">{{longDate .Date}}</h2>
+{{range .Shows}}
+<table role="presentation" style="width: 100%; margin-bottom: 16px;"><tr>
+{{if .PostImageURL}}<td style="width: 120px; vertical-align: top;"><img src="{{.PostImageURL}}" alt="" width="120" style="display: block; border-radius: 4px;"></td>{{end}}
+<td style="vertical-align: top; padding-left: 12px;">
+<div style="font-weight: bold;">{{if .URL}}<a href="{{.URL}}" style="color: #222;">{{.Summary}}</a>{{else}}{{.Summary}}{{end}}</div>
+<div style="color: #666;">{{time .Start}}</div>
+{{if .Teams}}<div>{{join .Teams ", "}}</div>{{end}}
+</td>
+</tr></table>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// discordMaxEmbeds is Discord's per-message embed limit.
+const discordMaxEmbeds = 10
+
+// discordColor is the embed sidebar colour.
+const discordColor = 0xE4572E
+
+// discordNotifier posts one rich embed per newly synced show to a Discord
+// channel webhook.
+type discordNotifier struct {
+	url    string
+	loc    *time.Location
+	client *http.Client
+}
+
+func newDiscordNotifier(u string) *discordNotifier {
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return &discordNotifier{url: u, loc: loc, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (d *discordNotifier) name() string { return "discord" }
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	URL         string              `json:"url,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Timestamp   string              `json:"timestamp,omitempty"`
+	Color       int                 `json:"color"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+	Image       *discordEmbedImage  `json:"image,omitempty"`
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type discordEmbedImage struct {
+	URL string `json:"url"`
+}
+
+// notify posts embeds for added shows only, in batches of discordMaxEmbeds.
+func (d *discordNotifier) notify(ctx context.Context, cs changeSet) error {
+	for start := 0; start < len(cs.Added); start += discordMaxEmbeds {
+		batch := cs.Added[start:min(start+discordMaxEmbeds, len(cs.Added))]
+		embeds := make([]discordEmbed, 0, len(batch))
+		for _, e := range batch {
+			embeds = append(embeds, discordShowEmbed(e))
+		}
+		msg := map[string]any{"embeds": embeds}
+		if start == 0 {
+			msg["content"] = fmt.Sprintf("%d new %s on the calendar", len(cs.Added), plural(len(cs.Added), "show", "shows"))
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		err = postWithRetry(ctx, d.client, func() (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Content-Type", "application/json")
+			return req, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discordShowEmbed is a show as a Discord embed, poster and all.
+func discordShowEmbed(e icalplayers.Event) discordEmbed {
+	em := discordEmbed{Title: truncateStr(e.Summary, 240), URL: e.URL, Color: discordColor}
+	if e.Start != nil {
+		// Discord renders <t:unix:F> in each reader's own timezone.
+		em.Description = fmt.Sprintf("<t:%d:F>", e.Start.Unix())
+		em.Timestamp = e.Start.UTC().Format(time.RFC3339)
+	}
+	if len(e.Teams) > 0 {
+		em.Fields = append(em.Fields, discordEmbedField{Name: "Teams", Value: truncateStr(strings.Join(e.Teams, ", "), 1000)})
+	}
+	if e.PostImageURL != "" {
+		em.Image = &discordEmbedImage{URL: e.PostImageURL}
+	}
+	return em
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/daterange"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// Discord interaction and response types used by the bot.
+const (
+	discordPing               = 1
+	discordCommand            = 2
+	discordAutocomplete       = 4
+	discordPong               = 1
+	discordMessage            = 4
+	discordAutocompleteResult = 8
+)
+
+// discordCommands are the bot's slash commands, as 'shopsync discord
+// register' sends them to Discord.
+var discordCommands = []map[string]any{
+	{
+		"name":        "nextshow",
+		"description": "A team's next show",
+		"options": []map[string]any{{
+			"type": 3, "name": "team", "description": "Team name", "required": true, "autocomplete": true,
+		}},
+	},
+	{"name": "tonight", "description": "Tonight's shows"},
+}
+
+// discordBot answers Discord slash commands from the store, at the
+// interactions endpoint configured in the Discord developer portal.
+type discordBot struct {
+	publicKey ed25519.PublicKey
+	store     *showstore.Store
+	loc       *time.Location
+}
+
+func newDiscordBot(publicKey string, store *showstore.Store, loc *time.Location) (*discordBot, error) {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid Discord public key: want the application's 64 hex digits")
+	}
+	return &discordBot{publicKey: key, store: store, loc: loc}, nil
+}
+
+func (b *discordBot) register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /discord/interactions", b.handleInteraction)
+}
+
+// discordInteraction is the part of an interaction the bot reads.
+type discordInteraction struct {
+	Type int `json:"type"`
+	Data struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+func (i discordInteraction) option(name string) string {
+	for _, o := range i.Data.Options {
+		if o.Name == name {
+			return strings.TrimSpace(o.Value)
+		}
+	}
+	return ""
+}
+
+// handleInteraction verifies Discord's Ed25519 signature, which Discord
+// requires and tests with bad signatures before accepting the endpoint,
+// and answers within its three-second deadline.
+func (b *discordBot) handleInteraction(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	sig, err := hex.DecodeString(r.Header.Get("X-Signature-Ed25519"))
+	ts := r.Header.Get("X-Signature-Timestamp")
+	if err != nil || ts == "" || !ed25519.Verify(b.publicKey, append([]byte(ts), body...), sig) {
+		writeError(w, http.StatusUnauthorized, errors.New("invalid request signature"))
+		return
+	}
+	var in discordInteraction
+	if err := json.Unmarshal(body, &in); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2500*time.Millisecond)
+	defer cancel()
+	switch in.Type {
+	case discordPing:
+		writeJSON(w, http.StatusOK, map[string]any{"type": discordPong})
+	case discordAutocomplete:
+		choices, err := b.teamChoices(ctx, in.option("team"))
+		if err != nil {
+			slog.Error("discord autocomplete failed", "err", err)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"type": discordAutocompleteResult, "data": map[string]any{"choices": choices}})
+	case discordCommand:
+		msg, err := b.answer(ctx, in)
+		if err != nil {
+			slog.Error("discord command failed", "command", in.Data.Name, "err", err)
+			msg = map[string]any{"content": "Sorry, the schedule isn't available right now."}
+		}
+		slog.Info("discord command", "command", in.Data.Name, "team", in.option("team"))
+		writeJSON(w, http.StatusOK, map[string]any{"type": discordMessage, "data": msg})
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported interaction type %d", in.Type))
+	}
+}
+
+// answer builds the message for a slash command.
+func (b *discordBot) answer(ctx context.Context, in discordInteraction) (map[string]any, error) {
+	switch in.Data.Name {
+	case "nextshow":
+		return b.nextShow(ctx, in.option("team"))
+	case "tonight":
+		return b.tonight(ctx)
+	}
+	return map[string]any{"content": fmt.Sprintf("Unknown command /%s.", in.Data.Name)}, nil
+}
+
+func (b *discordBot) nextShow(ctx context.Context, want string) (map[string]any, error) {
+	teams, err := b.store.GetAllTeams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t := lookupTeam(want, teams)
+	if t == nil {
+		return map[string]any{"content": fmt.Sprintf("No team called %q.", want)}, nil
+	}
+	shows, _, err := b.store.ListShows(ctx, showstore.ShowFilter{From: time.Now(), TeamID: t.ID, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(shows) == 0 {
+		return map[string]any{"content": fmt.Sprintf("%s has no upcoming shows on the calendar.", t.Name)}, nil
+	}
+	return map[string]any{
+		"content": fmt.Sprintf("%s's next show:", t.Name),
+		"embeds":  []discordEmbed{discordShowEmbed(shows[0])},
+	}, nil
+}
+
+func (b *discordBot) tonight(ctx context.Context) (map[string]any, error) {
+	today, err := daterange.Parse("today", time.Now().In(b.loc))
+	if err != nil {
+		return nil, err
+	}
+	shows, total, err := b.store.ListShows(ctx, showstore.ShowFilter{From: today.Start, To: today.End, Limit: discordMaxEmbeds})
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return map[string]any{"content": "No shows tonight."}, nil
+	}
+	content := fmt.Sprintf("%d %s tonight:", total, plural(total, "show", "shows"))
+	if total > len(shows) {
+		content += fmt.Sprintf(" (first %d)", len(shows))
+	}
+	embeds := make([]discordEmbed, 0, len(shows))
+	for _, e := range shows {
+		embeds = append(embeds, discordShowEmbed(e))
+	}
+	return map[string]any{"content": content, "embeds": embeds}, nil
+}
+
+// teamChoices offers up to 25 (Discord's limit) team names containing
+// typed.
+func (b *discordBot) teamChoices(ctx context.Context, typed string) ([]map[string]string, error) {
+	choices := []map[string]string{}
+	teams, err := b.store.GetAllTeams(ctx)
+	if err != nil {
+		return choices, err
+	}
+	typed = strings.ToLower(typed)
+	for _, t := range teams {
+		if len(choices) == 25 {
+			break
+		}
+		if strings.Contains(strings.ToLower(t.Name), typed) {
+			choices = append(choices, map[string]string{"name": truncateStr(t.Name, 96), "value": t.ID})
+		}
+	}
+	return choices, nil
+}
+
+// runDiscord manages the Discord bot:
+//
+//	shopsync discord register [-app-id ID] [-guild ID] [-dry-run=false]
+//
+// registers /nextshow and /tonight, with $DISCORD_BOT_TOKEN. Point the
+// application's Interactions Endpoint URL at serve's
+// /discord/interactions (serve -discord-public-key).
+func runDiscord(args []string) {
+	if len(args) == 0 || args[0] != "register" {
+		fmt.Fprintln(os.Stderr, "usage: shopsync discord register [flags]")
+		os.Exit(exitUsage)
+	}
+	fs := flag.NewFlagSet("discord register", flag.ExitOnError)
+	appID := fs.String("app-id", os.Getenv("DISCORD_APP_ID"), "Discord application ID (default $DISCORD_APP_ID)")
+	guild := fs.String("guild", "", "Register in this server only, which takes effect at once (global commands can take an hour)")
+	dryRun := fs.Bool("dry-run", true, "If set, only print the commands")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args[1:])
+	logOpts.setup()
+
+	body, err := json.MarshalIndent(discordCommands, "", "  ")
+	if err != nil {
+		exitErr(err)
+	}
+	if *dryRun {
+		fmt.Println(string(body))
+		return
+	}
+	token := os.Getenv("DISCORD_BOT_TOKEN")
+	if *appID == "" || token == "" {
+		exitErr(withCode(exitUsage, errors.New("discord register needs -app-id and $DISCORD_BOT_TOKEN")))
+	}
+	u := "https://discord.com/api/v10/applications/" + *appID + "/commands"
+	if *guild != "" {
+		u = "https://discord.com/api/v10/applications/" + *appID + "/guilds/" + *guild + "/commands"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	// PUT replaces the whole command set, so removed commands go away.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(body))
+	if err != nil {
+		exitErr(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bot "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		exitErr(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		exitErr(fmt.Errorf("discord: %s: %s", resp.Status, strings.TrimSpace(string(msg))))
+	}
+	slog.Info("registered discord commands", "commands", len(discordCommands), "guild", *guild)
+}
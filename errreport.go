@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// setupErrorReporting sends captured errors to Sentry when SENTRY_DSN is
+// set. SENTRY_ENVIRONMENT and SENTRY_RELEASE are read by the SDK. Without a
+// DSN reportError is a no-op.
+func setupErrorReporting() {
+	if os.Getenv("SENTRY_DSN") == "" {
+		return
+	}
+	if err := sentry.Init(sentry.ClientOptions{}); err != nil {
+		slog.Warn("error reporting disabled", "err", err)
+		return
+	}
+	icalplayers.ImageFetchFailed = func(e icalplayers.Event, err error) {
+		reportError(err, sentry.LevelWarning, map[string]string{
+			"kind": "image_fetch", "uid": e.UID, "summary": e.Summary, "url": e.URL,
+		})
+	}
+	prev := flushTelemetry
+	flushTelemetry = func() {
+		sentry.Flush(5 * time.Second)
+		prev()
+	}
+}
+
+// reportError captures err with tags describing where it happened.
+func reportError(err error, level sentry.Level, tags map[string]string) {
+	sentry.WithScope(func(scope *sentry.Scope) {
+		scope.SetLevel(level)
+		for k, v := range tags {
+			if v != "" {
+				scope.SetTag(k, v)
+			}
+		}
+		sentry.CaptureException(err)
+	})
+}
+
+// errorKind names the failure class of err for grouping in the tracker.
+func errorKind(err error) string {
+	switch exitCode(err) {
+	case exitParse:
+		return "parse"
+	case exitNetwork:
+		return "network"
+	case exitDB:
+		return "database"
+	case exitPartial:
+		return "partial"
+	case exitLocked:
+		return "locked"
+	}
+	return "other"
+}
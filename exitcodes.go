@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/tsny/shopsync/pkg/csvevents"
+	"github.com/tsny/shopsync/pkg/eventbrite"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+	"github.com/tsny/shopsync/pkg/squarespace"
+	"github.com/tsny/shopsync/pkg/wpevents"
+)
+
+// Process exit codes, one per failure class, so a scheduler can tell a
+// calendar outage from a broken database.
+const (
+	exitOK      = 0
+	exitGeneric = 1
+	exitUsage   = 2
+	exitParse   = 3 // the feed was fetched but could not be decoded
+	exitNetwork = 4 // the feed or an event page could not be fetched
+	exitDB      = 5 // the database rejected a query or was unreachable
+	exitPartial = 6 // the run finished but some events failed
+	exitLocked  = 7 // another sync holds the sync lock
+)
+
+// classedError tags an error with the exit code it should produce.
+type classedError struct {
+	code int
+	err  error
+}
+
+func (e *classedError) Error() string { return e.err.Error() }
+func (e *classedError) Unwrap() error { return e.err }
+
+func withCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classedError{code: code, err: err}
+}
+
+// dbErr marks err as a database failure.
+func dbErr(err error) error { return withCode(exitDB, err) }
+
+// fetchErr classifies an error from loading a feed: decoding problems are
+// parse errors, local file errors stay generic, and everything else is a
+// network error.
+func fetchErr(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return err
+	}
+	if isParseErr(err) {
+		return withCode(exitParse, err)
+	}
+	return withCode(exitNetwork, err)
+}
+
+func isParseErr(err error) bool {
+	var syn *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+	return errors.Is(err, icalplayers.ErrParse) || errors.Is(err, wpevents.ErrDecode) ||
+		errors.Is(err, eventbrite.ErrDecode) || errors.Is(err, squarespace.ErrDecode) ||
+		errors.Is(err, csvevents.ErrDecode) || errors.As(err, &syn) || errors.As(err, &typ)
+}
+
+// exitCode picks the exit code for err. Explicit tags win; otherwise
+// well-known error types are recognised.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var ce *classedError
+	if errors.As(err, &ce) {
+		return ce.code
+	}
+	var pgErr *pgconn.PgError
+	var connErr *pgconn.ConnectError
+	if errors.As(err, &pgErr) || errors.As(err, &connErr) ||
+		errors.Is(err, showstore.ErrVersionConflict) || errors.Is(err, showstore.ErrReadOnly) {
+		return exitDB
+	}
+	if isParseErr(err) {
+		return exitParse
+	}
+	return exitGeneric
+}
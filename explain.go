@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// minTeamNameLen is the shortest team name or alias the matcher will look
+// for; shorter ones ("Duo", "Jam") appear in too many descriptions.
+const minTeamNameLen = 5
+
+// Reasons a candidate name was not matched.
+const (
+	rejectShort    = "short name"
+	rejectCase     = "differs only in case"
+	rejectNotFound = "not in description"
+)
+
+// nameMatch reports whether name counts as appearing in desc, and if not,
+// why.
+func nameMatch(desc, name string) (bool, string) {
+	if len(name) < minTeamNameLen {
+		return false, rejectShort
+	}
+	if strings.Contains(desc, name) {
+		return true, ""
+	}
+	if strings.Contains(strings.ToLower(desc), strings.ToLower(name)) {
+		return false, rejectCase
+	}
+	return false, rejectNotFound
+}
+
+// teamCandidate is one name (team name or alias) tested against an event.
+type teamCandidate struct {
+	Team   string
+	Name   string
+	Alias  bool
+	Reason string // empty when matched
+}
+
+// explainMatch tests every team name and alias against desc the way
+// findTeamsInEventDescription does, keeping the outcome for each.
+func explainMatch(desc string, teams []showstore.Team) []teamCandidate {
+	var out []teamCandidate
+	for _, t := range teams {
+		for i, name := range append([]string{t.Name}, t.Aliases...) {
+			ok, reason := nameMatch(desc, name)
+			out = append(out, teamCandidate{Team: t.Name, Name: name, Alias: i > 0, Reason: reason})
+			if ok {
+				break
+			}
+		}
+	}
+	return out
+}
+
+// printMatchExplanation writes one block per event: matches, then the
+// rejections worth a human's attention. Names simply absent from the
+// description are only counted, since that's nearly every team.
+func printMatchExplanation(w io.Writer, e icalplayers.Event, cands []teamCandidate) {
+	fmt.Fprintf(w, "%s  %s\n", e.UID, e.Summary)
+	var absent int
+	var matched bool
+	for _, c := range cands {
+		label := fmt.Sprintf("%q", c.Name)
+		if c.Alias {
+			label += fmt.Sprintf(" (alias of %q)", c.Team)
+		}
+		switch c.Reason {
+		case "":
+			matched = true
+			fmt.Fprintf(w, "  match   %s\n", label)
+		case rejectNotFound:
+			absent++
+		default:
+			fmt.Fprintf(w, "  reject  %s: %s\n", label, c.Reason)
+		}
+	}
+	if !matched {
+		fmt.Fprintln(w, "  no teams matched")
+	}
+	fmt.Fprintf(w, "  %d other names not in description\n", absent)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// feedSize caps the number of entries in /feed.xml.
+const feedSize = 100
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// handleFeed serves upcoming shows as an Atom feed.
+func (s *server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	shows, _, err := s.store.ListShows(r.Context(), showstore.ShowFilter{From: time.Now(), Limit: feedSize, Types: []string{icalplayers.TypeShow}})
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	self := requestBaseURL(r) + r.URL.Path
+	feed := atomFeed{
+		ID:      self,
+		Title:   calendarName + " upcoming shows",
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Links:   []atomLink{{Href: self, Rel: "self", Type: "application/atom+xml"}},
+	}
+	for _, e := range shows {
+		feed.Entries = append(feed.Entries, s.atomEntry(e))
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	_ = enc.Encode(feed)
+}
+
+func (s *server) atomEntry(e icalplayers.Event) atomEntry {
+	when := e.Start.In(s.loc)
+	entry := atomEntry{
+		ID:    "urn:shopsync:show:" + e.UID,
+		Title: fmt.Sprintf("%s (%s)", e.Summary, venueLocale.DateTime(when)),
+	}
+	if e.UpdatedAt != nil {
+		entry.Updated = e.UpdatedAt.UTC().Format(time.RFC3339)
+	} else {
+		entry.Updated = time.Now().UTC().Format(time.RFC3339)
+	}
+	if e.URL != "" {
+		entry.Links = append(entry.Links, atomLink{Href: e.URL, Rel: "alternate", Type: "text/html"})
+	}
+	if e.PostImageURL != "" {
+		entry.Links = append(entry.Links, atomLink{Href: e.PostImageURL, Rel: "enclosure", Type: icalplayers.ImageContentType(e.PostImageURL)})
+	}
+	for _, t := range e.Teams {
+		entry.Categories = append(entry.Categories, atomCategory{Term: t})
+	}
+
+	var b strings.Builder
+	if e.PostImageURL != "" {
+		fmt.Fprintf(&b, `<p><img src="%s" alt="%s"></p>`, html.EscapeString(e.PostImageURL), html.EscapeString(e.Summary))
+	}
+	fmt.Fprintf(&b, "<p>%s</p>", html.EscapeString(venueLocale.FullDate(when)))
+	if len(e.Teams) > 0 {
+		fmt.Fprintf(&b, "<p>Teams: %s</p>", html.EscapeString(strings.Join(e.Teams, ", ")))
+	}
+	entry.Content = atomContent{Type: "html", Body: b.String()}
+	return entry
+}
+
+// requestBaseURL rebuilds scheme://host for r, trusting X-Forwarded-Proto
+// from the load balancer.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if p := r.Header.Get("X-Forwarded-Proto"); p != "" {
+		scheme = p
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix namespaces the environment variables that mirror flags.
+const envPrefix = "SHOPSYNC_"
+
+// flagEnvName maps a flag name to its environment variable, e.g.
+// "image-concurrency" to SHOPSYNC_IMAGE_CONCURRENCY.
+func flagEnvName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// commandLineOnly are the confirmation flags: the write and drop guards
+// (-dry-run, -yes, -force) must be given on the command line, so a stray
+// SHOPSYNC_FORCE or a profile written for another command can't wave a
+// destructive run through. The environment and profiles leave them alone.
+var commandLineOnly = map[string]bool{"dry-run": true, "yes": true, "force": true}
+
+// parseFlags parses args into fs, then fills flags not given on the
+// command line from SHOPSYNC_* environment variables and then from the
+// venue profile (see applyVenue). So the command line beats the
+// environment, which beats the profile, which beats the default.
+// Repeatable flags take a comma-separated list; commandLineOnly flags are
+// never filled. A bad value exits with exitUsage like a bad flag would.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	fs.VisitAll(func(f *flag.Flag) {
+		if explicit[f.Name] || commandLineOnly[f.Name] {
+			return
+		}
+		v, ok := os.LookupEnv(flagEnvName(f.Name))
+		if !ok {
+			return
+		}
+		vals := []string{v}
+		if _, repeatable := f.Value.(*stringList); repeatable {
+			vals = nil
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					vals = append(vals, s)
+				}
+			}
+		}
+		for _, s := range vals {
+			if err := fs.Set(f.Name, s); err != nil {
+				fmt.Fprintf(os.Stderr, "invalid %s=%q: %v\n", flagEnvName(f.Name), v, err)
+				os.Exit(exitUsage)
+			}
+		}
+		explicit[f.Name] = true
+	})
+	if err := applyVenue(fs, explicit); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/gcal"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// gcalTag marks calendar events shopsync owns, so publishing never touches
+// events staff added by hand.
+const gcalTag = "shopsync"
+
+// runPublishGCal mirrors upcoming stored shows into a Google Calendar:
+// new shows are created, changed ones updated and shows that no longer
+// exist (or moved out of the window) deleted. Calendar event IDs are
+// derived from show UIDs, so no mapping table is needed.
+func runPublishGCal(args []string) {
+	fs := flag.NewFlagSet("publish-gcal", flag.ExitOnError)
+	calendarID := fs.String("calendar", os.Getenv("GCAL_CALENDAR_ID"), "Google Calendar ID to write to (default $GCAL_CALENDAR_ID)")
+	credentials := fs.String("credentials", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"), "Service account key JSON; the calendar must be shared with its email (default $GOOGLE_APPLICATION_CREDENTIALS)")
+	days := fs.Int("days", 90, "Publish shows starting within this many days")
+	dryRun := fs.Bool("dry-run", true, "If set, only log what would change in the calendar")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if *calendarID == "" || *credentials == "" {
+		fmt.Fprintln(os.Stderr, "-calendar and -credentials are required")
+		os.Exit(exitUsage)
+	}
+	if *days < 1 {
+		fmt.Fprintln(os.Stderr, "-days must be at least 1")
+		os.Exit(exitUsage)
+	}
+	key, err := os.ReadFile(*credentials)
+	if err != nil {
+		exitErr(err)
+	}
+
+	ctx := context.Background()
+	client, err := gcal.New(ctx, key, *calendarID)
+	if err != nil {
+		exitErr(withCode(exitUsage, err))
+	}
+	store := openStore(ctx, showstore.ReadOnly())
+	defer store.Close()
+
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		exitErr(err)
+	}
+	y, m, d := time.Now().In(loc).Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 0, *days)
+	shows, _, err := store.ListShows(ctx, showstore.ShowFilter{From: from, To: to})
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	existing, err := client.List(ctx, gcalTag, "1", from, to)
+	if err != nil {
+		exitErr(withCode(exitNetwork, err))
+	}
+
+	res, err := publishGCal(ctx, client, shows, existing, loc, *dryRun)
+	slog.Info("published to Google Calendar", "dry_run", *dryRun, "created", res.created, "updated", res.updated, "deleted", res.deleted, "unchanged", res.unchanged, "failed", res.failed)
+	if err != nil {
+		exitErr(withCode(exitNetwork, err))
+	}
+	if res.failed > 0 {
+		os.Exit(exitPartial)
+	}
+}
+
+type gcalResult struct {
+	created, updated, deleted, unchanged, failed int
+}
+
+// publishGCal reconciles existing (the calendar's shopsync events) with
+// shows. Individual write failures are logged and counted.
+func publishGCal(ctx context.Context, client *gcal.Client, shows []icalplayers.Event, existing []gcal.Event, loc *time.Location, dryRun bool) (gcalResult, error) {
+	var res gcalResult
+	have := map[string]gcal.Event{}
+	for _, e := range existing {
+		have[e.ID] = e
+	}
+	want := map[string]bool{}
+	for _, s := range shows {
+		if s.Start == nil {
+			continue
+		}
+		ev := gcalEvent(s, loc)
+		want[ev.ID] = true
+		old, ok := have[ev.ID]
+		if ok && old.ExtendedProperties != nil && old.ExtendedProperties.Private["hash"] == ev.ExtendedProperties.Private["hash"] {
+			res.unchanged++
+			continue
+		}
+		log := eventLogger(s).With("event_id", ev.ID)
+		if dryRun {
+			if ok {
+				res.updated++
+				log.Info("dry run; would update calendar event")
+			} else {
+				res.created++
+				log.Info("dry run; would create calendar event")
+			}
+			continue
+		}
+		created, err := client.Put(ctx, ev)
+		if err != nil {
+			if ctx.Err() != nil {
+				return res, err
+			}
+			res.failed++
+			log.Error("calendar write failed", "err", err)
+			continue
+		}
+		if created {
+			res.created++
+			log.Info("created calendar event")
+		} else {
+			res.updated++
+			log.Info("updated calendar event")
+		}
+	}
+	for id, e := range have {
+		if want[id] {
+			continue
+		}
+		log := slog.With("event_id", id, "summary", e.Summary)
+		if dryRun {
+			res.deleted++
+			log.Info("dry run; would delete calendar event")
+			continue
+		}
+		if err := client.Delete(ctx, id); err != nil {
+			if ctx.Err() != nil {
+				return res, err
+			}
+			res.failed++
+			log.Error("calendar delete failed", "err", err)
+			continue
+		}
+		res.deleted++
+		log.Info("deleted calendar event")
+	}
+	return res, nil
+}
+
+// gcalEventID maps a show UID to a valid Calendar event ID (base32hex
+// characters, 5-1024 long). Hex digits are a subset of base32hex.
+func gcalEventID(uid string) string {
+	sum := sha256.Sum256([]byte(uid))
+	return hex.EncodeToString(sum[:16])
+}
+
+// gcalEvent renders a show for the calendar. Teams and players lead the
+// description since that's what staff look for; the poster is linked
+// because Calendar only attaches Drive files. The private "hash" property
+// lets the next publish skip unchanged events.
+func gcalEvent(s icalplayers.Event, loc *time.Location) gcal.Event {
+	end := s.Start.Add(icalplayers.DefaultEventDuration)
+	if s.End != nil {
+		end = *s.End
+	}
+	var desc []string
+	if len(s.Teams) > 0 {
+		desc = append(desc, "Teams: "+joinList(s.Teams))
+	}
+	if len(s.Players) > 0 {
+		desc = append(desc, "Players: "+joinList(s.Players))
+	}
+	if s.Description != "" {
+		desc = append(desc, s.Description)
+	}
+	if s.PostImageURL != "" {
+		desc = append(desc, "Poster: "+s.PostImageURL)
+	}
+	ev := gcal.Event{
+		ID:          gcalEventID(s.UID),
+		Summary:     s.Summary,
+		Description: strings.Join(desc, "\n\n"),
+		Location:    s.Location,
+		Start:       &gcal.EventTime{DateTime: s.Start.In(loc).Format(time.RFC3339), TimeZone: loc.String()},
+		End:         &gcal.EventTime{DateTime: end.In(loc).Format(time.RFC3339), TimeZone: loc.String()},
+	}
+	if s.URL != "" {
+		ev.Source = &gcal.Source{Title: s.Summary, URL: s.URL}
+	}
+	b, _ := json.Marshal(ev)
+	sum := sha256.Sum256(b)
+	ev.ExtendedProperties = &gcal.ExtendedProperties{Private: map[string]string{
+		gcalTag: "1",
+		"uid":   s.UID,
+		"hash":  hex.EncodeToString(sum[:8]),
+	}}
+	return ev
+}
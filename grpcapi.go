@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showpb"
+	"github.com/tsny/shopsync/pkg/showstore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// showsService implements the gRPC Shows API (pkg/showpb) over the same
+// read-only store as the JSON endpoints. WatchShows polls the store, since
+// serve and the syncing process don't share memory.
+type showsService struct {
+	showpb.UnimplementedShowsServer
+	store         *showstore.Store
+	watchInterval time.Duration
+}
+
+// serveGRPC serves the Shows API on addr until ctx is done.
+func serveGRPC(ctx context.Context, addr string, svc *showsService) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	gs := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(logUnaryRPC),
+		grpc.ChainStreamInterceptor(logStreamRPC),
+	)
+	showpb.RegisterShowsServer(gs, svc)
+	go func() {
+		<-ctx.Done()
+		// Watch streams never finish on their own, so give unary calls a
+		// moment and then cut everything.
+		t := time.AfterFunc(10*time.Second, gs.Stop)
+		gs.GracefulStop()
+		t.Stop()
+	}()
+	slog.Info("serving grpc", "addr", addr)
+	if err := gs.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+func (g *showsService) ListShows(ctx context.Context, req *showpb.ListShowsRequest) (*showpb.ListShowsResponse, error) {
+	f := showstore.ShowFilter{
+		TeamID: req.GetTeamId(),
+		Query:  req.GetQuery(),
+		Limit:  int(req.GetLimit()),
+		Offset: int(req.GetOffset()),
+	}
+	if req.From != nil {
+		f.From = req.GetFrom().AsTime()
+	}
+	if req.To != nil {
+		f.To = req.GetTo().AsTime()
+	}
+	switch {
+	case f.Limit == 0:
+		f.Limit = defaultPageSize
+	case f.Limit < 0 || f.Limit > maxPageSize:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid limit %d: want 1-%d", f.Limit, maxPageSize)
+	}
+	if f.Offset < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid offset %d", f.Offset)
+	}
+	shows, total, err := g.store.ListShows(ctx, f)
+	if err != nil {
+		return nil, rpcInternal("ListShows", err)
+	}
+	resp := &showpb.ListShowsResponse{Total: int32(total)}
+	for _, e := range shows {
+		resp.Shows = append(resp.Shows, showToProto(e))
+	}
+	return resp, nil
+}
+
+func (g *showsService) GetShow(ctx context.Context, req *showpb.GetShowRequest) (*showpb.Show, error) {
+	e, err := g.store.GetShow(ctx, req.GetUid())
+	if err != nil {
+		return nil, rpcInternal("GetShow", err)
+	}
+	if e == nil {
+		return nil, status.Error(codes.NotFound, "show not found")
+	}
+	return showToProto(*e), nil
+}
+
+func (g *showsService) ListTeams(ctx context.Context, _ *showpb.ListTeamsRequest) (*showpb.ListTeamsResponse, error) {
+	teams, err := g.store.GetAllTeams(ctx)
+	if err != nil {
+		return nil, rpcInternal("ListTeams", err)
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Name < teams[j].Name })
+	resp := &showpb.ListTeamsResponse{}
+	for _, t := range teams {
+		resp.Teams = append(resp.Teams, &showpb.Team{Id: t.ID, Name: t.Name, Aliases: t.Aliases})
+	}
+	return resp, nil
+}
+
+// WatchShows polls the store every watchInterval and sends the difference
+// from the previous poll, comparing row versions.
+func (g *showsService) WatchShows(req *showpb.WatchShowsRequest, stream grpc.ServerStreamingServer[showpb.ShowChange]) error {
+	ctx := stream.Context()
+	f := showstore.ShowFilter{From: time.Now(), TeamID: req.GetTeamId()}
+	if req.From != nil {
+		f.From = req.GetFrom().AsTime()
+	}
+	seen := map[string]int64{} // uid -> version
+	ticker := time.NewTicker(g.watchInterval)
+	defer ticker.Stop()
+	for {
+		shows, _, err := g.store.ListShows(ctx, f)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return rpcInternal("WatchShows", err)
+		}
+		current := make(map[string]bool, len(shows))
+		for _, e := range shows {
+			current[e.UID] = true
+			kind := showpb.ShowChange_UPDATED
+			if v, ok := seen[e.UID]; !ok {
+				kind = showpb.ShowChange_ADDED
+			} else if v == e.Version {
+				continue
+			}
+			seen[e.UID] = e.Version
+			if err := stream.Send(&showpb.ShowChange{Kind: kind, Show: showToProto(e)}); err != nil {
+				return err
+			}
+		}
+		for uid := range seen {
+			if current[uid] {
+				continue
+			}
+			delete(seen, uid)
+			if err := stream.Send(&showpb.ShowChange{Kind: showpb.ShowChange_REMOVED, Show: &showpb.Show{Uid: uid}}); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func showToProto(e icalplayers.Event) *showpb.Show {
+	s := &showpb.Show{
+		Uid:          e.UID,
+		Summary:      e.Summary,
+		Description:  e.Description,
+		Url:          e.URL,
+		PostImageUrl: e.PostImageURL,
+		Players:      e.Players,
+		Teams:        e.Teams,
+		TeamIds:      e.TeamIDs,
+		Version:      e.Version,
+	}
+	if e.Start != nil {
+		s.Start = timestamppb.New(*e.Start)
+	}
+	if e.UpdatedAt != nil {
+		s.UpdatedAt = timestamppb.New(*e.UpdatedAt)
+	}
+	return s
+}
+
+// rpcInternal logs err and hides it from the client, like internalError
+// does for HTTP.
+func rpcInternal(method string, err error) error {
+	slog.Error("rpc failed", "method", method, "err", err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+func logUnaryRPC(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	slog.Debug("rpc", "method", info.FullMethod, "code", status.Code(err), "duration", time.Since(start))
+	return resp, err
+}
+
+func logStreamRPC(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	slog.Debug("rpc stream", "method", info.FullMethod, "code", status.Code(err), "duration", time.Since(start))
+	return err
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// healthChecks serves /healthz and /readyz. Ready means the database
+// answers a ping and, when freshness is non-zero, the last successful sync
+// finished within freshness.
+type healthChecks struct {
+	store     *showstore.Store
+	freshness time.Duration
+	lastSync  func(ctx context.Context) (time.Time, error)
+}
+
+func (h *healthChecks) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", h.handleHealthz)
+	mux.HandleFunc("GET /readyz", h.handleReadyz)
+}
+
+func (h *healthChecks) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *healthChecks) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	checks := map[string]string{}
+	ready := true
+
+	if err := h.store.Ping(ctx); err != nil {
+		checks["database"] = err.Error()
+		ready = false
+	} else {
+		checks["database"] = "ok"
+	}
+
+	if h.freshness > 0 {
+		last, err := h.lastSync(ctx)
+		switch {
+		case err != nil:
+			checks["sync"] = err.Error()
+			ready = false
+		case last.IsZero():
+			checks["sync"] = "no successful sync recorded"
+			ready = false
+		case time.Since(last) > h.freshness:
+			checks["sync"] = fmt.Sprintf("last successful sync %s ago exceeds %s", time.Since(last).Round(time.Second), h.freshness)
+			ready = false
+		default:
+			checks["sync"] = "ok"
+		}
+	}
+
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]any{"ready": ready, "checks": checks})
+}
+
+// syncClock remembers when the daemon last synced successfully. It starts
+// at the daemon's start time so a fresh daemon is ready until its first sync
+// has had a chance to run.
+type syncClock struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+func newSyncClock() *syncClock { return &syncClock{last: time.Now()} }
+
+func (c *syncClock) mark() {
+	c.mu.Lock()
+	c.last = time.Now()
+	c.mu.Unlock()
+}
+
+func (c *syncClock) get(context.Context) (time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last, nil
+}
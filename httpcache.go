@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultCacheAges is how long browsers and CDNs may reuse each cacheable
+// serve endpoint's responses; -cache overrides them. Zero means
+// "no-cache": store, but revalidate every time (cheap with an ETag).
+var defaultCacheAges = map[string]time.Duration{
+	"shows":    0, // /shows and /shows/{uid}
+	"teams":    0, // /teams and /teams/{id}/shows
+	"players":  0,
+	"calendar": 5 * time.Minute,
+	"feed":     5 * time.Minute,
+	"schema":   time.Hour,
+	"qr":       time.Hour, // /shows/{uid}/qr.png
+}
+
+// parseCacheAges applies -cache ENDPOINT=DURATION overrides to
+// defaultCacheAges.
+func parseCacheAges(overrides []string) (map[string]time.Duration, error) {
+	ages := make(map[string]time.Duration, len(defaultCacheAges))
+	for k, v := range defaultCacheAges {
+		ages[k] = v
+	}
+	for _, o := range overrides {
+		endpoint, v, ok := strings.Cut(o, "=")
+		endpoint = strings.TrimSpace(endpoint)
+		if _, known := defaultCacheAges[endpoint]; !ok || !known {
+			names := make([]string, 0, len(defaultCacheAges))
+			for k := range defaultCacheAges {
+				names = append(names, k)
+			}
+			slices.Sort(names)
+			return nil, fmt.Errorf("invalid -cache %q: want ENDPOINT=DURATION with ENDPOINT one of %s", o, strings.Join(names, ", "))
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid -cache %q: want a duration like 5m", o)
+		}
+		ages[endpoint] = d
+	}
+	return ages, nil
+}
+
+// cacheControl is the Cache-Control of a response reusable for maxAge.
+func cacheControl(private bool, maxAge time.Duration) string {
+	scope := "public"
+	if private {
+		scope = "private"
+	}
+	if maxAge <= 0 {
+		return scope + ", no-cache"
+	}
+	return fmt.Sprintf("%s, max-age=%d", scope, int(maxAge.Seconds()))
+}
+
+// cached buffers h's successful responses to give them endpoint's
+// Cache-Control and, unless -etag=false, an ETag of the body, answering a
+// matching If-None-Match with 304. Responses to requests carrying an API
+// key or calendar token, or that h marks "Cache-Control: private", are
+// private so a CDN doesn't share them.
+func (s *server) cached(endpoint string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		buf := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+		h(buf, r)
+		hdr := w.Header()
+		if buf.status != http.StatusOK {
+			w.WriteHeader(buf.status)
+			_, _ = buf.body.WriteTo(w)
+			return
+		}
+		private := hdr.Get("Cache-Control") == "private" || requestAPIKey(r) != "" || calendarToken(r) != ""
+		hdr.Set("Cache-Control", cacheControl(private, s.cacheAges[endpoint]))
+		if s.etags {
+			etag := bodyETag(buf.body.Bytes())
+			hdr.Set("ETag", etag)
+			if etagMatches(r.Header.Get("If-None-Match"), etag) {
+				hdr.Del("Content-Type")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = buf.body.WriteTo(w)
+	}
+}
+
+// bufferedResponse holds a response back so headers depending on the body
+// can still be set.
+type bufferedResponse struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) { b.status = status }
+
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header names etag.
+func etagMatches(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/csvevents"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// runImport stores shows from a spreadsheet through the same team matching,
+// overrides and upsert path as ingest.
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", "csv", "Input format: csv or tsv")
+	var columns stringList
+	fs.Var(&columns, "map", "Map a column header to an event field, e.g. -map 'Show Name=summary'. Fields: "+strings.Join(csvevents.Fields, ", ")+". Repeatable")
+	var opts ingestOptions
+	fs.BoolVar(&opts.dryRun, "dry-run", true, "If set, print the planned changes instead of storing them")
+	fs.BoolVar(&opts.useTeamsFile, "use-teams-file", false, "If set, match against teams.txt instead of the Team table")
+	fs.BoolVar(&opts.useRosters, "use-rosters", true, "Check players inferred from descriptions against the matched teams' rosters")
+	fs.StringVar(&opts.overridesFile, "overrides", "", "Per-event team, player and image overrides: YAML or CSV file or URL (see ingest)")
+	fs.DurationVar(&opts.minTurnaround, "min-turnaround", defaultMinTurnaround, "Report shows on the same stage less than this apart (see ingest)")
+	fs.Var(&opts.types, "type", "Only import rows of this type: show, class or workshop (see ingest). Repeatable")
+	fs.Var(&opts.blackouts, "blackout", "Report shows on days the venue is dark: a date, range or .ics of closures (see ingest). Repeatable")
+	fs.BoolVar(&opts.explainMatching, "explain-matching", false, "Print to stderr, per event, which team names matched and why others were rejected")
+	fs.StringVar(&opts.onError, "on-error", "continue", "What to do when one event fails: fail-fast or continue")
+	fs.StringVar(&opts.conflict, "conflict", "feed-wins", "How to treat players and teams edited in /admin: feed-wins, db-wins, newest-wins or merge-fields (see ingest)")
+	output := fs.String("output", "text", "Run report format: text or json")
+	logOpts := addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: shopsync import [flags] <file|->")
+		fmt.Fprintln(fs.Output(), "\nThe first row names the columns; Title, Date, Time, Venue, Link, Poster, Teams and the like are recognised without -map. A teams cell forces those teams (names, aliases or IDs) instead of matching the description, and a players cell those players instead of inferring them.")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	logOpts.setup()
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	comma := ','
+	switch *format {
+	case "csv":
+	case "tsv":
+		comma = '\t'
+	default:
+		exitErr(withCode(exitUsage, fmt.Errorf("invalid -format %q (want csv or tsv)", *format)))
+	}
+	colMap := map[string]string{}
+	for _, c := range columns {
+		header, field, ok := strings.Cut(c, "=")
+		field = strings.TrimSpace(field)
+		if !ok || strings.TrimSpace(header) == "" || !slices.Contains(csvevents.Fields, field) {
+			exitErr(withCode(exitUsage, fmt.Errorf("invalid -map %q (want Header=field, field one of %s)", c, strings.Join(csvevents.Fields, ", "))))
+		}
+		colMap[header] = field
+	}
+	if !validOutput(*output) {
+		exitErr(withCode(exitUsage, fmt.Errorf("invalid -output %q (want text or json)", *output)))
+	}
+	opts.imageWorkers = icalplayers.DefaultImageWorkers
+	opts.imageRate = icalplayers.DefaultImageRate
+	if err := opts.validate(); err != nil {
+		exitErr(withCode(exitUsage, err))
+	}
+
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		exitErr(err)
+	}
+	path := fs.Arg(0)
+	events, err := readSheet(path, csvevents.Options{Comma: comma, Location: loc, Columns: colMap})
+	if err != nil {
+		exitErr(err)
+	}
+	slog.Info("read sheet", "path", path, "events", len(events))
+
+	// Teams and players cells are explicit choices, so they are applied
+	// like an override rather than left to description matching.
+	var forced overrides
+	for i := range events {
+		e := &events[i]
+		o := &override{UID: e.UID}
+		if names := e.Teams; len(names) > 0 {
+			o.Teams = &names
+			e.Teams = nil
+		}
+		if names := e.Players; len(names) > 0 {
+			o.Players = &names
+		} else {
+			e.Players = icalplayers.InferPlayerNames(e.Description, nil)
+		}
+		if o.Teams != nil || o.Players != nil {
+			forced = append(forced, o)
+		}
+	}
+
+	ctx := context.Background()
+	store := openStore(ctx)
+	defer store.Close()
+
+	in := &ingester{
+		store:    store,
+		opts:     opts,
+		progress: newProgress(true),
+		sinks:    configuredSinks(),
+		source: func(ctx context.Context, report *syncReport) ([]icalplayers.Event, error) {
+			report.Sources = append(report.Sources, "import:"+path)
+			return events, nil
+		},
+		extraOverrides: forced,
+	}
+	report, err := in.runRecorded(ctx)
+	if *output == "text" && report.Planned != nil {
+		printPlan(os.Stdout, report.Planned)
+	}
+	if *output == "text" && len(report.Failures) > 0 {
+		printFailures(os.Stdout, report.Failures)
+	}
+	report.print(*output)
+	if err != nil {
+		exitErr(err)
+	}
+}
+
+// readSheet reads events from path, or stdin for "-".
+func readSheet(path string, opts csvevents.Options) ([]icalplayers.Event, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	events, err := csvevents.Read(r, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return events, nil
+}
@@ -0,0 +1,649 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/tsny/shopsync/pkg/daterange"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+	"github.com/tsny/shopsync/pkg/source"
+	"github.com/tsny/shopsync/pkg/wpevents"
+)
+
+// calendarPageURL is scraped for the Google Calendar feed when no -src is
+// given. A venue profile's calendar_page replaces it.
+var calendarPageURL = "https://theimprovshop.com/show-calendar/list/?tribe_paged=1&tribe_event_display=list&tribe_venues=233"
+
+const defaultWPCacheFile = "wp_events_cache.json"
+
+// ingestOptions are the flags shared by ingest and daemon.
+type ingestOptions struct {
+	srcs            stringList
+	wpURL           string
+	wpCache         string
+	skipImageSearch bool
+	details         bool
+	useTeamsFile    bool
+	useRosters      bool
+	dryRun          bool
+	printSummary    bool
+	from            string
+	to              string
+	teams           stringList
+	onError         string
+	policy          showstore.ErrorPolicy
+	conflict        string
+	conflictPolicy  showstore.ConflictStrategy
+	imageWorkers    int
+	imageRate       float64
+	batchSize       int
+	dbWorkers       int
+	webhooks        stringList
+	slackWebhook    string
+	slackNotify     string
+	slackKinds      []string
+	discordWebhook  string
+	lockFile        string
+	explainMatching bool
+	overridesFile   string
+	ebOrganizers    stringList
+	ebToken         string
+	squarespace     stringList
+	preferSources   stringList
+	resync          bool
+	minTurnaround   time.Duration
+	blackouts       stringList
+	types           stringList
+	eventTypes      []string // types, parsed
+}
+
+func (o *ingestOptions) register(fs *flag.FlagSet) {
+	fs.Var(&o.srcs, "src", "Source to ingest: an .ics path or URL, '-' for stdin, or a scheme:value source such as gcal:<calendar ID>, eventbrite:<organizer ID>, wp+https://… or squarespace+https://…. Repeat to ingest several feeds in one run")
+	fs.StringVar(&o.wpURL, "wp", "", "URL to WordPress tribe/events API (e.g. https://theimprovshop.com/wp-json/tribe/events/v1/events)")
+	fs.StringVar(&o.wpCache, "wp-cache", "", "Path to cached WP events JSON; skips live fetch when set")
+	fs.Var(&o.ebOrganizers, "eventbrite-org", "Also sync live events from this Eventbrite organizer ID. Repeatable")
+	fs.Var(&o.squarespace, "squarespace", "Also sync upcoming events from this Squarespace events page URL (read via ?format=json). Repeatable")
+	fs.Var(&o.preferSources, "prefer-source", "When several sources list the same show (same start, title and venue), keep the copy from the first source whose spec contains this text, e.g. a venue's domain. Repeatable, most preferred first; otherwise the source listed first wins")
+	fs.StringVar(&o.ebToken, "eventbrite-token", os.Getenv("EVENTBRITE_TOKEN"), "Eventbrite private API token (default $EVENTBRITE_TOKEN)")
+	fs.BoolVar(&o.skipImageSearch, "skip-image-search", false, "If set, do not attempt to fetch post images")
+	fs.BoolVar(&o.details, "details", false, "Also read the event pages of shows that already have an image, for ticket price, door time, age restriction and lineup (pages fetched for images are always read for them)")
+	fs.BoolVar(&o.useTeamsFile, "use-teams-file", false, "If set, parse teams from teams.txt and match to events")
+	fs.BoolVar(&o.useRosters, "use-rosters", true, "Check inferred players against the matched teams' rosters (see 'shopsync rosters')")
+	fs.BoolVar(&o.dryRun, "dry-run", true, "If set, do not store events in the database")
+	fs.BoolVar(&o.resync, "resync", false, "Sync even when the sources return exactly what the last successful sync stored (normally such runs stop after fetching and log \"no changes\")")
+	fs.BoolVar(&o.printSummary, "summary", false, "If set, print a summary of events after parsing")
+	fs.StringVar(&o.from, "from", "", "Only sync events starting on or after this date, in venue time: YYYY-MM-DD or a phrase like 'today' or 'this weekend' (a period alone selects all of it)")
+	fs.StringVar(&o.to, "to", "", "Only sync events starting on or before this date, in venue time: YYYY-MM-DD or a phrase like 'friday' or 'next 14 days'")
+	fs.Var(&o.teams, "team", "Only sync events matched to this team name or ID. Repeatable")
+	fs.Var(&o.types, "type", "Only sync events of this type: show, class or workshop, as classified by summary keywords and feed categories (see the venue profile's event_types). Repeatable")
+	fs.DurationVar(&o.minTurnaround, "min-turnaround", defaultMinTurnaround, "Report shows on the same stage that overlap, or that the feed gives less than this between one's end and the next's start")
+	fs.Var(&o.blackouts, "blackout", "Report shows on days the venue is dark: a date or range with a reason (2026-11-26=Thanksgiving, 2026-12-24..2026-12-26=Winter break), or an .ics file or URL of holidays and closures. Repeatable; re-read every sync")
+	fs.StringVar(&o.overridesFile, "overrides", "", "Per-event team, player and image overrides, keyed by uid or summary+date: a YAML or CSV file, or an http(s) URL of one such as a Google Sheet. Re-read every sync")
+	fs.BoolVar(&o.explainMatching, "explain-matching", false, "Print to stderr, per event, which team names matched and why others were rejected")
+	fs.IntVar(&o.imageWorkers, "image-concurrency", icalplayers.DefaultImageWorkers, "Number of event pages fetched in parallel for post images")
+	fs.Float64Var(&o.imageRate, "rate-limit", icalplayers.DefaultImageRate, "Max event page fetches per second during image enrichment (0 = unlimited)")
+	fs.IntVar(&o.dbWorkers, "db-workers", 4, "Number of events upserted at once, each in its own transaction (WordPress merges are always one at a time)")
+	fs.IntVar(&o.batchSize, "batch-size", 0, "Enrich, match and store this many events at a time rather than the whole feed at once, to bound memory on very large feeds (0 = all at once)")
+	fs.StringVar(&o.onError, "on-error", "continue", "What to do when one event fails: fail-fast or continue")
+	fs.StringVar(&o.conflict, "conflict", "feed-wins", "When the feed disagrees with players, teams or an image staff edited in /admin: feed-wins, db-wins, newest-wins (feed wins if the source changed the event after the edit) or merge-fields (union lists, keep the edited image)")
+	fs.StringVar(&o.lockFile, "lock-file", "", "Guard against concurrent syncs with this lock file instead of a database advisory lock")
+	fs.Var(&o.webhooks, "webhook", "POST added/changed shows as JSON to this URL after each sync; signed with WEBHOOK_SECRET if set. Repeatable")
+	fs.StringVar(&o.slackWebhook, "slack-webhook", os.Getenv("SLACK_WEBHOOK_URL"), "Slack incoming webhook URL for schedule change messages (default $SLACK_WEBHOOK_URL)")
+	fs.StringVar(&o.discordWebhook, "discord-webhook", os.Getenv("DISCORD_WEBHOOK_URL"), "Discord channel webhook URL for new show embeds (default $DISCORD_WEBHOOK_URL)")
+	fs.StringVar(&o.slackNotify, "slack-notify", "new,time,lineup", "Comma-separated change types to post to Slack: new, time, lineup")
+}
+
+// notifiers builds the notifiers selected by flags and environment.
+func (o *ingestOptions) notifiers() []notifier {
+	var out []notifier
+	secret := os.Getenv("WEBHOOK_SECRET")
+	for _, u := range o.webhooks {
+		out = append(out, newWebhookNotifier(u, secret))
+	}
+	if o.slackWebhook != "" && len(o.slackKinds) > 0 {
+		out = append(out, newSlackNotifier(o.slackWebhook, o.slackKinds))
+	}
+	if o.discordWebhook != "" {
+		out = append(out, newDiscordNotifier(o.discordWebhook))
+	}
+	return out
+}
+
+// validate checks flag combinations and fills derived fields. Call it once
+// after parsing.
+func (o *ingestOptions) validate() error {
+	p, err := showstore.ParseErrorPolicy(o.onError)
+	if err != nil {
+		return err
+	}
+	o.policy = p
+	if o.conflictPolicy, err = showstore.ParseConflictStrategy(o.conflict); err != nil {
+		return err
+	}
+	if o.imageWorkers < 1 {
+		return fmt.Errorf("-image-concurrency must be at least 1, got %d", o.imageWorkers)
+	}
+	if o.imageRate < 0 {
+		return fmt.Errorf("-rate-limit must not be negative, got %g", o.imageRate)
+	}
+	for _, spec := range o.blackouts {
+		if blackoutDateRe.MatchString(strings.TrimSpace(spec)) {
+			if _, err := loadBlackouts(context.Background(), []string{spec}, time.UTC); err != nil {
+				return err
+			}
+		}
+	}
+	if o.eventTypes, err = parseEventTypes(o.types); err != nil {
+		return fmt.Errorf("-type: %w", err)
+	}
+	if o.minTurnaround < 0 {
+		return fmt.Errorf("-min-turnaround must not be negative, got %s", o.minTurnaround)
+	}
+	if o.dbWorkers < 1 {
+		return fmt.Errorf("-db-workers must be at least 1, got %d", o.dbWorkers)
+	}
+	if o.batchSize < 0 {
+		return fmt.Errorf("-batch-size must not be negative, got %d", o.batchSize)
+	}
+	stdinCount := 0
+	for _, src := range o.srcs {
+		if src == "-" {
+			stdinCount++
+		}
+	}
+	if stdinCount > 1 {
+		return errors.New("-src - may only be given once")
+	}
+	for _, u := range o.squarespace {
+		if !isURL(u) {
+			return fmt.Errorf("invalid -squarespace %q", u)
+		}
+	}
+	for _, spec := range o.sourceSpecs() {
+		if _, err := source.Open(spec); err != nil {
+			return fmt.Errorf("invalid -src: %w", err)
+		}
+		if strings.HasPrefix(spec, "eventbrite:") && o.ebToken == "" {
+			return errors.New("Eventbrite sources need -eventbrite-token or EVENTBRITE_TOKEN")
+		}
+	}
+	for _, u := range o.webhooks {
+		if !isURL(u) {
+			return fmt.Errorf("invalid -webhook %q", u)
+		}
+	}
+	if o.slackWebhook != "" && !isURL(o.slackWebhook) {
+		return errors.New("invalid -slack-webhook URL")
+	}
+	if o.discordWebhook != "" && !isURL(o.discordWebhook) {
+		return errors.New("invalid -discord-webhook URL")
+	}
+	if o.slackKinds, err = parseSlackKinds(o.slackNotify); err != nil {
+		return err
+	}
+	_, _, err = o.window()
+	return err
+}
+
+// window returns the [from, to) range selected by -from/-to, read by
+// dateWindow. A zero time means unbounded on that side.
+func (o *ingestOptions) window() (from, to time.Time, err error) {
+	return dateWindow(o.from, o.to)
+}
+
+// dateWindow reads -from and -to values (see daterange.Help) relative to
+// now in venue time.
+func dateWindow(from, to string) (time.Time, time.Time, error) {
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	start, end, err := daterange.Window(from, to, time.Now().In(loc))
+	if err != nil {
+		return start, end, fmt.Errorf("invalid -from/-to: %w", err)
+	}
+	return start, end, nil
+}
+
+// feedCacheEntry remembers the last successful fetch of a source so the
+// next run can pass its change token and reuse the events when unchanged.
+type feedCacheEntry struct {
+	token  string
+	events []icalplayers.Event
+}
+
+// ingester runs one sync. A daemon keeps the same ingester across runs so
+// feedCache survives; a one-shot ingest leaves feedCache nil.
+type ingester struct {
+	store     *showstore.Store
+	opts      ingestOptions
+	feedCache map[string]*feedCacheEntry
+	progress  *progress
+	notifiers []notifier
+	sinks     []sink
+	resolver  *resolver // nil unless -interactive
+	// source, when set, replaces the feeds selected in opts (import).
+	source func(ctx context.Context, report *syncReport) ([]icalplayers.Event, error)
+	// extraOverrides are applied alongside -overrides, e.g. the teams
+	// column of an imported sheet.
+	extraOverrides overrides
+	// resync makes the next run sync even if its sources are unchanged
+	// (see unchangedStage), like -resync; the daemon sets it for POST /sync.
+	resync bool
+	// idempotencyKeys are recorded with the next run: the keys of the
+	// POST /sync requests it answers.
+	idempotencyKeys []string
+}
+
+func runIngest(args []string) {
+	fs := flag.NewFlagSet("ingest", flag.ExitOnError)
+	var opts ingestOptions
+	opts.register(fs)
+	output := fs.String("output", "text", "Run report format: text or json (json prints a report to stdout)")
+	quiet := fs.Bool("quiet", false, "Suppress the progress indicator on stderr")
+	interactive := fs.Bool("interactive", false, "Prompt for a team for each event that matches none; choices are saved as aliases unless -dry-run")
+	profile := fs.String("profile", "", "Write CPU and heap profiles (cpu.pprof, heap.pprof) to this directory and print per-stage timings to stderr")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if !validOutput(*output) {
+		exitErr(fmt.Errorf("invalid -output %q (want text or json)", *output))
+	}
+	if err := opts.validate(); err != nil {
+		exitErr(err)
+	}
+	if *interactive {
+		if err := checkInteractive(&opts); err != nil {
+			exitErr(withCode(exitUsage, err))
+		}
+	}
+
+	ctx := context.Background()
+	store := openStore(ctx)
+	defer store.Close()
+
+	in := &ingester{store: store, opts: opts, progress: newProgress(*quiet || *interactive), notifiers: opts.notifiers(), sinks: configuredSinks()}
+	if *interactive {
+		in.resolver = newResolver(store, !opts.dryRun)
+	}
+	var stopProfile func() error
+	if *profile != "" {
+		var err error
+		if stopProfile, err = startProfile(*profile); err != nil {
+			exitErr(err)
+		}
+	}
+	began := time.Now()
+	report, err := in.runRecorded(ctx)
+	if stopProfile != nil {
+		if err := stopProfile(); err != nil {
+			slog.Error("writing profiles", "err", err)
+		}
+		printTimings(os.Stderr, report.timings, time.Since(began))
+		slog.Info("wrote profiles", "dir", *profile)
+	}
+	if *output == "text" && report.Planned != nil {
+		printPlan(os.Stdout, report.Planned)
+	}
+	if *output == "text" && len(report.Failures) > 0 {
+		printFailures(os.Stdout, report.Failures)
+	}
+	report.print(*output)
+	if err != nil {
+		exitErr(err)
+	}
+}
+
+// resolveEdits settles e against the hand edits on the stored show existing
+// by -conflict. The upsert path does the same inside its transaction.
+func (in *ingester) resolveEdits(ctx context.Context, existing, e icalplayers.Event) (icalplayers.Event, error) {
+	if in.opts.conflictPolicy == showstore.FeedWins {
+		return e, nil
+	}
+	ed, err := in.store.GetEdits(ctx, existing.UID)
+	if err != nil {
+		return e, err
+	}
+	if len(ed.Fields) > 0 {
+		eventLogger(e).Debug("settling hand edits", "fields", ed.Fields, "strategy", in.opts.conflictPolicy)
+	}
+	e, _ = showstore.ResolveConflict(existing, ed, e, in.opts.conflictPolicy)
+	return e, nil
+}
+
+// applyMerge writes the changed columns of a WordPress event onto the
+// matching stored show.
+func (in *ingester) applyMerge(ctx context.Context, existing *icalplayers.Event, e icalplayers.Event, descChanged, teamsChanged, imageChanged, detailsChanged bool) error {
+	if descChanged || teamsChanged {
+		if err := in.store.UpdateDescriptionAndTeams(ctx, existing.UID, existing.Version, e.Description, e.Teams, e.TeamIDs); err != nil {
+			return err
+		}
+	}
+	if imageChanged {
+		if err := in.store.UpdateShowImageURL(ctx, existing.UID, e.PostImageURL); err != nil {
+			return err
+		}
+	}
+	if detailsChanged {
+		if err := in.store.UpdateShowDetails(ctx, existing.UID, e.Details); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fail records a per-event failure. Under fail-fast it returns the error so
+// the caller aborts; under continue it returns nil and the run goes on.
+func (in *ingester) fail(report *syncReport, e icalplayers.Event, err error) error {
+	eventLogger(e).Error("event failed", "err", err)
+	reportEventError(report, e, err)
+	report.Failures = append(report.Failures, reportFailure{UID: e.UID, Summary: e.Summary, Error: err.Error()})
+	if in.opts.policy == showstore.FailFast {
+		return showstore.EventError{UID: e.UID, Summary: e.Summary, Err: err}
+	}
+	return nil
+}
+
+// reportEventError sends a per-event failure to the error tracker.
+func reportEventError(report *syncReport, e icalplayers.Event, err error) {
+	reportError(err, sentry.LevelError, map[string]string{
+		"kind": "event", "uid": e.UID, "summary": e.Summary, "url": e.URL, "sources": strings.Join(report.Sources, " "),
+	})
+}
+
+// fetch loads events from whichever source the options select.
+func (in *ingester) fetch(ctx context.Context, report *syncReport) ([]icalplayers.Event, error) {
+	if in.source != nil {
+		return in.source(ctx, report)
+	}
+	opts := in.opts
+	if opts.wpCache != "" {
+		report.Sources = append(report.Sources, opts.wpCache)
+		slog.Info("loading WP events from cache", "path", opts.wpCache)
+		events, err := wpevents.LoadCache(opts.wpCache)
+		if err != nil {
+			return nil, fmt.Errorf("wp cache load: %w", err)
+		}
+		slog.Info("loaded events from cache", "count", len(events))
+		return events, nil
+	}
+	if opts.wpURL != "" {
+		// Fetch events from the WordPress tribe/events API
+		report.Sources = append(report.Sources, opts.wpURL)
+		events, err := wpevents.FetchAll(ctx, opts.wpURL)
+		if err != nil {
+			return nil, fmt.Errorf("wp fetch: %w", err)
+		}
+		if err = wpevents.SaveCache(defaultWPCacheFile, events); err != nil {
+			slog.Warn("could not save WP cache", "err", err)
+			report.warn("could not save WP cache: %v", err)
+		} else {
+			slog.Info("saved WP events cache", "path", defaultWPCacheFile)
+		}
+		return events, nil
+	}
+
+	srcs := opts.sourceSpecs()
+	if len(opts.srcs) == 0 {
+		// Query the page to find the Google Calendar URL
+		slog.Info("no -src provided, fetching calendar URL from page")
+		calendarURL, err := extractGoogleCalendarURL(ctx, calendarPageURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract calendar URL: %w", err)
+		}
+		slog.Info("found calendar URL", "url", calendarURL)
+		srcs = append([]string{calendarURL}, srcs...)
+	}
+
+	source.EventbriteToken = opts.ebToken
+	var batches []sourceEvents
+	for _, src := range srcs {
+		report.Sources = append(report.Sources, src)
+		evs, err := in.loadSource(ctx, src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src, err)
+		}
+		batches = append(batches, sourceEvents{spec: src, events: evs})
+	}
+	if len(batches) == 1 {
+		return batches[0].events, nil
+	}
+	events, dropped := dedupeSources(batches, opts.preferSources)
+	slog.Info("merged sources", "sources", len(srcs), "events", len(events), "duplicates", dropped)
+	return events, nil
+}
+
+// sourceSpecs returns the -src values followed by the sources implied by
+// -eventbrite-org and -squarespace, in the form source.Open takes.
+func (o *ingestOptions) sourceSpecs() []string {
+	specs := slices.Clone([]string(o.srcs))
+	for _, org := range o.ebOrganizers {
+		specs = append(specs, "eventbrite:"+org)
+	}
+	for _, u := range o.squarespace {
+		specs = append(specs, "squarespace+"+u)
+	}
+	return specs
+}
+
+// filterByWindow keeps events starting in [from, to). Events without a start
+// time are dropped since they can't be placed in the window.
+func filterByWindow(events []icalplayers.Event, from, to time.Time) []icalplayers.Event {
+	var out []icalplayers.Event
+	for _, e := range events {
+		if e.Start == nil {
+			continue
+		}
+		if !from.IsZero() && e.Start.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !e.Start.Before(to) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// rosterOf returns the members of every roster in teamIDs, without
+// duplicates; nil if none of the teams has a stored roster.
+func rosterOf(rosters map[string][]string, teamIDs []string) []string {
+	var out []string
+	for _, id := range teamIDs {
+		for _, name := range rosters[id] {
+			if !slices.Contains(out, name) {
+				out = append(out, name)
+			}
+		}
+	}
+	return out
+}
+
+// filterByTeams keeps events matched to at least one of the given team names
+// (case-insensitive) or IDs.
+func filterByTeams(events []icalplayers.Event, teams []string) []icalplayers.Event {
+	var out []icalplayers.Event
+	for _, e := range events {
+		if eventHasTeam(e, teams) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func eventHasTeam(e icalplayers.Event, teams []string) bool {
+	for _, want := range teams {
+		for _, name := range e.Teams {
+			if strings.EqualFold(name, want) {
+				return true
+			}
+		}
+		for _, id := range e.TeamIDs {
+			if id == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// eventLogger returns the default logger annotated with the event's identity.
+func eventLogger(e icalplayers.Event) *slog.Logger {
+	return slog.With("uid", e.UID, "summary", e.Summary)
+}
+
+// loadSource reads events from a single source spec (see source.Open).
+// Sources that support change tokens go through feedCache when the ingester
+// has one.
+func (in *ingester) loadSource(ctx context.Context, spec string) ([]icalplayers.Event, error) {
+	src, err := source.Open(spec)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("reading source", "src", spec)
+	cond, ok := src.(source.Conditional)
+	if !ok || in.feedCache == nil {
+		return src.Fetch(ctx)
+	}
+	entry := in.feedCache[spec]
+	var token string
+	if entry != nil {
+		token = entry.token
+	}
+	evs, next, err := cond.FetchSince(ctx, token)
+	if errors.Is(err, source.ErrNotModified) && entry != nil {
+		metricFeedCache.WithLabelValues("hit").Inc()
+		slog.Info("feed not modified; reusing cached events", "src", spec, "count", len(entry.events))
+		return cloneEvents(entry.events), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	metricFeedCache.WithLabelValues("miss").Inc()
+	in.feedCache[spec] = &feedCacheEntry{token: next, events: cloneEvents(evs)}
+	return evs, nil
+}
+
+// cloneEvents copies events deeply enough that team matching on the copy
+// does not leak into the cached slice.
+func cloneEvents(in []icalplayers.Event) []icalplayers.Event {
+	out := make([]icalplayers.Event, len(in))
+	for i, e := range in {
+		e.Players = append([]string(nil), e.Players...)
+		e.Teams = append([]string(nil), e.Teams...)
+		e.TeamIDs = append([]string(nil), e.TeamIDs...)
+		e.Categories = append([]string(nil), e.Categories...)
+		out[i] = e
+	}
+	return out
+}
+
+// sourceEvents is what one source returned, for deduplication across
+// sources.
+type sourceEvents struct {
+	spec   string
+	events []icalplayers.Event
+}
+
+// dedupeSources merges several sources' events, dropping the ones that
+// appear in more than one: co-produced shows, or the same feed reached two
+// ways. Events are the same if they share a UID, or start at the same time
+// with the same normalized summary at the same place (see sameVenue). The
+// copy from the most preferred source (see sourceRank) is kept, taking any
+// image, page or details it lacks from the others.
+func dedupeSources(batches []sourceEvents, prefer []string) (out []icalplayers.Event, dropped int) {
+	batches = slices.Clone(batches)
+	slices.SortStableFunc(batches, func(a, b sourceEvents) int {
+		return sourceRank(a.spec, prefer) - sourceRank(b.spec, prefer)
+	})
+	byUID := map[string]int{}
+	byKey := map[string][]int{}
+	for _, b := range batches {
+	events:
+		for _, e := range b.events {
+			if i, ok := byUID[e.UID]; ok && e.UID != "" {
+				fillFromDuplicate(&out[i], e)
+				dropped++
+				continue
+			}
+			key := ""
+			if e.Start != nil {
+				key = e.Start.UTC().Format(time.RFC3339) + "|" + normalizeSummary(e.Summary)
+				for _, i := range byKey[key] {
+					if sameVenue(out[i].Location, e.Location) {
+						eventLogger(e).Debug("dropping duplicate from another source", "source", b.spec, "kept_uid", out[i].UID)
+						fillFromDuplicate(&out[i], e)
+						dropped++
+						continue events
+					}
+				}
+			}
+			byUID[e.UID] = len(out)
+			if key != "" {
+				byKey[key] = append(byKey[key], len(out))
+			}
+			out = append(out, e)
+		}
+	}
+	return out, dropped
+}
+
+// sourceRank is the index of the first -prefer-source entry that spec
+// contains, or len(prefer) if none does; lower is preferred.
+func sourceRank(spec string, prefer []string) int {
+	for i, p := range prefer {
+		if strings.Contains(spec, p) {
+			return i
+		}
+	}
+	return len(prefer)
+}
+
+// sameVenue compares the place part of two locations (before the first
+// comma, normalized, without a leading "the"). A missing location matches
+// anything, as does one place name containing the other, so "Improv Shop"
+// matches "The Improv Shop, 123 Main St".
+func sameVenue(a, b string) bool {
+	na, nb := venueKey(a), venueKey(b)
+	return na == "" || nb == "" || strings.Contains(na, nb) || strings.Contains(nb, na)
+}
+
+func venueKey(loc string) string {
+	place, _, _ := strings.Cut(strings.ToLower(loc), ",")
+	place = strings.TrimPrefix(strings.TrimSpace(place), "the ")
+	return normalizeSummary(place)
+}
+
+// fillFromDuplicate gives kept what dup has and it lacks.
+func fillFromDuplicate(kept *icalplayers.Event, dup icalplayers.Event) {
+	if kept.PostImageURL == "" {
+		kept.PostImageURL = dup.PostImageURL
+	}
+	if kept.URL == "" {
+		kept.URL = dup.URL
+	}
+	if kept.Location == "" {
+		kept.Location = dup.Location
+	}
+	if dup.Details != nil {
+		kept.Details = icalplayers.FillDetails(kept.Details, dup.Details)
+	}
+}
+
+var nonAlnumRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// normalizeSummary mirrors the store's dedup normalization: lowercase and
+// strip everything that isn't a letter or digit.
+func normalizeSummary(s string) string {
+	return nonAlnumRe.ReplaceAllString(strings.ToLower(s), "")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+func TestDedupeSources(t *testing.T) {
+	eight := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+	ten := eight.Add(2 * time.Hour)
+	ev := func(uid, summary, loc string, start *time.Time) icalplayers.Event {
+		return icalplayers.Event{UID: uid, Summary: summary, Location: loc, Start: start}
+	}
+	tests := []struct {
+		name    string
+		batches []sourceEvents
+		prefer  []string
+		want    []string // kept UIDs, in order
+		dropped int
+	}{
+		{
+			name: "same UID",
+			batches: []sourceEvents{
+				{"a.ics", []icalplayers.Event{ev("1", "Harold Night", "", &eight)}},
+				{"b.ics", []icalplayers.Event{ev("1", "Harold Night (late)", "", &ten)}},
+			},
+			want: []string{"1"}, dropped: 1,
+		},
+		{
+			name: "same start, summary and venue",
+			batches: []sourceEvents{
+				{"a.ics", []icalplayers.Event{ev("a1", "Friday Night Harold!", "The Improv Shop, 1900 Main St", &eight)}},
+				{"b.ics", []icalplayers.Event{ev("b1", "friday night harold", "Improv Shop", &eight)}},
+			},
+			want: []string{"a1"}, dropped: 1,
+		},
+		{
+			name: "a missing location matches any",
+			batches: []sourceEvents{
+				{"a.ics", []icalplayers.Event{ev("a1", "Harold Night", "Main Stage", &eight)}},
+				{"b.ics", []icalplayers.Event{ev("b1", "Harold Night", "", &eight)}},
+			},
+			want: []string{"a1"}, dropped: 1,
+		},
+		{
+			name: "different stages",
+			batches: []sourceEvents{
+				{"a.ics", []icalplayers.Event{ev("a1", "Harold Night", "Main Stage", &eight)}},
+				{"b.ics", []icalplayers.Event{ev("b1", "Harold Night", "Studio B", &eight)}},
+			},
+			want: []string{"a1", "b1"},
+		},
+		{
+			name: "different starts",
+			batches: []sourceEvents{
+				{"a.ics", []icalplayers.Event{ev("a1", "Harold Night", "", &eight)}},
+				{"b.ics", []icalplayers.Event{ev("b1", "Harold Night", "", &ten)}},
+			},
+			want: []string{"a1", "b1"},
+		},
+		{
+			name: "undated events without UIDs are all kept",
+			batches: []sourceEvents{
+				{"a.ics", []icalplayers.Event{ev("", "Harold Night", "", nil), ev("", "Harold Night", "", nil)}},
+			},
+			want: []string{"", ""},
+		},
+		{
+			name: "preferred source wins",
+			batches: []sourceEvents{
+				{"https://tickets.example.com/feed.ics", []icalplayers.Event{ev("t1", "Harold Night", "", &eight), ev("t2", "Jam", "", &ten)}},
+				{"https://theimprovshop.com/calendar.ics", []icalplayers.Event{ev("v1", "Harold Night", "", &eight)}},
+			},
+			prefer: []string{"theimprovshop.com"},
+			want:   []string{"v1", "t2"}, dropped: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, dropped := dedupeSources(tt.batches, tt.prefer)
+			var got []string
+			for _, e := range out {
+				got = append(got, e.UID)
+			}
+			if !slices.Equal(got, tt.want) || dropped != tt.dropped {
+				t.Errorf("kept %q, dropped %d; want %q, %d", got, dropped, tt.want, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestDedupeSourcesFillsGaps(t *testing.T) {
+	at := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+	kept := icalplayers.Event{UID: "a1", Summary: "Harold Night", Start: &at,
+		Details: &icalplayers.Details{Doors: "7:30 PM"}}
+	dup := icalplayers.Event{UID: "b1", Summary: "Harold Night", Start: &at, Location: "Main Stage",
+		URL: "https://example.com/harold", PostImageURL: "https://example.com/harold.jpg",
+		Details: &icalplayers.Details{Doors: "7 PM", Price: "$10"}}
+	out, _ := dedupeSources([]sourceEvents{{"a.ics", []icalplayers.Event{kept}}, {"b.ics", []icalplayers.Event{dup}}}, nil)
+	if len(out) != 1 {
+		t.Fatalf("kept %d events, want 1", len(out))
+	}
+	e := out[0]
+	if e.UID != "a1" || e.URL != dup.URL || e.PostImageURL != dup.PostImageURL || e.Location != dup.Location {
+		t.Errorf("kept %+v, want a1 with b1's page, image and location", e)
+	}
+	if e.Details == nil || e.Details.Doors != "7:30 PM" || e.Details.Price != "$10" {
+		t.Errorf("details %+v, want a1's doors and b1's price", e.Details)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+const (
+	defaultQRSize = 512
+	maxQRSize     = 2048
+)
+
+// runLinks gives each upcoming show with a ticket URL a short link, served
+// by serve at /s/{code}, and with -qr-dir writes a QR code PNG of it per
+// show for lobby screens and flyers.
+func runLinks(args []string) {
+	fs := flag.NewFlagSet("links", flag.ExitOnError)
+	days := fs.Int("days", 60, "Cover shows starting within this many days")
+	baseURL := fs.String("base-url", "", "Public URL of serve, e.g. https://shows.example.com; short links are <base-url>/s/<code>")
+	qrDir := fs.String("qr-dir", "", "Write a QR code of each short link to <qr-dir>/<code>.png (needs -base-url)")
+	qrSize := fs.Int("qr-size", defaultQRSize, "QR code width and height in pixels")
+	dryRun := fs.Bool("dry-run", true, "If set, only list shows and the links they already have")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if *days < 1 {
+		exitErr(withCode(exitUsage, errors.New("-days must be at least 1")))
+	}
+	if *qrSize < 21 || *qrSize > maxQRSize {
+		exitErr(withCode(exitUsage, fmt.Errorf("-qr-size must be 21-%d", maxQRSize)))
+	}
+	if *qrDir != "" && *baseURL == "" {
+		exitErr(withCode(exitUsage, errors.New("-qr-dir needs -base-url")))
+	}
+	if *baseURL != "" && !isURL(*baseURL) {
+		exitErr(withCode(exitUsage, fmt.Errorf("invalid -base-url %q", *baseURL)))
+	}
+	base := strings.TrimSuffix(*baseURL, "/")
+	if *qrDir != "" && !*dryRun {
+		if err := os.MkdirAll(*qrDir, 0o755); err != nil {
+			exitErr(err)
+		}
+	}
+
+	ctx := context.Background()
+	var storeOpts []showstore.Option
+	if *dryRun {
+		storeOpts = append(storeOpts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, storeOpts...)
+	defer store.Close()
+
+	now := time.Now()
+	shows, _, err := store.ListShows(ctx, showstore.ShowFilter{From: now, To: now.AddDate(0, 0, *days)})
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CODE\tLINK\tSHOW\tTARGET")
+	var created, failed int
+	for _, e := range shows {
+		if e.URL == "" {
+			continue
+		}
+		var code string
+		if *dryRun {
+			code, err = store.ShortLinkCode(ctx, e.UID)
+		} else {
+			had, _ := store.ShortLinkCode(ctx, e.UID)
+			if code, err = store.EnsureShortLink(ctx, e.UID); err == nil && had == "" {
+				created++
+			}
+		}
+		if err != nil {
+			slog.Error("short link failed", "uid", e.UID, "err", dbErr(err))
+			failed++
+			continue
+		}
+		link := "-"
+		if code == "" {
+			code = "(new)"
+		} else if base != "" {
+			link = base + "/s/" + code
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", code, link, truncateStr(e.Summary, 40), e.URL)
+		if *qrDir == "" || *dryRun {
+			continue
+		}
+		if err := qrcode.WriteFile(link, qrcode.Medium, *qrSize, filepath.Join(*qrDir, code+".png")); err != nil {
+			slog.Error("writing QR code failed", "uid", e.UID, "err", err)
+			failed++
+		}
+	}
+	tw.Flush()
+	slog.Info("short links", "shows", len(shows), "created", created, "failed", failed, "dry_run", *dryRun)
+	if failed > 0 {
+		os.Exit(exitPartial)
+	}
+}
+
+// handleShortLink redirects a short link to its show's ticket URL as it
+// is now, so a flyer keeps working when the URL changes.
+func (s *server) handleShortLink(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.store.ResolveShortLink(r.Context(), r.PathValue("code"))
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	if uid == "" {
+		writeError(w, http.StatusNotFound, errors.New("unknown link"))
+		return
+	}
+	e, err := s.store.GetShow(r.Context(), uid)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	if e == nil || e.URL == "" {
+		writeError(w, http.StatusNotFound, errors.New("this show has no ticket link"))
+		return
+	}
+	http.Redirect(w, r, e.URL, http.StatusFound)
+}
+
+// handleQR serves a QR code PNG for a show: of its short link when it has
+// one, otherwise of its ticket URL. ?size= sets the width in pixels.
+func (s *server) handleQR(w http.ResponseWriter, r *http.Request) {
+	size := defaultQRSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 21 || n > maxQRSize {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid size %q: want 21-%d", v, maxQRSize))
+			return
+		}
+		size = n
+	}
+	uid := r.PathValue("uid")
+	e, err := s.store.GetShow(r.Context(), uid)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	if e == nil || e.URL == "" {
+		writeError(w, http.StatusNotFound, errors.New("show not found or has no ticket link"))
+		return
+	}
+	target := e.URL
+	code, err := s.store.ShortLinkCode(r.Context(), uid)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	if code != "" {
+		target = requestBaseURL(r) + "/s/" + code
+	}
+	png, err := qrcode.Encode(target, qrcode.Medium, size)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(png)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// localeConfig is a venue profile's locale: the language for month and
+// weekday names, a 12h or 24h clock, and optionally its own layouts (Go
+// reference-time layouts, e.g. "Monday 2 January"). It applies to the email
+// digest, the lobby board, the Atom feed, the schedule site, captions and
+// the -summary listing.
+type localeConfig struct {
+	Language  string   `yaml:"language"` // en (default) or fr; fr-CA and the like count as fr
+	Clock     string   `yaml:"clock"`    // 12h or 24h; defaults to the language's
+	LongDate  string   `yaml:"long_date"`
+	ShortDate string   `yaml:"short_date"`
+	Time      string   `yaml:"time"`
+	DateTime  string   `yaml:"date_time"`
+	FullDate  string   `yaml:"full_date"` // a date, year, time and zone
+	Weekdays  []string `yaml:"weekdays"`  // 7 names, Sunday first
+	Months    []string `yaml:"months"`    // 12 names, January first
+}
+
+// locale formats dates and times for people. Go's time.Format only knows
+// English names, so format swaps in the locale's.
+type locale struct {
+	lang                                          string // for <html lang>, e.g. fr-CA
+	longDate, shortDate, time, dateTime, fullDate string
+	phrases                                       map[string]string
+
+	weekdays, shortWeekdays [7]string
+	months, shortMonths     [12]string
+}
+
+// localeLanguage holds one language's names and layouts; layouts take the
+// clock's time layout. phrases translate the few fixed words the built-in
+// templates use; English needs none.
+type localeLanguage struct {
+	weekdays, shortWeekdays [7]string
+	months, shortMonths     [12]string
+	clock                   string // 12h or 24h
+	time12, time24          string
+	layouts                 func(tm string) (long, short, dateTime, full string)
+	phrases                 map[string]string
+}
+
+var localeLanguages = map[string]localeLanguage{
+	"en": {
+		weekdays:      [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		shortWeekdays: [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+		months:        [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		shortMonths:   [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		clock:         "12h", time12: "3:04 PM", time24: "15:04",
+		layouts: func(tm string) (string, string, string, string) {
+			return "Monday, January 2", "Jan 2", "Mon Jan 2, " + tm, "Monday, January 2, 2006 at " + tm + " MST"
+		},
+	},
+	"fr": {
+		weekdays:      [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		shortWeekdays: [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		months:        [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		shortMonths:   [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		clock:         "24h", time12: "3:04 PM", time24: "15 h 04",
+		layouts: func(tm string) (string, string, string, string) {
+			return "Monday 2 January", "2 Jan", "Mon 2 Jan, " + tm, "Monday 2 January 2006 à " + tm + " MST"
+		},
+		phrases: map[string]string{
+			"at":                 "à",
+			"Week of":            "Semaine du",
+			"Updated":            "Mis à jour le",
+			"No upcoming shows.": "Aucun spectacle à venir.",
+		},
+	},
+}
+
+// venueLocale is the active venue's locale; English with a 12h clock
+// unless its profile sets one.
+var venueLocale = mustNewLocale(localeConfig{})
+
+// newLocale checks c and fills in what it leaves out from its language.
+func newLocale(c localeConfig) (*locale, error) {
+	lang := strings.ToLower(c.Language)
+	if lang == "" {
+		lang = "en"
+	}
+	if base, _, ok := strings.Cut(strings.ReplaceAll(lang, "_", "-"), "-"); ok {
+		lang = base
+	}
+	ll, ok := localeLanguages[lang]
+	if !ok {
+		return nil, fmt.Errorf("locale: unknown language %q (want en or fr)", c.Language)
+	}
+	clock := c.Clock
+	if clock == "" {
+		clock = ll.clock
+	}
+	tm := ll.time12
+	switch clock {
+	case "12h":
+	case "24h":
+		tm = ll.time24
+	default:
+		return nil, fmt.Errorf("locale: invalid clock %q (want 12h or 24h)", c.Clock)
+	}
+	tag := c.Language
+	if tag == "" {
+		tag = lang
+	}
+	l := &locale{lang: tag, time: tm, phrases: ll.phrases, weekdays: ll.weekdays, shortWeekdays: ll.shortWeekdays, months: ll.months, shortMonths: ll.shortMonths}
+	l.longDate, l.shortDate, l.dateTime, l.fullDate = ll.layouts(tm)
+	for _, o := range []struct {
+		layout *string
+		v      string
+	}{{&l.longDate, c.LongDate}, {&l.shortDate, c.ShortDate}, {&l.time, c.Time}, {&l.dateTime, c.DateTime}, {&l.fullDate, c.FullDate}} {
+		if o.v != "" {
+			*o.layout = o.v
+		}
+	}
+	if c.Weekdays != nil {
+		if len(c.Weekdays) != 7 {
+			return nil, fmt.Errorf("locale: weekdays has %d names, want 7 starting with Sunday", len(c.Weekdays))
+		}
+		copy(l.weekdays[:], c.Weekdays)
+	}
+	if c.Months != nil {
+		if len(c.Months) != 12 {
+			return nil, fmt.Errorf("locale: months has %d names, want 12", len(c.Months))
+		}
+		copy(l.months[:], c.Months)
+	}
+	return l, nil
+}
+
+func mustNewLocale(c localeConfig) *locale {
+	l, err := newLocale(c)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// localeNames are the layout elements format replaces, longest first so
+// "Monday" isn't read as "Mon".
+var localeNames = []string{"January", "Monday", "Jan", "Mon"}
+
+// format is t.Format(layout) with the locale's month and weekday names.
+func (l *locale) format(t time.Time, layout string) string {
+	var b strings.Builder
+	for layout != "" {
+		at, name := -1, ""
+		for _, n := range localeNames {
+			if i := strings.Index(layout, n); i >= 0 && (at < 0 || i < at) {
+				at, name = i, n
+			}
+		}
+		if at < 0 {
+			b.WriteString(t.Format(layout))
+			break
+		}
+		b.WriteString(t.Format(layout[:at]))
+		switch name {
+		case "January":
+			b.WriteString(l.months[t.Month()-1])
+		case "Jan":
+			b.WriteString(l.shortMonths[t.Month()-1])
+		case "Monday":
+			b.WriteString(l.weekdays[t.Weekday()])
+		case "Mon":
+			b.WriteString(l.shortWeekdays[t.Weekday()])
+		}
+		layout = layout[at+len(name):]
+	}
+	return b.String()
+}
+
+// LongDate, ShortDate, Time, DateTime and FullDate format t with the
+// locale's layouts.
+func (l *locale) LongDate(t time.Time) string  { return l.format(t, l.longDate) }
+func (l *locale) ShortDate(t time.Time) string { return l.format(t, l.shortDate) }
+func (l *locale) Time(t time.Time) string      { return l.format(t, l.time) }
+func (l *locale) DateTime(t time.Time) string  { return l.format(t, l.dateTime) }
+func (l *locale) FullDate(t time.Time) string  { return l.format(t, l.fullDate) }
+
+// Weekday is t's weekday name.
+func (l *locale) Weekday(t time.Time) string { return l.weekdays[t.Weekday()] }
+
+// phrase translates one of the built-in templates' English phrases, or
+// returns it as is.
+func (l *locale) phrase(s string) string {
+	if p, ok := l.phrases[s]; ok {
+		return p
+	}
+	return s
+}
+
+// localeFuncs are the template functions for venueLocale, looked up when a
+// template runs so a venue profile applied after parsing still counts.
+func localeFuncs() map[string]any {
+	return map[string]any{
+		"lang":      func() string { return venueLocale.lang },
+		"longDate":  func(t time.Time) string { return venueLocale.LongDate(t) },
+		"shortDate": func(t time.Time) string { return venueLocale.ShortDate(t) },
+		"time":      func(t time.Time) string { return venueLocale.Time(t) },
+		"dateTime":  func(t time.Time) string { return venueLocale.DateTime(t) },
+		"weekday":   func(t time.Time) string { return venueLocale.Weekday(t) },
+		"phrase":    func(s string) string { return venueLocale.phrase(s) },
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// errSyncLocked means another sync holds the lock.
+var errSyncLocked = errors.New("another sync is already running")
+
+// acquireSyncLock takes the lock file when lockFile is set, otherwise the
+// database advisory lock. On databases without advisory locks it logs a
+// warning and proceeds unlocked; use -lock-file there.
+func (in *ingester) acquireSyncLock(ctx context.Context) (func(), error) {
+	if in.opts.lockFile != "" {
+		return lockFile(in.opts.lockFile)
+	}
+	unlock, err := in.store.TryLock(ctx, syncLockKey())
+	switch {
+	case errors.Is(err, showstore.ErrLocked):
+		return nil, withCode(exitLocked, fmt.Errorf("%w (database advisory lock %d is held)", errSyncLocked, syncLockKey()))
+	case errors.Is(err, showstore.ErrLockUnsupported):
+		slog.Warn("database has no advisory locks; concurrent syncs are not prevented (use -lock-file)")
+		return func() {}, nil
+	case err != nil:
+		return nil, dbErr(fmt.Errorf("take sync lock: %w", err))
+	}
+	return unlock, nil
+}
+
+// lockFile takes an exclusive flock on path, writing our PID into it so the
+// message for the loser says who holds it. The kernel drops the lock if the
+// process dies.
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		holder, _ := os.ReadFile(path)
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			msg := fmt.Sprintf("%s is locked", path)
+			if pid := strings.TrimSpace(string(holder)); pid != "" {
+				msg += " by pid " + pid
+			}
+			return nil, withCode(exitLocked, fmt.Errorf("%w (%s)", errSyncLocked, msg))
+		}
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	_ = f.Truncate(0)
+	_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return func() {
+		_ = f.Truncate(0)
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
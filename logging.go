@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/tsny/shopsync/pkg/httpclient"
+)
+
+// logOptions holds the logging and display flags shared by every
+// subcommand.
+type logOptions struct {
+	level    string
+	format   string
+	tz       string
+	cacheDir string
+}
+
+func addLogFlags(fs *flag.FlagSet) *logOptions {
+	o := &logOptions{}
+	fs.StringVar(&o.level, "log-level", "info", "Log level: debug, info, warn or error")
+	fs.StringVar(&o.format, "log-format", "text", "Log format: text or json")
+	addConfigFlags(fs)
+	fs.StringVar(&o.tz, "tz", "", "Show times in this IANA timezone (e.g. America/New_York, UTC, Local) in summaries, exports, digests and the API; stored times stay UTC")
+	fs.StringVar(&o.cacheDir, "cache-dir", httpclient.DefaultCacheDir(), "Directory for cached feed, page and image responses (see 'shopsync cache'); empty keeps them in memory only")
+	return o
+}
+
+// setup installs the default slog logger. Logs always go to stderr so that
+// stdout stays usable for command output.
+func (o *logOptions) setup() {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(o.level)); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -log-level %q\n", o.level)
+		os.Exit(exitUsage)
+	}
+	hopts := &slog.HandlerOptions{Level: lvl}
+	var h slog.Handler
+	switch strings.ToLower(o.format) {
+	case "json":
+		h = slog.NewJSONHandler(os.Stderr, hopts)
+	case "text", "":
+		h = slog.NewTextHandler(os.Stderr, hopts)
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -log-format %q\n", o.format)
+		os.Exit(exitUsage)
+	}
+	slog.SetDefault(slog.New(h))
+	if err := setOutputTZ(o.tz); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+	httpclient.CacheDir = o.cacheDir
+}
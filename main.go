@@ -1,370 +1,321 @@
-// main.go
-package main
-
-import (
-	"bufio"
-	"context"
-	"errors"
-	"flag"
-	"fmt"
-	"log"
-	"net/http"
-	"net/url"
-	"os"
-	"sort"
-	"strings"
-
-	_ "time/tzdata"
-
-	"github.com/PuerkitoBio/goquery"
-	"github.com/joho/godotenv"
-	"github.com/tsny/shopsync/pkg/icalplayers"
-	"github.com/tsny/shopsync/pkg/showstore"
-	"github.com/tsny/shopsync/pkg/wpevents"
-	"github.com/tsny/shopsync/pkg/wpimg"
-)
-
-func main() {
-	src := flag.String("src", "", "Path or URL to an .ics file. Use '-' to read from stdin")
-	wpURL := flag.String("wp", "", "URL to WordPress tribe/events API (e.g. https://theimprovshop.com/wp-json/tribe/events/v1/events)")
-	wpCache := flag.String("wp-cache", "", "Path to cached WP events JSON; skips live fetch when set")
-	postURL := flag.String("post-url", "", "testing param: grabs image from given post URL")
-	skipImageSearch := flag.Bool("skip-image-search", false, "If set, do not attempt to fetch post images")
-	useTeamsFile := flag.Bool("use-teams-file", false, "If set, parse teams from teams.txt and match to events")
-	dryRun := flag.Bool("dry-run", true, "If set, do not store events in the database")
-	printSummary := flag.Bool("summary", false, "If set, print a summary of events after parsing")
-	flag.Parse()
-
-	if *skipImageSearch {
-		icalplayers.SkipImageSearch = true
-	}
-
-	_ = godotenv.Load()
-
-	if postURL != nil && *postURL != "" {
-		// https://theimprovshop.com/show/teams-level-2-student-showcase-16/
-		res, err := wpimg.Fetch(context.Background(), *postURL)
-		if err != nil {
-			log.Fatal(err)
-		}
-		fmt.Println("Fetched image:", res.ImageURL)
-		return
-	}
-
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
-		log.Fatal("DATABASE_URL missing")
-	}
-
-	ctx := context.Background()
-	store, err := showstore.Open(ctx, dbURL)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer store.Close()
-
-	var events []icalplayers.Event
-
-	const defaultWPCacheFile = "wp_events_cache.json"
-
-	if *wpCache != "" {
-		fmt.Printf("Loading WP events from cache: %s\n", *wpCache)
-		events, err = wpevents.LoadCache(*wpCache)
-		if err != nil {
-			exitErr(fmt.Errorf("wp cache load: %w", err))
-		}
-		fmt.Printf("Loaded %d events from cache.\n", len(events))
-	} else if *wpURL != "" {
-		// Fetch events from the WordPress tribe/events API
-		events, err = wpevents.FetchAll(ctx, *wpURL)
-		if err != nil {
-			exitErr(fmt.Errorf("wp fetch: %w", err))
-		}
-		if err = wpevents.SaveCache(defaultWPCacheFile, events); err != nil {
-			fmt.Fprintf(os.Stderr, "warning: could not save WP cache: %v\n", err)
-		} else {
-			fmt.Printf("Saved WP events cache to %s\n", defaultWPCacheFile)
-		}
-	} else {
-		var calendarURL string
-		if *src == "" {
-			// Query the page to find the Google Calendar URL
-			fmt.Println("No -src provided, fetching calendar URL from page...")
-			pageURL := "https://theimprovshop.com/show-calendar/list/?tribe_paged=1&tribe_event_display=list&tribe_venues=233"
-			calendarURL, err = extractGoogleCalendarURL(ctx, pageURL)
-			if err != nil {
-				exitErr(fmt.Errorf("failed to extract calendar URL: %w", err))
-			}
-			fmt.Printf("Found calendar URL: %s\n", calendarURL)
-		} else {
-			calendarURL = *src
-		}
-
-		if isURL(calendarURL) {
-			fmt.Printf("Reading ICS from URL: %s\n", calendarURL)
-			events, err = icalplayers.FromURL(context.Background(), calendarURL, http.DefaultClient, nil)
-			if err != nil {
-				exitErr(err)
-			}
-		} else {
-			fmt.Printf("Reading ICS from file: %s\n", calendarURL)
-			events, err = icalplayers.FromFile(calendarURL, nil)
-			if err != nil {
-				exitErr(err)
-			}
-		}
-	}
-
-	if len(events) == 0 {
-		fmt.Println("No events found")
-		return
-	}
-
-	var teams []showstore.Team
-	if *useTeamsFile {
-		teamList, err := ReadLinesToArray("teams.txt")
-		if err != nil {
-			exitErr(err)
-		}
-		for _, t := range teamList {
-			teams = append(teams, showstore.Team{Name: t})
-		}
-	} else {
-		teams, err = store.GetAllTeams(ctx)
-		if err != nil {
-			exitErr(err)
-		}
-		fmt.Printf("Loaded %d teams from database.\n", len(teams))
-	}
-
-	for i, ev := range events {
-		parsedTeams := findTeamsInEventDescription(ev.Description, teams)
-		if len(parsedTeams) > 0 {
-			for _, t := range parsedTeams {
-				if t.ID == "" {
-					fmt.Printf("Skipping team with empty ID: %s\n", t.Name)
-					return
-				}
-				events[i].TeamIDs = append(events[i].TeamIDs, t.ID)
-				events[i].Teams = append(events[i].Teams, t.Name)
-			}
-		} else {
-			fmt.Printf("Event %s matches no teams.\n", ev.Summary)
-		}
-	}
-
-	for i, ev := range events {
-		if ev.PostImageURL != "" {
-			events[i].PostImageURL = wpevents.RewriteCdnCgiURL(ev.PostImageURL)
-		}
-	}
-
-	if *printSummary {
-		icalplayers.SummarizeEvents(events)
-	}
-
-	if *dryRun {
-		fmt.Println("Dry run; not storing events.")
-		return
-	}
-
-	if *wpURL != "" || *wpCache != "" {
-		// Use InsertIfNew to avoid overwriting or duplicating events already imported via ICS.
-		// Deduplication is by (date, summary) so collisions across different source IDs are caught.
-		var inserted, updated, skipped int
-		for _, e := range events {
-			existing, err := store.FindByDateAndSummary(ctx, e.Start, e.Summary)
-			if err != nil {
-				exitErr(err)
-			}
-			if existing == nil {
-				ok, err := store.InsertIfNew(ctx, e)
-				if err != nil {
-					exitErr(err)
-				}
-				if ok {
-					inserted++
-					fmt.Printf("Inserted: %s (%s)\n", e.Summary, e.Start)
-				} else {
-					fmt.Printf("%v already exists, skipping insert: %s (%s)\n", e.Start, e.Summary, e.UID)
-				}
-				continue
-			}
-			descChanged := existing.Description != e.Description
-			teamsChanged := !teamsEqualSorted(existing.Teams, e.Teams)
-			imageChanged := e.PostImageURL != "" && existing.PostImageURL != e.PostImageURL
-			if !descChanged && !teamsChanged && !imageChanged {
-				skipped++
-				fmt.Printf("Unchanged: %s (%s)\n", e.Summary, e.Start)
-				continue
-			}
-			fmt.Printf("Updating: %s (%s)\n", e.Summary, e.Start)
-			if descChanged {
-				fmt.Printf("  description: %q\n            -> %q\n",
-					truncateStr(existing.Description, 80), truncateStr(e.Description, 80))
-			}
-			if teamsChanged {
-				fmt.Printf("  teams: %v -> %v\n", existing.Teams, e.Teams)
-			}
-			if imageChanged {
-				fmt.Printf("  image: %s -> %s\n", existing.PostImageURL, e.PostImageURL)
-			}
-			if descChanged || teamsChanged {
-				if err := store.UpdateDescriptionAndTeams(ctx, existing.UID, e.Description, e.Teams, e.TeamIDs); err != nil {
-					exitErr(err)
-				}
-			}
-			if imageChanged {
-				if err := store.UpdateShowImageURL(ctx, existing.UID, e.PostImageURL); err != nil {
-					exitErr(err)
-				}
-			}
-			updated++
-		}
-		fmt.Printf("Inserted %d, updated %d, unchanged %d.\n", inserted, updated, skipped)
-	} else {
-		for _, e := range events {
-			if err := store.Upsert(ctx, e); err != nil {
-				exitErr(err)
-			}
-		}
-		fmt.Printf("Stored %d events.\n", len(events))
-	}
-}
-
-func truncateStr(s string, n int) string {
-	if len(s) <= n {
-		return s
-	}
-	return s[:n] + "..."
-}
-
-func teamsEqualSorted(a, b []string) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	ac, bc := make([]string, len(a)), make([]string, len(b))
-	copy(ac, a)
-	copy(bc, b)
-	sort.Strings(ac)
-	sort.Strings(bc)
-	for i := range ac {
-		if ac[i] != bc[i] {
-			return false
-		}
-	}
-	return true
-}
-
-func isURL(s string) bool {
-	u, err := url.Parse(s)
-	return err == nil && u.Scheme != "" && u.Host != ""
-}
-
-func exitErr(err error) {
-	fmt.Fprintln(os.Stderr, "error:", err)
-	os.Exit(1)
-}
-
-// findTeamsInEventDescription from event description
-func findTeamsInEventDescription(desc string, teams []showstore.Team) []showstore.Team {
-	var matches []showstore.Team
-	for _, t := range teams {
-		if len(t.Name) <= 4 { // skip short/generic names
-			continue
-		}
-		if strings.Contains(desc, t.Name) {
-			fmt.Println(t.Name)
-			matches = append(matches, t)
-		}
-	}
-	return matches
-}
-
-// read new line separated file into array
-func ReadLinesToArray(path string) ([]string, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-	return lines, nil
-}
-
-// extractGoogleCalendarURL fetches the page and extracts the calendar URL from the Google Calendar link
-func extractGoogleCalendarURL(ctx context.Context, pageURL string) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	// Find the Google Calendar link
-	var calendarURL string
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
-		text := strings.TrimSpace(s.Text())
-		if strings.Contains(text, "Google Calendar") {
-			href, exists := s.Attr("href")
-			if exists {
-				calendarURL = href
-			}
-		}
-	})
-
-	if calendarURL == "" {
-		return "", errors.New("Google Calendar link not found on page")
-	}
-
-	// Parse the Google Calendar URL to extract the cid parameter
-	parsedURL, err := url.Parse(calendarURL)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse Google Calendar URL: %w", err)
-	}
-
-	cid := parsedURL.Query().Get("cid")
-	if cid == "" {
-		return "", errors.New("cid parameter not found in Google Calendar URL")
-	}
-
-	// URL decode the cid parameter
-	decodedCID, err := url.QueryUnescape(cid)
-	if err != nil {
-		return "", fmt.Errorf("failed to decode cid parameter: %w", err)
-	}
-
-	// Parse the decoded webcal URL
-	webcalURL, err := url.Parse(decodedCID)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse webcal URL: %w", err)
-	}
-
-	// Convert webcal:// to https:// to get the actual .ics file URL
-	if webcalURL.Scheme == "webcal" {
-		webcalURL.Scheme = "https"
-	}
-
-	return webcalURL.String(), nil
-}
+// main.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	_ "time/tzdata"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/joho/godotenv"
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+func main() {
+	_ = godotenv.Load()
+	setupTracing(context.Background())
+	setupErrorReporting()
+	defer flushTelemetry()
+
+	args := os.Args[1:]
+	// Bare flags (e.g. the Cloud Run job's "-wp ... -dry-run=false") keep
+	// working as an implicit ingest.
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && !slices.Contains([]string{"-h", "-help", "--help", "-version", "--version"}, args[0])) {
+		runIngest(args)
+		return
+	}
+
+	switch args[0] {
+	case "ingest":
+		runIngest(args[1:])
+	case "daemon":
+		runDaemon(args[1:])
+	case "backup":
+		runBackup(args[1:])
+	case "restore":
+		runRestore(args[1:])
+	case "cache":
+		runCache(args[1:])
+	case "cards":
+		runCards(args[1:])
+	case "captions":
+		runCaptions(args[1:])
+	case "db":
+		runDB(args[1:])
+	case "dedupe":
+		runDedupe(args[1:])
+	case "dict":
+		runDict(args[1:])
+	case "discord":
+		runDiscord(args[1:])
+	case "digest":
+		runDigest(args[1:])
+	case "export":
+		runExport(args[1:])
+	case "backfill-images":
+		runBackfillImages(args[1:])
+	case "refresh-images":
+		runRefreshImages(args[1:])
+	case "publish-airtable":
+		runPublishAirtable(args[1:])
+	case "publish-gcal":
+		runPublishGCal(args[1:])
+	case "publish-notion":
+		runPublishNotion(args[1:])
+	case "render":
+		runRender(args[1:])
+	case "reprocess":
+		runReprocess(args[1:])
+	case "rosters":
+		runRosters(args[1:])
+	case "links":
+		runLinks(args[1:])
+	case "prune":
+		runPrune(args[1:])
+	case "serve":
+		runServe(args[1:])
+	case "stats":
+		runStats(args[1:])
+	case "teams":
+		runTeams(args[1:])
+	case "image":
+		runImage(args[1:])
+	case "import":
+		runImport(args[1:])
+	case "attendance":
+		runAttendance(args[1:])
+	case "seasons":
+		runSeasons(args[1:])
+	case "review":
+		runReview(args[1:])
+	case "tokens":
+		runTokens(args[1:])
+	case "keys":
+		runKeys(args[1:])
+	case "validate":
+		runValidate(args[1:])
+	case "webhook":
+		runWebhook(args[1:])
+	case "version", "-version", "--version":
+		runVersion(args[1:])
+	case "help", "-h", "-help", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		usage()
+		os.Exit(exitUsage)
+	}
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `usage: shopsync <command> [flags]
+
+Commands:
+  ingest            fetch a feed, match teams and store shows (default)
+  daemon            run ingest repeatedly on an interval
+  backup            write every table (and optionally post images) to one .tar.gz archive
+  restore <file>    replay a backup archive into an empty database
+  cache             show the HTTP cache's size by kind and host
+  cache purge       delete cached responses by age, host or kind
+  cards             draw an Open Graph share card PNG for each upcoming show
+  captions          print a ready-to-post promo caption for each upcoming show
+  db migrate        create or update the schema
+  db drop           drop every shopsync table, API keys and sync history included
+  db recreate       drop and re-create the schema
+  export            write stored shows as JSON
+  import <file>     store shows from a CSV or TSV spreadsheet
+  links             give upcoming shows short links (/s/{code}) and QR code PNGs
+  dedupe            find stored shows listed twice under different UIDs and merge them
+  dict build        add confirmed players from rosters and stored shows to the name dictionary
+  discord register  register the Discord bot's /nextshow and /tonight slash commands
+  digest            email the next week's shows grouped by night
+  backfill-images   look up post images for stored shows that have none
+  refresh-images    re-scrape replacements for post images that return 404
+  publish-airtable  upsert upcoming shows into an Airtable table
+  publish-gcal      mirror upcoming shows into a shared Google Calendar
+  publish-notion    mirror upcoming shows into a Notion database
+  render            write upcoming shows as a static HTML schedule
+  reprocess         re-run player and team inference over stored shows
+  review            list, approve, reassign or dismiss shows that matched no team
+  rosters           scrape team pages for performer names and headshots
+  prune             delete old shows, orphaned show_teams rows and unused images
+  serve             serve shows and teams as a read-only JSON API
+  stats             upcoming show counts, idle teams, missing images and sync history
+  attendance        record (set) or list house counts and capacity per show
+  seasons           group shows into named runs, by hand or from recurring series
+  teams             list teams from the database
+  teams export      write the Team table in teams-file format
+  tokens            add, list or revoke private calendar subscriber tokens
+  keys              add, list or revoke API keys for serve -api-keys
+  validate          lint a feed without touching the database
+  webhook verify    check a received webhook body against its signature headers
+  image <url>       fetch the post image URL for a single event page
+  version           print version, commit, build date and schema version
+
+Run "shopsync <command> -h" for command flags. Flags can also be set in
+the environment as SHOPSYNC_<FLAG>, e.g. SHOPSYNC_SRC=a.ics,b.ics; flags
+on the command line take precedence. -dry-run, -yes and -force are only
+read from the command line.
+
+Exit codes: 0 ok, 1 other error, 2 usage, 3 feed parse error,
+4 network error, 5 database error, 6 some events failed,
+7 another sync is already running.
+`)
+}
+
+// openStore connects to DATABASE_URL or exits.
+func openStore(ctx context.Context, opts ...showstore.Option) *showstore.Store {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		exitErr(errors.New("DATABASE_URL missing"))
+	}
+	store, err := showstore.Open(ctx, dbURL, storeOptions(opts)...)
+	if err != nil {
+		exitErr(err)
+	}
+	return store
+}
+
+// venueTimezone is the venue's local zone, The Improv Shop's unless a venue
+// profile says otherwise. Dates given on the command line are interpreted
+// in it.
+var venueTimezone = "America/Chicago"
+
+// stringList is a flag.Value that collects every occurrence of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func truncateStr(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func teamsEqualSorted(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	ac, bc := make([]string, len(a)), make([]string, len(b))
+	copy(ac, a)
+	copy(bc, b)
+	sort.Strings(ac)
+	sort.Strings(bc)
+	for i := range ac {
+		if ac[i] != bc[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// exitErr logs err and exits with the code for its failure class.
+func exitErr(err error) {
+	slog.Error(err.Error())
+	flushTelemetry()
+	os.Exit(exitCode(err))
+}
+
+// findTeamsInEventDescription from event description
+func findTeamsInEventDescription(desc string, teams []showstore.Team) []showstore.Team {
+	var matches []showstore.Team
+	for _, t := range teams {
+		for _, name := range append([]string{t.Name}, t.Aliases...) {
+			if ok, _ := nameMatch(desc, name); ok {
+				matches = append(matches, t)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// extractGoogleCalendarURL fetches the page and extracts the calendar URL from the Google Calendar link
+func extractGoogleCalendarURL(ctx context.Context, pageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := httpclient.Default.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	// Find the Google Calendar link
+	var calendarURL string
+	doc.Find("a").Each(func(i int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		if strings.Contains(text, "Google Calendar") {
+			href, exists := s.Attr("href")
+			if exists {
+				calendarURL = href
+			}
+		}
+	})
+
+	if calendarURL == "" {
+		return "", errors.New("Google Calendar link not found on page")
+	}
+
+	// Parse the Google Calendar URL to extract the cid parameter
+	parsedURL, err := url.Parse(calendarURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Google Calendar URL: %w", err)
+	}
+
+	cid := parsedURL.Query().Get("cid")
+	if cid == "" {
+		return "", errors.New("cid parameter not found in Google Calendar URL")
+	}
+
+	// URL decode the cid parameter
+	decodedCID, err := url.QueryUnescape(cid)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode cid parameter: %w", err)
+	}
+
+	// Parse the decoded webcal URL
+	webcalURL, err := url.Parse(decodedCID)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse webcal URL: %w", err)
+	}
+
+	// Convert webcal:// to https:// to get the actual .ics file URL
+	if webcalURL.Scheme == "webcal" {
+		webcalURL.Scheme = "https"
+	}
+
+	return webcalURL.String(), nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+var (
+	metricEventsParsed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "shopsync_events_parsed_total",
+		Help: "Events parsed from feeds.",
+	})
+	metricRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "shopsync_rows_total",
+		Help: "Show rows written by outcome (upserted, inserted, updated, unchanged, failed).",
+	}, []string{"outcome"})
+	metricSyncDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "shopsync_sync_duration_seconds",
+		Help:    "Duration of sync runs by result.",
+		Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"result"})
+	metricLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "shopsync_last_successful_sync_timestamp_seconds",
+		Help: "Unix time the last sync finished without error.",
+	})
+	metricImageFetch = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "shopsync_image_fetch_duration_seconds",
+		Help:    "Event page fetches for post images by result (found, error).",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 8),
+	}, []string{"result"})
+	metricFeedCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "shopsync_feed_cache_total",
+		Help: "Conditional ICS fetches by result (hit means the server answered 304).",
+	}, []string{"result"})
+	metricHTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "shopsync_http_requests_total",
+		Help: "API requests by route and status code.",
+	}, []string{"route", "code"})
+	metricOutbound = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "shopsync_outbound_request_duration_seconds",
+		Help:    "Requests to feeds and sites by host and result (status code, cached or error), retries included.",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"host", "result"})
+	metricOutboundRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "shopsync_outbound_retries_total",
+		Help: "Retried requests to feeds and sites by host.",
+	}, []string{"host"})
+)
+
+func init() {
+	icalplayers.ImageFetchObserver = func(d time.Duration, err error) {
+		result := "found"
+		if err != nil {
+			result = "error"
+		}
+		metricImageFetch.WithLabelValues(result).Observe(d.Seconds())
+	}
+	httpclient.Observer = func(o httpclient.Observation) {
+		result := strconv.Itoa(o.Status)
+		switch {
+		case o.Err != nil:
+			result = "error"
+		case o.Cached:
+			result = "cached"
+		}
+		metricOutbound.WithLabelValues(o.Host, result).Observe(o.Duration.Seconds())
+		if o.Retries > 0 {
+			metricOutboundRetries.WithLabelValues(o.Host).Add(float64(o.Retries))
+		}
+	}
+}
+
+// observeSync records the metrics for one finished sync.
+func observeSync(report *syncReport, d time.Duration, err error) {
+	metricEventsParsed.Add(float64(report.EventsParsed))
+	rows := report.Rows
+	for outcome, n := range map[string]int{
+		"upserted":  rows.Upserted,
+		"inserted":  rows.Inserted,
+		"updated":   rows.Updated,
+		"unchanged": rows.Unchanged,
+		"failed":    len(report.Failures),
+	} {
+		metricRows.WithLabelValues(outcome).Add(float64(n))
+	}
+	result := "ok"
+	if err != nil {
+		result = "error"
+	} else {
+		if report.Skipped {
+			result = "skipped"
+		}
+		metricLastSuccess.SetToCurrentTime()
+	}
+	metricSyncDuration.WithLabelValues(result).Observe(d.Seconds())
+}
+
+// metricsHandler serves the default registry.
+func metricsHandler() http.Handler { return promhttp.Handler() }
+
+// serveOps starts an HTTP listener on addr for the daemon's operational
+// endpoints. It returns immediately; listen errors are logged.
+func serveOps(addr string, mux *http.ServeMux) *http.Server {
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		slog.Info("serving operational endpoints", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("operational endpoint listener failed", "addr", addr, "err", err)
+		}
+	}()
+	return srv
+}
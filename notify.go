@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// changeSet is what a sync did to the stored shows. Ingest never deletes,
+// so Removed is currently always empty; it's part of the payload so
+// consumers don't have to change when prune starts reporting.
+type changeSet struct {
+	RunID    string              `json:"runId,omitempty"`
+	SyncedAt time.Time           `json:"syncedAt"`
+	Added    []icalplayers.Event `json:"added"`
+	Changed  []changedShow       `json:"changed"`
+	Removed  []icalplayers.Event `json:"removed"`
+}
+
+// changedShow is a show as it is after the sync, plus the fields that moved.
+type changedShow struct {
+	Show   icalplayers.Event `json:"show"`
+	Fields []fieldChange     `json:"fields"`
+}
+
+func (c changeSet) empty() bool {
+	return len(c.Added) == 0 && len(c.Changed) == 0 && len(c.Removed) == 0
+}
+
+// scheduleChanges are the changed shows whose start or venue moved, with
+// only those fields. A venue appearing where none was stored before (as
+// happens once after location started being stored) isn't a move.
+func (c changeSet) scheduleChanges() []changedShow {
+	var out []changedShow
+	for _, ch := range c.Changed {
+		var fields []fieldChange
+		for _, f := range ch.Fields {
+			if f.Field == "start" || f.Field == "location" && f.Old != "" {
+				fields = append(fields, f)
+			}
+		}
+		if len(fields) > 0 {
+			out = append(out, changedShow{Show: ch.Show, Fields: fields})
+		}
+	}
+	return out
+}
+
+// notifier is told about every non-empty change set after a real sync.
+type notifier interface {
+	name() string
+	notify(ctx context.Context, cs changeSet) error
+}
+
+// pendingChange pairs the planned change for an event with the event itself,
+// so the change set can be built once we know which writes succeeded.
+type pendingChange struct {
+	plan  plannedChange
+	event icalplayers.Event
+}
+
+// changeSet builds the change set for report, leaving out events that
+// failed to write.
+func (r *syncReport) changeSet() changeSet {
+	failed := map[string]bool{}
+	for _, f := range r.Failures {
+		failed[f.UID] = true
+	}
+	cs := changeSet{
+		RunID:    r.RunID,
+		SyncedAt: time.Now().UTC(),
+		Added:    []icalplayers.Event{},
+		Changed:  []changedShow{},
+		Removed:  []icalplayers.Event{},
+	}
+	for _, p := range r.pending {
+		if failed[p.event.UID] {
+			continue
+		}
+		e := p.event
+		e.UID = p.plan.UID // merges land on the existing row's UID
+		switch p.plan.Action {
+		case "insert":
+			cs.Added = append(cs.Added, e)
+		case "update":
+			cs.Changed = append(cs.Changed, changedShow{Show: e, Fields: p.plan.Fields})
+		}
+	}
+	return cs
+}
+
+// notify hands the run's changes to every notifier. Notification failures
+// are logged and never fail the sync.
+func (in *ingester) notify(ctx context.Context, report *syncReport) {
+	if len(in.notifiers) == 0 {
+		return
+	}
+	cs := report.changeSet()
+	if cs.empty() {
+		slog.Debug("no show changes to notify")
+		return
+	}
+	for _, n := range in.notifiers {
+		if err := n.notify(ctx, cs); err != nil {
+			slog.Warn("notification failed", "notifier", n.name(), "err", err)
+			continue
+		}
+		slog.Info("sent notification", "notifier", n.name(), "added", len(cs.Added), "changed", len(cs.Changed))
+	}
+}
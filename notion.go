@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/notion"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// Property names publish-notion expects in the target database.
+const (
+	notionTitle  = "Name"   // title
+	notionDate   = "Date"   // date
+	notionTeams  = "Teams"  // multi-select
+	notionPoster = "Poster" // files & media
+	notionLink   = "Link"   // URL
+	notionUID    = "UID"    // text; how rows are matched to shows
+)
+
+// notionWriteGap keeps writes under Notion's average of three requests a
+// second.
+const notionWriteGap = 350 * time.Millisecond
+
+// runPublishNotion mirrors upcoming stored shows into a Notion database,
+// creating a row per show and updating rows whose fields drifted. Rows are
+// matched by the UID property and never deleted, so producers' own columns
+// and notes are safe.
+func runPublishNotion(args []string) {
+	fs := flag.NewFlagSet("publish-notion", flag.ExitOnError)
+	database := fs.String("database", os.Getenv("NOTION_DATABASE_ID"), "Notion database ID to write to (default $NOTION_DATABASE_ID)")
+	token := fs.String("token", os.Getenv("NOTION_TOKEN"), "Notion integration token; share the database with the integration (default $NOTION_TOKEN)")
+	days := fs.Int("days", 90, "Publish shows starting within this many days")
+	dryRun := fs.Bool("dry-run", true, "If set, only log what would change in Notion")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if *database == "" || *token == "" {
+		fmt.Fprintln(os.Stderr, "-database and -token are required")
+		os.Exit(exitUsage)
+	}
+	if *days < 1 {
+		fmt.Fprintln(os.Stderr, "-days must be at least 1")
+		os.Exit(exitUsage)
+	}
+
+	ctx := context.Background()
+	store := openStore(ctx, showstore.ReadOnly())
+	defer store.Close()
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		exitErr(err)
+	}
+	y, m, d := time.Now().In(loc).Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	shows, _, err := store.ListShows(ctx, showstore.ShowFilter{From: from, To: from.AddDate(0, 0, *days)})
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+
+	client := notion.New(*token)
+	pages, err := client.QueryDatabase(ctx, *database, map[string]any{
+		"property": notionUID, "rich_text": map[string]any{"is_not_empty": true},
+	})
+	if err != nil {
+		exitErr(withCode(exitNetwork, err))
+	}
+	byUID := map[string]notion.Page{}
+	for _, p := range pages {
+		if !p.Archived {
+			byUID[p.Properties[notionUID].Text()] = p
+		}
+	}
+
+	var created, updated, unchanged, failed int
+	for _, s := range shows {
+		if s.Start == nil {
+			continue
+		}
+		log := eventLogger(s)
+		page, exists := byUID[s.UID]
+		if exists && notionPageCurrent(page, s, loc) {
+			unchanged++
+			continue
+		}
+		if *dryRun {
+			if exists {
+				updated++
+				log.Info("dry run; would update Notion row")
+			} else {
+				created++
+				log.Info("dry run; would create Notion row")
+			}
+			continue
+		}
+		time.Sleep(notionWriteGap)
+		props := notionProperties(s, loc)
+		if exists {
+			err = client.UpdatePage(ctx, page.ID, props)
+		} else {
+			_, err = client.CreatePage(ctx, *database, props)
+		}
+		if err != nil {
+			failed++
+			log.Error("Notion write failed", "err", err)
+			continue
+		}
+		if exists {
+			updated++
+			log.Info("updated Notion row")
+		} else {
+			created++
+			log.Info("created Notion row")
+		}
+	}
+	slog.Info("published to Notion", "dry_run", *dryRun, "created", created, "updated", updated, "unchanged", unchanged, "failed", failed)
+	if failed > 0 {
+		os.Exit(exitPartial)
+	}
+}
+
+// notionTeamNames returns s's teams as select options, which can't
+// contain commas.
+func notionTeamNames(s icalplayers.Event) []string {
+	var out []string
+	for _, t := range nonEmpty(s.Teams) {
+		out = append(out, strings.ReplaceAll(t, ",", ""))
+	}
+	return out
+}
+
+func notionProperties(s icalplayers.Event, loc *time.Location) map[string]any {
+	teams := []map[string]string{}
+	for _, t := range notionTeamNames(s) {
+		teams = append(teams, map[string]string{"name": t})
+	}
+	poster := []map[string]any{}
+	if s.PostImageURL != "" {
+		poster = append(poster, map[string]any{
+			"name": "poster", "type": "external", "external": map[string]string{"url": s.PostImageURL},
+		})
+	}
+	var link any
+	if s.URL != "" {
+		link = s.URL
+	}
+	return map[string]any{
+		notionTitle:  map[string]any{"title": []map[string]any{{"text": map[string]string{"content": s.Summary}}}},
+		notionUID:    map[string]any{"rich_text": []map[string]any{{"text": map[string]string{"content": s.UID}}}},
+		notionDate:   map[string]any{"date": map[string]string{"start": s.Start.In(loc).Format(time.RFC3339)}},
+		notionTeams:  map[string]any{"multi_select": teams},
+		notionPoster: map[string]any{"files": poster},
+		notionLink:   map[string]any{"url": link},
+	}
+}
+
+// notionPageCurrent reports whether p already shows what notionProperties
+// would write for s.
+func notionPageCurrent(p notion.Page, s icalplayers.Event, loc *time.Location) bool {
+	if p.Properties[notionTitle].Text() != s.Summary {
+		return false
+	}
+	date := p.Properties[notionDate].Date
+	if date == nil {
+		return false
+	}
+	if t, err := time.Parse(time.RFC3339, date.Start); err != nil || !t.Equal(*s.Start) {
+		return false
+	}
+	var teams []string
+	for _, o := range p.Properties[notionTeams].MultiSelect {
+		teams = append(teams, o.Name)
+	}
+	if !teamsEqualSorted(teams, notionTeamNames(s)) {
+		return false
+	}
+	var posters []string
+	for _, f := range p.Properties[notionPoster].Files {
+		if f.External != nil {
+			posters = append(posters, f.External.URL)
+		}
+	}
+	if !slices.Equal(posters, nonEmpty([]string{s.PostImageURL})) {
+		return false
+	}
+	link := ""
+	if u := p.Properties[notionLink].URL; u != nil {
+		link = *u
+	}
+	return link == s.URL
+}
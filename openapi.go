@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// apiRoute is one documented serve endpoint: routes registers handler on
+// it and /openapi.json describes it from doc, so the two can't drift.
+type apiRoute struct {
+	method, path string
+	handler      http.HandlerFunc
+	doc          apiDoc
+}
+
+// apiDoc is what the OpenAPI document says about a route.
+type apiDoc struct {
+	summary  string
+	query    []apiParam
+	read     bool   // needs a read-scope key under -api-keys
+	admin    bool   // needs an admin-scope key
+	media    string // response content type; application/json when empty
+	schema   string // components/schemas entry of a JSON response
+	array    bool   // the response is an array of schema
+	notFound bool   // may answer 404
+	redirect bool   // answers 302 instead of 200
+	accepted bool   // answers 202, or 200 for a repeated request
+}
+
+type apiParam struct {
+	name, typ, desc string
+	enum            []string
+}
+
+// showQuery are the listing parameters showFilter and writeShows read.
+var showQuery = []apiParam{
+	{name: "from", typ: "string", desc: "Earliest start: YYYY-MM-DD or a phrase like \"this weekend\", venue time"},
+	{name: "to", typ: "string", desc: "Latest start, inclusive; same forms as from"},
+	{name: "upcoming", typ: "boolean", desc: "Only shows that haven't started"},
+	{name: "type", typ: "string", desc: "Comma-separated event types: show, class, workshop (default show; /classes defaults to class,workshop)"},
+	{name: "q", typ: "string", desc: "Case-insensitive text in the summary or description"},
+	{name: "sort", typ: "string", desc: "Order; updated_at puts the oldest change first", enum: []string{"start", "updated_at"}},
+	{name: "fields", typ: "string", desc: "Comma-separated show fields to return, e.g. uid,summary,start"},
+	{name: "limit", typ: "integer", desc: fmt.Sprintf("Page size, 1-%d (default %d)", maxPageSize, defaultPageSize)},
+	{name: "offset", typ: "integer", desc: "Shows to skip; not with cursor"},
+	{name: "cursor", typ: "string", desc: "nextCursor of the previous page"},
+}
+
+// openAPIVersion is the OpenAPI release the document follows; 3.1 schemas
+// are JSON Schema 2020-12, like event.schema.json.
+const openAPIVersion = "3.1.0"
+
+var pathParamRe = regexp.MustCompile(`\{(\w+)\}`)
+
+// openAPI builds the document for routes, as served from baseURL.
+func (s *server) openAPI(routes []apiRoute, baseURL string) (map[string]any, error) {
+	schemas, err := openAPISchemas()
+	if err != nil {
+		return nil, err
+	}
+	paths := map[string]any{}
+	for _, rt := range routes {
+		item, _ := paths[rt.path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[rt.path] = item
+		}
+		item[strings.ToLower(rt.method)] = s.openAPIOperation(rt)
+	}
+	doc := map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":       calendarName + " shows API",
+			"version":     currentBuildInfo().Version,
+			"description": "Read-only access to the shows shopsync keeps in sync with the venue's calendar.",
+		},
+		"servers":    []any{map[string]any{"url": baseURL}},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+	if s.keys != nil {
+		doc["components"].(map[string]any)["securitySchemes"] = map[string]any{
+			"bearer": map[string]any{"type": "http", "scheme": "bearer", "description": "An API key from 'shopsync keys add'"},
+			"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+		}
+	}
+	return doc, nil
+}
+
+func (s *server) openAPIOperation(rt apiRoute) map[string]any {
+	d := rt.doc
+	var params []any
+	for _, m := range pathParamRe.FindAllStringSubmatch(rt.path, -1) {
+		params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+	}
+	for _, p := range d.query {
+		schema := map[string]any{"type": p.typ}
+		if p.enum != nil {
+			schema["enum"] = p.enum
+		}
+		params = append(params, map[string]any{"name": p.name, "in": "query", "description": p.desc, "schema": schema})
+	}
+
+	media := map[string]any{}
+	if d.schema != "" {
+		var schema any = map[string]any{"$ref": "#/components/schemas/" + d.schema}
+		if d.array {
+			schema = map[string]any{"type": "array", "items": schema}
+		}
+		media["schema"] = schema
+	}
+	body := map[string]any{cmp.Or(d.media, "application/json"): media}
+	responses := map[string]any{"200": map[string]any{"description": "OK", "content": body}}
+	if d.accepted {
+		responses["202"] = map[string]any{"description": "Accepted", "content": body}
+	}
+	if d.redirect {
+		responses = map[string]any{"302": map[string]any{"description": "Redirect", "headers": map[string]any{
+			"Location": map[string]any{"schema": map[string]any{"type": "string", "format": "uri"}},
+		}}}
+	}
+	errResp := func(desc string) map[string]any {
+		return map[string]any{"description": desc, "content": map[string]any{
+			"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}},
+		}}
+	}
+	if len(d.query) > 0 {
+		responses["400"] = errResp("Invalid query parameter")
+	}
+	if d.notFound {
+		responses["404"] = errResp("Not found")
+	}
+	op := map[string]any{"summary": d.summary, "responses": responses}
+	if params != nil {
+		op["parameters"] = params
+	}
+	if (d.read || d.admin) && s.keys != nil {
+		scope := "read"
+		if d.admin {
+			scope = "admin"
+		}
+		responses["401"] = errResp("Missing or invalid API key")
+		responses["403"] = errResp("API key lacks " + scope + " scope")
+		op["security"] = []any{map[string]any{"bearer": []string{}}, map[string]any{"apiKey": []string{}}}
+	}
+	return op
+}
+
+// openAPISchemas are the component schemas: the event contract from
+// event.schema.json plus the API's own envelopes.
+func openAPISchemas() (map[string]any, error) {
+	raw := bytes.ReplaceAll(icalplayers.EventSchema(), []byte(`"#/$defs/`), []byte(`"#/components/schemas/`))
+	var eventDoc struct {
+		Defs map[string]any `json:"$defs"`
+	}
+	if err := json.Unmarshal(raw, &eventDoc); err != nil {
+		return nil, fmt.Errorf("embedded event schema: %w", err)
+	}
+	schemas := eventDoc.Defs
+	str := map[string]any{"type": "string"}
+	strList := map[string]any{"$ref": "#/components/schemas/stringList"}
+	schemas["ShowPage"] = map[string]any{
+		"type":     "object",
+		"required": []string{"shows", "total", "limit", "offset"},
+		"properties": map[string]any{
+			"shows":      map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/event"}, "description": "Shows, or just the requested fields of each"},
+			"total":      map[string]any{"type": "integer", "description": "Every match, ignoring paging"},
+			"limit":      map[string]any{"type": "integer"},
+			"offset":     map[string]any{"type": "integer"},
+			"nextCursor": map[string]any{"type": "string", "description": "Pass as cursor for the next page; absent on the last"},
+		},
+	}
+	schemas["Team"] = map[string]any{
+		"type":       "object",
+		"required":   []string{"id", "name"},
+		"properties": map[string]any{"id": str, "name": str, "aliases": strList},
+	}
+	overlapShow := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"uid": str, "summary": str, "start": map[string]any{"type": "string", "format": "date-time"}},
+	}
+	schemas["Overlap"] = map[string]any{
+		"type":     "object",
+		"required": []string{"kind", "first", "second", "gapMinutes"},
+		"properties": map[string]any{
+			"kind":       map[string]any{"type": "string", "enum": []string{"overlap", "turnaround"}},
+			"location":   str,
+			"first":      overlapShow,
+			"second":     overlapShow,
+			"gapMinutes": map[string]any{"type": "integer", "description": "From the first show's end to the second's start; negative when they overlap"},
+		},
+	}
+	schemas["Player"] = map[string]any{
+		"type":     "object",
+		"required": []string{"name", "teams", "upcoming", "recent"},
+		"properties": map[string]any{
+			"name": str, "headshotUrl": str, "profileUrl": str,
+			"teamIds":  map[string]any{"$ref": "#/components/schemas/stringList", "description": "Teams whose rosters list them"},
+			"teams":    map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/Team"}, "description": "Roster teams and the teams of their shows"},
+			"upcoming": map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/event"}, "description": fmt.Sprintf("Next %d shows naming them, soonest first", playerUpcoming)},
+			"recent":   map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/event"}, "description": fmt.Sprintf("Their shows in the past %d days, latest first", int(playerRecent.Hours()/24))},
+		},
+	}
+	season := map[string]any{
+		"id": str, "name": str,
+		"start":  map[string]any{"type": "string", "format": "date"},
+		"end":    map[string]any{"type": "string", "format": "date", "description": "Last date, inclusive"},
+		"series": map[string]any{"type": "string", "description": "Normalized summary of the series the season was grouped from; absent for hand-made seasons"},
+		"shows":  map[string]any{"type": "integer"},
+	}
+	schemas["Season"] = map[string]any{
+		"type":       "object",
+		"required":   []string{"id", "name", "start", "end", "shows"},
+		"properties": season,
+	}
+	page := map[string]any{"dates": map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/event"}, "description": "Every show of the season, soonest first"}}
+	for k, v := range season {
+		page[k] = v
+	}
+	schemas["SeasonPage"] = map[string]any{
+		"type":       "object",
+		"required":   []string{"id", "name", "start", "end", "shows", "dates"},
+		"properties": page,
+	}
+	schemas["BuildInfo"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"version": str, "commit": str, "buildDate": str, "goVersion": str,
+			"schemaVersion": map[string]any{"type": "integer"}, "eventSchemaVersion": map[string]any{"type": "integer"},
+		},
+	}
+	schemas["Error"] = map[string]any{
+		"type":       "object",
+		"required":   []string{"error"},
+		"properties": map[string]any{"error": str},
+	}
+	return schemas, nil
+}
+
+func (s *server) handleOpenAPI(routes []apiRoute) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		doc, err := s.openAPI(routes, requestBaseURL(r))
+		if err != nil {
+			s.internalError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, doc)
+	}
+}
+
+// swaggerUI is the -swagger-ui page. The viewer's assets come from a CDN,
+// so the binary doesn't carry them.
+var swaggerUI = template.Must(template.New("docs").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.}} API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+<script>
+window.onload = () => { window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"}); };
+</script>
+</body>
+</html>
+`))
+
+func (s *server) handleSwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = swaggerUI.Execute(w, calendarName)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// defaultMinTurnaround is the shortest gap -min-turnaround and
+// /overlaps accept between back-to-back shows on one stage.
+const defaultMinTurnaround = 15 * time.Minute
+
+// showOverlap is two shows on the same stage that run into each other
+// (kind "overlap") or leave less than the minimum turnaround between them
+// (kind "turnaround").
+type showOverlap struct {
+	Kind     string      `json:"kind"`
+	Location string      `json:"location,omitempty"`
+	First    overlapShow `json:"first"`
+	Second   overlapShow `json:"second"`
+	// GapMinutes is from the first show's end to the second's start;
+	// negative when they overlap.
+	GapMinutes int `json:"gapMinutes"`
+}
+
+type overlapShow struct {
+	UID     string    `json:"uid"`
+	Summary string    `json:"summary"`
+	Start   time.Time `json:"start"`
+}
+
+func (o showOverlap) String() string {
+	if o.Kind == "overlap" {
+		return fmt.Sprintf("%q overlaps %q by %d min", o.First.Summary, o.Second.Summary, -o.GapMinutes)
+	}
+	return fmt.Sprintf("only %d min between %q and %q", o.GapMinutes, o.First.Summary, o.Second.Summary)
+}
+
+// findOverlaps reports double-booked stages among events: shows at the
+// same place (venueKey, so "Improv Shop, Main St" and "The Improv Shop"
+// agree and different rooms don't) whose times overlap, or whose gap is
+// under minTurnaround. Shows without an end are taken to last length;
+// with length 0 they are taken to last icalplayers.DefaultEventDuration,
+// and since that end is only a guess they are checked for overlap but not
+// turnaround. All-day events are skipped.
+func findOverlaps(events []icalplayers.Event, minTurnaround, length time.Duration) []showOverlap {
+	byVenue := map[string][]icalplayers.Event{}
+	for _, e := range events {
+		if e.Start != nil && !e.AllDay {
+			k := venueKey(e.Location)
+			byVenue[k] = append(byVenue[k], e)
+		}
+	}
+	var out []showOverlap
+	for _, evs := range byVenue {
+		slices.SortFunc(evs, func(a, b icalplayers.Event) int {
+			return cmp.Or(a.Start.Compare(*b.Start), cmp.Compare(a.UID, b.UID))
+		})
+		for i, a := range evs {
+			end, known := showEnd(a, length)
+			for _, b := range evs[i+1:] {
+				gap := b.Start.Sub(end)
+				if gap >= minTurnaround || gap >= 0 && !known {
+					break
+				}
+				o := showOverlap{Kind: "turnaround", Location: a.Location, First: overlapShowOf(a), Second: overlapShowOf(b), GapMinutes: int(gap.Minutes())}
+				if gap < 0 {
+					o.Kind = "overlap"
+				}
+				out = append(out, o)
+			}
+		}
+	}
+	slices.SortFunc(out, func(a, b showOverlap) int {
+		return cmp.Or(a.First.Start.Compare(b.First.Start), cmp.Compare(a.First.UID, b.First.UID), cmp.Compare(a.Second.UID, b.Second.UID))
+	})
+	return out
+}
+
+// showEnd is when e ends, and whether that is known rather than guessed.
+func showEnd(e icalplayers.Event, length time.Duration) (time.Time, bool) {
+	switch {
+	case e.End != nil && e.End.After(*e.Start):
+		return *e.End, true
+	case length > 0:
+		return e.Start.Add(length), true
+	}
+	return e.Start.Add(icalplayers.DefaultEventDuration), false
+}
+
+func overlapShowOf(e icalplayers.Event) overlapShow {
+	return overlapShow{UID: e.UID, Summary: e.Summary, Start: inOutputTZ(*e.Start)}
+}
+
+// overlapFindings turns overlaps into lint findings: a double-booked stage
+// is an error, a tight turnaround a warning.
+func overlapFindings(overlaps []showOverlap) []lintFinding {
+	out := make([]lintFinding, 0, len(overlaps))
+	for _, o := range overlaps {
+		f := lintFinding{Severity: "warning", Check: "short-turnaround", UID: o.Second.UID, Summary: o.Second.Summary, Message: o.String()}
+		if o.Kind == "overlap" {
+			f.Severity, f.Check = "error", "overlap"
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// overlapStage checks the whole window for double-booked stages before
+// any batching, logging each and listing them in the report. It never
+// stops the sync: the feed is what the venue published.
+func overlapStage(minTurnaround time.Duration) stage {
+	return stage{name: "overlaps", run: func(_ context.Context, st *syncState) error {
+		st.report.Overlaps = findOverlaps(st.events, minTurnaround, 0)
+		for _, o := range st.report.Overlaps {
+			slog.Warn("schedule conflict", "kind", o.Kind, "first", o.First.UID, "second", o.Second.UID, "gap_minutes", o.GapMinutes, "location", o.Location)
+		}
+		return nil
+	}}
+}
+
+// handleOverlaps lists double-booked stages among stored shows, by default
+// the upcoming ones. Stored shows have no end time: without ?length only
+// overlaps of hour-long shows are found, and ?turnaround needs ?length.
+func (s *server) handleOverlaps(w http.ResponseWriter, r *http.Request) {
+	f, err := s.showFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if r.URL.Query().Get("from") == "" && r.URL.Query().Get("upcoming") == "" {
+		f.From = time.Now()
+	}
+	turnaround, length := defaultMinTurnaround, time.Duration(0)
+	for name, d := range map[string]*time.Duration{"turnaround": &turnaround, "length": &length} {
+		if v := r.URL.Query().Get(name); v != "" {
+			if *d, err = time.ParseDuration(v); err != nil || *d < 0 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s %q: want a duration like 15m", name, v))
+				return
+			}
+		}
+	}
+	shows, _, err := s.store.ListShows(r.Context(), showstore.ShowFilter{From: f.From, To: f.To, TeamID: f.TeamID, Query: f.Query})
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	out := findOverlaps(shows, turnaround, length)
+	if out == nil {
+		out = []showOverlap{}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
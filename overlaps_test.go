@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// at is 2026-10-16 at hh:mm UTC.
+func at(hh, mm int) *time.Time {
+	t := time.Date(2026, time.October, 16, hh, mm, 0, 0, time.UTC)
+	return &t
+}
+
+func TestFindOverlaps(t *testing.T) {
+	const stage = "The Improv Shop"
+	show := func(uid, loc string, start, end *time.Time) icalplayers.Event {
+		return icalplayers.Event{UID: uid, Summary: uid, Location: loc, Start: start, End: end}
+	}
+	type pair struct {
+		kind, first, second string
+		gap                 int
+	}
+	tests := []struct {
+		name       string
+		events     []icalplayers.Event
+		turnaround time.Duration
+		length     time.Duration
+		want       []pair
+	}{
+		{
+			name:       "overlapping",
+			events:     []icalplayers.Event{show("a", stage, at(19, 0), at(20, 30)), show("b", stage, at(20, 0), at(21, 0))},
+			turnaround: defaultMinTurnaround,
+			want:       []pair{{"overlap", "a", "b", -30}},
+		},
+		{
+			name:       "adjacent, under the turnaround",
+			events:     []icalplayers.Event{show("a", stage, at(19, 0), at(20, 0)), show("b", stage, at(20, 0), at(21, 0))},
+			turnaround: defaultMinTurnaround,
+			want:       []pair{{"turnaround", "a", "b", 0}},
+		},
+		{
+			name:   "adjacent, no turnaround needed",
+			events: []icalplayers.Event{show("a", stage, at(19, 0), at(20, 0)), show("b", stage, at(20, 0), at(21, 0))},
+		},
+		{
+			name:       "gap of exactly the turnaround",
+			events:     []icalplayers.Event{show("a", stage, at(19, 0), at(20, 0)), show("b", stage, at(20, 15), at(21, 0))},
+			turnaround: defaultMinTurnaround,
+		},
+		{
+			name:       "different stages",
+			events:     []icalplayers.Event{show("a", stage, at(19, 0), at(20, 30)), show("b", "Studio B", at(20, 0), at(21, 0))},
+			turnaround: defaultMinTurnaround,
+		},
+		{
+			name:       "same stage written differently",
+			events:     []icalplayers.Event{show("a", "Improv Shop, 1900 Main St", at(19, 0), at(20, 30)), show("b", stage, at(20, 0), at(21, 0))},
+			turnaround: defaultMinTurnaround,
+			want:       []pair{{"overlap", "a", "b", -30}},
+		},
+		{
+			name:       "guessed ends overlap",
+			events:     []icalplayers.Event{show("a", stage, at(19, 0), nil), show("b", stage, at(19, 30), nil)},
+			turnaround: defaultMinTurnaround,
+			want:       []pair{{"overlap", "a", "b", -30}},
+		},
+		{
+			name:       "guessed ends aren't held to the turnaround",
+			events:     []icalplayers.Event{show("a", stage, at(19, 0), nil), show("b", stage, at(20, 5), nil)},
+			turnaround: defaultMinTurnaround,
+		},
+		{
+			name:       "length makes ends known",
+			events:     []icalplayers.Event{show("a", stage, at(19, 0), nil), show("b", stage, at(20, 40), nil)},
+			turnaround: defaultMinTurnaround,
+			length:     90 * time.Minute,
+			want:       []pair{{"turnaround", "a", "b", 10}},
+		},
+		{
+			name:       "one show overlapping two",
+			events:     []icalplayers.Event{show("c", stage, at(20, 0), at(21, 0)), show("a", stage, at(19, 0), at(22, 0)), show("b", stage, at(19, 30), at(20, 0))},
+			turnaround: defaultMinTurnaround,
+			want:       []pair{{"overlap", "a", "b", -150}, {"overlap", "a", "c", -120}, {"turnaround", "b", "c", 0}},
+		},
+		{
+			name: "all-day and undated events are skipped",
+			events: []icalplayers.Event{
+				{UID: "a", Location: stage, Start: at(0, 0), End: at(23, 59), AllDay: true},
+				{UID: "b", Location: stage},
+				show("c", stage, at(19, 0), at(20, 0)),
+			},
+			turnaround: defaultMinTurnaround,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findOverlaps(tt.events, tt.turnaround, tt.length)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d overlaps %v, want %d", len(got), got, len(tt.want))
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if g.Kind != w.kind || g.First.UID != w.first || g.Second.UID != w.second || g.GapMinutes != w.gap {
+					t.Errorf("overlap %d = %s %s/%s gap %d, want %s %s/%s gap %d", i, g.Kind, g.First.UID, g.Second.UID, g.GapMinutes, w.kind, w.first, w.second, w.gap)
+				}
+			}
+		})
+	}
+}
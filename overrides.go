@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+	"go.yaml.in/yaml/v3"
+)
+
+// override forces fields on one event that inference gets wrong. It matches
+// by UID, or by summary and start date (venue time) for feeds whose UIDs
+// aren't stable. A nil field is left alone; an empty list clears it.
+type override struct {
+	UID     string    `yaml:"uid"`
+	Summary string    `yaml:"summary"`
+	Date    string    `yaml:"date"`  // YYYY-MM-DD
+	Teams   *[]string `yaml:"teams"` // team names, aliases or IDs
+	Players *[]string `yaml:"players"`
+	Image   string    `yaml:"image"`
+	Type    string    `yaml:"type"` // show, class or workshop
+
+	date time.Time
+	row  int // CSV row, for errors
+	used bool
+}
+
+// overrides is a parsed -overrides file.
+type overrides []*override
+
+// loadOverrides reads overrides from src: a YAML list in a file, a CSV
+// file, or an http(s) URL of either, such as a published Google Sheet
+// (see overridesURL). An empty src means none.
+func loadOverrides(ctx context.Context, src string, loc *time.Location) (overrides, error) {
+	if src == "" {
+		return nil, nil
+	}
+	b, isCSV, err := readOverrides(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	var out overrides
+	if isCSV {
+		out, err = parseOverridesCSV(b)
+	} else {
+		err = yaml.Unmarshal(b, &out)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", src, err)
+	}
+	entry := "entry"
+	if isCSV {
+		entry = "row" // counting the header, as a spreadsheet numbers them
+	}
+	for i, o := range out {
+		n := i + 1
+		if isCSV {
+			n = o.row
+		}
+		switch {
+		case o.UID != "" && (o.Summary != "" || o.Date != ""):
+			return nil, fmt.Errorf("%s: %s %d: give uid or summary+date, not both", src, entry, n)
+		case o.UID == "" && (o.Summary == "" || o.Date == ""):
+			return nil, fmt.Errorf("%s: %s %d: needs uid, or both summary and date", src, entry, n)
+		}
+		if o.Date != "" {
+			if o.date, err = parseOverrideDate(o.Date, loc); err != nil {
+				return nil, fmt.Errorf("%s: %s %d: invalid date: %w", src, entry, n, err)
+			}
+		}
+		if o.Type != "" {
+			o.Type = strings.ToLower(o.Type)
+			if !slices.Contains(icalplayers.EventTypes, o.Type) {
+				return nil, fmt.Errorf("%s: %s %d: invalid type %q (want %s)", src, entry, n, o.Type, strings.Join(icalplayers.EventTypes, ", "))
+			}
+		}
+		if o.Teams == nil && o.Players == nil && o.Image == "" && o.Type == "" {
+			return nil, fmt.Errorf("%s: %s %d: sets nothing", src, entry, n)
+		}
+	}
+	return out, nil
+}
+
+// parseOverrideDate reads YYYY-MM-DD, or M/D/YYYY as spreadsheets
+// display dates.
+func parseOverrideDate(s string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02", s, loc)
+	if err != nil {
+		if t2, err2 := time.ParseInLocation("1/2/2006", s, loc); err2 == nil {
+			return t2, nil
+		}
+	}
+	return t, err
+}
+
+// readOverrides returns the document at src and whether it is CSV.
+func readOverrides(ctx context.Context, src string) ([]byte, bool, error) {
+	if !isURL(src) {
+		b, err := os.ReadFile(src)
+		return b, strings.EqualFold(filepath.Ext(src), ".csv"), err
+	}
+	u := overridesURL(src)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	resp, err := httpclient.Default.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("fetching overrides: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("fetching overrides: %s: %s (is the sheet shared or published?)", src, resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, false, fmt.Errorf("fetching overrides: %w", err)
+	}
+	ct := resp.Header.Get("Content-Type")
+	if strings.Contains(ct, "text/html") {
+		return nil, false, fmt.Errorf("fetching overrides: %s returned a web page, not CSV or YAML (publish the sheet to the web as CSV, or share it with anyone who has the link)", src)
+	}
+	path, _, _ := strings.Cut(u, "?")
+	return b, strings.Contains(ct, "csv") || strings.EqualFold(filepath.Ext(path), ".csv"), nil
+}
+
+// sheetURLRe matches a Google Sheets editor link.
+var sheetURLRe = regexp.MustCompile(`^https://docs\.google\.com/spreadsheets/d/([\w-]+)`)
+
+// overridesURL turns a Google Sheets editor link into its CSV export, for
+// the tab in its gid (the first tab without one). Other URLs, including
+// "Publish to the web" CSV links, are used as they are.
+func overridesURL(src string) string {
+	m := sheetURLRe.FindStringSubmatch(src)
+	if m == nil || strings.Contains(src, "/export?") || strings.Contains(src, "/pub?") || strings.Contains(src, "/d/e/") {
+		return src
+	}
+	u := "https://docs.google.com/spreadsheets/d/" + m[1] + "/export?format=csv"
+	if i := strings.Index(src, "gid="); i >= 0 {
+		gid := src[i+len("gid="):]
+		if j := strings.IndexFunc(gid, func(r rune) bool { return r < '0' || r > '9' }); j >= 0 {
+			gid = gid[:j]
+		}
+		if gid != "" {
+			u += "&gid=" + gid
+		}
+	}
+	return u
+}
+
+// parseOverridesCSV reads overrides from a sheet whose header names some of
+// the columns uid, summary, date, teams, players, image and type (any case;
+// other columns, e.g. notes, are ignored). Team and player cells list
+// entries separated by commas or newlines; an empty cell leaves the field
+// alone and "-" clears it. Blank rows are skipped.
+func parseOverridesCSV(b []byte) (overrides, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	col := map[string]int{}
+	for i, h := range rows[0] {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := col["uid"]; !ok {
+		if _, ok := col["summary"]; !ok {
+			return nil, errors.New("header needs a uid or summary column")
+		}
+	}
+	var out overrides
+	for n, row := range rows[1:] {
+		cell := func(name string) string {
+			if i, ok := col[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		list := func(name string) *[]string {
+			v := cell(name)
+			switch v {
+			case "":
+				return nil
+			case "-":
+				return &[]string{}
+			}
+			items := []string{}
+			for _, it := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == '\n' }) {
+				if it = strings.TrimSpace(it); it != "" {
+					items = append(items, it)
+				}
+			}
+			return &items
+		}
+		o := &override{UID: cell("uid"), Summary: cell("summary"), Date: cell("date"), Teams: list("teams"), Players: list("players"), Image: cell("image"), Type: cell("type"), row: n + 2}
+		if o.UID == "" && o.Summary == "" && o.Date == "" && o.Teams == nil && o.Players == nil && o.Image == "" && o.Type == "" {
+			continue
+		}
+		out = append(out, o)
+	}
+	return out, nil
+}
+
+// find returns the override for e, if any. UID entries win over
+// summary+date ones.
+func (ovs overrides) find(e icalplayers.Event, loc *time.Location) *override {
+	var byDate *override
+	for _, o := range ovs {
+		if o.UID != "" {
+			if o.UID == e.UID {
+				return o
+			}
+			continue
+		}
+		if byDate == nil && e.Start != nil && strings.EqualFold(strings.TrimSpace(o.Summary), strings.TrimSpace(e.Summary)) {
+			y, m, d := e.Start.In(loc).Date()
+			if oy, om, od := o.date.Date(); y == oy && m == om && d == od {
+				byDate = o
+			}
+		}
+	}
+	return byDate
+}
+
+// apply sets the overridden players, image and type on e and returns the forced
+// teams, or nil if o leaves matching alone. Team entries are resolved
+// against teams by ID, name or alias, case-insensitively.
+func (o *override) apply(e *icalplayers.Event, teams []showstore.Team) ([]showstore.Team, error) {
+	o.used = true
+	if o.Players != nil {
+		e.Players = append([]string(nil), *o.Players...)
+	}
+	if o.Image != "" {
+		e.PostImageURL = o.Image
+	}
+	if o.Type != "" {
+		e.EventType = o.Type
+	}
+	if o.Teams == nil {
+		return nil, nil
+	}
+	forced := []showstore.Team{}
+	for _, want := range *o.Teams {
+		t := lookupTeam(want, teams)
+		if t == nil {
+			return nil, fmt.Errorf("override for %s: unknown team %q", o.key(), want)
+		}
+		forced = append(forced, *t)
+	}
+	return forced, nil
+}
+
+func (o *override) key() string {
+	if o.UID != "" {
+		return "uid " + o.UID
+	}
+	return fmt.Sprintf("%q on %s", o.Summary, o.Date)
+}
+
+// warnUnused logs entries that matched no event in this run, which usually
+// means the show has passed or its summary changed.
+func (ovs overrides) warnUnused() {
+	for _, o := range ovs {
+		if !o.used {
+			slog.Warn("override matched no event", "override", o.key())
+		}
+	}
+}
+
+func lookupTeam(want string, teams []showstore.Team) *showstore.Team {
+	want = strings.TrimSpace(want)
+	for i, t := range teams {
+		if t.ID == want || strings.EqualFold(t.Name, want) {
+			return &teams[i]
+		}
+	}
+	for i, t := range teams {
+		for _, a := range t.Aliases {
+			if strings.EqualFold(a, want) {
+				return &teams[i]
+			}
+		}
+	}
+	return nil
+}
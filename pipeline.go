@@ -0,0 +1,775 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+	"github.com/tsny/shopsync/pkg/wpevents"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// A sync is a pipeline of stages: source → unchanged → window → enrich →
+// match → teams → store → record → sink. ingest, import, the daemon (including
+// syncs triggered through POST /sync) and validate all build their run from
+// these stages, so there is one code path to change and to test.
+
+// syncState is what flows through a pipeline. Stages read and replace
+// events and add to report.
+type syncState struct {
+	report  *syncReport
+	events  []icalplayers.Event
+	started time.Time
+	// err is the error that stopped the pipeline, for stages that always run.
+	err error
+	// done ends the run early without an error, e.g. when no events are left.
+	// Within a batch it only ends that batch.
+	done bool
+	// more is set while batchStage has batches left after this one.
+	more bool
+}
+
+// stage is one step of a sync. Each is traced as a span named after it.
+type stage struct {
+	name string
+	// always stages run even after an earlier stage failed or finished the
+	// run early; they see that stage's error in syncState.err.
+	always bool
+	run    func(ctx context.Context, st *syncState) error
+}
+
+// pipeline runs its stages in order.
+type pipeline []stage
+
+// batchStage runs inner over the events size at a time, so a very large
+// feed never has more than one batch's page lookups, matches and diffs in
+// memory: each batch is dropped once it's stored. Every batch sees the
+// same report. Events that fail don't stop later batches unless the error
+// policy says so; the run is still partial at the end.
+func batchStage(size int, inner pipeline) stage {
+	return stage{name: "batches", run: func(ctx context.Context, st *syncState) error {
+		all := st.events
+		st.events = nil
+		var partial error
+		for i := 0; i < len(all); i += size {
+			j := min(i+size, len(all))
+			slog.Debug("processing batch", "from", i, "to", j, "of", len(all))
+			bst := &syncState{report: st.report, events: all[i:j], started: st.started, more: j < len(all)}
+			err := inner.run(ctx, bst)
+			clear(all[i:j])
+			var ce *classedError
+			if errors.As(err, &ce) && ce.code == exitPartial {
+				partial = err
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if partial != nil {
+			return withCode(exitPartial, fmt.Errorf("%d events failed to sync", len(st.report.Failures)))
+		}
+		return nil
+	}}
+}
+
+// run runs p over st. The first stage error stops the remaining stages,
+// except those marked always, and is returned; per-event failures are
+// collected in st.report instead (see ingester.fail).
+func (p pipeline) run(ctx context.Context, st *syncState) error {
+	for _, s := range p {
+		if (st.err != nil || st.done) && !s.always {
+			continue
+		}
+		sctx, span := tracer.Start(ctx, s.name)
+		began := time.Now()
+		err := s.run(sctx, st)
+		st.report.timings = append(st.report.timings, stageTiming{s.name, time.Since(began)})
+		endSpan(span, err)
+		if err != nil && st.err == nil {
+			st.err = err
+		}
+	}
+	return st.err
+}
+
+// enrichOptions configures the enrich stage.
+type enrichOptions struct {
+	skip    bool    // leave images alone
+	details bool    // also read pages of events that have an image but no details
+	workers int     // event pages fetched in parallel
+	rate    float64 // page fetches per second; 0 is unlimited
+}
+
+// matchOptions configures the match stage.
+type matchOptions struct {
+	useTeamsFile  bool // read teams from teams.txt instead of the database
+	useRosters    bool // check inferred players against team rosters
+	explain       bool // print how each event was matched
+	overridesFile string
+}
+
+// writeOptions configures the store stage.
+type writeOptions struct {
+	dryRun bool
+	// merge stores by date and summary with InsertIfNew and field merges,
+	// for WordPress sources; otherwise events are upserted by UID.
+	merge    bool
+	policy   showstore.ErrorPolicy
+	conflict showstore.ConflictStrategy
+	workers  int // upserts in flight at once
+}
+
+func (o *ingestOptions) enrichOptions() enrichOptions {
+	return enrichOptions{skip: o.skipImageSearch, details: o.details, workers: o.imageWorkers, rate: o.imageRate}
+}
+
+func (o *ingestOptions) matchOptions() matchOptions {
+	return matchOptions{useTeamsFile: o.useTeamsFile, useRosters: o.useRosters, explain: o.explainMatching, overridesFile: o.overridesFile}
+}
+
+func (o *ingestOptions) writeOptions() writeOptions {
+	return writeOptions{dryRun: o.dryRun, merge: o.wpURL != "" || o.wpCache != "", policy: o.policy, conflict: o.conflictPolicy, workers: o.dbWorkers}
+}
+
+// pipeline builds the full sync the options describe.
+func (in *ingester) pipeline() (pipeline, error) {
+	opts := in.opts
+	from, to, err := opts.window()
+	if err != nil {
+		return nil, err
+	}
+	p := pipeline{in.sourceStage()}
+	if !opts.dryRun && in.source == nil {
+		p = append(p, in.unchangedStage())
+	}
+	if !from.IsZero() || !to.IsZero() {
+		p = append(p, windowStage(from, to))
+	}
+	p = append(p, overlapStage(opts.minTurnaround))
+	if len(opts.blackouts) > 0 {
+		p = append(p, blackoutStage(opts.blackouts))
+	}
+	each := pipeline{in.enrichStage(opts.enrichOptions()), in.matchStage(opts.matchOptions())}
+	if len(opts.teams) > 0 {
+		each = append(each, teamsStage(opts.teams))
+	}
+	if len(opts.eventTypes) > 0 {
+		each = append(each, typesStage(opts.eventTypes))
+	}
+	if opts.printSummary {
+		each = append(each, stage{name: "summary", run: func(_ context.Context, st *syncState) error {
+			icalplayers.SummarizeEvents(localizeEvents(st.events), venueLocale.FullDate)
+			return nil
+		}})
+	}
+	each = append(each, in.storeStage(opts.writeOptions()))
+	if !opts.dryRun && !opts.useTeamsFile {
+		each = append(each, in.reviewStage())
+	}
+	if opts.batchSize > 0 {
+		p = append(p, batchStage(opts.batchSize, each))
+	} else {
+		p = append(p, each...)
+	}
+	if !opts.dryRun {
+		p = append(p, in.recordStage(), in.sinkStage())
+	}
+	return p, nil
+}
+
+// runRecorded runs one sync and returns its report, which is never nil.
+// Real runs hold the sync lock throughout; if another run has it,
+// runRecorded returns an exitLocked error without syncing.
+func (in *ingester) runRecorded(ctx context.Context) (*syncReport, error) {
+	if !in.opts.dryRun {
+		unlock, err := in.acquireSyncLock(ctx)
+		if err != nil {
+			return newSyncReport(), err
+		}
+		defer unlock()
+	}
+	st := &syncState{report: newSyncReport(), started: time.Now()}
+	st.report.DryRun = in.opts.dryRun
+	ctx, span := tracer.Start(ctx, "sync", trace.WithAttributes(attribute.Bool("dry_run", in.opts.dryRun)))
+	p, err := in.pipeline()
+	if err == nil {
+		err = p.run(ctx, st)
+	}
+	in.progress.finish()
+	report := st.report
+	span.SetAttributes(attribute.Int("events_parsed", report.EventsParsed), attribute.Int("failed", len(report.Failures)))
+	endSpan(span, err)
+	observeSync(report, time.Since(st.started), err)
+	if err != nil {
+		reportError(err, sentry.LevelError, map[string]string{
+			"kind": errorKind(err), "sources": strings.Join(report.Sources, " "), "dry_run": strconv.FormatBool(in.opts.dryRun),
+		})
+	}
+	return report, err
+}
+
+// sourceStage fetches events from the sources the options select (see
+// fetch). Images are left to the enrich stage.
+func (in *ingester) sourceStage() stage {
+	return stage{name: "source", run: func(ctx context.Context, st *syncState) error {
+		in.progress.update("parse", 0, 0)
+		events, err := in.fetch(ctx, st.report)
+		if err != nil {
+			return fetchErr(err)
+		}
+		st.events = events
+		st.report.EventsParsed = len(events)
+		if len(events) == 0 {
+			slog.Info("no events found")
+			st.report.warn("no events found")
+			st.done = true
+		}
+		return nil
+	}}
+}
+
+// unchangedStage hashes what the sources returned, together with the
+// options that filter, settle or report on events (see feedHash), and
+// ends the run when the last successful sync saw the same: most scheduled
+// runs find nothing new, and there's no point enriching and diffing every
+// event to learn that. The record stage stores the hash for the next run.
+func (in *ingester) unchangedStage() stage {
+	return stage{name: "unchanged", run: func(ctx context.Context, st *syncState) error {
+		h, err := in.feedHash(ctx, st)
+		if err != nil {
+			slog.Warn("could not hash feed; syncing anyway", "err", err)
+			return nil
+		}
+		st.report.feedHash = h
+		if in.opts.resync || in.resync {
+			return nil
+		}
+		last, err := in.store.LastFeedHash(ctx)
+		if err != nil {
+			slog.Warn("could not read the last sync's feed hash; syncing anyway", "err", err)
+			return nil
+		}
+		if last == h {
+			slog.Info("no changes since the last sync; skipping", "events", len(st.events))
+			st.report.Skipped = true
+			st.done = true
+		}
+		return nil
+	}}
+}
+
+// feedHash is a SHA-256 over st's sources and events (by UID) and the
+// options that filter, override or settle them: the window, -team, -type,
+// -prefer-source, the overrides document, -conflict, and -blackout and
+// -min-turnaround for the reports. A -blackout calendar is hashed by
+// name, not contents, and team names, rosters and hand edits in the
+// database aren't hashed at all; -resync covers those.
+func (in *ingester) feedHash(ctx context.Context, st *syncState) (string, error) {
+	events := slices.Clone(st.events)
+	slices.SortStableFunc(events, func(a, b icalplayers.Event) int { return strings.Compare(a.UID, b.UID) })
+	var overridesFile []byte
+	if in.opts.overridesFile != "" {
+		b, _, err := readOverrides(ctx, in.opts.overridesFile)
+		if err != nil {
+			return "", err
+		}
+		overridesFile = b
+	}
+	// The resolved window, so "today" moves on at midnight.
+	from, to, err := in.opts.window()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	err = json.NewEncoder(h).Encode(struct {
+		Sources       []string
+		From, To      time.Time
+		Teams         []string
+		Types         []string
+		PreferSources []string
+		Overrides     []byte
+		Conflict      showstore.ConflictStrategy
+		Blackouts     []string
+		MinTurnaround time.Duration
+		Events        []icalplayers.Event
+	}{st.report.Sources, from, to, in.opts.teams, in.opts.eventTypes, in.opts.preferSources, overridesFile,
+		in.opts.conflictPolicy, in.opts.blackouts, in.opts.minTurnaround, events})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// windowStage keeps events starting in [from, to); see filterByWindow.
+func windowStage(from, to time.Time) stage {
+	return stage{name: "window", run: func(_ context.Context, st *syncState) error {
+		before := len(st.events)
+		st.events = filterByWindow(st.events, from, to)
+		st.report.EventsParsed = len(st.events)
+		slog.Info("filtered by date", "from", from, "to", to, "kept", len(st.events), "dropped", before-len(st.events))
+		if len(st.events) == 0 {
+			slog.Info("no events found")
+			st.report.warn("no events found")
+			st.done = true
+		}
+		return nil
+	}}
+}
+
+// enrichStage looks up the post image of every event that has a page but
+// no image yet, then normalises image URLs. Images found are written back
+// to the feed cache so an unchanged feed isn't enriched twice.
+func (in *ingester) enrichStage(o enrichOptions) stage {
+	return stage{name: "enrich", run: func(ctx context.Context, st *syncState) error {
+		if !o.skip {
+			var need []int
+			for i, e := range st.events {
+				if e.URL != "" && (e.PostImageURL == "" || o.details && e.Details == nil) {
+					need = append(need, i)
+				}
+			}
+			if len(need) > 0 {
+				batch := make([]icalplayers.Event, len(need))
+				for j, i := range need {
+					batch[j] = st.events[i]
+				}
+				icalplayers.EnrichImages(ctx, batch, icalplayers.WithImageWorkers(o.workers), icalplayers.WithImageRate(o.rate),
+					icalplayers.WithImageProgress(func(done, total int) { in.progress.update("enrich", done, total) }))
+				for j, i := range need {
+					st.events[i].PostImageURL = batch[j].PostImageURL
+					st.events[i].Details = batch[j].Details
+				}
+				in.rememberImages(batch)
+			}
+		}
+		for i, e := range st.events {
+			if e.PostImageURL != "" {
+				st.events[i].PostImageURL = wpevents.RewriteCdnCgiURL(e.PostImageURL)
+			}
+		}
+		return nil
+	}}
+}
+
+// rememberImages copies images and details found for events into the
+// feed cache's copies of them.
+func (in *ingester) rememberImages(events []icalplayers.Event) {
+	if in.feedCache == nil {
+		return
+	}
+	found := map[string]icalplayers.Event{}
+	for _, e := range events {
+		if e.PostImageURL != "" || e.Details != nil {
+			found[e.UID] = e
+		}
+	}
+	for _, entry := range in.feedCache {
+		for i, e := range entry.events {
+			f, ok := found[e.UID]
+			if !ok {
+				continue
+			}
+			if e.PostImageURL == "" {
+				entry.events[i].PostImageURL = f.PostImageURL
+			}
+			if f.Details != nil {
+				entry.events[i].Details = f.Details
+			}
+		}
+	}
+}
+
+// matchStage attaches teams to events from their descriptions, overrides
+// and, with -interactive, the user's choices, then checks players against
+// the matched teams' rosters.
+func (in *ingester) matchStage(o matchOptions) stage {
+	// Loaded by the first batch and kept for the rest, so teams added
+	// with -interactive and overrides already used carry over.
+	var (
+		loaded  bool
+		teams   []showstore.Team
+		rosters map[string][]string
+		loc     *time.Location
+		ovr     overrides
+	)
+	return stage{name: "match", run: func(ctx context.Context, st *syncState) error {
+		report := st.report
+		var err error
+		if !loaded {
+			if o.useTeamsFile {
+				teams, err = readTeamsFile(defaultTeamsFile)
+				if err != nil {
+					return err
+				}
+			} else {
+				teams, err = in.store.GetAllTeams(ctx)
+				if err != nil {
+					return dbErr(err)
+				}
+				slog.Info("loaded teams from database", "count", len(teams))
+			}
+			if o.useRosters {
+				if rosters, err = in.store.GetRosters(ctx); err != nil {
+					return dbErr(err)
+				}
+				slog.Debug("loaded rosters", "teams", len(rosters))
+			}
+
+			if loc, err = time.LoadLocation(venueTimezone); err != nil {
+				return err
+			}
+			if ovr, err = loadOverrides(ctx, o.overridesFile, loc); err != nil {
+				return withCode(exitUsage, err)
+			}
+			ovr = append(ovr, in.extraOverrides...)
+			loaded = true
+		}
+
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(attribute.Int("events", len(st.events)), attribute.Int("teams", len(teams)))
+		matched := st.events[:0]
+		total := len(st.events)
+		for n, ev := range st.events {
+			in.progress.update("match", n, total)
+			log := eventLogger(ev)
+			if ev.EventType == "" {
+				var why string
+				if ev.EventType, why = classifyEvent(ev); why != "" {
+					log.Debug("classified event", "type", ev.EventType, "because", why)
+				}
+			}
+			if o.explain {
+				printMatchExplanation(os.Stderr, ev, explainMatch(ev.Description, teams))
+			}
+			parsedTeams := findTeamsInEventDescription(ev.Description, teams)
+			teamsForced, playersForced := false, false
+			if ov := ovr.find(ev, loc); ov != nil {
+				forced, err := ov.apply(&ev, teams)
+				if err != nil {
+					if err := in.fail(report, ev, err); err != nil {
+						return err
+					}
+					continue
+				}
+				if forced != nil {
+					parsedTeams, teamsForced = forced, true
+				}
+				playersForced = ov.Players != nil
+				log.Debug("applied override", "override", ov.key())
+			}
+			if len(parsedTeams) == 0 && !teamsForced && in.resolver != nil {
+				if parsedTeams, err = in.resolver.resolve(ctx, ev, &teams); err != nil {
+					return err
+				}
+			}
+			if len(parsedTeams) == 0 {
+				log.Info("event matches no teams")
+				if ev.EventType != icalplayers.TypeClass {
+					report.UnmatchedEvents = append(report.UnmatchedEvents, reportEvent{UID: ev.UID, Summary: ev.Summary})
+				}
+				if isShow(ev) && !teamsForced {
+					c := reviewCandidate{uid: ev.UID}
+					c.confidence, c.suggestions = reviewSuggestions(ev.Description, teams)
+					report.review = append(report.review, c)
+				}
+				matched = append(matched, ev)
+				continue
+			}
+			var teamErr error
+			for _, t := range parsedTeams {
+				if t.ID == "" {
+					teamErr = fmt.Errorf("matched team %q has an empty ID", t.Name)
+					break
+				}
+				log.Debug("matched team", "team", t.Name)
+				ev.TeamIDs = append(ev.TeamIDs, t.ID)
+				ev.Teams = append(ev.Teams, t.Name)
+			}
+			if teamErr != nil {
+				if err := in.fail(report, ev, teamErr); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, name := range ev.Teams {
+				report.MatchedTeams[name]++
+			}
+			if r := rosterOf(rosters, ev.TeamIDs); len(r) > 0 && !playersForced {
+				players := icalplayers.InferPlayerNamesWithRoster(ev.Description, nil, r)
+				if !slices.Equal(players, ev.Players) {
+					log.Debug("checked players against roster", "inferred", ev.Players, "kept", players)
+				}
+				ev.Players = players
+			}
+			matched = append(matched, ev)
+		}
+		st.events = matched
+		if !st.more {
+			ovr.warnUnused()
+		}
+		in.progress.update("match", total, total)
+		span.SetAttributes(attribute.Int("unmatched", len(report.UnmatchedEvents)))
+		return nil
+	}}
+}
+
+// teamsStage keeps events matched to one of teams (-team).
+func teamsStage(teams stringList) stage {
+	kept := 0 // over all batches
+	return stage{name: "teams", run: func(_ context.Context, st *syncState) error {
+		before := len(st.events)
+		st.events = filterByTeams(st.events, teams)
+		kept += len(st.events)
+		slog.Info("filtered by team", "teams", []string(teams), "kept", len(st.events), "dropped", before-len(st.events))
+		if kept == 0 && !st.more {
+			st.report.warn("no events matched -team %s", teams.String())
+		}
+		if len(st.events) == 0 {
+			st.done = true
+		}
+		return nil
+	}}
+}
+
+// storeStage writes the events, or on a dry run plans what writing them
+// would change.
+func (in *ingester) storeStage(o writeOptions) stage {
+	return stage{name: "store", run: func(ctx context.Context, st *syncState) error {
+		report, events := st.report, st.events
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int("events", len(events)))
+		if o.dryRun {
+			plan, err := in.planChanges(ctx, events)
+			if err != nil {
+				return dbErr(fmt.Errorf("plan changes: %w", err))
+			}
+			report.Planned = append(report.Planned, plan...)
+			slog.Info("dry run; not storing events")
+			return nil
+		}
+
+		// Schedule change tracking, notifiers and sinks need to know what
+		// changed, which the write path doesn't report, so diff against the
+		// store before writing.
+		plan, err := in.planChanges(ctx, events)
+		if err != nil {
+			slog.Warn("could not diff events for schedule changes, notifications and sinks", "err", err)
+		}
+		for i, pc := range plan {
+			if pc.Action != "unchanged" {
+				report.pending = append(report.pending, pendingChange{plan: pc, event: events[i]})
+			}
+		}
+
+		if o.merge {
+			err = in.storeMerged(ctx, report, events)
+		} else {
+			err = in.storeUpserted(ctx, report, withPlannedVersions(events, plan), o)
+		}
+		if err != nil {
+			return err
+		}
+		if len(report.Failures) > 0 {
+			return withCode(exitPartial, fmt.Errorf("%d events failed to sync", len(report.Failures)))
+		}
+		return nil
+	}}
+}
+
+// storeMerged stores WordPress events with InsertIfNew to avoid overwriting
+// or duplicating events already imported via ICS. Deduplication is by
+// (date, summary) so collisions across different source IDs are caught;
+// existing shows only get their description, teams and image updated.
+func (in *ingester) storeMerged(ctx context.Context, report *syncReport, events []icalplayers.Event) error {
+	store := in.store
+	var inserted, updated, skipped int
+	for n, e := range events {
+		in.progress.update("store", n, len(events))
+		log := eventLogger(e)
+		existing, err := store.FindByDateAndSummary(ctx, e.Start, e.Summary)
+		if err != nil {
+			if err := in.fail(report, e, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if existing == nil {
+			ok, err := store.InsertIfNew(ctx, e)
+			if err != nil {
+				if err := in.fail(report, e, err); err != nil {
+					return err
+				}
+				continue
+			}
+			if ok {
+				inserted++
+				log.Info("inserted")
+			} else {
+				log.Info("already exists, skipping insert")
+				skipped++
+			}
+			continue
+		}
+		if e, err = in.resolveEdits(ctx, *existing, e); err != nil {
+			if err := in.fail(report, e, err); err != nil {
+				return err
+			}
+			continue
+		}
+		descChanged := existing.Description != e.Description
+		teamsChanged := !teamsEqualSorted(existing.Teams, e.Teams)
+		imageChanged := e.PostImageURL != "" && existing.PostImageURL != e.PostImageURL
+		detailsChanged := e.Details != nil && !icalplayers.SameDetails(existing.Details, e.Details)
+		if !descChanged && !teamsChanged && !imageChanged && !detailsChanged {
+			skipped++
+			log.Debug("unchanged")
+			continue
+		}
+		log = log.With("existing_uid", existing.UID)
+		if descChanged {
+			log.Info("description changed",
+				"old", truncateStr(existing.Description, 80), "new", truncateStr(e.Description, 80))
+		}
+		if teamsChanged {
+			log.Info("teams changed", "old", existing.Teams, "new", e.Teams)
+		}
+		if imageChanged {
+			log.Info("image changed", "old", existing.PostImageURL, "new", e.PostImageURL)
+		}
+		if detailsChanged {
+			log.Info("details changed", "old", existing.Details, "new", e.Details)
+		}
+		if err := in.applyMerge(ctx, existing, e, descChanged, teamsChanged, imageChanged, detailsChanged); err != nil {
+			if err := in.fail(report, e, err); err != nil {
+				return err
+			}
+			continue
+		}
+		updated++
+	}
+	in.progress.update("store", len(events), len(events))
+	slog.Info("sync complete", "inserted", inserted, "updated", updated, "unchanged", skipped, "failed", len(report.Failures))
+	report.Rows.Inserted += inserted
+	report.Rows.Updated += updated
+	report.Rows.Unchanged += skipped
+	report.Rows.Failed = len(report.Failures)
+	return nil
+}
+
+// withPlannedVersions sets each event's Version to the one planChanges
+// read, so an upsert fails with ErrVersionConflict instead of overwriting
+// a show edited since the diff. Without a plan, or for a UID the feed
+// lists twice, Version stays 0 and the upsert is unconditional.
+func withPlannedVersions(events []icalplayers.Event, plan []plannedChange) []icalplayers.Event {
+	if len(plan) != len(events) {
+		return events
+	}
+	uids := map[string]int{}
+	for _, e := range events {
+		uids[e.UID]++
+	}
+	out := slices.Clone(events)
+	for i := range out {
+		if uids[out[i].UID] == 1 {
+			out[i].Version = plan[i].version
+		}
+	}
+	return out
+}
+
+// storeUpserted upserts events by UID, o.workers at a time.
+func (in *ingester) storeUpserted(ctx context.Context, report *syncReport, events []icalplayers.Event, o writeOptions) error {
+	res, err := in.store.UpsertAll(ctx, events, o.policy, showstore.WithConflictStrategy(o.conflict), showstore.WithWorkers(o.workers), showstore.WithProgress(func(done, total int) {
+		in.progress.update("store", done, total)
+	}))
+	for _, f := range res.Failed {
+		slog.Error("upsert failed", "uid", f.UID, "summary", f.Summary, "err", f.Err)
+		reportEventError(report, icalplayers.Event{UID: f.UID, Summary: f.Summary}, f.Err)
+		report.Failures = append(report.Failures, reportFailure{UID: f.UID, Summary: f.Summary, Error: f.Err.Error()})
+	}
+	if err != nil {
+		return dbErr(err)
+	}
+	slog.Info("sync complete", "stored", res.Succeeded, "attempted", res.Attempted, "failed", len(res.Failed))
+	report.Rows.Upserted += res.Succeeded
+	report.Rows.Failed = len(report.Failures)
+	return nil
+}
+
+// recordStage writes the run's outcome to sync_runs. Failing to record is
+// logged but doesn't fail the sync.
+func (in *ingester) recordStage() stage {
+	return stage{name: "record", always: true, run: func(ctx context.Context, st *syncState) error {
+		report := st.report
+		run := showstore.SyncRun{
+			StartedAt:       st.started,
+			FinishedAt:      time.Now(),
+			Sources:         report.Sources,
+			EventsParsed:    report.EventsParsed,
+			Inserted:        report.Rows.Inserted + report.Rows.Upserted,
+			Updated:         report.Rows.Updated,
+			Unchanged:       report.Rows.Unchanged,
+			Failed:          len(report.Failures),
+			FeedHash:        report.feedHash,
+			IdempotencyKeys: in.idempotencyKeys,
+		}
+		if st.err != nil {
+			run.Error = st.err.Error()
+		}
+		if id, err := in.store.RecordSyncRun(ctx, run); err != nil {
+			slog.Warn("could not record sync run", "err", err)
+		} else {
+			report.RunID = id
+		}
+		if st.err != nil && in.opts.policy == showstore.FailFast {
+			// We can't tell which pending changes were written before the abort.
+			report.pending = nil
+		}
+		in.recordScheduleChanges(ctx, report)
+		return nil
+	}}
+}
+
+// recordScheduleChanges keeps the run's start and venue moves in
+// schedule_changes for the digest.
+func (in *ingester) recordScheduleChanges(ctx context.Context, report *syncReport) {
+	var changes []showstore.ScheduleChange
+	for _, c := range report.changeSet().scheduleChanges() {
+		for _, f := range c.Fields {
+			changes = append(changes, showstore.ScheduleChange{UID: c.Show.UID, RunID: report.RunID, Field: f.Field, Old: f.Old, New: f.New})
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	if err := in.store.RecordScheduleChanges(ctx, changes); err != nil {
+		slog.Warn("could not record schedule changes", "err", err)
+		return
+	}
+	slog.Info("schedule changes", "count", len(changes))
+}
+
+// sinkStage hands the changes that were written to the notifiers and
+// sinks. A failing sink fails an otherwise successful run as partial.
+func (in *ingester) sinkStage() stage {
+	return stage{name: "sink", always: true, run: func(ctx context.Context, st *syncState) error {
+		in.notify(ctx, st.report)
+		if err := in.applySinks(ctx, st.report); err != nil {
+			return withCode(exitPartial, err)
+		}
+		return nil
+	}}
+}
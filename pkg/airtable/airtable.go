@@ -0,0 +1,135 @@
+// Package airtable is a small Airtable Web API client: list a table's
+// records and upsert records keyed by a field.
+package airtable
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// BaseURL is the Airtable API root.
+var BaseURL = "https://api.airtable.com/v0"
+
+// batchSize is the most records Airtable accepts per write.
+const batchSize = 10
+
+// requestGap keeps us under Airtable's five requests a second per base.
+const requestGap = 250 * time.Millisecond
+
+// ErrUnauthorized is returned when Airtable rejects the token.
+var ErrUnauthorized = errors.New("airtable rejected the access token")
+
+// Client reads and writes one table.
+type Client struct {
+	token string
+	base  string
+	table string
+	http  *http.Client
+}
+
+// New returns a client for table (name or ID) in base, authenticated with
+// a personal access token that has data.records:read and write scopes.
+func New(token, base, table string) *Client {
+	return &Client{token: token, base: base, table: table, http: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Record is one row. Fields uses Airtable's JSON cell values.
+type Record struct {
+	ID     string         `json:"id,omitempty"`
+	Fields map[string]any `json:"fields"`
+}
+
+// List returns every record in the table, limited to fields if given.
+func (c *Client) List(ctx context.Context, fields ...string) ([]Record, error) {
+	var out []Record
+	offset := ""
+	for {
+		q := url.Values{"pageSize": {"100"}}
+		for _, f := range fields {
+			q.Add("fields[]", f)
+		}
+		if offset != "" {
+			q.Set("offset", offset)
+		}
+		var res struct {
+			Records []Record `json:"records"`
+			Offset  string   `json:"offset"`
+		}
+		if err := c.do(ctx, http.MethodGet, "?"+q.Encode(), nil, &res); err != nil {
+			return nil, err
+		}
+		out = append(out, res.Records...)
+		if res.Offset == "" {
+			return out, nil
+		}
+		offset = res.Offset
+	}
+}
+
+// Upsert creates or updates records, matching existing rows on mergeOn.
+// Select options are created as needed (typecast). It returns how many
+// records were written before the first error.
+func (c *Client) Upsert(ctx context.Context, records []Record, mergeOn string) (int, error) {
+	done := 0
+	for start := 0; start < len(records); start += batchSize {
+		batch := records[start:min(start+batchSize, len(records))]
+		body := map[string]any{
+			"performUpsert": map[string]any{"fieldsToMergeOn": []string{mergeOn}},
+			"records":       batch,
+			"typecast":      true,
+		}
+		if err := c.do(ctx, http.MethodPatch, "", body, nil); err != nil {
+			return done, err
+		}
+		done += len(batch)
+		select {
+		case <-time.After(requestGap):
+		case <-ctx.Done():
+			return done, ctx.Err()
+		}
+	}
+	return done, nil
+}
+
+func (c *Client) do(ctx context.Context, method, suffix string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	u := BaseURL + "/" + url.PathEscape(c.base) + "/" + url.PathEscape(c.table) + suffix
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("airtable %s: http status %d: %s", method, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
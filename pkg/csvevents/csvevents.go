@@ -0,0 +1,301 @@
+// Package csvevents reads a spreadsheet of shows, exported as CSV or TSV,
+// into icalplayers.Event. It is for one-off shows that never reach a feed.
+package csvevents
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// ErrDecode wraps errors from rows that can't be turned into events.
+var ErrDecode = errors.New("decode")
+
+// Fields are the event fields a column can map to. A show needs summary and
+// either start or date (plus time, unless it runs all day).
+var Fields = []string{
+	"uid", "summary", "description", "date", "time", "start", "end",
+	"location", "url", "image", "organizer", "teams", "players",
+}
+
+// defaultColumns maps normalized header names to fields.
+var defaultColumns = map[string]string{
+	"uid": "uid", "id": "uid",
+	"summary": "summary", "title": "summary", "show": "summary", "showname": "summary", "name": "summary",
+	"description": "description", "desc": "description", "details": "description",
+	"date": "date", "day": "date",
+	"time": "time", "starttime": "time",
+	"start": "start", "startsat": "start", "datetime": "start",
+	"end": "end", "endtime": "end", "ends": "end",
+	"location": "location", "venue": "location",
+	"url": "url", "link": "url", "tickets": "url", "ticketurl": "url",
+	"image": "image", "poster": "image", "imageurl": "image", "postimageurl": "image",
+	"organizer": "organizer", "host": "organizer",
+	"teams": "teams", "team": "teams", "lineup": "teams",
+	"players": "players", "cast": "players",
+}
+
+// Options control how a sheet is read.
+type Options struct {
+	// Comma is the field separator; zero means ','.
+	Comma rune
+	// Location is the zone of dates and times without an offset; nil
+	// means UTC.
+	Location *time.Location
+	// Columns maps header names (case, spaces and punctuation ignored) to
+	// Fields, on top of the built-in names such as "Title" or "Venue".
+	Columns map[string]string
+}
+
+// Read parses a sheet with a header row. Events without a uid column get a
+// stable one derived from start and summary, so re-importing an edited
+// sheet updates the same shows. Teams and Players hold the cells as
+// written and are empty without one; inferring players from the
+// description is left to the caller. Every bad row is reported, wrapped in
+// ErrDecode.
+func Read(r io.Reader, opts Options) ([]icalplayers.Event, error) {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: empty sheet", ErrDecode)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+	cols, err := mapColumns(header, opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []icalplayers.Event
+	var errs []error
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+		}
+		line, _ := cr.FieldPos(0)
+		row := map[string]string{}
+		for i, v := range rec[:min(len(rec), len(cols))] {
+			if f := cols[i]; f != "" && strings.TrimSpace(v) != "" {
+				row[f] = strings.TrimSpace(v)
+			}
+		}
+		if len(row) == 0 {
+			continue
+		}
+		e, err := rowEvent(row, loc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: line %d: %v", ErrDecode, line, err))
+			continue
+		}
+		events = append(events, e)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return events, nil
+}
+
+// mapColumns returns the field for each header cell, "" for ignored ones.
+func mapColumns(header []string, extra map[string]string) ([]string, error) {
+	names := map[string]string{}
+	for k, v := range defaultColumns {
+		names[k] = v
+	}
+	for k, v := range extra {
+		if !isField(v) {
+			return nil, fmt.Errorf("unknown field %q for column %q (want one of %s)", v, k, strings.Join(Fields, ", "))
+		}
+		names[normalize(k)] = v
+	}
+	cols := make([]string, len(header))
+	seen := map[string]string{}
+	for i, h := range header {
+		if i == 0 {
+			h = strings.TrimPrefix(h, "\uFEFF") // Excel's byte order mark
+		}
+		f := names[normalize(h)]
+		if f == "" {
+			continue
+		}
+		if prev, dup := seen[f]; dup {
+			return nil, fmt.Errorf("%w: columns %q and %q both map to %s", ErrDecode, prev, h, f)
+		}
+		seen[f] = h
+		cols[i] = f
+	}
+	if seen["summary"] == "" {
+		return nil, fmt.Errorf("%w: no summary column (e.g. Title or Show)", ErrDecode)
+	}
+	if seen["start"] == "" && seen["date"] == "" {
+		return nil, fmt.Errorf("%w: no start or date column", ErrDecode)
+	}
+	return cols, nil
+}
+
+func isField(f string) bool {
+	for _, x := range Fields {
+		if x == f {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(h string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, h)
+}
+
+func rowEvent(row map[string]string, loc *time.Location) (icalplayers.Event, error) {
+	e := icalplayers.Event{
+		UID:          row["uid"],
+		Summary:      row["summary"],
+		Description:  row["description"],
+		Location:     row["location"],
+		URL:          row["url"],
+		PostImageURL: row["image"],
+		Organizer:    row["organizer"],
+		Teams:        splitList(row["teams"]),
+	}
+	if e.Summary == "" {
+		return e, errors.New("no summary")
+	}
+	var start time.Time
+	var err error
+	switch {
+	case row["start"] != "":
+		if start, err = parseDateTime(row["start"], loc); err != nil {
+			return e, err
+		}
+	case row["date"] != "":
+		day, err := parseDate(row["date"], loc)
+		if err != nil {
+			return e, err
+		}
+		if row["time"] == "" {
+			start, e.AllDay = day, true
+			break
+		}
+		if start, err = atClock(day, row["time"]); err != nil {
+			return e, err
+		}
+	default:
+		return e, errors.New("no start or date")
+	}
+	e.Start = &start
+	if v := row["end"]; v != "" {
+		end, err := parseDateTime(v, loc)
+		if err != nil {
+			// A bare time ends the show on its start day.
+			y, m, d := start.Date()
+			if end, err = atClock(time.Date(y, m, d, 0, 0, 0, 0, loc), v); err != nil {
+				return e, fmt.Errorf("end: %v", err)
+			}
+		}
+		if !end.After(start) {
+			return e, fmt.Errorf("end %s is not after start", v)
+		}
+		e.End = &end
+	}
+	if v, ok := row["players"]; ok {
+		e.Players = splitList(v)
+	}
+	if e.UID == "" {
+		sum := sha256.Sum256([]byte(start.UTC().Format(time.RFC3339) + "\x00" + strings.ToLower(e.Summary)))
+		e.UID = "csv-" + hex.EncodeToString(sum[:8])
+	}
+	return e, nil
+}
+
+var (
+	dateLayouts  = []string{"2006-01-02", "1/2/2006", "1/2/06", "Jan 2, 2006", "January 2, 2006", "Mon, Jan 2, 2006", "Monday, January 2, 2006"}
+	clockLayouts = []string{"15:04", "3:04 PM", "3:04PM", "3 PM", "3PM"}
+)
+
+func parseDate(s string, loc *time.Location) (time.Time, error) {
+	for _, l := range dateLayouts {
+		if t, err := time.ParseInLocation(l, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q (want YYYY-MM-DD or M/D/YYYY)", s)
+}
+
+// atClock returns day at the wall-clock time s.
+func atClock(day time.Time, s string) (time.Time, error) {
+	s = strings.ToUpper(strings.ReplaceAll(s, ".", ""))
+	for _, l := range clockLayouts {
+		if c, err := time.Parse(l, s); err == nil {
+			y, m, d := day.Date()
+			return time.Date(y, m, d, c.Hour(), c.Minute(), 0, 0, day.Location()), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q (want e.g. 19:30 or 7:30 PM)", s)
+}
+
+// parseDateTime reads an RFC 3339 timestamp, or a date and a time separated
+// by a space or T in loc.
+func parseDateTime(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if i := strings.IndexAny(s, " T"); i > 0 {
+		// Dates like "Jan 2, 2006" contain spaces too, so try every split.
+		for j := i; j >= 0 && j < len(s); j = nextSep(s, j) {
+			day, err := parseDate(strings.TrimSpace(s[:j]), loc)
+			if err != nil {
+				continue
+			}
+			if t, err := atClock(day, strings.TrimSpace(s[j+1:])); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date and time %q", s)
+}
+
+// nextSep returns the index of the next space or T after i, or -1.
+func nextSep(s string, i int) int {
+	if k := strings.IndexAny(s[i+1:], " T"); k >= 0 {
+		return i + 1 + k
+	}
+	return -1
+}
+
+// splitList splits a cell on commas, semicolons and newlines.
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == '\n' }) {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
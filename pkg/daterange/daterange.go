@@ -0,0 +1,152 @@
+// Package daterange reads the date expressions accepted by -from, -to and
+// the API's from/to parameters: a YYYY-MM-DD date or a phrase such as
+// "today", "this weekend" or "next 14 days", relative to a given moment.
+package daterange
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Range is the days an expression names, as [Start, End) at midnight in
+// the location of the reference time.
+type Range struct {
+	Start, End time.Time
+	// Span is set for expressions naming a period (a week, a weekend, the
+	// next N days) rather than a single day.
+	Span bool
+}
+
+// ErrSyntax is wrapped by Parse errors for expressions it doesn't know.
+var ErrSyntax = errors.New("unrecognised date")
+
+// Help lists the accepted forms, for flag and error messages.
+const Help = `YYYY-MM-DD, today, tomorrow, yesterday, [this|next] <weekday>, this|next weekend, this|next week, this|next month, next|last N days|weeks`
+
+var countRe = regexp.MustCompile(`^(next|last|past) (\d+) (day|days|week|weeks)$`)
+
+// Parse returns the days s names, relative to now and in now's location.
+// Weeks start on Monday; a weekend is Friday to Sunday, the nights most
+// shows run. "next N days" starts today, "last N days" ends with today.
+func Parse(s string, now time.Time) (Range, error) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	day := func(d time.Time) Range { return Range{Start: d, End: d.AddDate(0, 0, 1)} }
+	span := func(start time.Time, days int) Range {
+		return Range{Start: start, End: start.AddDate(0, 0, days), Span: true}
+	}
+
+	if t, err := time.ParseInLocation(time.DateOnly, s, loc); err == nil {
+		return day(t), nil
+	}
+	switch s {
+	case "today":
+		return day(today), nil
+	case "tomorrow":
+		return day(today.AddDate(0, 0, 1)), nil
+	case "yesterday":
+		return day(today.AddDate(0, 0, -1)), nil
+	case "this weekend", "weekend":
+		return span(weekendStart(today), 3), nil
+	case "next weekend":
+		return span(weekendStart(today).AddDate(0, 0, 7), 3), nil
+	case "this week":
+		return span(weekStart(today), 7), nil
+	case "next week":
+		return span(weekStart(today).AddDate(0, 0, 7), 7), nil
+	case "this month", "next month":
+		first := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, loc)
+		if s == "next month" {
+			first = first.AddDate(0, 1, 0)
+		}
+		return Range{Start: first, End: first.AddDate(0, 1, 0), Span: true}, nil
+	}
+	if m := countRe.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[2])
+		if err != nil || n < 1 || n > 3660 {
+			return Range{}, fmt.Errorf("%w %q: count must be 1 or more", ErrSyntax, s)
+		}
+		if strings.HasPrefix(m[3], "week") {
+			n *= 7
+		}
+		if m[1] == "next" {
+			return span(today, n), nil
+		}
+		return span(today.AddDate(0, 0, 1-n), n), nil
+	}
+	next := false
+	name := s
+	if rest, ok := strings.CutPrefix(s, "this "); ok {
+		name = rest
+	} else if rest, ok := strings.CutPrefix(s, "next "); ok {
+		name, next = rest, true
+	}
+	if wd, ok := weekday(name); ok {
+		d := today.AddDate(0, 0, (int(wd)-int(today.Weekday())+7)%7)
+		if next {
+			d = d.AddDate(0, 0, 7)
+		}
+		return day(d), nil
+	}
+	return Range{}, fmt.Errorf("%w %q (want %s)", ErrSyntax, s, Help)
+}
+
+// weekStart returns the Monday on or before d.
+func weekStart(d time.Time) time.Time {
+	return d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+}
+
+// weekendStart returns the Friday starting the weekend d is in, or the next
+// one if d is Monday to Thursday.
+func weekendStart(d time.Time) time.Time {
+	switch d.Weekday() {
+	case time.Saturday:
+		return d.AddDate(0, 0, -1)
+	case time.Sunday:
+		return d.AddDate(0, 0, -2)
+	}
+	return d.AddDate(0, 0, (int(time.Friday)-int(d.Weekday())+7)%7)
+}
+
+func weekday(name string) (time.Weekday, bool) {
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		full := strings.ToLower(wd.String())
+		if name == full || name == full[:3] {
+			return wd, true
+		}
+	}
+	return 0, false
+}
+
+// Window turns a from and to expression into a [from, to) window; either
+// may be empty for no bound. from contributes its first day and to its last,
+// so "-from today -to friday" includes Friday. A period given as from with
+// no to is the whole window: "-from 'this weekend'" stops at Sunday night.
+func Window(from, to string, now time.Time) (start, end time.Time, err error) {
+	if from != "" {
+		r, err := Parse(from, now)
+		if err != nil {
+			return start, end, fmt.Errorf("from: %w", err)
+		}
+		start = r.Start
+		if r.Span && to == "" {
+			end = r.End
+		}
+	}
+	if to != "" {
+		r, err := Parse(to, now)
+		if err != nil {
+			return start, end, fmt.Errorf("to: %w", err)
+		}
+		end = r.End
+	}
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		return start, end, fmt.Errorf("from %q is after to %q", from, to)
+	}
+	return start, end, nil
+}
@@ -0,0 +1,123 @@
+package daterange
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var chicago = time.FixedZone("CDT", -5*60*60)
+
+// day is midnight on m d, 2026, in chicago.
+func day(m time.Month, d int) time.Time {
+	return time.Date(2026, m, d, 0, 0, 0, 0, chicago)
+}
+
+// wednesday is Wednesday, October 14, 2026, 8pm.
+var wednesday = time.Date(2026, time.October, 14, 20, 0, 0, 0, chicago)
+
+func TestParse(t *testing.T) {
+	oct := time.October
+	tests := []struct {
+		expr       string
+		now        time.Time
+		start, end time.Time
+		span       bool
+	}{
+		{"2026-10-20", wednesday, day(oct, 20), day(oct, 21), false},
+		{"today", wednesday, day(oct, 14), day(oct, 15), false},
+		{"tomorrow", wednesday, day(oct, 15), day(oct, 16), false},
+		{"yesterday", wednesday, day(oct, 13), day(oct, 14), false},
+		{"  This   Weekend ", wednesday, day(oct, 16), day(oct, 19), true},
+
+		// A weekend runs Friday to Sunday; on Saturday or Sunday it's the
+		// one under way.
+		{"this weekend", day(oct, 16), day(oct, 16), day(oct, 19), true},
+		{"this weekend", day(oct, 17), day(oct, 16), day(oct, 19), true},
+		{"weekend", day(oct, 18).Add(23 * time.Hour), day(oct, 16), day(oct, 19), true},
+		{"next weekend", day(oct, 18), day(oct, 23), day(oct, 26), true},
+		{"this weekend", day(oct, 19), day(oct, 23), day(oct, 26), true},
+
+		// Weeks start on Monday.
+		{"this week", wednesday, day(oct, 12), day(oct, 19), true},
+		{"this week", day(oct, 18), day(oct, 12), day(oct, 19), true},
+		{"next week", wednesday, day(oct, 19), day(oct, 26), true},
+		{"this month", wednesday, day(oct, 1), day(time.November, 1), true},
+		{"next month", wednesday, day(time.November, 1), day(time.December, 1), true},
+
+		// "next N" starts today; "last N" ends with today.
+		{"next 3 days", wednesday, day(oct, 14), day(oct, 17), true},
+		{"next 1 day", wednesday, day(oct, 14), day(oct, 15), true},
+		{"last 3 days", wednesday, day(oct, 12), day(oct, 15), true},
+		{"past 1 day", wednesday, day(oct, 14), day(oct, 15), true},
+		{"last 1 week", wednesday, day(oct, 8), day(oct, 15), true},
+		{"next 2 weeks", wednesday, day(oct, 14), day(oct, 28), true},
+
+		// A bare or "this" weekday is the next one, today included;
+		// "next" is the one a week after that.
+		{"friday", wednesday, day(oct, 16), day(oct, 17), false},
+		{"fri", wednesday, day(oct, 16), day(oct, 17), false},
+		{"wednesday", wednesday, day(oct, 14), day(oct, 15), false},
+		{"this wednesday", wednesday, day(oct, 14), day(oct, 15), false},
+		{"next wednesday", wednesday, day(oct, 21), day(oct, 22), false},
+		{"tuesday", wednesday, day(oct, 20), day(oct, 21), false},
+		{"next friday", wednesday, day(oct, 23), day(oct, 24), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr+"@"+tt.now.Format("Mon"), func(t *testing.T) {
+			r, err := Parse(tt.expr, tt.now)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			if !r.Start.Equal(tt.start) || !r.End.Equal(tt.end) || r.Span != tt.span {
+				t.Errorf("Parse(%q) = [%s, %s) span=%v, want [%s, %s) span=%v", tt.expr,
+					r.Start.Format(time.DateOnly), r.End.Format(time.DateOnly), r.Span,
+					tt.start.Format(time.DateOnly), tt.end.Format(time.DateOnly), tt.span)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"", "someday", "next 0 days", "last -1 days", "next fortnight", "2026-13-01", "this tomorrow"} {
+		if _, err := Parse(expr, wednesday); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Parse(%q) error = %v, want ErrSyntax", expr, err)
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	oct := time.October
+	var none time.Time
+	tests := []struct {
+		from, to   string
+		start, end time.Time
+	}{
+		{"", "", none, none},
+		{"today", "", day(oct, 14), none},
+		{"", "tomorrow", none, day(oct, 16)},
+		// to contributes its last day.
+		{"today", "friday", day(oct, 14), day(oct, 17)},
+		// A period as from, with no to, is the whole window.
+		{"this weekend", "", day(oct, 16), day(oct, 19)},
+		{"next 7 days", "", day(oct, 14), day(oct, 21)},
+		// With a to, the period only gives the start.
+		{"this week", "today", day(oct, 12), day(oct, 15)},
+	}
+	for _, tt := range tests {
+		start, end, err := Window(tt.from, tt.to, wednesday)
+		if err != nil {
+			t.Errorf("Window(%q, %q): %v", tt.from, tt.to, err)
+			continue
+		}
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Errorf("Window(%q, %q) = [%v, %v), want [%v, %v)", tt.from, tt.to, start, end, tt.start, tt.end)
+		}
+	}
+
+	for _, bad := range [][2]string{{"friday", "today"}, {"today", "yesterday"}, {"soon", ""}, {"", "later"}} {
+		if _, _, err := Window(bad[0], bad[1], wednesday); err == nil {
+			t.Errorf("Window(%q, %q) succeeded, want an error", bad[0], bad[1])
+		}
+	}
+}
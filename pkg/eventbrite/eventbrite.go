@@ -0,0 +1,174 @@
+// Package eventbrite fetches an organizer's live events from the Eventbrite
+// API and converts them to icalplayers.Event, for ticketed shows that are
+// only listed there.
+package eventbrite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// BaseURL is the Eventbrite v3 API root.
+var BaseURL = "https://www.eventbriteapi.com/v3"
+
+// ErrDecode wraps errors from decoding an API response body.
+var ErrDecode = errors.New("decode")
+
+// ErrUnauthorized is returned when Eventbrite rejects the token.
+var ErrUnauthorized = errors.New("eventbrite rejected the API token")
+
+type text struct {
+	Text string `json:"text"`
+}
+
+type when struct {
+	Timezone string `json:"timezone"`
+	UTC      string `json:"utc"`
+}
+
+// ebEvent mirrors the fields of an Eventbrite event object we use.
+type ebEvent struct {
+	ID          string `json:"id"`
+	Name        text   `json:"name"`
+	Summary     string `json:"summary"`
+	Description text   `json:"description"`
+	URL         string `json:"url"`
+	Start       when   `json:"start"`
+	End         when   `json:"end"`
+	Logo        *struct {
+		URL      string `json:"url"`
+		Original struct {
+			URL string `json:"url"`
+		} `json:"original"`
+	} `json:"logo"`
+	Venue *struct {
+		Name    string `json:"name"`
+		Address struct {
+			Localized string `json:"localized_address_display"`
+		} `json:"address"`
+	} `json:"venue"`
+	Organizer *struct {
+		Name string `json:"name"`
+	} `json:"organizer"`
+}
+
+type apiResponse struct {
+	Events     []ebEvent `json:"events"`
+	Pagination struct {
+		HasMoreItems bool   `json:"has_more_items"`
+		Continuation string `json:"continuation"`
+	} `json:"pagination"`
+}
+
+// FetchOrganizer returns every live event for organizerID, following
+// continuation tokens across pages.
+func FetchOrganizer(ctx context.Context, token, organizerID string) ([]icalplayers.Event, error) {
+	if token == "" {
+		return nil, errors.New("eventbrite: no API token")
+	}
+	var all []icalplayers.Event
+	continuation := ""
+	for page := 1; ; page++ {
+		q := url.Values{"status": {"live"}, "expand": {"venue,organizer"}, "order_by": {"start_asc"}}
+		if continuation != "" {
+			q.Set("continuation", continuation)
+		}
+		u := fmt.Sprintf("%s/organizers/%s/events/?%s", BaseURL, url.PathEscape(organizerID), q.Encode())
+		slog.Debug("fetching Eventbrite events page", "page", page, "organizer", organizerID)
+		resp, err := fetchPage(ctx, u, token)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+		for _, e := range resp.Events {
+			all = append(all, convert(e))
+		}
+		if !resp.Pagination.HasMoreItems || resp.Pagination.Continuation == "" {
+			break
+		}
+		continuation = resp.Pagination.Continuation
+	}
+	slog.Info("fetched Eventbrite events", "organizer", organizerID, "count", len(all))
+	return all, nil
+}
+
+func fetchPage(ctx context.Context, u, token string) (*apiResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := httpclient.Default.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, ErrUnauthorized
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("http status %d", resp.StatusCode)
+	}
+
+	var result apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	return &result, nil
+}
+
+func convert(e ebEvent) icalplayers.Event {
+	desc := strings.TrimSpace(e.Description.Text)
+	if desc == "" {
+		desc = strings.TrimSpace(e.Summary)
+	}
+	ev := icalplayers.Event{
+		UID:         "eventbrite-" + e.ID,
+		Summary:     strings.TrimSpace(e.Name.Text),
+		Description: desc,
+		URL:         e.URL,
+		Start:       parseTime(e.Start),
+		End:         parseTime(e.End),
+		Players:     icalplayers.InferPlayerNames(desc, nil),
+	}
+	if e.Logo != nil {
+		ev.PostImageURL = e.Logo.Original.URL
+		if ev.PostImageURL == "" {
+			ev.PostImageURL = e.Logo.URL
+		}
+	}
+	if e.Venue != nil {
+		ev.Location = e.Venue.Name
+		if a := e.Venue.Address.Localized; a != "" {
+			ev.Location += ", " + a
+		}
+	}
+	if e.Organizer != nil {
+		ev.Organizer = e.Organizer.Name
+	}
+	return ev
+}
+
+// parseTime reads the UTC timestamp and moves it into the event's own
+// timezone when Eventbrite gives one we know.
+func parseTime(w when) *time.Time {
+	t, err := time.Parse(time.RFC3339, w.UTC)
+	if err != nil {
+		return nil
+	}
+	if loc, err := time.LoadLocation(w.Timezone); err == nil {
+		t = t.In(loc)
+	}
+	return &t
+}
@@ -0,0 +1,171 @@
+// Package gcal is a small Google Calendar v3 client for writing events into
+// a calendar shared with a service account.
+package gcal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/oauth2/google"
+)
+
+// BaseURL is the Calendar API root.
+var BaseURL = "https://www.googleapis.com/calendar/v3"
+
+const scope = "https://www.googleapis.com/auth/calendar.events"
+
+// ErrNotFound is returned for events the calendar doesn't have.
+var ErrNotFound = errors.New("gcal: event not found")
+
+// Client reads and writes events on one calendar.
+type Client struct {
+	http       *http.Client
+	calendarID string
+}
+
+// New returns a client authenticated as the service account in
+// credentialsJSON. The calendar must be shared with that account with
+// "make changes to events" permission.
+func New(ctx context.Context, credentialsJSON []byte, calendarID string) (*Client, error) {
+	cfg, err := google.JWTConfigFromJSON(credentialsJSON, scope)
+	if err != nil {
+		return nil, fmt.Errorf("gcal credentials: %w", err)
+	}
+	return &Client{http: cfg.Client(ctx), calendarID: calendarID}, nil
+}
+
+// EventTime is a timed (DateTime) or all-day (Date) boundary.
+type EventTime struct {
+	DateTime string `json:"dateTime,omitempty"` // RFC 3339
+	Date     string `json:"date,omitempty"`     // YYYY-MM-DD
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// ExtendedProperties holds key/value pairs private to the writing app.
+type ExtendedProperties struct {
+	Private map[string]string `json:"private,omitempty"`
+}
+
+// Source links an event back to where it came from.
+type Source struct {
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url"`
+}
+
+// Event is the subset of a Calendar event resource shopsync sets.
+type Event struct {
+	ID                 string              `json:"id,omitempty"`
+	Status             string              `json:"status,omitempty"`
+	Summary            string              `json:"summary,omitempty"`
+	Description        string              `json:"description,omitempty"`
+	Location           string              `json:"location,omitempty"`
+	Start              *EventTime          `json:"start,omitempty"`
+	End                *EventTime          `json:"end,omitempty"`
+	Source             *Source             `json:"source,omitempty"`
+	ExtendedProperties *ExtendedProperties `json:"extendedProperties,omitempty"`
+	HTMLLink           string              `json:"htmlLink,omitempty"`
+}
+
+// List returns the calendar's events tagged with the private extended
+// property key=value that overlap [timeMin, timeMax), following page
+// tokens. Cancelled events are skipped.
+func (c *Client) List(ctx context.Context, key, value string, timeMin, timeMax time.Time) ([]Event, error) {
+	var out []Event
+	token := ""
+	for {
+		q := url.Values{
+			"privateExtendedProperty": {key + "=" + value},
+			"timeMin":                 {timeMin.Format(time.RFC3339)},
+			"timeMax":                 {timeMax.Format(time.RFC3339)},
+			"maxResults":              {"250"},
+		}
+		if token != "" {
+			q.Set("pageToken", token)
+		}
+		var page struct {
+			Items         []Event `json:"items"`
+			NextPageToken string  `json:"nextPageToken"`
+		}
+		if err := c.do(ctx, http.MethodGet, c.eventsURL("")+"?"+q.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, e := range page.Items {
+			if e.Status != "cancelled" {
+				out = append(out, e)
+			}
+		}
+		if page.NextPageToken == "" {
+			return out, nil
+		}
+		token = page.NextPageToken
+	}
+}
+
+// Put writes e under e.ID, creating it if the calendar doesn't have it and
+// restoring it if it was deleted. created reports which happened.
+func (c *Client) Put(ctx context.Context, e Event) (created bool, err error) {
+	e.Status = "confirmed"
+	err = c.do(ctx, http.MethodPut, c.eventsURL(e.ID), e, nil)
+	if !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+	return true, c.do(ctx, http.MethodPost, c.eventsURL(""), e, nil)
+}
+
+// Delete removes the event with id. Events already gone are not an error.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	err := c.do(ctx, http.MethodDelete, c.eventsURL(id), nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (c *Client) eventsURL(id string) string {
+	u := BaseURL + "/calendars/" + url.PathEscape(c.calendarID) + "/events"
+	if id != "" {
+		u += "/" + url.PathEscape(id)
+	}
+	return u
+}
+
+func (c *Client) do(ctx context.Context, method, u string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrNotFound
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("gcal %s: http status %d: %s", method, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,167 @@
+package httpclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CacheDir, when set, keeps cached responses on disk under CacheDir/http
+// so they outlive the process; otherwise they are kept in memory only.
+var CacheDir string
+
+// DefaultCacheDir is the user cache directory's shopsync folder, or "" if
+// the platform has none.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "shopsync")
+}
+
+// Cache kinds, from the response's content type.
+const (
+	KindFeed  = "feed"  // calendars and JSON APIs
+	KindPage  = "page"  // HTML, e.g. event pages scraped for images
+	KindImage = "image" // images
+	KindOther = "other"
+)
+
+// Entry is one response in the disk cache.
+type Entry struct {
+	URL      string      `json:"url"`
+	Host     string      `json:"-"`
+	Kind     string      `json:"kind"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	StoredAt time.Time   `json:"storedAt"` // when the body was fetched
+	Size     int64       `json:"-"`        // bytes on disk
+
+	path string // without extension
+}
+
+func kindOf(contentType string) string {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mt == "text/calendar", mt == "application/json", strings.HasSuffix(mt, "+json"):
+		return KindFeed
+	case mt == "text/html", mt == "application/xhtml+xml":
+		return KindPage
+	case strings.HasPrefix(mt, "image/"):
+		return KindImage
+	}
+	return KindOther
+}
+
+// diskPath is where the response for rawURL lives under dir, without
+// extension: http/<host>/<hash of the URL>.
+func diskPath(dir, rawURL string) string {
+	host := "unknown"
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host = strings.NewReplacer(":", "_", "/", "_").Replace(u.Host)
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, "http", host, hex.EncodeToString(sum[:16]))
+}
+
+func diskLoad(dir, rawURL string) *cacheEntry {
+	p := diskPath(dir, rawURL)
+	var e Entry
+	meta, err := os.ReadFile(p + ".meta")
+	if err != nil || json.Unmarshal(meta, &e) != nil || e.URL != rawURL {
+		return nil
+	}
+	body, err := os.ReadFile(p + ".body")
+	if err != nil {
+		return nil
+	}
+	return &cacheEntry{status: e.Status, header: e.Header, body: body}
+}
+
+// diskSave writes the body before the metadata, so a reader never sees
+// metadata without its body. Failures just leave the entry uncached.
+func diskSave(dir, rawURL string, c *cacheEntry) {
+	p := diskPath(dir, rawURL)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return
+	}
+	meta, err := json.Marshal(Entry{
+		URL: rawURL, Kind: kindOf(c.header.Get("Content-Type")), Status: c.status,
+		Header: c.header, StoredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return
+	}
+	if writeFile(p+".body", c.body) == nil {
+		_ = writeFile(p+".meta", meta)
+	}
+}
+
+func writeFile(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Entries lists the disk cache under dir. A missing cache is empty.
+func Entries(dir string) ([]Entry, error) {
+	root := filepath.Join(dir, "http")
+	var out []Entry
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && p == root {
+			return fs.SkipAll
+		}
+		if err != nil || d.IsDir() || filepath.Ext(p) != ".meta" {
+			return err
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		var e Entry
+		if json.Unmarshal(b, &e) != nil {
+			// Leftovers from an interrupted write; list them so they can be
+			// purged.
+			e = Entry{URL: "?", Kind: KindOther}
+		}
+		e.path = strings.TrimSuffix(p, ".meta")
+		e.Host = filepath.Base(filepath.Dir(p))
+		if fi, err := os.Stat(e.path + ".body"); err == nil {
+			e.Size = fi.Size()
+		}
+		e.Size += int64(len(b))
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
+
+// Remove deletes e from the disk cache. The in-memory copy of a running
+// process is unaffected.
+func Remove(e Entry) error {
+	err := os.Remove(e.path + ".meta")
+	if berr := os.Remove(e.path + ".body"); err == nil && !errors.Is(berr, fs.ErrNotExist) {
+		err = berr
+	}
+	return err
+}
@@ -0,0 +1,315 @@
+// Package httpclient is the HTTP layer shared by everything that reads
+// other people's sites: feeds, event pages, team pages and images. It sets
+// one User-Agent, spaces requests to each host, retries transient failures
+// of GET and HEAD requests, revalidates cached responses with their ETag or
+// Last-Modified (in memory, and under CacheDir when set), and reports every
+// request to Observer for metrics.
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// UserAgent is sent on every request that doesn't set its own.
+var UserAgent = "shopsync/1.0 (+https://github.com/tsny/shopsync)"
+
+// HostRate caps requests per second to any one host. Zero means unlimited.
+var HostRate float64 = 5
+
+// Attempts is how many times an idempotent request is tried before its
+// last error or response is returned.
+var Attempts = 3
+
+// Backoff is the wait before the first retry; it doubles for each one
+// after. A Retry-After header from the server takes precedence.
+var Backoff = time.Second
+
+// Observation describes one request, retries included.
+type Observation struct {
+	Host     string
+	Method   string
+	Status   int // 0 when no response arrived
+	Duration time.Duration
+	Err      error
+	Retries  int
+	// Cached is set when the server answered 304 and the body came from
+	// the cache.
+	Cached bool
+}
+
+// Observer, if set, is called after every request.
+var Observer func(Observation)
+
+// Default is the client for fetching feeds and pages.
+var Default = &http.Client{Timeout: 30 * time.Second, Transport: NewTransport(http.DefaultTransport)}
+
+// Transport adds the package's behaviour to an underlying RoundTripper.
+type Transport struct {
+	base http.RoundTripper
+
+	mu    sync.Mutex
+	hosts map[string]*Limiter
+	cache map[string]*cacheEntry
+	order []string // cache keys, oldest first
+}
+
+// maxCacheEntries and maxCacheBody bound the response cache.
+const (
+	maxCacheEntries = 128
+	maxCacheBody    = 4 << 20
+)
+
+type cacheEntry struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// NewTransport wraps base, http.DefaultTransport if nil.
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base, hosts: map[string]*Limiter{}, cache: map[string]*cacheEntry{}}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	obs := Observation{Host: req.URL.Host, Method: req.Method}
+	resp, err := t.roundTrip(req, &obs)
+	obs.Duration, obs.Err = time.Since(start), err
+	if resp != nil {
+		obs.Status = resp.StatusCode
+	}
+	if Observer != nil {
+		Observer(obs)
+	}
+	return resp, err
+}
+
+func (t *Transport) roundTrip(req *http.Request, obs *Observation) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", UserAgent)
+	}
+	idempotent := (req.Method == http.MethodGet || req.Method == http.MethodHead) && (req.Body == nil || req.Body == http.NoBody)
+	// Callers doing their own revalidation, and authenticated requests,
+	// bypass the cache.
+	cacheable := req.Method == http.MethodGet && req.Header.Get("Authorization") == "" &&
+		req.Header.Get("If-None-Match") == "" && req.Header.Get("If-Modified-Since") == ""
+	key := req.URL.String()
+	var cached *cacheEntry
+	if cacheable {
+		cached = t.lookup(key)
+		if cached != nil {
+			if v := cached.header.Get("ETag"); v != "" {
+				req.Header.Set("If-None-Match", v)
+			}
+			if v := cached.header.Get("Last-Modified"); v != "" {
+				req.Header.Set("If-Modified-Since", v)
+			}
+		}
+	}
+
+	attempts := 1
+	if idempotent {
+		attempts = max(Attempts, 1)
+	}
+	var (
+		resp *http.Response
+		err  error
+	)
+	for attempt := range attempts {
+		if attempt > 0 {
+			obs.Retries++
+			if werr := sleep(req.Context(), retryDelay(resp, attempt)); werr != nil {
+				return nil, werr
+			}
+			if resp != nil {
+				resp.Body.Close()
+			}
+		}
+		if werr := t.limiter(req.URL.Host).Wait(req.Context()); werr != nil {
+			return nil, werr
+		}
+		resp, err = t.base.RoundTrip(req)
+		if !retryable(resp, err) || req.Context().Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		obs.Cached = true
+		return cached.response(req), nil
+	}
+	if cacheable && resp.StatusCode == http.StatusOK &&
+		(resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "") {
+		t.store(key, resp)
+	}
+	return resp, nil
+}
+
+// store caches resp's body if it is small enough, replacing resp.Body with
+// an equivalent reader either way.
+func (t *Transport) store(key string, resp *http.Response) {
+	if resp.ContentLength > maxCacheBody {
+		return
+	}
+	head, err := io.ReadAll(io.LimitReader(resp.Body, maxCacheBody+1))
+	if err != nil || len(head) > maxCacheBody {
+		resp.Body = readCloser{io.MultiReader(bytes.NewReader(head), errReader{err}, resp.Body), resp.Body}
+		return
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(head))
+
+	e := &cacheEntry{status: resp.StatusCode, header: resp.Header.Clone(), body: head}
+	t.remember(key, e)
+	if CacheDir != "" {
+		diskSave(CacheDir, key, e)
+	}
+}
+
+// lookup returns the cached response for key from memory or, failing
+// that, CacheDir.
+func (t *Transport) lookup(key string) *cacheEntry {
+	t.mu.Lock()
+	e := t.cache[key]
+	t.mu.Unlock()
+	if e == nil && CacheDir != "" {
+		if e = diskLoad(CacheDir, key); e != nil {
+			t.remember(key, e)
+		}
+	}
+	return e
+}
+
+// remember keeps e in memory, evicting the oldest entries past
+// maxCacheEntries.
+func (t *Transport) remember(key string, e *cacheEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.cache[key]; !ok {
+		t.order = append(t.order, key)
+	}
+	t.cache[key] = e
+	for len(t.order) > maxCacheEntries {
+		delete(t.cache, t.order[0])
+		t.order = t.order[1:]
+	}
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(e.status) + " " + http.StatusText(e.status),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+func (t *Transport) limiter(host string) *Limiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.hosts[host]
+	if !ok {
+		l = NewLimiter(HostRate)
+		t.hosts[host] = l
+	}
+	return l
+}
+
+// retryable reports whether a try ended in a network error, a 429 or a
+// 5xx.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// retryDelay honours a Retry-After in seconds, up to a minute, and
+// otherwise backs off exponentially.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if resp != nil {
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s >= 0 {
+			return min(time.Duration(s)*time.Second, time.Minute)
+		}
+	}
+	return Backoff << (attempt - 1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Limiter spaces calls at least 1/rate apart. A nil Limiter never waits.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewLimiter returns a limiter for rate calls per second, or nil (no
+// limit) if rate is not positive.
+func NewLimiter(rate float64) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &Limiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// Wait blocks until the next call may go ahead or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+	return sleep(ctx, time.Until(at))
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// errReader returns err, or io.EOF when err is nil.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return 0, io.EOF
+}
@@ -18,10 +18,26 @@ import (
 	"unicode"
 
 	ics "github.com/arran4/golang-ical"
-	"github.com/tsny/shopsync/pkg/wpimg"
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var SkipImageSearch = false
+var tracer = otel.Tracer("github.com/tsny/shopsync/pkg/icalplayers")
+
+// Event is one show. Its JSON form is the export contract described by
+// event.schema.json; keep the two in step (see EventSchemaVersion).
+// Event types. Venue feeds mix classes and workshops in with shows.
+const (
+	TypeShow     = "show"
+	TypeClass    = "class"
+	TypeWorkshop = "workshop"
+)
+
+// EventTypes are the valid values of Event.EventType, shows first.
+var EventTypes = []string{TypeShow, TypeClass, TypeWorkshop}
 
 type Event struct {
 	UID          string     `json:"uid"`
@@ -37,20 +53,95 @@ type Event struct {
 	Players      []string   `json:"players,omitempty"`
 	Teams        []string   `json:"teams,omitempty"`
 	TeamIDs      []string   `json:"teamIds,omitempty"`
+	// Details are extras the feed leaves out, read from the event page
+	// (or the source's API); nil when nothing was found.
+	Details *Details `json:"details,omitempty"`
+	// Categories are the source's own event categories, where it has them
+	// (ICS CATEGORIES, The Events Calendar API). They aren't stored.
+	Categories []string `json:"categories,omitempty"`
+	// EventType is TypeShow, TypeClass or TypeWorkshop, as classified at
+	// ingest; "" for events that haven't been, which count as shows.
+	EventType string `json:"eventType,omitempty"`
+	// Version is the stored row version when the event was read from the
+	// database; zero for events that came from a feed.
+	Version int64 `json:"version,omitempty"`
+	// UpdatedAt is when the stored row last changed; nil for feed events.
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	// Modified is when the source last changed the event (ICS
+	// LAST-MODIFIED, WordPress modified_utc); nil if the source doesn't
+	// say. It isn't stored.
+	Modified *time.Time `json:"modified,omitempty"`
+}
+
+// Details are ticketing and lineup facts scraped from an event page, as
+// the page words them.
+type Details struct {
+	Price  string `json:"price,omitempty"`  // e.g. "$10", "Free"
+	Doors  string `json:"doors,omitempty"`  // door time, e.g. "7:30 PM"
+	Ages   string `json:"ages,omitempty"`   // e.g. "18+", "All ages"
+	Lineup string `json:"lineup,omitempty"` // the full lineup text
+}
+
+// DetailsFrom builds Details from fields keyed price, doors, ages and
+// lineup (as wpimg returns them); nil if all are empty.
+func DetailsFrom(fields map[string]string) *Details {
+	d := Details{Price: fields["price"], Doors: fields["doors"], Ages: fields["ages"], Lineup: fields["lineup"]}
+	if d == (Details{}) {
+		return nil
+	}
+	return &d
+}
+
+// SameDetails reports whether a and b hold the same details, treating nil
+// as empty.
+func SameDetails(a, b *Details) bool {
+	var x, y Details
+	if a != nil {
+		x = *a
+	}
+	if b != nil {
+		y = *b
+	}
+	return x == y
 }
 
+// NameDict holds known first, last and full names, lower-cased. The CSV
+// form read by LoadNameDict has first,last,full columns, any of which may be
+// empty; `shopsync dict build` writes it.
 type NameDict struct {
 	First map[string]struct{}
 	Last  map[string]struct{}
 	Full  map[string]struct{}
 }
 
-func LoadNameDict(csvPath string) (*NameDict, error) {
+// NewNameDict returns a dictionary of the given full names.
+func NewNameDict(names ...string) *NameDict {
 	nd := &NameDict{
 		First: map[string]struct{}{},
 		Last:  map[string]struct{}{},
 		Full:  map[string]struct{}{},
 	}
+	for _, n := range names {
+		nd.Add(n)
+	}
+	return nd
+}
+
+// Add records a full name along with its first and last words.
+func (nd *NameDict) Add(full string) {
+	parts := strings.Fields(strings.ToLower(full))
+	if len(parts) == 0 {
+		return
+	}
+	nd.Full[strings.Join(parts, " ")] = struct{}{}
+	nd.First[parts[0]] = struct{}{}
+	if len(parts) > 1 {
+		nd.Last[parts[len(parts)-1]] = struct{}{}
+	}
+}
+
+func LoadNameDict(csvPath string) (*NameDict, error) {
+	nd := NewNameDict()
 	if csvPath == "" {
 		return nd, nil
 	}
@@ -85,61 +176,129 @@ func LoadNameDict(csvPath string) (*NameDict, error) {
 	return nd, nil
 }
 
+// ErrParse wraps errors from decoding the calendar itself, as opposed to
+// fetching it.
+var ErrParse = errors.New("parse ics")
+
 // Top-level helpers
 
-func FromReader(r io.Reader, dict *NameDict) ([]Event, error) {
+// ParseOption tunes FromReader and the functions built on it.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	skipImages bool
+}
+
+// SkipImages leaves post images alone, for callers that run EnrichImages
+// themselves (or not at all). By default every parsed event with a URL is
+// enriched.
+func SkipImages() ParseOption {
+	return func(c *parseConfig) { c.skipImages = true }
+}
+
+func FromReader(r io.Reader, dict *NameDict, opts ...ParseOption) ([]Event, error) {
+	return FromReaderContext(context.Background(), r, dict, opts...)
+}
+
+// FromReaderContext is FromReader with a context for image enrichment and
+// tracing.
+func FromReaderContext(ctx context.Context, r io.Reader, dict *NameDict, opts ...ParseOption) ([]Event, error) {
+	var cfg parseConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	_, span := tracer.Start(ctx, "icalplayers.parse")
 	cal, err := ics.ParseCalendar(r)
 	if err != nil {
-		return nil, fmt.Errorf("parse ics: %w", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "parse failed")
+		span.End()
+		return nil, fmt.Errorf("%w: %w", ErrParse, err)
 	}
 	evs := collectEvents(cal)
 	for i := range evs {
 		evs[i].Players = InferPlayerNames(evs[i].Description, dict)
-		if !SkipImageSearch {
-			postResult, _ := wpimg.Fetch(context.Background(), evs[i].URL)
-			if postResult.ImageURL != "" {
-				evs[i].PostImageURL = postResult.ImageURL
-				fmt.Println("Fetched post image:", postResult.ImageURL)
-			}
-		}
+	}
+	span.SetAttributes(attribute.Int("events", len(evs)))
+	span.End()
+	if !cfg.skipImages {
+		EnrichImages(ctx, evs)
 	}
 	return evs, nil
 }
 
-func FromFile(path string, dict *NameDict) ([]Event, error) {
+func FromFile(path string, dict *NameDict, opts ...ParseOption) ([]Event, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
-	return FromReader(f, dict)
+	return FromReader(f, dict, opts...)
+}
+
+func FromURL(ctx context.Context, raw string, client *http.Client, dict *NameDict, opts ...ParseOption) ([]Event, error) {
+	evs, _, err := FromURLConditional(ctx, raw, client, dict, Validators{}, opts...)
+	return evs, err
+}
+
+// ErrNotModified is returned by FromURLConditional when the server reports
+// that the feed has not changed since the given validators were issued.
+var ErrNotModified = errors.New("feed not modified")
+
+// Validators are the HTTP cache validators returned with a feed response.
+type Validators struct {
+	ETag         string
+	LastModified string
 }
 
-func FromURL(ctx context.Context, raw string, client *http.Client, dict *NameDict) ([]Event, error) {
+// FromURLConditional fetches and parses a feed, sending If-None-Match and
+// If-Modified-Since from prev. On a 304 it returns ErrNotModified and prev.
+func FromURLConditional(ctx context.Context, raw string, client *http.Client, dict *NameDict, prev Validators, opts ...ParseOption) ([]Event, Validators, error) {
+	ctx, span := tracer.Start(ctx, "icalplayers.fetch", trace.WithAttributes(attribute.String("url", raw)))
+	defer span.End()
 	if client == nil {
-		client = http.DefaultClient
+		client = httpclient.Default
 	}
 	u, err := url.Parse(raw)
 	if err != nil || u.Scheme == "" || u.Host == "" {
-		return nil, errors.New("invalid url")
+		return nil, prev, errors.New("invalid url")
 	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, raw, nil)
 	if err != nil {
-		return nil, err
+		return nil, prev, err
+	}
+	if prev.ETag != "" {
+		req.Header.Set("If-None-Match", prev.ETag)
+	}
+	if prev.LastModified != "" {
+		req.Header.Set("If-Modified-Since", prev.LastModified)
 	}
-	req.Header.Set("User-Agent", "icalplayers/1.0")
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "request failed")
+		return nil, prev, err
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, prev, ErrNotModified
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("http status %d", resp.StatusCode)
+		return nil, prev, fmt.Errorf("http status %d", resp.StatusCode)
 	}
-	return FromReader(resp.Body, dict)
+	next := Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	evs, err := FromReaderContext(ctx, resp.Body, dict, opts...)
+	return evs, next, err
 }
 
 func JSON(evs []Event) []byte {
+	if evs == nil {
+		evs = []Event{} // the export contract is always an array
+	}
 	b, _ := json.MarshalIndent(evs, "", "  ")
 	return b
 }
@@ -164,6 +323,19 @@ func collectEvents(cal *ics.Calendar) []Event {
 		if t, err := ve.GetEndAt(); err == nil {
 			ev.End = &t
 		}
+		if t, err := ve.GetLastModifiedAt(); err == nil {
+			ev.Modified = &t
+		}
+		for _, p := range ve.Properties {
+			if p.IANAToken != string(ics.ComponentPropertyCategories) {
+				continue
+			}
+			for _, c := range strings.Split(p.Value, ",") {
+				if c = strings.TrimSpace(c); c != "" {
+					ev.Categories = append(ev.Categories, c)
+				}
+			}
+		}
 		out = append(out, ev)
 	}
 	return out
@@ -215,12 +387,56 @@ var (
 	}
 )
 
+// SetCuePattern replaces the regular expression that recognises cue lines
+// such as "Cast: A, B". It must match a whole line and capture the role
+// ("cast") then the names; roles containing "host" or "musical" are still
+// skipped. It is meant for start-up, before any inference runs.
+func SetCuePattern(expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	if re.NumSubexp() != 2 {
+		return fmt.Errorf("cue pattern must have 2 capture groups (role, names), has %d", re.NumSubexp())
+	}
+	cueLine = re
+	return nil
+}
+
 // InferPlayerNames extracts plausible player names from DESCRIPTION.
 // dict is optional but boosts precision.
 func InferPlayerNames(desc string, dict *NameDict) []string {
+	cued, loose := inferCandidates(desc, dict)
+	return normalizeAndDedup(append(cued, loose...))
+}
+
+// InferPlayerNamesWithRoster is InferPlayerNames for an event whose teams'
+// rosters are known. Roster members mentioned anywhere in desc are found
+// even without a cue line, and names from cue lines ("Cast: ...") are kept
+// as before, since guests aren't on any roster. Names found only by
+// title-case guessing must be on the roster, which drops most of the noise
+// that guessing picks up; a lone first name matching exactly one member
+// becomes that member's full name. With an empty roster it is
+// InferPlayerNames.
+func InferPlayerNamesWithRoster(desc string, dict *NameDict, roster []string) []string {
+	if len(roster) == 0 {
+		return InferPlayerNames(desc, dict)
+	}
+	cued, loose := inferCandidates(desc, dict)
+	out := append(rosterMentions(desc, roster), cued...)
+	for _, c := range loose {
+		if m := rosterMember(c, roster); m != "" {
+			out = append(out, m)
+		}
+	}
+	return normalizeAndDedup(out)
+}
+
+// inferCandidates returns the raw names from cue lines and, only when there
+// are none, the looser title-case guesses.
+func inferCandidates(desc string, dict *NameDict) (cued, loose []string) {
 	desc = strings.ReplaceAll(desc, "\r\n", "\n")
 	lines := strings.Split(desc, "\n")
-	var candidates []string
 
 	// 1) Cue lines
 	for _, ln := range lines {
@@ -238,34 +454,79 @@ func InferPlayerNames(desc string, dict *NameDict) []string {
 					if strings.Contains(role, "host") || strings.Contains(role, "musical") {
 						continue
 					}
-					candidates = append(candidates, n)
+					cued = append(cued, n)
 				}
 			}
 		}
 	}
+	if len(cued) > 0 {
+		return cued, nil
+	}
 
 	// 2) Title-Case chunking if nothing direct
-	if len(candidates) == 0 {
-		for _, chunk := range titleCaseChunks(desc) {
-			if isStopPhrase(chunk) {
-				continue
-			}
-			if acceptByDict(chunk, dict) {
-				candidates = append(candidates, chunk)
-			}
+	for _, chunk := range titleCaseChunks(desc) {
+		if isStopPhrase(chunk) {
+			continue
+		}
+		if acceptByDict(chunk, dict) {
+			loose = append(loose, chunk)
 		}
 	}
 
 	// 3) If still empty, allow single tokens from dict.First
-	if len(candidates) == 0 && dict != nil && len(dict.First) > 0 {
+	if len(loose) == 0 && dict != nil && len(dict.First) > 0 {
 		for _, tok := range singleTitleTokens(desc) {
 			if _, ok := dict.First[strings.ToLower(tok)]; ok && !isStopSingle(tok) {
-				candidates = append(candidates, tok)
+				loose = append(loose, tok)
 			}
 		}
 	}
+	return nil, loose
+}
 
-	return normalizeAndDedup(candidates)
+// rosterMentions returns the roster members whose full name appears in desc
+// as whole words, ignoring case.
+func rosterMentions(desc string, roster []string) []string {
+	text := " " + strings.Join(strings.FieldsFunc(strings.ToLower(desc), notNameRune), " ") + " "
+	var out []string
+	for _, name := range roster {
+		words := strings.FieldsFunc(strings.ToLower(name), notNameRune)
+		if len(words) > 0 && strings.Contains(text, " "+strings.Join(words, " ")+" ") {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// rosterMember returns the roster spelling of name: the member with that
+// full name, or the only member with that first name if name is one word.
+func rosterMember(name string, roster []string) string {
+	var byFirst []string
+	for _, m := range roster {
+		if strings.EqualFold(m, name) {
+			return m
+		}
+		if first, _, _ := strings.Cut(m, " "); strings.EqualFold(first, name) {
+			byFirst = append(byFirst, m)
+		}
+	}
+	if len(byFirst) == 1 {
+		return byFirst[0]
+	}
+	return ""
+}
+
+func notNameRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '’'
+}
+
+// LooksLikeName reports whether s reads as a person's name by the same
+// rules inference uses: one to three title-case words that aren't a stock
+// phrase such as "Improv Show". Single words are allowed, so callers with
+// less context should also require a space.
+func LooksLikeName(s string) bool {
+	s = strings.Join(strings.Fields(s), " ")
+	return s != "" && cleanName(s) == s && !isStopPhrase(s) && !isStopSingle(s)
 }
 
 func containsStopContext(line string) bool {
@@ -442,7 +703,12 @@ func normalizeAndDedup(in []string) []string {
 	return out
 }
 
-func SummarizeEvents(events []Event) {
+// SummarizeEvents prints events for a person to read. formatStart renders
+// start times; nil means RFC 3339.
+func SummarizeEvents(events []Event, formatStart func(time.Time) string) {
+	if formatStart == nil {
+		formatStart = func(t time.Time) string { return t.Format(time.RFC3339) }
+	}
 	// Text output
 	if len(events) == 0 {
 		fmt.Println("No VEVENTs found.")
@@ -453,7 +719,7 @@ func SummarizeEvents(events []Event) {
 		fmt.Printf("Summary:     %s\n", ev.Summary)
 		fmt.Printf("URL:        %s\n", ev.URL)
 		if ev.Start != nil {
-			fmt.Printf("Start:       %s\n", ev.Start.Format(time.RFC3339))
+			fmt.Printf("Start:       %s\n", formatStart(*ev.Start))
 		}
 		// fmt.Printf("Players:   %v\n", ev.Players)
 		fmt.Printf("Description:\n%s\n", coalesce(ev.Description, "(none)"))
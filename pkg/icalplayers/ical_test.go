@@ -0,0 +1,41 @@
+package icalplayers
+
+import "testing"
+
+var benchDescriptions = []struct{ name, desc string }{
+	{"cued", "Two teams, one stage, no script.\n\nCast: Maya Ortiz, Devon Clarke, Priya Raman and Sam O'Neil\nHosted by: Jordan Lee\n\nTickets at the door."},
+	{"loose", "Join Maya Ortiz and Devon Clarke for a night of long-form improv. Priya Raman directs, with music by Sam O'Neil. " +
+		"Doors open at 7:30 PM. All ages welcome. The Improv Shop is at 1900 Main Street."},
+	{"long", func() string {
+		s := ""
+		for range 40 {
+			s += "An evening of improvised scenes inspired by Audience Suggestions. Featuring Maya Ortiz and Friends.\n"
+		}
+		return s
+	}()},
+}
+
+func BenchmarkInferPlayerNames(b *testing.B) {
+	dict := NewNameDict("Maya Ortiz", "Devon Clarke", "Priya Raman", "Sam O'Neil", "Jordan Lee")
+	for _, bd := range benchDescriptions {
+		b.Run(bd.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				InferPlayerNames(bd.desc, dict)
+			}
+		})
+	}
+}
+
+func BenchmarkInferPlayerNamesWithRoster(b *testing.B) {
+	dict := NewNameDict("Maya Ortiz", "Devon Clarke")
+	roster := []string{"Maya Ortiz", "Devon Clarke", "Priya Raman", "Sam O'Neil", "Alex Kim", "Robin Fox"}
+	for _, bd := range benchDescriptions {
+		b.Run(bd.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				InferPlayerNamesWithRoster(bd.desc, dict, roster)
+			}
+		})
+	}
+}
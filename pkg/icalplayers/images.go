@@ -0,0 +1,147 @@
+package icalplayers
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"github.com/tsny/shopsync/pkg/wpimg"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Defaults for EnrichImages: how many event pages are fetched in parallel,
+// and how many per second across all workers.
+const (
+	DefaultImageWorkers         = 4
+	DefaultImageRate    float64 = 2
+)
+
+// ImageOption tunes one EnrichImages call.
+type ImageOption func(*imageConfig)
+
+type imageConfig struct {
+	workers  int
+	rate     float64
+	progress func(done, total int)
+}
+
+// WithImageWorkers fetches up to n event pages at once.
+func WithImageWorkers(n int) ImageOption {
+	return func(c *imageConfig) { c.workers = n }
+}
+
+// WithImageRate caps event page fetches per second across all workers.
+// Zero means unlimited.
+func WithImageRate(perSecond float64) ImageOption {
+	return func(c *imageConfig) { c.rate = perSecond }
+}
+
+// WithImageProgress calls fn as event pages finish.
+func WithImageProgress(fn func(done, total int)) ImageOption {
+	return func(c *imageConfig) { c.progress = fn }
+}
+
+// ImageFetchObserver, if set, is called after every event page fetch with
+// how long it took and its error, if any.
+var ImageFetchObserver func(d time.Duration, err error)
+
+// ImageFetchFailed, if set, is called with the event whose page fetch failed.
+var ImageFetchFailed func(e Event, err error)
+
+// EnrichImages looks up the WordPress post image and page details (see
+// wpimg.DetailSelectors) for every event that has a URL, using
+// DefaultImageWorkers workers throttled to DefaultImageRate unless opts
+// say otherwise. Failures are logged and leave PostImageURL untouched;
+// details found on a page without an image are still kept.
+func EnrichImages(ctx context.Context, evs []Event, opts ...ImageOption) {
+	ctx, span := tracer.Start(ctx, "icalplayers.enrich_images")
+	defer span.End()
+	cfg := imageConfig{workers: DefaultImageWorkers, rate: DefaultImageRate}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	workers := max(cfg.workers, 1)
+	lim := httpclient.NewLimiter(cfg.rate)
+
+	total := 0
+	for i := range evs {
+		if evs[i].URL != "" {
+			total++
+		}
+	}
+	span.SetAttributes(attribute.Int("pages", total), attribute.Int("workers", workers))
+	var doneMu sync.Mutex
+	done := 0
+	report := func() {
+		if cfg.progress == nil {
+			return
+		}
+		doneMu.Lock()
+		done++
+		n := done
+		doneMu.Unlock()
+		cfg.progress(n, total)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := lim.Wait(ctx); err != nil {
+					return
+				}
+				start := time.Now()
+				postResult, err := wpimg.Fetch(ctx, evs[i].URL)
+				if ImageFetchObserver != nil {
+					ImageFetchObserver(time.Since(start), err)
+				}
+				report()
+				if d := DetailsFrom(postResult.Details); d != nil {
+					evs[i].Details = FillDetails(evs[i].Details, d)
+				}
+				if err != nil {
+					slog.Debug("post image lookup failed", "uid", evs[i].UID, "url", evs[i].URL, "err", err)
+					if ImageFetchFailed != nil {
+						ImageFetchFailed(evs[i], err)
+					}
+					continue
+				}
+				if postResult.ImageURL != "" {
+					evs[i].PostImageURL = postResult.ImageURL
+					slog.Debug("fetched post image", "uid", evs[i].UID, "image", postResult.ImageURL)
+				}
+			}
+		}()
+	}
+	for i := range evs {
+		if evs[i].URL == "" {
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// FillDetails returns have with its empty fields taken from found, so what
+// the source's API said wins over what was scraped.
+func FillDetails(have, found *Details) *Details {
+	if have == nil {
+		return found
+	}
+	return &Details{
+		Price:  cmp.Or(have.Price, found.Price),
+		Doors:  cmp.Or(have.Doors, found.Doors),
+		Ages:   cmp.Or(have.Ages, found.Ages),
+		Lineup: cmp.Or(have.Lineup, found.Lineup),
+	}
+}
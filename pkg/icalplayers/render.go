@@ -0,0 +1,118 @@
+package icalplayers
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// DefaultEventDuration is used for DTEND when an event has no end time,
+// which is the case for everything read back from the database.
+var DefaultEventDuration = time.Hour
+
+// WriteICS renders evs as a VCALENDAR named name. Teams become CATEGORIES
+// and the post image becomes an ATTACH. Events without a start are skipped
+// since DTSTART is required. Each of alarms adds a VALARM reminding
+// subscribers that long before every event.
+func WriteICS(w io.Writer, evs []Event, name string, alarms ...time.Duration) error {
+	cal := ics.NewCalendarFor("shopsync")
+	cal.SetMethod(ics.MethodPublish)
+	cal.SetName(name)
+	cal.SetXWRCalName(name)
+	cal.SetRefreshInterval("PT1H")
+	cal.SetXPublishedTTL("PT1H")
+
+	now := time.Now().UTC()
+	for _, e := range evs {
+		if e.Start == nil {
+			continue
+		}
+		ve := cal.AddEvent(e.UID)
+		ve.SetDtStampTime(now)
+		ve.SetStartAt(*e.Start)
+		if e.End != nil {
+			ve.SetEndAt(*e.End)
+		} else {
+			ve.SetEndAt(e.Start.Add(DefaultEventDuration))
+		}
+		ve.SetSummary(e.Summary)
+		if e.Description != "" {
+			ve.SetDescription(e.Description)
+		}
+		if e.Location != "" {
+			ve.SetLocation(e.Location)
+		}
+		if e.URL != "" {
+			ve.SetURL(e.URL)
+		}
+		if e.Version > 0 {
+			ve.SetSequence(int(e.Version))
+		}
+		for _, t := range e.Teams {
+			// CATEGORIES is a list, so the library leaves commas alone.
+			ve.AddCategory(strings.ReplaceAll(t, ",", `\,`))
+		}
+		if e.PostImageURL != "" {
+			ve.AddAttachmentURL(e.PostImageURL, ImageContentType(e.PostImageURL))
+		}
+		for _, d := range alarms {
+			a := ve.AddAlarm()
+			a.SetAction(ics.ActionDisplay)
+			a.SetTrigger("-" + ICSDuration(d))
+			// DISPLAY alarms need a DESCRIPTION; clients show it as the
+			// reminder text.
+			a.SetProperty(ics.ComponentPropertyDescription, e.Summary)
+		}
+	}
+	return cal.SerializeTo(w)
+}
+
+// ICSDuration formats d as an RFC 5545 duration, e.g. PT2H, PT1H30M or
+// P1D, to the second.
+func ICSDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + ICSDuration(-d)
+	}
+	secs := int64(d / time.Second)
+	if secs == 0 {
+		return "PT0S"
+	}
+	out := "P"
+	if days := secs / 86400; days > 0 {
+		out += fmt.Sprintf("%dD", days)
+		secs %= 86400
+	}
+	if secs == 0 {
+		return out
+	}
+	out += "T"
+	for _, u := range []struct {
+		n    int64
+		unit string
+	}{{3600, "H"}, {60, "M"}, {1, "S"}} {
+		if v := secs / u.n; v > 0 {
+			out += fmt.Sprintf("%d%s", v, u.unit)
+			secs %= u.n
+		}
+	}
+	return out
+}
+
+// ImageContentType guesses a MIME type from the URL's extension, falling
+// back to image/jpeg, which is what the venue posts almost always are.
+func ImageContentType(raw string) string {
+	p := raw
+	if u, err := url.Parse(raw); err == nil {
+		p = u.Path
+	}
+	if ct := mime.TypeByExtension(path.Ext(p)); ct != "" {
+		return ct
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,194 @@
+package icalplayers
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EventSchemaVersion is the version of the JSON contract for exported
+// events. Bump it, and the $id in event.schema.json, whenever a field is
+// removed, renamed or changes meaning; adding an optional field doesn't need
+// a bump but does need the schema updated, since it forbids unknown fields.
+const EventSchemaVersion = 1
+
+//go:embed event.schema.json
+var eventSchema []byte
+
+// EventSchema returns the JSON Schema (draft 2020-12) describing the output
+// of JSON: an array of events.
+func EventSchema() []byte {
+	return bytes.Clone(eventSchema)
+}
+
+// SchemaError lists every place a document departs from the event schema.
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	const show = 5
+	msg := fmt.Sprintf("%d schema violations: %s", len(e.Problems), strings.Join(e.Problems[:min(show, len(e.Problems))], "; "))
+	if len(e.Problems) > show {
+		msg += "; ..."
+	}
+	return msg
+}
+
+// ValidateJSON checks doc against the event schema. It returns a
+// *SchemaError if the document parses but doesn't conform.
+//
+// Only the keywords event.schema.json uses are understood: $ref (local),
+// type, required, properties, additionalProperties, items, minLength,
+// minimum and format date-time.
+func ValidateJSON(doc []byte) error {
+	var root map[string]any
+	if err := json.Unmarshal(eventSchema, &root); err != nil {
+		return fmt.Errorf("embedded event schema: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("decode document: %w", err)
+	}
+	sv := schemaValidator{root: root}
+	sv.check("", root, v)
+	if len(sv.problems) > 0 {
+		return &SchemaError{Problems: sv.problems}
+	}
+	return nil
+}
+
+type schemaValidator struct {
+	root     map[string]any
+	problems []string
+}
+
+func (sv *schemaValidator) fail(path, format string, args ...any) {
+	if path == "" {
+		path = "/"
+	}
+	sv.problems = append(sv.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (sv *schemaValidator) check(path string, schema map[string]any, v any) {
+	if ref, ok := schema["$ref"].(string); ok {
+		target, err := sv.resolve(ref)
+		if err != nil {
+			sv.fail(path, "%v", err)
+			return
+		}
+		sv.check(path, target, v)
+	}
+	if t, ok := schema["type"].(string); ok && !hasType(v, t) {
+		sv.fail(path, "want %s, got %s", t, jsonType(v))
+		return
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		if req, ok := schema["required"].([]any); ok {
+			for _, r := range req {
+				if name, _ := r.(string); name != "" {
+					if _, present := v[name]; !present {
+						sv.fail(path, "missing required property %q", name)
+					}
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ps, known := props[k].(map[string]any)
+			if !known {
+				if ap, ok := schema["additionalProperties"].(bool); ok && !ap {
+					sv.fail(path, "unknown property %q", k)
+				}
+				continue
+			}
+			sv.check(path+"/"+k, ps, v[k])
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				sv.check(fmt.Sprintf("%s/%d", path, i), items, item)
+			}
+		}
+	case string:
+		if n, ok := schema["minLength"].(float64); ok && float64(len(v)) < n {
+			sv.fail(path, "shorter than %d", int(n))
+		}
+		if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, any(v)) {
+			sv.fail(path, "%q is not one of %v", v, enum)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				sv.fail(path, "not an RFC 3339 date-time: %q", v)
+			}
+		}
+	case json.Number:
+		if n, ok := schema["minimum"].(float64); ok {
+			if f, err := v.Float64(); err == nil && f < n {
+				sv.fail(path, "less than %v", n)
+			}
+		}
+	}
+}
+
+// resolve follows a "#/$defs/name" reference.
+func (sv *schemaValidator) resolve(ref string) (map[string]any, error) {
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+	defs, _ := sv.root["$defs"].(map[string]any)
+	target, ok := defs[name].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unknown $ref %q", ref)
+	}
+	return target, nil
+}
+
+func hasType(v any, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	}
+	return jsonType(v) == t
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,182 @@
+// Package notion is a small client for the Notion API, enough to query a
+// database and create or update its pages.
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// BaseURL is the Notion API root.
+var BaseURL = "https://api.notion.com/v1"
+
+// apiVersion is sent as Notion-Version; property shapes below follow it.
+const apiVersion = "2022-06-28"
+
+// ErrUnauthorized is returned when Notion rejects the integration token.
+var ErrUnauthorized = errors.New("notion rejected the integration token")
+
+// Client talks to Notion as one integration. The database must be shared
+// with the integration.
+type Client struct {
+	token string
+	http  *http.Client
+}
+
+func New(token string) *Client {
+	return &Client{token: token, http: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Page is a database row as returned by queries.
+type Page struct {
+	ID         string              `json:"id"`
+	Archived   bool                `json:"archived"`
+	Properties map[string]Property `json:"properties"`
+}
+
+// Property is the read shape of the property types shopsync uses.
+type Property struct {
+	Type        string     `json:"type"`
+	Title       []RichText `json:"title"`
+	RichText    []RichText `json:"rich_text"`
+	Date        *Date      `json:"date"`
+	MultiSelect []Option   `json:"multi_select"`
+	Files       []File     `json:"files"`
+	URL         *string    `json:"url"`
+}
+
+// Text joins a title or rich_text property's plain text.
+func (p Property) Text() string {
+	var b bytes.Buffer
+	for _, rt := range append(p.Title, p.RichText...) {
+		b.WriteString(rt.PlainText)
+	}
+	return b.String()
+}
+
+type RichText struct {
+	PlainText string `json:"plain_text"`
+}
+
+type Date struct {
+	Start string  `json:"start"`
+	End   *string `json:"end"`
+}
+
+type Option struct {
+	Name string `json:"name"`
+}
+
+type File struct {
+	Name     string `json:"name"`
+	External *struct {
+		URL string `json:"url"`
+	} `json:"external"`
+	File *struct {
+		URL string `json:"url"`
+	} `json:"file"`
+}
+
+// QueryDatabase returns every page of databaseID matching filter (nil for
+// all), following cursors.
+func (c *Client) QueryDatabase(ctx context.Context, databaseID string, filter any) ([]Page, error) {
+	var out []Page
+	cursor := ""
+	for {
+		body := map[string]any{"page_size": 100}
+		if filter != nil {
+			body["filter"] = filter
+		}
+		if cursor != "" {
+			body["start_cursor"] = cursor
+		}
+		var res struct {
+			Results    []Page `json:"results"`
+			HasMore    bool   `json:"has_more"`
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := c.do(ctx, http.MethodPost, "/databases/"+databaseID+"/query", body, &res); err != nil {
+			return nil, err
+		}
+		out = append(out, res.Results...)
+		if !res.HasMore || res.NextCursor == "" {
+			return out, nil
+		}
+		cursor = res.NextCursor
+	}
+}
+
+// CreatePage adds a row to databaseID. props uses Notion's write format,
+// e.g. {"Name": {"title": [{"text": {"content": "..."}}]}}.
+func (c *Client) CreatePage(ctx context.Context, databaseID string, props map[string]any) (string, error) {
+	body := map[string]any{
+		"parent":     map[string]string{"database_id": databaseID},
+		"properties": props,
+	}
+	var res Page
+	err := c.do(ctx, http.MethodPost, "/pages", body, &res)
+	return res.ID, err
+}
+
+// UpdatePage overwrites the given properties of pageID, leaving the rest.
+func (c *Client) UpdatePage(ctx context.Context, pageID string, props map[string]any) error {
+	return c.do(ctx, http.MethodPatch, "/pages/"+pageID, map[string]any{"properties": props}, nil)
+}
+
+// do sends one request, waiting out 429s as Notion's Retry-After asks (up to
+// three times).
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, BaseURL+path, bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Notion-Version", apiVersion)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < 3 {
+			resp.Body.Close()
+			wait, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			select {
+			case <-time.After(time.Duration(max(wait, 1)) * time.Second):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		defer resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized:
+			return ErrUnauthorized
+		case resp.StatusCode < 200 || resp.StatusCode >= 300:
+			var apiErr struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			}
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+			if json.Unmarshal(msg, &apiErr) == nil && apiErr.Message != "" {
+				return fmt.Errorf("notion %s %s: %s: %s", method, path, apiErr.Code, apiErr.Message)
+			}
+			return fmt.Errorf("notion %s %s: http status %d", method, path, resp.StatusCode)
+		}
+		if out == nil {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+}
@@ -0,0 +1,147 @@
+// Package roster scrapes a team's page on the venue site for the names and
+// headshots of its performers.
+package roster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// ErrNotFound is returned when the team page doesn't exist.
+var ErrNotFound = errors.New("team page not found")
+
+// Member is one performer listed on a team page.
+type Member struct {
+	Name        string
+	HeadshotURL string // absolute; empty if the page shows none
+	ProfileURL  string // absolute link from the headshot or name; may be empty
+}
+
+// Fetch returns the members listed on pageURL.
+func Fetch(ctx context.Context, pageURL string) ([]Member, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpclient.Default.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get page: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("get page: unexpected status %s", resp.Status)
+	}
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parse HTML: %w", err)
+	}
+	return Parse(doc, resp.Request.URL), nil
+}
+
+// Parse finds members in a team page. Team pages are hand-built WordPress
+// posts, so it looks for the common shapes: a headshot <img> whose caption,
+// alt text or neighbouring heading is a person's name. Images in the site
+// header, footer and navigation are ignored.
+func Parse(doc *goquery.Document, base *url.URL) []Member {
+	var out []Member
+	seen := map[string]bool{}
+	doc.Find("img").Each(func(_ int, img *goquery.Selection) {
+		if img.Closest("header, footer, nav, .site-header, .site-footer").Length() > 0 {
+			return
+		}
+		if class, _ := img.Attr("class"); strings.Contains(class, "logo") || strings.Contains(class, "wp-post-image") {
+			return
+		}
+		name := memberName(img)
+		if name == "" || seen[strings.ToLower(name)] {
+			return
+		}
+		seen[strings.ToLower(name)] = true
+		m := Member{Name: name, HeadshotURL: resolve(base, imgSrc(img))}
+		if href, ok := img.Closest("a").Attr("href"); ok {
+			m.ProfileURL = resolve(base, href)
+		}
+		out = append(out, m)
+	})
+	return out
+}
+
+// memberName picks the first candidate around img that looks like a name:
+// the figure caption, then the nearest heading or bold text in the same
+// block, then the alt text.
+func memberName(img *goquery.Selection) string {
+	var candidates []string
+	if fig := img.Closest("figure"); fig.Length() > 0 {
+		candidates = append(candidates, firstLine(fig.Find("figcaption").First().Text()))
+	}
+	block := img.Parent()
+	if block.Is("a") {
+		block = block.Parent()
+	}
+	for range 2 {
+		// A block with several images is a grid of members, whose first
+		// heading belongs to someone else.
+		if block.Length() == 0 || block.Find("img").Length() > 1 {
+			break
+		}
+		candidates = append(candidates, firstLine(block.Find("h2, h3, h4, h5, h6, strong, b").First().Text()))
+		block = block.Parent()
+	}
+	alt, _ := img.Attr("alt")
+	candidates = append(candidates, firstLine(alt))
+	for _, c := range candidates {
+		if strings.Contains(c, " ") && icalplayers.LooksLikeName(c) {
+			return c
+		}
+	}
+	return ""
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, "\n|–—"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// imgSrc prefers src, then the first srcset entry, then lazy-load
+// attributes.
+func imgSrc(img *goquery.Selection) string {
+	for _, attr := range []string{"src", "srcset", "data-src", "data-lazy-src"} {
+		v, _ := img.Attr(attr)
+		if attr == "srcset" {
+			v, _, _ = strings.Cut(strings.TrimSpace(v), " ")
+		}
+		if v = strings.TrimSpace(v); v != "" && !strings.HasPrefix(v, "data:") {
+			return v
+		}
+	}
+	return ""
+}
+
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
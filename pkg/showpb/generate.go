@@ -0,0 +1,6 @@
+// Package showpb is the generated gRPC API served by 'shopsync serve
+// -grpc-addr'. Edit shows.proto and run go generate; don't edit the .pb.go
+// files.
+package showpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative shows.proto
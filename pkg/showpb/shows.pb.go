@@ -0,0 +1,764 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.29.3
+// source: shows.proto
+
+package showpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ShowChange_Kind int32
+
+const (
+	ShowChange_KIND_UNSPECIFIED ShowChange_Kind = 0
+	ShowChange_ADDED            ShowChange_Kind = 1
+	ShowChange_UPDATED          ShowChange_Kind = 2
+	ShowChange_REMOVED          ShowChange_Kind = 3
+)
+
+// Enum value maps for ShowChange_Kind.
+var (
+	ShowChange_Kind_name = map[int32]string{
+		0: "KIND_UNSPECIFIED",
+		1: "ADDED",
+		2: "UPDATED",
+		3: "REMOVED",
+	}
+	ShowChange_Kind_value = map[string]int32{
+		"KIND_UNSPECIFIED": 0,
+		"ADDED":            1,
+		"UPDATED":          2,
+		"REMOVED":          3,
+	}
+)
+
+func (x ShowChange_Kind) Enum() *ShowChange_Kind {
+	p := new(ShowChange_Kind)
+	*p = x
+	return p
+}
+
+func (x ShowChange_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ShowChange_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_shows_proto_enumTypes[0].Descriptor()
+}
+
+func (ShowChange_Kind) Type() protoreflect.EnumType {
+	return &file_shows_proto_enumTypes[0]
+}
+
+func (x ShowChange_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ShowChange_Kind.Descriptor instead.
+func (ShowChange_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_shows_proto_rawDescGZIP(), []int{8, 0}
+}
+
+type Show struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Uid          string                 `protobuf:"bytes,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	Summary      string                 `protobuf:"bytes,2,opt,name=summary,proto3" json:"summary,omitempty"`
+	Description  string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Url          string                 `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
+	PostImageUrl string                 `protobuf:"bytes,5,opt,name=post_image_url,json=postImageUrl,proto3" json:"post_image_url,omitempty"`
+	Start        *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=start,proto3" json:"start,omitempty"`
+	Players      []string               `protobuf:"bytes,7,rep,name=players,proto3" json:"players,omitempty"`
+	Teams        []string               `protobuf:"bytes,8,rep,name=teams,proto3" json:"teams,omitempty"`
+	TeamIds      []string               `protobuf:"bytes,9,rep,name=team_ids,json=teamIds,proto3" json:"team_ids,omitempty"`
+	// version is bumped on every change to the stored row.
+	Version       int64                  `protobuf:"varint,10,opt,name=version,proto3" json:"version,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Show) Reset() {
+	*x = Show{}
+	mi := &file_shows_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Show) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Show) ProtoMessage() {}
+
+func (x *Show) ProtoReflect() protoreflect.Message {
+	mi := &file_shows_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Show.ProtoReflect.Descriptor instead.
+func (*Show) Descriptor() ([]byte, []int) {
+	return file_shows_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Show) GetUid() string {
+	if x != nil {
+		return x.Uid
+	}
+	return ""
+}
+
+func (x *Show) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+func (x *Show) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Show) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Show) GetPostImageUrl() string {
+	if x != nil {
+		return x.PostImageUrl
+	}
+	return ""
+}
+
+func (x *Show) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *Show) GetPlayers() []string {
+	if x != nil {
+		return x.Players
+	}
+	return nil
+}
+
+func (x *Show) GetTeams() []string {
+	if x != nil {
+		return x.Teams
+	}
+	return nil
+}
+
+func (x *Show) GetTeamIds() []string {
+	if x != nil {
+		return x.TeamIds
+	}
+	return nil
+}
+
+func (x *Show) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Show) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type Team struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Aliases       []string               `protobuf:"bytes,3,rep,name=aliases,proto3" json:"aliases,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Team) Reset() {
+	*x = Team{}
+	mi := &file_shows_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Team) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Team) ProtoMessage() {}
+
+func (x *Team) ProtoReflect() protoreflect.Message {
+	mi := &file_shows_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Team.ProtoReflect.Descriptor instead.
+func (*Team) Descriptor() ([]byte, []int) {
+	return file_shows_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Team) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Team) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Team) GetAliases() []string {
+	if x != nil {
+		return x.Aliases
+	}
+	return nil
+}
+
+type ListShowsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// from and to bound the start time; to is exclusive. Either may be unset.
+	From   *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	TeamId string                 `protobuf:"bytes,3,opt,name=team_id,json=teamId,proto3" json:"team_id,omitempty"`
+	// query is a case-insensitive substring of the summary or description.
+	Query string `protobuf:"bytes,4,opt,name=query,proto3" json:"query,omitempty"`
+	// limit defaults to 50 and may be at most 500.
+	Limit         int32 `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32 `protobuf:"varint,6,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListShowsRequest) Reset() {
+	*x = ListShowsRequest{}
+	mi := &file_shows_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListShowsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListShowsRequest) ProtoMessage() {}
+
+func (x *ListShowsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shows_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListShowsRequest.ProtoReflect.Descriptor instead.
+func (*ListShowsRequest) Descriptor() ([]byte, []int) {
+	return file_shows_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListShowsRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *ListShowsRequest) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *ListShowsRequest) GetTeamId() string {
+	if x != nil {
+		return x.TeamId
+	}
+	return ""
+}
+
+func (x *ListShowsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *ListShowsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListShowsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type ListShowsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Shows []*Show                `protobuf:"bytes,1,rep,name=shows,proto3" json:"shows,omitempty"`
+	// total counts every match, ignoring limit and offset.
+	Total         int32 `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListShowsResponse) Reset() {
+	*x = ListShowsResponse{}
+	mi := &file_shows_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListShowsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListShowsResponse) ProtoMessage() {}
+
+func (x *ListShowsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shows_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListShowsResponse.ProtoReflect.Descriptor instead.
+func (*ListShowsResponse) Descriptor() ([]byte, []int) {
+	return file_shows_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListShowsResponse) GetShows() []*Show {
+	if x != nil {
+		return x.Shows
+	}
+	return nil
+}
+
+func (x *ListShowsResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type GetShowRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uid           string                 `protobuf:"bytes,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetShowRequest) Reset() {
+	*x = GetShowRequest{}
+	mi := &file_shows_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetShowRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetShowRequest) ProtoMessage() {}
+
+func (x *GetShowRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shows_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetShowRequest.ProtoReflect.Descriptor instead.
+func (*GetShowRequest) Descriptor() ([]byte, []int) {
+	return file_shows_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetShowRequest) GetUid() string {
+	if x != nil {
+		return x.Uid
+	}
+	return ""
+}
+
+type ListTeamsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTeamsRequest) Reset() {
+	*x = ListTeamsRequest{}
+	mi := &file_shows_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTeamsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTeamsRequest) ProtoMessage() {}
+
+func (x *ListTeamsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shows_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTeamsRequest.ProtoReflect.Descriptor instead.
+func (*ListTeamsRequest) Descriptor() ([]byte, []int) {
+	return file_shows_proto_rawDescGZIP(), []int{5}
+}
+
+type ListTeamsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Teams         []*Team                `protobuf:"bytes,1,rep,name=teams,proto3" json:"teams,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTeamsResponse) Reset() {
+	*x = ListTeamsResponse{}
+	mi := &file_shows_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTeamsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTeamsResponse) ProtoMessage() {}
+
+func (x *ListTeamsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shows_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTeamsResponse.ProtoReflect.Descriptor instead.
+func (*ListTeamsResponse) Descriptor() ([]byte, []int) {
+	return file_shows_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListTeamsResponse) GetTeams() []*Team {
+	if x != nil {
+		return x.Teams
+	}
+	return nil
+}
+
+type WatchShowsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// from defaults to now; shows starting earlier aren't watched.
+	From          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	TeamId        string                 `protobuf:"bytes,2,opt,name=team_id,json=teamId,proto3" json:"team_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchShowsRequest) Reset() {
+	*x = WatchShowsRequest{}
+	mi := &file_shows_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchShowsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchShowsRequest) ProtoMessage() {}
+
+func (x *WatchShowsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shows_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchShowsRequest.ProtoReflect.Descriptor instead.
+func (*WatchShowsRequest) Descriptor() ([]byte, []int) {
+	return file_shows_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *WatchShowsRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *WatchShowsRequest) GetTeamId() string {
+	if x != nil {
+		return x.TeamId
+	}
+	return ""
+}
+
+type ShowChange struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Kind  ShowChange_Kind        `protobuf:"varint,1,opt,name=kind,proto3,enum=shopsync.v1.ShowChange_Kind" json:"kind,omitempty"`
+	// show is the new state; for REMOVED only uid is set.
+	Show          *Show `protobuf:"bytes,2,opt,name=show,proto3" json:"show,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShowChange) Reset() {
+	*x = ShowChange{}
+	mi := &file_shows_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShowChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShowChange) ProtoMessage() {}
+
+func (x *ShowChange) ProtoReflect() protoreflect.Message {
+	mi := &file_shows_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShowChange.ProtoReflect.Descriptor instead.
+func (*ShowChange) Descriptor() ([]byte, []int) {
+	return file_shows_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ShowChange) GetKind() ShowChange_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return ShowChange_KIND_UNSPECIFIED
+}
+
+func (x *ShowChange) GetShow() *Show {
+	if x != nil {
+		return x.Show
+	}
+	return nil
+}
+
+var File_shows_proto protoreflect.FileDescriptor
+
+const file_shows_proto_rawDesc = "" +
+	"\n" +
+	"\vshows.proto\x12\vshopsync.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xde\x02\n" +
+	"\x04Show\x12\x10\n" +
+	"\x03uid\x18\x01 \x01(\tR\x03uid\x12\x18\n" +
+	"\asummary\x18\x02 \x01(\tR\asummary\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x10\n" +
+	"\x03url\x18\x04 \x01(\tR\x03url\x12$\n" +
+	"\x0epost_image_url\x18\x05 \x01(\tR\fpostImageUrl\x120\n" +
+	"\x05start\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12\x18\n" +
+	"\aplayers\x18\a \x03(\tR\aplayers\x12\x14\n" +
+	"\x05teams\x18\b \x03(\tR\x05teams\x12\x19\n" +
+	"\bteam_ids\x18\t \x03(\tR\ateamIds\x12\x18\n" +
+	"\aversion\x18\n" +
+	" \x01(\x03R\aversion\x129\n" +
+	"\n" +
+	"updated_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"D\n" +
+	"\x04Team\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x18\n" +
+	"\aaliases\x18\x03 \x03(\tR\aaliases\"\xcb\x01\n" +
+	"\x10ListShowsRequest\x12.\n" +
+	"\x04from\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\x12\x17\n" +
+	"\ateam_id\x18\x03 \x01(\tR\x06teamId\x12\x14\n" +
+	"\x05query\x18\x04 \x01(\tR\x05query\x12\x14\n" +
+	"\x05limit\x18\x05 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x06 \x01(\x05R\x06offset\"R\n" +
+	"\x11ListShowsResponse\x12'\n" +
+	"\x05shows\x18\x01 \x03(\v2\x11.shopsync.v1.ShowR\x05shows\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\"\"\n" +
+	"\x0eGetShowRequest\x12\x10\n" +
+	"\x03uid\x18\x01 \x01(\tR\x03uid\"\x12\n" +
+	"\x10ListTeamsRequest\"<\n" +
+	"\x11ListTeamsResponse\x12'\n" +
+	"\x05teams\x18\x01 \x03(\v2\x11.shopsync.v1.TeamR\x05teams\"\\\n" +
+	"\x11WatchShowsRequest\x12.\n" +
+	"\x04from\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12\x17\n" +
+	"\ateam_id\x18\x02 \x01(\tR\x06teamId\"\xa8\x01\n" +
+	"\n" +
+	"ShowChange\x120\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x1c.shopsync.v1.ShowChange.KindR\x04kind\x12%\n" +
+	"\x04show\x18\x02 \x01(\v2\x11.shopsync.v1.ShowR\x04show\"A\n" +
+	"\x04Kind\x12\x14\n" +
+	"\x10KIND_UNSPECIFIED\x10\x00\x12\t\n" +
+	"\x05ADDED\x10\x01\x12\v\n" +
+	"\aUPDATED\x10\x02\x12\v\n" +
+	"\aREMOVED\x10\x032\xa3\x02\n" +
+	"\x05Shows\x12J\n" +
+	"\tListShows\x12\x1d.shopsync.v1.ListShowsRequest\x1a\x1e.shopsync.v1.ListShowsResponse\x129\n" +
+	"\aGetShow\x12\x1b.shopsync.v1.GetShowRequest\x1a\x11.shopsync.v1.Show\x12J\n" +
+	"\tListTeams\x12\x1d.shopsync.v1.ListTeamsRequest\x1a\x1e.shopsync.v1.ListTeamsResponse\x12G\n" +
+	"\n" +
+	"WatchShows\x12\x1e.shopsync.v1.WatchShowsRequest\x1a\x17.shopsync.v1.ShowChange0\x01B%Z#github.com/tsny/shopsync/pkg/showpbb\x06proto3"
+
+var (
+	file_shows_proto_rawDescOnce sync.Once
+	file_shows_proto_rawDescData []byte
+)
+
+func file_shows_proto_rawDescGZIP() []byte {
+	file_shows_proto_rawDescOnce.Do(func() {
+		file_shows_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_shows_proto_rawDesc), len(file_shows_proto_rawDesc)))
+	})
+	return file_shows_proto_rawDescData
+}
+
+var file_shows_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_shows_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_shows_proto_goTypes = []any{
+	(ShowChange_Kind)(0),          // 0: shopsync.v1.ShowChange.Kind
+	(*Show)(nil),                  // 1: shopsync.v1.Show
+	(*Team)(nil),                  // 2: shopsync.v1.Team
+	(*ListShowsRequest)(nil),      // 3: shopsync.v1.ListShowsRequest
+	(*ListShowsResponse)(nil),     // 4: shopsync.v1.ListShowsResponse
+	(*GetShowRequest)(nil),        // 5: shopsync.v1.GetShowRequest
+	(*ListTeamsRequest)(nil),      // 6: shopsync.v1.ListTeamsRequest
+	(*ListTeamsResponse)(nil),     // 7: shopsync.v1.ListTeamsResponse
+	(*WatchShowsRequest)(nil),     // 8: shopsync.v1.WatchShowsRequest
+	(*ShowChange)(nil),            // 9: shopsync.v1.ShowChange
+	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
+}
+var file_shows_proto_depIdxs = []int32{
+	10, // 0: shopsync.v1.Show.start:type_name -> google.protobuf.Timestamp
+	10, // 1: shopsync.v1.Show.updated_at:type_name -> google.protobuf.Timestamp
+	10, // 2: shopsync.v1.ListShowsRequest.from:type_name -> google.protobuf.Timestamp
+	10, // 3: shopsync.v1.ListShowsRequest.to:type_name -> google.protobuf.Timestamp
+	1,  // 4: shopsync.v1.ListShowsResponse.shows:type_name -> shopsync.v1.Show
+	2,  // 5: shopsync.v1.ListTeamsResponse.teams:type_name -> shopsync.v1.Team
+	10, // 6: shopsync.v1.WatchShowsRequest.from:type_name -> google.protobuf.Timestamp
+	0,  // 7: shopsync.v1.ShowChange.kind:type_name -> shopsync.v1.ShowChange.Kind
+	1,  // 8: shopsync.v1.ShowChange.show:type_name -> shopsync.v1.Show
+	3,  // 9: shopsync.v1.Shows.ListShows:input_type -> shopsync.v1.ListShowsRequest
+	5,  // 10: shopsync.v1.Shows.GetShow:input_type -> shopsync.v1.GetShowRequest
+	6,  // 11: shopsync.v1.Shows.ListTeams:input_type -> shopsync.v1.ListTeamsRequest
+	8,  // 12: shopsync.v1.Shows.WatchShows:input_type -> shopsync.v1.WatchShowsRequest
+	4,  // 13: shopsync.v1.Shows.ListShows:output_type -> shopsync.v1.ListShowsResponse
+	1,  // 14: shopsync.v1.Shows.GetShow:output_type -> shopsync.v1.Show
+	7,  // 15: shopsync.v1.Shows.ListTeams:output_type -> shopsync.v1.ListTeamsResponse
+	9,  // 16: shopsync.v1.Shows.WatchShows:output_type -> shopsync.v1.ShowChange
+	13, // [13:17] is the sub-list for method output_type
+	9,  // [9:13] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_shows_proto_init() }
+func file_shows_proto_init() {
+	if File_shows_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_shows_proto_rawDesc), len(file_shows_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_shows_proto_goTypes,
+		DependencyIndexes: file_shows_proto_depIdxs,
+		EnumInfos:         file_shows_proto_enumTypes,
+		MessageInfos:      file_shows_proto_msgTypes,
+	}.Build()
+	File_shows_proto = out.File
+	file_shows_proto_goTypes = nil
+	file_shows_proto_depIdxs = nil
+}
@@ -0,0 +1,255 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.29.3
+// source: shows.proto
+
+package showpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Shows_ListShows_FullMethodName  = "/shopsync.v1.Shows/ListShows"
+	Shows_GetShow_FullMethodName    = "/shopsync.v1.Shows/GetShow"
+	Shows_ListTeams_FullMethodName  = "/shopsync.v1.Shows/ListTeams"
+	Shows_WatchShows_FullMethodName = "/shopsync.v1.Shows/WatchShows"
+)
+
+// ShowsClient is the client API for Shows service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Shows is the read-only show API served alongside the JSON one by
+// 'shopsync serve -grpc-addr'.
+type ShowsClient interface {
+	// ListShows returns a page of shows ordered by start.
+	ListShows(ctx context.Context, in *ListShowsRequest, opts ...grpc.CallOption) (*ListShowsResponse, error)
+	// GetShow returns one show by UID, or NOT_FOUND.
+	GetShow(ctx context.Context, in *GetShowRequest, opts ...grpc.CallOption) (*Show, error)
+	// ListTeams returns every team, ordered by name.
+	ListTeams(ctx context.Context, in *ListTeamsRequest, opts ...grpc.CallOption) (*ListTeamsResponse, error)
+	// WatchShows sends every matching show as ADDED, then a change each time
+	// one is added, updated or removed, until the client hangs up.
+	WatchShows(ctx context.Context, in *WatchShowsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ShowChange], error)
+}
+
+type showsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewShowsClient(cc grpc.ClientConnInterface) ShowsClient {
+	return &showsClient{cc}
+}
+
+func (c *showsClient) ListShows(ctx context.Context, in *ListShowsRequest, opts ...grpc.CallOption) (*ListShowsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListShowsResponse)
+	err := c.cc.Invoke(ctx, Shows_ListShows_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *showsClient) GetShow(ctx context.Context, in *GetShowRequest, opts ...grpc.CallOption) (*Show, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Show)
+	err := c.cc.Invoke(ctx, Shows_GetShow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *showsClient) ListTeams(ctx context.Context, in *ListTeamsRequest, opts ...grpc.CallOption) (*ListTeamsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTeamsResponse)
+	err := c.cc.Invoke(ctx, Shows_ListTeams_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *showsClient) WatchShows(ctx context.Context, in *WatchShowsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ShowChange], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Shows_ServiceDesc.Streams[0], Shows_WatchShows_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchShowsRequest, ShowChange]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Shows_WatchShowsClient = grpc.ServerStreamingClient[ShowChange]
+
+// ShowsServer is the server API for Shows service.
+// All implementations must embed UnimplementedShowsServer
+// for forward compatibility.
+//
+// Shows is the read-only show API served alongside the JSON one by
+// 'shopsync serve -grpc-addr'.
+type ShowsServer interface {
+	// ListShows returns a page of shows ordered by start.
+	ListShows(context.Context, *ListShowsRequest) (*ListShowsResponse, error)
+	// GetShow returns one show by UID, or NOT_FOUND.
+	GetShow(context.Context, *GetShowRequest) (*Show, error)
+	// ListTeams returns every team, ordered by name.
+	ListTeams(context.Context, *ListTeamsRequest) (*ListTeamsResponse, error)
+	// WatchShows sends every matching show as ADDED, then a change each time
+	// one is added, updated or removed, until the client hangs up.
+	WatchShows(*WatchShowsRequest, grpc.ServerStreamingServer[ShowChange]) error
+	mustEmbedUnimplementedShowsServer()
+}
+
+// UnimplementedShowsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedShowsServer struct{}
+
+func (UnimplementedShowsServer) ListShows(context.Context, *ListShowsRequest) (*ListShowsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListShows not implemented")
+}
+func (UnimplementedShowsServer) GetShow(context.Context, *GetShowRequest) (*Show, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetShow not implemented")
+}
+func (UnimplementedShowsServer) ListTeams(context.Context, *ListTeamsRequest) (*ListTeamsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListTeams not implemented")
+}
+func (UnimplementedShowsServer) WatchShows(*WatchShowsRequest, grpc.ServerStreamingServer[ShowChange]) error {
+	return status.Error(codes.Unimplemented, "method WatchShows not implemented")
+}
+func (UnimplementedShowsServer) mustEmbedUnimplementedShowsServer() {}
+func (UnimplementedShowsServer) testEmbeddedByValue()               {}
+
+// UnsafeShowsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ShowsServer will
+// result in compilation errors.
+type UnsafeShowsServer interface {
+	mustEmbedUnimplementedShowsServer()
+}
+
+func RegisterShowsServer(s grpc.ServiceRegistrar, srv ShowsServer) {
+	// If the following call panics, it indicates UnimplementedShowsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Shows_ServiceDesc, srv)
+}
+
+func _Shows_ListShows_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListShowsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShowsServer).ListShows(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shows_ListShows_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShowsServer).ListShows(ctx, req.(*ListShowsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shows_GetShow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetShowRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShowsServer).GetShow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shows_GetShow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShowsServer).GetShow(ctx, req.(*GetShowRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shows_ListTeams_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTeamsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShowsServer).ListTeams(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shows_ListTeams_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShowsServer).ListTeams(ctx, req.(*ListTeamsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shows_WatchShows_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchShowsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShowsServer).WatchShows(m, &grpc.GenericServerStream[WatchShowsRequest, ShowChange]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Shows_WatchShowsServer = grpc.ServerStreamingServer[ShowChange]
+
+// Shows_ServiceDesc is the grpc.ServiceDesc for Shows service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Shows_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "shopsync.v1.Shows",
+	HandlerType: (*ShowsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListShows",
+			Handler:    _Shows_ListShows_Handler,
+		},
+		{
+			MethodName: "GetShow",
+			Handler:    _Shows_GetShow_Handler,
+		},
+		{
+			MethodName: "ListTeams",
+			Handler:    _Shows_ListTeams_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchShows",
+			Handler:       _Shows_WatchShows_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "shows.proto",
+}
@@ -0,0 +1,118 @@
+package showstore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// API key scopes. Admin keys can do everything read keys can.
+const (
+	ScopeRead  = "read"
+	ScopeAdmin = "admin"
+)
+
+// apiKeyPrefix starts every API key, so a leaked one is recognisable.
+const apiKeyPrefix = "ssk_"
+
+// APIKey is one key for serve's JSON API and /admin. As with calendar
+// tokens, only a hash of the key itself is stored.
+type APIKey struct {
+	Name      string
+	Scope     string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// Allows reports whether the key's scope covers scope.
+func (k APIKey) Allows(scope string) bool {
+	return k.Scope == ScopeAdmin || k.Scope == scope
+}
+
+// CreateAPIKey issues a key for name with scope and returns it. This is
+// the only time the key is available in clear.
+func (s *Store) CreateAPIKey(ctx context.Context, name, scope string) (string, error) {
+	if err := s.checkWritable(); err != nil {
+		return "", err
+	}
+	if scope != ScopeRead && scope != ScopeAdmin {
+		return "", fmt.Errorf("unknown scope %q (want %s or %s)", scope, ScopeRead, ScopeAdmin)
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	key := apiKeyPrefix + hex.EncodeToString(b)
+	const q = `
+INSERT INTO api_keys (key_hash, name, scope)
+VALUES ($1, $2, $3)
+`
+	if _, err := s.pool.Exec(ctx, q, hashToken(key), name, scope); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// LookupAPIKey returns the live key matching key, or nil if it was never
+// issued or is revoked. A database without api_keys has no keys.
+func (s *Store) LookupAPIKey(ctx context.Context, key string) (*APIKey, error) {
+	const q = `
+SELECT name, scope, created_at, revoked_at
+FROM api_keys
+WHERE key_hash = $1 AND revoked_at IS NULL
+`
+	var k APIKey
+	err := s.pool.QueryRow(ctx, q, hashToken(key)).Scan(&k.Name, &k.Scope, &k.CreatedAt, &k.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) || isUndefinedTable(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ListAPIKeys returns every key ever issued, oldest first.
+func (s *Store) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	const q = `
+SELECT name, scope, created_at, revoked_at
+FROM api_keys
+ORDER BY created_at
+`
+	rows, err := s.pool.Query(ctx, q)
+	if err != nil {
+		if isUndefinedTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+	var out []APIKey
+	for rows.Next() {
+		var k APIKey
+		if err := rows.Scan(&k.Name, &k.Scope, &k.CreatedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// RevokeAPIKeys revokes every live key issued to name and returns how
+// many there were.
+func (s *Store) RevokeAPIKeys(ctx context.Context, name string) (int64, error) {
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+	const q = `
+UPDATE api_keys SET revoked_at = NOW()
+WHERE name = $1 AND revoked_at IS NULL
+`
+	tag, err := s.pool.Exec(ctx, q, name)
+	return tag.RowsAffected(), err
+}
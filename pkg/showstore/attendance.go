@@ -0,0 +1,81 @@
+package showstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ShowAttendance is the house count staff recorded for a show. Either
+// number may be unknown (nil).
+type ShowAttendance struct {
+	UID        string     `json:"uid"`
+	Summary    string     `json:"summary"`
+	Start      *time.Time `json:"start,omitempty"`
+	Teams      []string   `json:"teams"` // names of the linked teams
+	Capacity   *int       `json:"capacity"`
+	Attendance *int       `json:"attendance"`
+}
+
+// SetAttendance records a show's capacity and attendance; a nil value
+// leaves that column as it is. It reports whether the show exists. The
+// counts aren't part of the event, so the show's version and updated_at
+// don't change and syncs never overwrite them.
+func (s *Store) SetAttendance(ctx context.Context, uid string, capacity, attendance *int) (bool, error) {
+	if err := s.checkWritable(); err != nil {
+		return false, err
+	}
+	const q = `
+UPDATE shows
+SET capacity   = COALESCE($2, capacity),
+    attendance = COALESCE($3, attendance)
+WHERE uid = $1
+`
+	tag, err := s.pool.Exec(ctx, q, uid, capacity, attendance)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+const attendanceColumns = `
+SELECT uid, summary, start, capacity, attendance,
+  ARRAY(SELECT t.name FROM show_teams st JOIN "Team" t ON t.id = st.team_id WHERE st.show_uid = shows.uid ORDER BY t.name)
+FROM shows
+`
+
+// GetAttendance returns a show's recorded counts, or nil if the show
+// doesn't exist.
+func (s *Store) GetAttendance(ctx context.Context, uid string) (*ShowAttendance, error) {
+	var a ShowAttendance
+	err := s.pool.QueryRow(ctx, attendanceColumns+"WHERE uid = $1", uid).
+		Scan(&a.UID, &a.Summary, &a.Start, &a.Capacity, &a.Attendance, &a.Teams)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ListAttendance returns the shows starting in [from, to) that have an
+// attendance recorded, oldest first.
+func (s *Store) ListAttendance(ctx context.Context, from, to time.Time) ([]ShowAttendance, error) {
+	rows, err := s.pool.Query(ctx, attendanceColumns+"WHERE attendance IS NOT NULL AND start >= $1 AND start < $2 ORDER BY start, uid", from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ShowAttendance
+	for rows.Next() {
+		var a ShowAttendance
+		if err := rows.Scan(&a.UID, &a.Summary, &a.Start, &a.Capacity, &a.Attendance, &a.Teams); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
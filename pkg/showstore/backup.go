@@ -0,0 +1,152 @@
+package showstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// BackupTables are the tables DumpTable and Restorer cover, parents before
+// children so rows can be replayed in this order.
+var BackupTables = []string{
+	"Team", "shows", "show_teams", "team_aliases", "players", "team_rosters",
+	"name_dict", "calendar_tokens", "api_keys", "short_links", "sync_runs", "schedule_changes",
+	"seasons", "season_shows", "review_queue",
+}
+
+// ErrNotEmpty is returned by BeginRestore when the database already has
+// shows.
+var ErrNotEmpty = errors.New("database is not empty")
+
+func backupTable(table string) (string, error) {
+	if !slices.Contains(BackupTables, table) {
+		return "", fmt.Errorf("unknown table %q", table)
+	}
+	return pgx.Identifier{table}.Sanitize(), nil
+}
+
+// DumpTable calls fn with every row of table as a JSON object, column
+// names as keys. A database without the table has no rows.
+func (s *Store) DumpTable(ctx context.Context, table string, fn func(row json.RawMessage) error) error {
+	t, err := backupTable(table)
+	if err != nil {
+		return err
+	}
+	rows, err := s.pool.Query(ctx, "SELECT row_to_json(t) FROM "+t+" t")
+	if err != nil {
+		if isUndefinedTable(err) {
+			return nil
+		}
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EnsureTeamTable creates a bare "Team" table (id, name) in public when
+// there is none, so Migrate can run against a brand-new database.
+func (s *Store) EnsureTeamTable(ctx context.Context) error {
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+	const q = `
+CREATE TABLE IF NOT EXISTS public."Team" (
+  id   TEXT PRIMARY KEY,
+  name TEXT NOT NULL
+)
+`
+	_, err := s.pool.Exec(ctx, q)
+	return err
+}
+
+// Restorer replays dumped rows in a single transaction; nothing is
+// visible until Commit.
+type Restorer struct {
+	tx pgx.Tx
+}
+
+// BeginRestore starts a restore into the migrated schema. It refuses a
+// database that already has shows.
+func (s *Store) BeginRestore(ctx context.Context) (*Restorer, error) {
+	if err := s.checkWritable(); err != nil {
+		return nil, err
+	}
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM shows").Scan(&n); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, err
+	}
+	if n > 0 {
+		_ = tx.Rollback(ctx)
+		return nil, fmt.Errorf("%w: it has %d shows", ErrNotEmpty, n)
+	}
+	return &Restorer{tx: tx}, nil
+}
+
+// Insert writes rows (JSON objects from DumpTable) into table. Columns the
+// rows lack get their defaults and keys the table lacks are ignored, so
+// an archive from an older schema restores into a newer one. Teams are
+// shared between venues; ones already present are kept.
+func (r *Restorer) Insert(ctx context.Context, table string, rows []json.RawMessage) (int64, error) {
+	t, err := backupTable(table)
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	q := "INSERT INTO " + t + " SELECT * FROM json_populate_recordset(NULL::" + t + ", $1::JSON)"
+	if table == "Team" {
+		q += " ON CONFLICT DO NOTHING"
+	}
+	var arr bytes.Buffer
+	arr.WriteByte('[')
+	for i, row := range rows {
+		if i > 0 {
+			arr.WriteByte(',')
+		}
+		arr.Write(row)
+	}
+	arr.WriteByte(']')
+	tag, err := r.tx.Exec(ctx, q, arr.String())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", table, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Commit moves schedule_changes' id sequence past the restored rows and
+// commits the restore.
+func (r *Restorer) Commit(ctx context.Context) error {
+	const q = `
+SELECT setval(pg_get_serial_sequence('schedule_changes', 'id'), COALESCE(MAX(id), 0) + 1, false)
+FROM schedule_changes
+`
+	if _, err := r.tx.Exec(ctx, q); err != nil {
+		return err
+	}
+	return r.tx.Commit(ctx)
+}
+
+// Rollback abandons the restore. It is safe to call after Commit.
+func (r *Restorer) Rollback(ctx context.Context) {
+	_ = r.tx.Rollback(ctx)
+}
@@ -0,0 +1,210 @@
+package showstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// ErrorPolicy controls how batch operations react to a failing event.
+type ErrorPolicy int
+
+const (
+	// FailFast stops at the first failing event.
+	FailFast ErrorPolicy = iota
+	// Continue records the failure and moves on to the next event.
+	Continue
+)
+
+// ParseErrorPolicy maps "fail-fast" / "continue" to an ErrorPolicy.
+func ParseErrorPolicy(s string) (ErrorPolicy, error) {
+	switch s {
+	case "fail-fast", "failfast", "":
+		return FailFast, nil
+	case "continue":
+		return Continue, nil
+	}
+	return FailFast, fmt.Errorf("unknown error policy %q (want fail-fast or continue)", s)
+}
+
+func (p ErrorPolicy) String() string {
+	if p == Continue {
+		return "continue"
+	}
+	return "fail-fast"
+}
+
+// EventError ties a failure to the event that caused it.
+type EventError struct {
+	UID     string
+	Summary string
+	Err     error
+}
+
+func (e EventError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.UID, e.Summary, e.Err)
+}
+
+func (e EventError) Unwrap() error { return e.Err }
+
+// BatchResult summarises a batch write.
+type BatchResult struct {
+	Attempted int
+	Succeeded int
+	Failed    []EventError
+}
+
+// Err returns nil if every event succeeded, otherwise an error describing
+// how many failed.
+func (r BatchResult) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	if len(r.Failed) == 1 {
+		return r.Failed[0]
+	}
+	return fmt.Errorf("%d of %d events failed; first: %w", len(r.Failed), r.Attempted, r.Failed[0])
+}
+
+// BatchOption tunes a batch write.
+type BatchOption func(*batchConfig)
+
+type batchConfig struct {
+	progress func(done, total int)
+	conflict ConflictStrategy
+	workers  int
+}
+
+// WithProgress calls fn after each event is written (successfully or not).
+func WithProgress(fn func(done, total int)) BatchOption {
+	return func(c *batchConfig) { c.progress = fn }
+}
+
+// WithConflictStrategy settles disagreements with hand-edited fields by c
+// instead of overwriting them.
+func WithConflictStrategy(c ConflictStrategy) BatchOption {
+	return func(cfg *batchConfig) { cfg.conflict = c }
+}
+
+// WithWorkers writes up to n events at once, each in its own transaction
+// on its own connection. The pool's size bounds it too. The default is 1.
+func WithWorkers(n int) BatchOption {
+	return func(c *batchConfig) { c.workers = n }
+}
+
+// UpsertAll upserts each event in its own transaction. With FailFast it
+// stops at the first failure; with Continue it keeps going and collects
+// every failure in the result. The returned error is non-nil only for
+// FailFast, or when the context is cancelled.
+func (s *Store) UpsertAll(ctx context.Context, events []icalplayers.Event, policy ErrorPolicy, opts ...BatchOption) (BatchResult, error) {
+	if err := s.checkWritable(); err != nil {
+		return BatchResult{}, err
+	}
+	var cfg batchConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	write := func(ctx context.Context, e icalplayers.Event) error { return s.upsert(ctx, e, cfg.conflict) }
+	if cfg.workers > 1 && len(events) > 1 {
+		return upsertParallel(ctx, events, policy, cfg, write)
+	}
+	return upsertSerial(ctx, events, policy, cfg, write)
+}
+
+// upsertSerial is UpsertAll with one writer.
+func upsertSerial(ctx context.Context, events []icalplayers.Event, policy ErrorPolicy, cfg batchConfig, write func(context.Context, icalplayers.Event) error) (BatchResult, error) {
+	var res BatchResult
+	for _, e := range events {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		res.Attempted++
+		err := write(ctx, e)
+		if err == nil {
+			res.Succeeded++
+		} else {
+			res.Failed = append(res.Failed, EventError{UID: e.UID, Summary: e.Summary, Err: err})
+		}
+		if cfg.progress != nil {
+			cfg.progress(res.Attempted, len(events))
+		}
+		if err != nil && policy == FailFast {
+			return res, res.Failed[len(res.Failed)-1]
+		}
+	}
+	return res, nil
+}
+
+// upsertParallel is UpsertAll with cfg.workers writers. Failures are
+// reported in event order; with FailFast no new event is started after
+// the first failure, though ones already in flight finish.
+func upsertParallel(ctx context.Context, events []icalplayers.Event, policy ErrorPolicy, cfg batchConfig, write func(context.Context, icalplayers.Event) error) (BatchResult, error) {
+	var res BatchResult
+	// stop ends the feed without cancelling writes already in flight.
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	defer stopOnce.Do(func() { close(stop) })
+
+	type result struct {
+		i   int
+		err error
+	}
+	jobs := make(chan int)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for range min(cfg.workers, len(events)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results <- result{i, write(ctx, events[i])}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range events {
+			select {
+			case jobs <- i:
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	errs := make([]error, len(events))
+	done := make([]bool, len(events))
+	for r := range results {
+		res.Attempted++
+		done[r.i] = true
+		errs[r.i] = r.err
+		if r.err == nil {
+			res.Succeeded++
+		} else if policy == FailFast {
+			stopOnce.Do(func() { close(stop) })
+		}
+		if cfg.progress != nil {
+			cfg.progress(res.Attempted, len(events))
+		}
+	}
+	for i, e := range events {
+		if done[i] && errs[i] != nil {
+			res.Failed = append(res.Failed, EventError{UID: e.UID, Summary: e.Summary, Err: errs[i]})
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	if len(res.Failed) > 0 && policy == FailFast {
+		return res, res.Failed[0]
+	}
+	return res, nil
+}
@@ -0,0 +1,174 @@
+package showstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+var errWrite = errors.New("write failed")
+
+func batchEvents(n int) []icalplayers.Event {
+	events := make([]icalplayers.Event, n)
+	for i := range events {
+		events[i] = icalplayers.Event{UID: fmt.Sprintf("e%d", i), Summary: fmt.Sprintf("Show %d", i)}
+	}
+	return events
+}
+
+// failing writes events after a delay that shrinks with their index, so
+// parallel writes finish out of order, and fails the ones in fail.
+func failing(fail ...string) func(context.Context, icalplayers.Event) error {
+	return func(_ context.Context, e icalplayers.Event) error {
+		var i int
+		fmt.Sscanf(e.UID, "e%d", &i)
+		time.Sleep(time.Duration(10-i%10) * time.Millisecond)
+		if slices.Contains(fail, e.UID) {
+			return errWrite
+		}
+		return nil
+	}
+}
+
+// writeBatch dispatches like UpsertAll.
+func writeBatch(ctx context.Context, events []icalplayers.Event, policy ErrorPolicy, cfg batchConfig, write func(context.Context, icalplayers.Event) error) (BatchResult, error) {
+	if cfg.workers > 1 && len(events) > 1 {
+		return upsertParallel(ctx, events, policy, cfg, write)
+	}
+	return upsertSerial(ctx, events, policy, cfg, write)
+}
+
+func failedUIDs(res BatchResult) []string {
+	var uids []string
+	for _, f := range res.Failed {
+		uids = append(uids, f.UID)
+	}
+	return uids
+}
+
+func TestUpsertBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		workers    int
+		policy     ErrorPolicy
+		events     int
+		fail       []string
+		attempted  int
+		succeeded  int
+		wantFailed []string
+		wantErr    bool
+	}{
+		{"all succeed", 1, FailFast, 5, nil, 5, 5, nil, false},
+		{"all succeed, parallel", 4, FailFast, 5, nil, 5, 5, nil, false},
+		{"continue collects every failure", 1, Continue, 5, []string{"e3", "e1"}, 5, 3, []string{"e1", "e3"}, false},
+		{"continue, parallel, failures in event order", 4, Continue, 10, []string{"e8", "e2", "e5"}, 10, 7, []string{"e2", "e5", "e8"}, false},
+		{"fail-fast stops at the first failure", 1, FailFast, 5, []string{"e2", "e4"}, 3, 2, []string{"e2"}, true},
+		{"more workers than events", 8, Continue, 3, []string{"e0"}, 3, 2, []string{"e0"}, false},
+		{"no events", 4, Continue, 0, nil, 0, 0, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var progress [][2]int
+			cfg := batchConfig{workers: tt.workers, progress: func(done, total int) {
+				mu.Lock()
+				progress = append(progress, [2]int{done, total})
+				mu.Unlock()
+			}}
+			res, err := writeBatch(context.Background(), batchEvents(tt.events), tt.policy, cfg, failing(tt.fail...))
+			if res.Attempted != tt.attempted || res.Succeeded != tt.succeeded {
+				t.Errorf("attempted %d, succeeded %d; want %d, %d", res.Attempted, res.Succeeded, tt.attempted, tt.succeeded)
+			}
+			if got := failedUIDs(res); !slices.Equal(got, tt.wantFailed) {
+				t.Errorf("failed %v, want %v", got, tt.wantFailed)
+			}
+			for _, f := range res.Failed {
+				if !errors.Is(f, errWrite) || f.Summary == "" {
+					t.Errorf("failure %+v doesn't carry the event and its error", f)
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			var ee EventError
+			if err != nil && (!errors.As(err, &ee) || ee.UID != tt.wantFailed[0]) {
+				t.Errorf("err = %v, want the first failure", err)
+			}
+			if tt.events > 0 {
+				if last := progress[len(progress)-1]; last != [2]int{tt.attempted, tt.events} {
+					t.Errorf("last progress %v, want %d of %d", last, tt.attempted, tt.events)
+				}
+			}
+		})
+	}
+}
+
+func TestUpsertParallelFailFast(t *testing.T) {
+	events := batchEvents(20)
+	res, err := upsertParallel(context.Background(), events, FailFast, batchConfig{workers: 2}, failing("e0"))
+	var ee EventError
+	if !errors.As(err, &ee) || ee.UID != "e0" || !errors.Is(err, errWrite) {
+		t.Fatalf("err = %v, want e0's failure", err)
+	}
+	if got := failedUIDs(res); !slices.Equal(got, []string{"e0"}) {
+		t.Errorf("failed %v, want [e0]", got)
+	}
+	// Writes in flight finish; nothing new starts.
+	if res.Attempted >= len(events) || res.Succeeded != res.Attempted-1 {
+		t.Errorf("attempted %d, succeeded %d of %d after an early failure", res.Attempted, res.Succeeded, len(events))
+	}
+}
+
+func TestUpsertParallelFansOut(t *testing.T) {
+	var mu sync.Mutex
+	active, most := 0, 0
+	write := func(context.Context, icalplayers.Event) error {
+		mu.Lock()
+		active++
+		most = max(most, active)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return nil
+	}
+	res, err := upsertParallel(context.Background(), batchEvents(12), Continue, batchConfig{workers: 3}, write)
+	if err != nil || res.Succeeded != 12 {
+		t.Fatalf("succeeded %d, err %v", res.Succeeded, err)
+	}
+	if most < 2 || most > 3 {
+		t.Errorf("%d writes at once, want 2 or 3 with 3 workers", most)
+	}
+}
+
+func TestUpsertBatchCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, workers := range []int{1, 4} {
+		res, err := writeBatch(ctx, batchEvents(5), Continue, batchConfig{workers: workers}, failing())
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("workers %d: err = %v, want context.Canceled", workers, err)
+		}
+		if res.Succeeded > res.Attempted || len(res.Failed) != 0 {
+			t.Errorf("workers %d: %+v", workers, res)
+		}
+	}
+}
+
+func TestParseErrorPolicy(t *testing.T) {
+	for in, want := range map[string]ErrorPolicy{"": FailFast, "fail-fast": FailFast, "failfast": FailFast, "continue": Continue} {
+		if got, err := ParseErrorPolicy(in); err != nil || got != want {
+			t.Errorf("ParseErrorPolicy(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseErrorPolicy("retry"); err == nil {
+		t.Error(`ParseErrorPolicy("retry") succeeded`)
+	}
+}
@@ -0,0 +1,144 @@
+package showstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// Fields staff can edit by hand, as recorded in shows.edited_fields.
+const (
+	FieldPlayers = "players"
+	FieldTeams   = "teams"
+	FieldImage   = "post_image_url"
+)
+
+// ConflictStrategy decides what a sync does with a field the feed disagrees
+// with after staff edited it by hand (see MarkEdited). Fields nobody edited
+// always take the feed's value.
+type ConflictStrategy int
+
+const (
+	// FeedWins overwrites the edit and forgets it was made.
+	FeedWins ConflictStrategy = iota
+	// DBWins keeps the edit.
+	DBWins
+	// NewestWins keeps the edit unless the source changed the event after
+	// it (Event.Modified). Sources that don't report a modification time
+	// never beat an edit.
+	NewestWins
+	// MergeFields unions the edited and feed players and teams and keeps
+	// an edited image.
+	MergeFields
+)
+
+var conflictNames = []string{"feed-wins", "db-wins", "newest-wins", "merge-fields"}
+
+// ParseConflictStrategy maps feed-wins, db-wins, newest-wins or
+// merge-fields to a ConflictStrategy.
+func ParseConflictStrategy(s string) (ConflictStrategy, error) {
+	if s == "" {
+		return FeedWins, nil
+	}
+	if i := slices.Index(conflictNames, s); i >= 0 {
+		return ConflictStrategy(i), nil
+	}
+	return FeedWins, fmt.Errorf("unknown conflict strategy %q (want feed-wins, db-wins, newest-wins or merge-fields)", s)
+}
+
+func (c ConflictStrategy) String() string {
+	if int(c) < len(conflictNames) {
+		return conflictNames[c]
+	}
+	return fmt.Sprintf("ConflictStrategy(%d)", int(c))
+}
+
+// Edits records which fields of a stored show were edited by hand, and when
+// the latest edit was made.
+type Edits struct {
+	Fields []string
+	At     time.Time
+}
+
+// ResolveConflict settles each hand-edited field of existing against the
+// incoming feed event e using c. It returns e with the surviving edits
+// applied, and the fields that are still hand-edited afterwards.
+func ResolveConflict(existing icalplayers.Event, ed Edits, e icalplayers.Event, c ConflictStrategy) (icalplayers.Event, []string) {
+	var kept []string
+	for _, f := range ed.Fields {
+		switch c {
+		case FeedWins:
+			continue
+		case NewestWins:
+			if e.Modified != nil && e.Modified.After(ed.At) {
+				continue
+			}
+		}
+		kept = append(kept, f)
+		merge := c == MergeFields
+		switch f {
+		case FieldPlayers:
+			e.Players = pick(existing.Players, e.Players, merge)
+		case FieldTeams:
+			e.Teams = pick(existing.Teams, e.Teams, merge)
+			e.TeamIDs = pick(existing.TeamIDs, e.TeamIDs, merge)
+		case FieldImage:
+			e.PostImageURL = existing.PostImageURL
+		}
+	}
+	return e, kept
+}
+
+// pick returns the edited list, with the feed's extra entries appended when
+// merging.
+func pick(edited, feed []string, merge bool) []string {
+	out := append([]string(nil), edited...)
+	if merge {
+		for _, v := range feed {
+			if !slices.Contains(out, v) {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// MarkEdited records that fields of a show were just edited by hand, so
+// later syncs settle disagreements with the feed by their ConflictStrategy
+// instead of overwriting them.
+func (s *Store) MarkEdited(ctx context.Context, uid string, fields ...string) error {
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+	const q = `
+UPDATE shows
+SET edited_fields = ARRAY(SELECT DISTINCT f FROM unnest(edited_fields || $2::TEXT[]) AS f ORDER BY f),
+    edited_at     = NOW()
+WHERE uid = $1
+`
+	_, err := s.pool.Exec(ctx, q, uid, fields)
+	return err
+}
+
+// GetEdits returns the hand edits recorded for a show; the zero Edits if
+// there are none or the show doesn't exist.
+func (s *Store) GetEdits(ctx context.Context, uid string) (Edits, error) {
+	var ed Edits
+	var at *time.Time
+	err := s.pool.QueryRow(ctx, `SELECT edited_fields, edited_at FROM shows WHERE uid = $1`, uid).Scan(&ed.Fields, &at)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Edits{}, nil
+		}
+		return ed, err
+	}
+	if at != nil {
+		ed.At = *at
+	}
+	return ed, nil
+}
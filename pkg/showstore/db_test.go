@@ -0,0 +1,103 @@
+package showstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// benchStore opens SHOPSYNC_BENCH_DATABASE_URL with the tables in a
+// throwaway schema, dropped when the benchmark ends. A database without a
+// "Team" table gets an empty one in public.
+func benchStore(b *testing.B) *Store {
+	b.Helper()
+	dbURL := os.Getenv("SHOPSYNC_BENCH_DATABASE_URL")
+	if dbURL == "" {
+		b.Skip("SHOPSYNC_BENCH_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	schema := fmt.Sprintf("shopsync_bench_%d", time.Now().UnixNano())
+	s, err := Open(ctx, dbURL, Schema(schema))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DROP SCHEMA IF EXISTS "+pgx.Identifier{schema}.Sanitize()+" CASCADE")
+		s.Close()
+	})
+	if err := s.EnsureTeamTable(ctx); err != nil {
+		b.Fatal(err)
+	}
+	if err := s.Migrate(ctx); err != nil {
+		b.Fatal(err)
+	}
+	return s
+}
+
+func benchEvent(i int) icalplayers.Event {
+	start := time.Date(2030, 1, 1, 19, 30, 0, 0, time.UTC).Add(time.Duration(i) * time.Hour)
+	return icalplayers.Event{
+		UID:          fmt.Sprintf("bench-%d", i),
+		Summary:      fmt.Sprintf("Bench Show %d", i),
+		Description:  "Cast: Maya Ortiz, Devon Clarke, Priya Raman",
+		URL:          fmt.Sprintf("https://example.com/show/bench-%d/", i),
+		PostImageURL: fmt.Sprintf("https://example.com/img/bench-%d.jpg", i),
+		Start:        &start,
+		Players:      []string{"Maya Ortiz", "Devon Clarke", "Priya Raman"},
+	}
+}
+
+// BenchmarkUpsert measures inserting new shows and re-upserting existing
+// ones, the two cases a sync hits.
+func BenchmarkUpsert(b *testing.B) {
+	s := benchStore(b)
+	ctx := context.Background()
+	b.Run("insert", func(b *testing.B) {
+		i := 0
+		for b.Loop() {
+			if err := s.Upsert(ctx, benchEvent(i)); err != nil {
+				b.Fatal(err)
+			}
+			i++
+		}
+	})
+	b.Run("update", func(b *testing.B) {
+		e := benchEvent(-1)
+		if err := s.Upsert(ctx, e); err != nil {
+			b.Fatal(err)
+		}
+		for b.Loop() {
+			if err := s.Upsert(ctx, e); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkUpsertAll stores 200 new shows per iteration with 1 and with
+// several writers, to see what round-trip latency costs.
+func BenchmarkUpsertAll(b *testing.B) {
+	s := benchStore(b)
+	ctx := context.Background()
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			n := 0
+			for b.Loop() {
+				events := make([]icalplayers.Event, 200)
+				for i := range events {
+					events[i] = benchEvent(n)
+					events[i].UID = fmt.Sprintf("bench-all-%d-%d", workers, n)
+					n++
+				}
+				if _, err := s.UpsertAll(ctx, events, FailFast, WithWorkers(workers)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
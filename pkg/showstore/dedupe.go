@@ -0,0 +1,211 @@
+package showstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// FindDuplicates groups stored shows that InsertIfNew would have treated as
+// one: summaries equal once lower-cased and stripped of punctuation, and
+// starts within 12 hours of each other. Shows chain into one group through
+// any pair that matches. Groups are ordered by their first start; each has
+// at least two shows, ordered by start and uid, with TeamIDs and UpdatedAt
+// filled.
+func (s *Store) FindDuplicates(ctx context.Context) ([][]icalplayers.Event, error) {
+	const pairs = `
+WITH n AS (
+  SELECT uid, start, lower(regexp_replace(summary, '[^a-zA-Z0-9 ]', '', 'g')) AS norm
+  FROM shows
+  WHERE start IS NOT NULL
+)
+SELECT a.uid, b.uid
+FROM n a
+JOIN n b ON a.norm = b.norm AND a.uid < b.uid
+WHERE b.start BETWEEN a.start - INTERVAL '12 hours' AND a.start + INTERVAL '12 hours'
+`
+	rows, err := s.pool.Query(ctx, pairs)
+	if err != nil {
+		return nil, err
+	}
+	parent := map[string]string{}
+	var find func(string) string
+	find = func(u string) string {
+		p, ok := parent[u]
+		if !ok || p == u {
+			parent[u] = u
+			return u
+		}
+		root := find(p)
+		parent[u] = root
+		return root
+	}
+	for rows.Next() {
+		var a, b string
+		if err := rows.Scan(&a, &b); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		parent[find(b)] = find(a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(parent) == 0 {
+		return nil, nil
+	}
+
+	uids := make([]string, 0, len(parent))
+	for u := range parent {
+		uids = append(uids, u)
+	}
+	const shows = `
+SELECT uid, summary, description, COALESCE(url, ''), COALESCE(post_image_url, ''), start, players, teams, version, updated_at,
+  ARRAY(SELECT team_id FROM show_teams st WHERE st.show_uid = shows.uid ORDER BY team_id)
+FROM shows
+WHERE uid = ANY($1::TEXT[])
+ORDER BY start, uid
+`
+	rows, err = s.pool.Query(ctx, shows, uids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var groups [][]icalplayers.Event
+	index := map[string]int{}
+	for rows.Next() {
+		var e icalplayers.Event
+		if err := rows.Scan(&e.UID, &e.Summary, &e.Description, &e.URL, &e.PostImageURL, &e.Start, &e.Players, &e.Teams, &e.Version, &e.UpdatedAt, &e.TeamIDs); err != nil {
+			return nil, err
+		}
+		root := find(e.UID)
+		i, ok := index[root]
+		if !ok {
+			i = len(groups)
+			index[root] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], e)
+	}
+	return groups, rows.Err()
+}
+
+// MergeDuplicates picks which show of a duplicate group survives and what
+// it should hold. The survivor is the richest show (image, URL, players,
+// teams and description, in that order of weight; the earliest on a tie).
+// It keeps its own summary and start, and takes the longest description,
+// any image or URL it lacks, and the union of everyone's players and teams.
+// The other shows' UIDs are returned in drop.
+func MergeDuplicates(group []icalplayers.Event) (merged icalplayers.Event, drop []string) {
+	if len(group) == 0 {
+		return merged, nil
+	}
+	best := 0
+	for i, e := range group {
+		if richness(e) > richness(group[best]) {
+			best = i
+		}
+	}
+	merged = group[best]
+	merged.Players = slices.Clone(merged.Players)
+	merged.Teams = slices.Clone(merged.Teams)
+	merged.TeamIDs = slices.Clone(merged.TeamIDs)
+	for i, e := range group {
+		if i == best {
+			continue
+		}
+		drop = append(drop, e.UID)
+		if len(e.Description) > len(merged.Description) {
+			merged.Description = e.Description
+		}
+		if merged.URL == "" {
+			merged.URL = e.URL
+		}
+		if merged.PostImageURL == "" {
+			merged.PostImageURL = e.PostImageURL
+		}
+		merged.Players = pick(merged.Players, e.Players, true)
+		merged.Teams = pick(merged.Teams, e.Teams, true)
+		merged.TeamIDs = pick(merged.TeamIDs, e.TeamIDs, true)
+	}
+	return merged, drop
+}
+
+func richness(e icalplayers.Event) int {
+	n := 0
+	if e.PostImageURL != "" {
+		n += 1000
+	}
+	if e.URL != "" {
+		n += 500
+	}
+	n += 50*len(e.Players) + 50*len(e.TeamIDs)
+	return n + min(len(e.Description), 490)/10
+}
+
+// MergeShows writes merged (from MergeDuplicates) over its stored row and
+// deletes the drop shows, moving their team links and hand-edit marks to
+// the survivor. merged.Version guards the survivor as in
+// UpdateDescriptionAndTeams; the dropped rows are deleted whatever their
+// version.
+func (s *Store) MergeShows(ctx context.Context, merged icalplayers.Event, drop []string) (err error) {
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	const update = `
+UPDATE shows
+SET description    = $2,
+    url            = $3,
+    post_image_url = NULLIF($4, ''),
+    players        = $5,
+    teams          = $6,
+    edited_fields  = ARRAY(
+      SELECT DISTINCT f FROM (
+        SELECT unnest(edited_fields)
+        UNION ALL
+        SELECT unnest(d.edited_fields) FROM shows d WHERE d.uid = ANY($8::TEXT[])
+      ) AS e(f) ORDER BY f),
+    edited_at      = (SELECT MAX(edited_at) FROM shows d WHERE d.uid = $1 OR d.uid = ANY($8::TEXT[])),
+    version        = version + 1,
+    updated_at     = NOW()
+WHERE uid = $1
+  AND ($7::BIGINT = 0 OR version = $7::BIGINT)
+`
+	tag, err := tx.Exec(ctx, update, merged.UID, merged.Description, merged.URL, merged.PostImageURL,
+		strSliceToTextArray(merged.Players), strSliceToTextArray(merged.Teams), merged.Version, drop)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		err = ErrVersionConflict
+		return err
+	}
+	const repoint = `
+INSERT INTO show_teams (show_uid, team_id)
+SELECT $1, team_id FROM show_teams WHERE show_uid = ANY($2::TEXT[])
+ON CONFLICT DO NOTHING
+`
+	if _, err = tx.Exec(ctx, repoint, merged.UID, drop); err != nil {
+		return err
+	}
+	if err = syncShowTeams(ctx, tx, merged.UID, merged.TeamIDs); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM shows WHERE uid = ANY($1::TEXT[])`, drop); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,45 @@
+package showstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SyncLockKey is the advisory lock key held for the duration of a sync.
+const SyncLockKey int64 = 0x73686f7073796e63 // "shopsync"
+
+// ErrLocked is returned by TryLock when another session holds the lock.
+var ErrLocked = errors.New("lock is held by another session")
+
+// ErrLockUnsupported is returned by TryLock when the database has no
+// advisory locks (e.g. CockroachDB).
+var ErrLockUnsupported = errors.New("database does not support advisory locks")
+
+// TryLock takes the session-level advisory lock key without waiting. The
+// lock lives on a dedicated pooled connection until unlock is called.
+func (s *Store) TryLock(ctx context.Context, key int64) (unlock func(), err error) {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ok bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&ok); err != nil {
+		conn.Release()
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && (pgErr.Code == "42883" || pgErr.Code == "0A000") {
+			return nil, ErrLockUnsupported
+		}
+		return nil, err
+	}
+	if !ok {
+		conn.Release()
+		return nil, ErrLocked
+	}
+	return func() {
+		// A fresh context: unlocking must happen even if the sync's was cancelled.
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, key)
+		conn.Release()
+	}, nil
+}
@@ -0,0 +1,154 @@
+package showstore
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// Name dictionary sources.
+const (
+	DictSourceRoster = "roster"
+	DictSourceShows  = "shows"
+)
+
+// DictEntry is one full name in the name dictionary.
+type DictEntry struct {
+	Name   string
+	Source string // DictSourceRoster or DictSourceShows
+	Shows  int    // stored shows listing the name
+}
+
+// PlayerMention is a name as it appears in shows.players.
+type PlayerMention struct {
+	Name   string
+	Shows  int
+	Edited bool // listed by at least one show whose players were edited by hand
+}
+
+// PlayerMentions counts the shows listing each player name, spelled as
+// stored.
+func (s *Store) PlayerMentions(ctx context.Context) ([]PlayerMention, error) {
+	const q = `
+SELECT p, COUNT(*), BOOL_OR('players' = ANY(edited_fields))
+FROM shows, unnest(players) AS p
+WHERE p <> ''
+GROUP BY p
+ORDER BY p
+`
+	rows, err := s.pool.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []PlayerMention
+	for rows.Next() {
+		var m PlayerMention
+		if err := rows.Scan(&m.Name, &m.Shows, &m.Edited); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// ListPlayers returns every stored player with the teams whose rosters list
+// them. A database without the players table has none.
+func (s *Store) ListPlayers(ctx context.Context) ([]Player, error) {
+	const q = `
+SELECT name, COALESCE(headshot_url, ''), COALESCE(profile_url, ''),
+  ARRAY(SELECT team_id FROM team_rosters r WHERE r.player_key = players.name_key ORDER BY team_id)
+FROM players
+ORDER BY name_key
+`
+	rows, err := s.pool.Query(ctx, q)
+	if err != nil {
+		if isUndefinedTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Player
+	for rows.Next() {
+		var p Player
+		if err := rows.Scan(&p.Name, &p.HeadshotURL, &p.ProfileURL, &p.TeamIDs); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil && !isUndefinedTable(err) {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpsertNameDict adds entries to the name dictionary, updating the
+// spelling, source and show count of names already in it. Names are never
+// removed, so hand-added rows survive a rebuild. It returns how many names
+// were new.
+func (s *Store) UpsertNameDict(ctx context.Context, entries []DictEntry) (added int, err error) {
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+	const q = `
+INSERT INTO name_dict (name_key, name, source, shows)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (name_key) DO UPDATE
+SET name       = EXCLUDED.name,
+    source     = EXCLUDED.source,
+    shows      = EXCLUDED.shows,
+    updated_at = NOW()
+RETURNING (xmax = 0)
+`
+	for _, e := range entries {
+		key := playerKey(e.Name)
+		if key == "" {
+			continue
+		}
+		var inserted bool
+		if err = tx.QueryRow(ctx, q, key, strings.TrimSpace(e.Name), e.Source, e.Shows).Scan(&inserted); err != nil {
+			return 0, err
+		}
+		if inserted {
+			added++
+		}
+	}
+	return added, tx.Commit(ctx)
+}
+
+// GetNameDict returns the name dictionary in the form inference takes. A
+// database without the name_dict table has an empty one.
+func (s *Store) GetNameDict(ctx context.Context) (*icalplayers.NameDict, error) {
+	nd := icalplayers.NewNameDict()
+	rows, err := s.pool.Query(ctx, `SELECT name FROM name_dict`)
+	if err != nil {
+		if isUndefinedTable(err) {
+			return nd, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		nd.Add(name)
+	}
+	if err := rows.Err(); err != nil && !isUndefinedTable(err) {
+		return nil, err
+	}
+	return nd, nil
+}
@@ -0,0 +1,138 @@
+package showstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Player is a performer known from a team roster. Players are keyed by
+// name, case-insensitively, so the names inference puts in shows.players
+// link straight to a profile.
+type Player struct {
+	Name        string   `json:"name"`
+	HeadshotURL string   `json:"headshotUrl,omitempty"`
+	ProfileURL  string   `json:"profileUrl,omitempty"`
+	TeamIDs     []string `json:"teamIds,omitempty"`
+}
+
+func playerKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// ReplaceRoster makes members the roster of teamID: players are created or
+// updated (a missing headshot or profile keeps the stored one), and anyone
+// no longer listed is dropped from the team but kept as a player. It
+// returns how many members joined and left.
+func (s *Store) ReplaceRoster(ctx context.Context, teamID string, members []Player) (added, removed int, err error) {
+	if err := s.checkWritable(); err != nil {
+		return 0, 0, err
+	}
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	const upsertPlayer = `
+INSERT INTO players (name_key, name, headshot_url, profile_url)
+VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+ON CONFLICT (name_key) DO UPDATE
+SET name         = EXCLUDED.name,
+    headshot_url = COALESCE(EXCLUDED.headshot_url, players.headshot_url),
+    profile_url  = COALESCE(EXCLUDED.profile_url, players.profile_url),
+    updated_at   = NOW()
+`
+	const link = `
+INSERT INTO team_rosters (team_id, player_key)
+VALUES ($1, $2)
+ON CONFLICT (team_id, player_key) DO NOTHING
+`
+	keys := make([]string, 0, len(members))
+	for _, m := range members {
+		key := playerKey(m.Name)
+		if key == "" {
+			continue
+		}
+		keys = append(keys, key)
+		if _, err = tx.Exec(ctx, upsertPlayer, key, strings.TrimSpace(m.Name), m.HeadshotURL, m.ProfileURL); err != nil {
+			return 0, 0, err
+		}
+		tag, err := tx.Exec(ctx, link, teamID, key)
+		if err != nil {
+			return 0, 0, err
+		}
+		added += int(tag.RowsAffected())
+	}
+	const unlink = `
+DELETE FROM team_rosters
+WHERE team_id = $1 AND NOT (player_key = ANY($2::TEXT[]))
+`
+	tag, err := tx.Exec(ctx, unlink, teamID, keys)
+	if err != nil {
+		return 0, 0, err
+	}
+	removed = int(tag.RowsAffected())
+	return added, removed, tx.Commit(ctx)
+}
+
+// GetPlayer returns the player called name (any case) with the teams whose
+// rosters list them, or nil if there is none. A database without the
+// players table has none.
+func (s *Store) GetPlayer(ctx context.Context, name string) (*Player, error) {
+	const q = `
+SELECT name, COALESCE(headshot_url, ''), COALESCE(profile_url, ''),
+  ARRAY(SELECT team_id FROM team_rosters r WHERE r.player_key = players.name_key ORDER BY team_id)
+FROM players
+WHERE name_key = $1
+`
+	var p Player
+	err := s.pool.QueryRow(ctx, q, playerKey(name)).Scan(&p.Name, &p.HeadshotURL, &p.ProfileURL, &p.TeamIDs)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || isUndefinedTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetRosters returns the member names of every stored roster, keyed by team
+// ID. A database without rosters returns an empty map.
+func (s *Store) GetRosters(ctx context.Context) (map[string][]string, error) {
+	const q = `
+SELECT r.team_id, p.name
+FROM team_rosters r
+JOIN players p ON p.name_key = r.player_key
+ORDER BY r.team_id, p.name
+`
+	out := map[string][]string{}
+	rows, err := s.pool.Query(ctx, q)
+	if err != nil {
+		if isUndefinedTable(err) {
+			return out, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var teamID, name string
+		if err := rows.Scan(&teamID, &name); err != nil {
+			return nil, err
+		}
+		out[teamID] = append(out[teamID], name)
+	}
+	if err := rows.Err(); err != nil {
+		if isUndefinedTable(err) {
+			return map[string][]string{}, nil
+		}
+		return nil, err
+	}
+	return out, nil
+}
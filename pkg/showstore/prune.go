@@ -0,0 +1,87 @@
+package showstore
+
+import (
+	"context"
+	"time"
+)
+
+// CountShowsBefore counts shows that started before cutoff.
+func (s *Store) CountShowsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	var n int64
+	err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM shows WHERE start < $1`, cutoff).Scan(&n)
+	return n, err
+}
+
+// DeleteShowsBefore deletes shows that started before cutoff. Their
+// show_teams rows go with them via ON DELETE CASCADE.
+func (s *Store) DeleteShowsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+	tag, err := s.pool.Exec(ctx, `DELETE FROM shows WHERE start < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteShows deletes the shows with the given UIDs, with their show_teams
+// rows. UIDs that aren't stored are ignored.
+func (s *Store) DeleteShows(ctx context.Context, uids []string) (int64, error) {
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+	tag, err := s.pool.Exec(ctx, `DELETE FROM shows WHERE uid = ANY($1::TEXT[])`, uids)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// orphanedShowTeams matches show_teams rows whose show or team is gone. The
+// foreign keys should prevent these, but older databases were created
+// without them.
+const orphanedShowTeams = `
+NOT EXISTS (SELECT 1 FROM shows sh WHERE sh.uid = show_teams.show_uid)
+OR NOT EXISTS (SELECT 1 FROM "Team" t WHERE t.id = show_teams.team_id)
+`
+
+// CountOrphanedShowTeams counts show_teams rows pointing at a missing show
+// or team.
+func (s *Store) CountOrphanedShowTeams(ctx context.Context) (int64, error) {
+	var n int64
+	err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM show_teams WHERE `+orphanedShowTeams).Scan(&n)
+	return n, err
+}
+
+// DeleteOrphanedShowTeams removes show_teams rows pointing at a missing show
+// or team.
+func (s *Store) DeleteOrphanedShowTeams(ctx context.Context) (int64, error) {
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+	tag, err := s.pool.Exec(ctx, `DELETE FROM show_teams WHERE `+orphanedShowTeams)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetImageURLs returns every non-empty post_image_url.
+func (s *Store) GetImageURLs(ctx context.Context) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `SELECT post_image_url FROM shows WHERE post_image_url IS NOT NULL AND post_image_url <> ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,174 @@
+package showstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// ShowFilter narrows ListShows. Zero values mean "no constraint".
+type ShowFilter struct {
+	From   time.Time // start >= From
+	To     time.Time // start < To
+	TeamID string    // linked to this team via show_teams
+	Player string    // in players (any case, spacing ignored)
+	Season string    // a member of this season (season_shows)
+	Types  []string  // event_type in these (icalplayers.EventTypes); unset counts as a show
+	Query  string    // case-insensitive substring of summary or description
+	Sort   ShowSort  // SortStart when empty
+	After  *ShowKey  // only shows after this one in Sort order (keyset paging)
+	Limit  int
+	Offset int
+}
+
+// ShowSort is the column ListShows orders by, ties broken by uid.
+type ShowSort string
+
+const (
+	SortStart   ShowSort = "start"      // missing starts last
+	SortUpdated ShowSort = "updated_at" // oldest change first, so a client can resume from its last page
+)
+
+// ParseShowSort maps "start" / "updated_at" to a ShowSort; "" is SortStart.
+func ParseShowSort(s string) (ShowSort, error) {
+	switch ShowSort(s) {
+	case "", SortStart:
+		return SortStart, nil
+	case SortUpdated:
+		return SortUpdated, nil
+	}
+	return SortStart, fmt.Errorf("unknown sort %q (want start or updated_at)", s)
+}
+
+// ShowKey is a show's position in a ShowSort order: its sort column (nil
+// for a missing start) and uid.
+type ShowKey struct {
+	At  *time.Time
+	UID string
+}
+
+// Key returns e's position in the order.
+func (o ShowSort) Key(e icalplayers.Event) ShowKey {
+	if o == SortUpdated {
+		return ShowKey{At: e.UpdatedAt, UID: e.UID}
+	}
+	return ShowKey{At: e.Start, UID: e.UID}
+}
+
+// ListShows returns the page of shows matching f in f.Sort order, along
+// with the total number of matches ignoring After, Limit and Offset.
+// TeamIDs is filled from show_teams and UpdatedAt from updated_at.
+func (s *Store) ListShows(ctx context.Context, f ShowFilter) ([]icalplayers.Event, int, error) {
+	col := "start"
+	if f.Sort == SortUpdated {
+		col = "updated_at"
+	}
+	orderKey := "COALESCE(" + col + ", 'infinity')"
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if !f.From.IsZero() {
+		where = append(where, "start >= "+arg(f.From))
+	}
+	if !f.To.IsZero() {
+		where = append(where, "start < "+arg(f.To))
+	}
+	if f.TeamID != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM show_teams st WHERE st.show_uid = shows.uid AND st.team_id = "+arg(f.TeamID)+")")
+	}
+	if f.Season != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM season_shows ss WHERE ss.show_uid = shows.uid AND ss.season_id = "+arg(f.Season)+")")
+	}
+	if len(f.Types) > 0 {
+		where = append(where, "COALESCE(event_type, 'show') = ANY("+arg(f.Types)+")")
+	}
+	if f.Player != "" {
+		where = append(where, `EXISTS (SELECT 1 FROM unnest(players) p WHERE lower(regexp_replace(btrim(p), '\s+', ' ', 'g')) = `+arg(playerKey(f.Player))+")")
+	}
+	if f.Query != "" {
+		p := arg("%" + f.Query + "%")
+		where = append(where, "(summary ILIKE "+p+" OR description ILIKE "+p+")")
+	}
+	cond := ""
+	if len(where) > 0 {
+		cond = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM shows "+cond, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if f.After != nil {
+		after := "(" + orderKey + ", uid) > (COALESCE(" + arg(f.After.At) + "::timestamptz, 'infinity'), " + arg(f.After.UID) + ")"
+		if cond == "" {
+			cond = "WHERE " + after
+		} else {
+			cond += " AND " + after
+		}
+	}
+
+	q := `
+SELECT uid, summary, description, COALESCE(url, ''), COALESCE(post_image_url, ''), start, players, teams, version, updated_at, details, COALESCE(location, ''), COALESCE(event_type, ''),
+  ARRAY(SELECT team_id FROM show_teams st WHERE st.show_uid = shows.uid ORDER BY team_id)
+FROM shows
+` + cond + `
+ORDER BY ` + orderKey + `, uid`
+	if f.Limit > 0 {
+		q += " LIMIT " + arg(f.Limit)
+	}
+	if f.Offset > 0 {
+		q += " OFFSET " + arg(f.Offset)
+	}
+	rows, err := s.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []icalplayers.Event
+	for rows.Next() {
+		var e icalplayers.Event
+		var players, teams, teamIDs []string
+		if err := rows.Scan(&e.UID, &e.Summary, &e.Description, &e.URL, &e.PostImageURL, &e.Start, &players, &teams, &e.Version, &e.UpdatedAt, &e.Details, &e.Location, &e.EventType, &teamIDs); err != nil {
+			return nil, 0, err
+		}
+		e.Players = players
+		e.Teams = teams
+		e.TeamIDs = teamIDs
+		out = append(out, e)
+	}
+	if rows.Err() != nil {
+		return nil, 0, rows.Err()
+	}
+	return out, total, nil
+}
+
+// GetTeam returns the team with the given ID, or nil if there is none.
+func (s *Store) GetTeam(ctx context.Context, id string) (*Team, error) {
+	const q = `
+SELECT name, id
+FROM "Team"
+WHERE id = $1
+`
+	var t Team
+	if err := s.pool.QueryRow(ctx, q, id).Scan(&t.Name, &t.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	aliases, err := s.GetTeamAliases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.Aliases = aliases[t.ID]
+	return &t, nil
+}
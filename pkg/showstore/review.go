@@ -0,0 +1,168 @@
+package showstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Review queue statuses. Shows wait as ReviewPending until staff approve a
+// suggested team, reassign the show to other teams, or dismiss it (not a
+// team show).
+const (
+	ReviewPending    = "pending"
+	ReviewApproved   = "approved"
+	ReviewReassigned = "reassigned"
+	ReviewDismissed  = "dismissed"
+)
+
+// ReviewStatuses are the valid ReviewItem statuses.
+var ReviewStatuses = []string{ReviewPending, ReviewApproved, ReviewReassigned, ReviewDismissed}
+
+// ReviewSuggestion is a team that resembles some of a show's description.
+type ReviewSuggestion struct {
+	TeamID string  `json:"teamId"`
+	Team   string  `json:"team"`
+	Text   string  `json:"text"`  // the stretch of description that resembles it
+	Score  float64 `json:"score"` // similarity, 0-1
+}
+
+// ReviewItem is a show whose team match is in doubt: no team name was found
+// in its description. Confidence is the best suggestion's score, 0 with
+// none.
+type ReviewItem struct {
+	UID         string             `json:"uid"`
+	Summary     string             `json:"summary"`
+	Start       *time.Time         `json:"start,omitempty"`
+	Description string             `json:"description"`
+	Confidence  float64            `json:"confidence"`
+	Suggestions []ReviewSuggestion `json:"suggestions"`
+	Status      string             `json:"status"`
+	TeamIDs     []string           `json:"teamIds,omitempty"` // as resolved
+	Aliases     []string           `json:"aliases,omitempty"` // saved when resolved
+	QueuedAt    time.Time          `json:"queuedAt"`
+	ResolvedAt  *time.Time         `json:"resolvedAt,omitempty"`
+}
+
+// QueueReview puts the stored show uid in the review queue, or refreshes
+// its suggestions while it is pending. Resolved entries are left alone, so
+// a dismissed show isn't queued again; shows not stored are skipped.
+func (s *Store) QueueReview(ctx context.Context, uid string, confidence float64, suggestions []ReviewSuggestion) error {
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+	if suggestions == nil {
+		suggestions = []ReviewSuggestion{}
+	}
+	b, err := json.Marshal(suggestions)
+	if err != nil {
+		return err
+	}
+	const q = `
+INSERT INTO review_queue (show_uid, confidence, suggestions)
+SELECT uid, $2, $3 FROM shows WHERE uid = $1
+ON CONFLICT (show_uid) DO UPDATE
+SET confidence  = EXCLUDED.confidence,
+    suggestions = EXCLUDED.suggestions,
+    updated_at  = NOW()
+WHERE review_queue.status = 'pending'
+`
+	_, err = s.pool.Exec(ctx, q, uid, confidence, b)
+	return err
+}
+
+// ClearReview drops the pending entries of uids, which now match a team on
+// their own. It returns how many there were.
+func (s *Store) ClearReview(ctx context.Context, uids []string) (int64, error) {
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+	tag, err := s.pool.Exec(ctx, `DELETE FROM review_queue WHERE status = 'pending' AND show_uid = ANY($1::TEXT[])`, uids)
+	if isUndefinedTable(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const reviewColumns = `
+SELECT q.show_uid, s.summary, s.start, s.description, q.confidence, q.suggestions, q.status,
+  COALESCE(q.team_ids, '{}'), COALESCE(q.aliases, '{}'), q.created_at, q.resolved_at
+FROM review_queue q JOIN shows s ON s.uid = q.show_uid
+`
+
+func scanReview(row pgx.Row) (ReviewItem, error) {
+	var it ReviewItem
+	var sugg []byte
+	err := row.Scan(&it.UID, &it.Summary, &it.Start, &it.Description, &it.Confidence, &sugg, &it.Status,
+		&it.TeamIDs, &it.Aliases, &it.QueuedAt, &it.ResolvedAt)
+	if err != nil {
+		return it, err
+	}
+	if err := json.Unmarshal(sugg, &it.Suggestions); err != nil {
+		return it, fmt.Errorf("review suggestions of %s: %w", it.UID, err)
+	}
+	return it, nil
+}
+
+// GetReview returns the queue entry of the show uid, or nil if it has none.
+func (s *Store) GetReview(ctx context.Context, uid string) (*ReviewItem, error) {
+	it, err := scanReview(s.pool.QueryRow(ctx, reviewColumns+"WHERE q.show_uid = $1", uid))
+	if errors.Is(err, pgx.ErrNoRows) || isUndefinedTable(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &it, nil
+}
+
+// ListReview returns the queue entries with status, soonest show first.
+func (s *Store) ListReview(ctx context.Context, status string) ([]ReviewItem, error) {
+	rows, err := s.pool.Query(ctx, reviewColumns+"WHERE q.status = $1 ORDER BY s.start NULLS LAST, q.show_uid", status)
+	if isUndefinedTable(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ReviewItem
+	for rows.Next() {
+		it, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+// ResolveReview records how staff settled the queue entry of uid: its new
+// status, the teams they gave the show and the aliases saved for them. It
+// reports whether there was an entry.
+func (s *Store) ResolveReview(ctx context.Context, uid, status string, teamIDs, aliases []string) (bool, error) {
+	if err := s.checkWritable(); err != nil {
+		return false, err
+	}
+	const q = `
+UPDATE review_queue
+SET status      = $2,
+    team_ids    = $3,
+    aliases     = $4,
+    resolved_at = NOW(),
+    updated_at  = NOW()
+WHERE show_uid = $1
+`
+	tag, err := s.pool.Exec(ctx, q, uid, status, strSliceToTextArray(teamIDs), strSliceToTextArray(aliases))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
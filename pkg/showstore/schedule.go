@@ -0,0 +1,72 @@
+package showstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ScheduleChange is a show's start or location moving between syncs.
+type ScheduleChange struct {
+	UID   string
+	RunID string // the sync that saw it; may be empty
+	Field string // "start" or "location"
+	Old   string
+	New   string
+	At    time.Time
+
+	// Summary and Start are the show as it is now; filled by
+	// ScheduleChangesSince.
+	Summary string
+	Start   *time.Time
+}
+
+// RecordScheduleChanges appends changes to schedule_changes.
+func (s *Store) RecordScheduleChanges(ctx context.Context, changes []ScheduleChange) error {
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	const q = `
+INSERT INTO schedule_changes (show_uid, run_id, field, old_value, new_value)
+VALUES ($1, NULLIF($2, '')::UUID, $3, $4, $5)
+`
+	var b pgx.Batch
+	for _, c := range changes {
+		b.Queue(q, c.UID, c.RunID, c.Field, c.Old, c.New)
+	}
+	return s.pool.SendBatch(ctx, &b).Close()
+}
+
+// ScheduleChangesSince returns the changes recorded at or after since to
+// shows that haven't started yet, oldest first. A database without the
+// schedule_changes table has none.
+func (s *Store) ScheduleChangesSince(ctx context.Context, since time.Time) ([]ScheduleChange, error) {
+	const q = `
+SELECT c.show_uid, COALESCE(c.run_id::text, ''), c.field, c.old_value, c.new_value, c.changed_at, s.summary, s.start
+FROM schedule_changes c
+JOIN shows s ON s.uid = c.show_uid
+WHERE c.changed_at >= $1 AND s.start >= NOW()
+ORDER BY c.changed_at, c.id
+`
+	rows, err := s.pool.Query(ctx, q, since)
+	if err != nil {
+		if isUndefinedTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ScheduleChange
+	for rows.Next() {
+		var c ScheduleChange
+		if err := rows.Scan(&c.UID, &c.RunID, &c.Field, &c.Old, &c.New, &c.At, &c.Summary, &c.Start); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
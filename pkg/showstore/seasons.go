@@ -0,0 +1,117 @@
+package showstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Season is a named run of shows, such as "Fall Mainstage Run", with its
+// first and last dates. Staff define seasons by hand; `shopsync seasons
+// group` also makes them from recurring series, and marks them with the
+// series' normalized summary so later groupings extend the same season.
+type Season struct {
+	ID     string `json:"id"` // URL slug
+	Name   string `json:"name"`
+	Start  string `json:"start"` // YYYY-MM-DD, venue time
+	End    string `json:"end"`   // YYYY-MM-DD, inclusive
+	Series string `json:"series,omitempty"`
+	Shows  int    `json:"shows"` // member shows still stored
+}
+
+// SaveSeason creates or replaces the season se.ID and makes uids its
+// shows. UIDs that aren't stored shows are skipped.
+func (s *Store) SaveSeason(ctx context.Context, se Season, uids []string) (err error) {
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+	const upsert = `
+INSERT INTO seasons (id, name, starts, ends, series)
+VALUES ($1, $2, $3::date, $4::date, NULLIF($5, ''))
+ON CONFLICT (id) DO UPDATE
+SET name       = EXCLUDED.name,
+    starts     = EXCLUDED.starts,
+    ends       = EXCLUDED.ends,
+    series     = EXCLUDED.series,
+    updated_at = NOW()
+`
+	if _, err = tx.Exec(ctx, upsert, se.ID, se.Name, se.Start, se.End, se.Series); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM season_shows WHERE season_id = $1 AND NOT (show_uid = ANY($2::TEXT[]))`, se.ID, uids); err != nil {
+		return err
+	}
+	const link = `
+INSERT INTO season_shows (season_id, show_uid)
+SELECT $1, uid FROM shows WHERE uid = ANY($2::TEXT[])
+ON CONFLICT DO NOTHING
+`
+	if _, err = tx.Exec(ctx, link, se.ID, uids); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+const seasonColumns = `
+SELECT id, name, to_char(starts, 'YYYY-MM-DD'), to_char(ends, 'YYYY-MM-DD'), COALESCE(series, ''),
+  (SELECT COUNT(*) FROM season_shows ss WHERE ss.season_id = seasons.id)
+FROM seasons
+`
+
+// GetSeason returns the season id, or nil if there is none.
+func (s *Store) GetSeason(ctx context.Context, id string) (*Season, error) {
+	var se Season
+	err := s.pool.QueryRow(ctx, seasonColumns+"WHERE id = $1", id).
+		Scan(&se.ID, &se.Name, &se.Start, &se.End, &se.Series, &se.Shows)
+	if errors.Is(err, pgx.ErrNoRows) || isUndefinedTable(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &se, nil
+}
+
+// ListSeasons returns every season, latest first.
+func (s *Store) ListSeasons(ctx context.Context) ([]Season, error) {
+	rows, err := s.pool.Query(ctx, seasonColumns+"ORDER BY starts DESC, id")
+	if isUndefinedTable(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Season
+	for rows.Next() {
+		var se Season
+		if err := rows.Scan(&se.ID, &se.Name, &se.Start, &se.End, &se.Series, &se.Shows); err != nil {
+			return nil, err
+		}
+		out = append(out, se)
+	}
+	return out, rows.Err()
+}
+
+// DeleteSeason removes the season id, leaving its shows. It reports
+// whether there was one.
+func (s *Store) DeleteSeason(ctx context.Context, id string) (bool, error) {
+	if err := s.checkWritable(); err != nil {
+		return false, err
+	}
+	tag, err := s.pool.Exec(ctx, `DELETE FROM seasons WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
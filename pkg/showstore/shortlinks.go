@@ -0,0 +1,80 @@
+package showstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// shortCodeLen is the length codes start at; a code taken by another show
+// grows by a character.
+const shortCodeLen = 6
+
+var shortCodeEncoding = base32.NewEncoding("abcdefghijkmnpqrstuvwxyz23456789").WithPadding(base32.NoPadding)
+
+// shortCodes are the candidate codes for uid, shortest first. They come
+// from a hash of the UID, so a show gets the same code on every database.
+func shortCodes(uid string) []string {
+	sum := sha256.Sum256([]byte(uid))
+	full := shortCodeEncoding.EncodeToString(sum[:])
+	var out []string
+	for n := shortCodeLen; n <= len(full); n++ {
+		out = append(out, full[:n])
+	}
+	return out
+}
+
+// EnsureShortLink returns the short link code of the show uid, creating
+// it on first use. A code never changes once issued.
+func (s *Store) EnsureShortLink(ctx context.Context, uid string) (string, error) {
+	if err := s.checkWritable(); err != nil {
+		return "", err
+	}
+	if code, err := s.ShortLinkCode(ctx, uid); err != nil || code != "" {
+		return code, err
+	}
+	const q = `
+INSERT INTO short_links (code, show_uid)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+	for _, code := range shortCodes(uid) {
+		if _, err := s.pool.Exec(ctx, q, code, uid); err != nil {
+			return "", err
+		}
+		// Ours, a concurrent caller's for the same show, or another
+		// show's code, in which case try a longer one.
+		got, err := s.ShortLinkCode(ctx, uid)
+		if err != nil || got != "" {
+			return got, err
+		}
+	}
+	return "", errors.New("no free short link code")
+}
+
+// ShortLinkCode returns the short link code of the show uid, or "" if it
+// has none.
+func (s *Store) ShortLinkCode(ctx context.Context, uid string) (string, error) {
+	var code string
+	err := s.pool.QueryRow(ctx, `SELECT code FROM short_links WHERE show_uid = $1`, uid).Scan(&code)
+	if errors.Is(err, pgx.ErrNoRows) || isUndefinedTable(err) {
+		return "", nil
+	}
+	return code, err
+}
+
+// ResolveShortLink returns the show a short link code points at, or "" if
+// the code is unknown. Codes are case-insensitive, since people type them
+// off flyers.
+func (s *Store) ResolveShortLink(ctx context.Context, code string) (string, error) {
+	var uid string
+	err := s.pool.QueryRow(ctx, `SELECT show_uid FROM short_links WHERE code = $1`, strings.ToLower(code)).Scan(&uid)
+	if errors.Is(err, pgx.ErrNoRows) || isUndefinedTable(err) {
+		return "", nil
+	}
+	return uid, err
+}
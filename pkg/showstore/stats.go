@@ -0,0 +1,64 @@
+package showstore
+
+import (
+	"context"
+)
+
+// UpcomingCounts counts shows that haven't started yet.
+type UpcomingCounts struct {
+	Total        int
+	Next7Days    int
+	Next30Days   int
+	MissingImage int
+	WithoutTeams int
+}
+
+// CountUpcoming summarises future shows.
+func (s *Store) CountUpcoming(ctx context.Context) (UpcomingCounts, error) {
+	const q = `
+SELECT
+  COUNT(*),
+  COUNT(*) FILTER (WHERE start < NOW() + INTERVAL '7 days'),
+  COUNT(*) FILTER (WHERE start < NOW() + INTERVAL '30 days'),
+  COUNT(*) FILTER (WHERE post_image_url IS NULL OR post_image_url = ''),
+  COUNT(*) FILTER (WHERE NOT EXISTS (SELECT 1 FROM show_teams st WHERE st.show_uid = shows.uid))
+FROM shows
+WHERE start >= NOW()
+`
+	var c UpcomingCounts
+	err := s.pool.QueryRow(ctx, q).Scan(&c.Total, &c.Next7Days, &c.Next30Days, &c.MissingImage, &c.WithoutTeams)
+	return c, err
+}
+
+// TeamsWithoutUpcomingShows returns teams not linked to any future show.
+func (s *Store) TeamsWithoutUpcomingShows(ctx context.Context) ([]Team, error) {
+	const q = `
+SELECT t.name, t.id
+FROM "Team" t
+WHERE NOT EXISTS (
+  SELECT 1
+  FROM show_teams st
+  JOIN shows sh ON sh.uid = st.show_uid
+  WHERE st.team_id = t.id AND sh.start >= NOW()
+)
+ORDER BY t.name
+`
+	rows, err := s.pool.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Team
+	for rows.Next() {
+		var t Team
+		if err := rows.Scan(&t.Name, &t.ID); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
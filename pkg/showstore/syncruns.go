@@ -0,0 +1,142 @@
+package showstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SyncRun is one recorded ingest run.
+type SyncRun struct {
+	ID           string
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	Sources      []string
+	EventsParsed int
+	Inserted     int
+	Updated      int
+	Unchanged    int
+	Failed       int
+	Error        string
+	// FeedHash identifies what the sources returned (see the ingest
+	// command's unchanged stage); empty if not computed.
+	FeedHash string
+	// IdempotencyKeys are the keys of the POST /sync requests this run
+	// answered.
+	IdempotencyKeys []string
+}
+
+// RecordSyncRun stores a finished run in sync_runs and returns its ID.
+func (s *Store) RecordSyncRun(ctx context.Context, r SyncRun) (string, error) {
+	if err := s.checkWritable(); err != nil {
+		return "", err
+	}
+	const q = `
+INSERT INTO sync_runs (started_at, finished_at, sources, events_parsed, inserted, updated, unchanged, failed, error, feed_hash, idempotency_keys)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), $11)
+RETURNING id::text
+`
+	var keys []string // NULL when there are none
+	if len(r.IdempotencyKeys) > 0 {
+		keys = r.IdempotencyKeys
+	}
+	var id string
+	err := s.pool.QueryRow(ctx, q, r.StartedAt, r.FinishedAt, strSliceToTextArray(r.Sources),
+		r.EventsParsed, r.Inserted, r.Updated, r.Unchanged, r.Failed, r.Error, r.FeedHash, keys).Scan(&id)
+	return id, err
+}
+
+// SyncRunForIdempotencyKey returns the ID of the run that answered the
+// POST /sync request with key, or "" if none did.
+func (s *Store) SyncRunForIdempotencyKey(ctx context.Context, key string) (string, error) {
+	const q = `
+SELECT id::text
+FROM sync_runs
+WHERE $1 = ANY(idempotency_keys)
+ORDER BY started_at DESC
+LIMIT 1
+`
+	var id string
+	err := s.pool.QueryRow(ctx, q, key).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) || isUndefinedTable(err) {
+		return "", nil
+	}
+	return id, err
+}
+
+// LastFeedHash returns the FeedHash of the newest run that finished
+// without errors or failed events, or "" if there is none.
+func (s *Store) LastFeedHash(ctx context.Context) (string, error) {
+	const q = `
+SELECT COALESCE(feed_hash, '')
+FROM sync_runs
+WHERE error IS NULL AND failed = 0
+ORDER BY started_at DESC
+LIMIT 1
+`
+	var h string
+	err := s.pool.QueryRow(ctx, q).Scan(&h)
+	if errors.Is(err, pgx.ErrNoRows) || isUndefinedTable(err) {
+		return "", nil
+	}
+	return h, err
+}
+
+// RecentSyncRuns returns up to limit runs, newest first. A database without
+// the sync_runs table has no history.
+func (s *Store) RecentSyncRuns(ctx context.Context, limit int) ([]SyncRun, error) {
+	const q = `
+SELECT id::text, started_at, finished_at, sources, events_parsed, inserted, updated, unchanged, failed, COALESCE(error, '')
+FROM sync_runs
+ORDER BY started_at DESC
+LIMIT $1
+`
+	rows, err := s.pool.Query(ctx, q, limit)
+	if err != nil {
+		if isUndefinedTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SyncRun
+	for rows.Next() {
+		var r SyncRun
+		if err := rows.Scan(&r.ID, &r.StartedAt, &r.FinishedAt, &r.Sources, &r.EventsParsed,
+			&r.Inserted, &r.Updated, &r.Unchanged, &r.Failed, &r.Error); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if rows.Err() != nil {
+		if isUndefinedTable(rows.Err()) {
+			return nil, nil
+		}
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// LastSuccessfulSync returns when the newest error-free run finished, or the
+// zero time if there is none.
+func (s *Store) LastSuccessfulSync(ctx context.Context) (time.Time, error) {
+	const q = `
+SELECT finished_at
+FROM sync_runs
+WHERE error IS NULL
+ORDER BY finished_at DESC
+LIMIT 1
+`
+	var t time.Time
+	err := s.pool.QueryRow(ctx, q).Scan(&t)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || isUndefinedTable(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return t, nil
+}
@@ -0,0 +1,99 @@
+package showstore
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// CalendarToken is one private calendar subscriber. Only a hash of the
+// token itself is stored.
+type CalendarToken struct {
+	Name      string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateCalendarToken issues a token for the subscriber name and returns
+// it. This is the only time the token is available in clear.
+func (s *Store) CreateCalendarToken(ctx context.Context, name string) (string, error) {
+	if err := s.checkWritable(); err != nil {
+		return "", err
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	const q = `
+INSERT INTO calendar_tokens (token_hash, name)
+VALUES ($1, $2)
+`
+	if _, err := s.pool.Exec(ctx, q, hashToken(token), name); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ValidCalendarToken reports whether token was issued and not revoked. A
+// database without calendar_tokens has no valid tokens.
+func (s *Store) ValidCalendarToken(ctx context.Context, token string) (bool, error) {
+	const q = `
+SELECT EXISTS (SELECT 1 FROM calendar_tokens WHERE token_hash = $1 AND revoked_at IS NULL)
+`
+	var ok bool
+	if err := s.pool.QueryRow(ctx, q, hashToken(token)).Scan(&ok); err != nil {
+		if isUndefinedTable(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return ok, nil
+}
+
+// ListCalendarTokens returns every token ever issued, oldest first.
+func (s *Store) ListCalendarTokens(ctx context.Context) ([]CalendarToken, error) {
+	const q = `
+SELECT name, created_at, revoked_at
+FROM calendar_tokens
+ORDER BY created_at
+`
+	rows, err := s.pool.Query(ctx, q)
+	if err != nil {
+		if isUndefinedTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+	var out []CalendarToken
+	for rows.Next() {
+		var t CalendarToken
+		if err := rows.Scan(&t.Name, &t.CreatedAt, &t.RevokedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// RevokeCalendarTokens revokes every live token issued to name and returns
+// how many there were.
+func (s *Store) RevokeCalendarTokens(ctx context.Context, name string) (int64, error) {
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+	const q = `
+UPDATE calendar_tokens SET revoked_at = NOW()
+WHERE name = $1 AND revoked_at IS NULL
+`
+	tag, err := s.pool.Exec(ctx, q, name)
+	return tag.RowsAffected(), err
+}
@@ -1,6 +1,7 @@
 package showstore
 
 type Team struct {
-	Name string
-	ID   string
+	Name    string
+	ID      string
+	Aliases []string
 }
@@ -0,0 +1,23 @@
+package source
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	Register("gcal", func(spec string) (Source, error) {
+		id := strings.TrimPrefix(strings.TrimPrefix(spec[len("gcal:"):], "//"), "/")
+		if id == "" {
+			return nil, errors.New("want gcal:<calendar ID>")
+		}
+		return GoogleCalendar(id), nil
+	})
+}
+
+// GoogleCalendar returns the public ICS feed of a Google Calendar, given its
+// ID (e.g. abc123@group.calendar.google.com).
+func GoogleCalendar(id string) *ICSURL {
+	return &ICSURL{URL: "https://calendar.google.com/calendar/ical/" + url.PathEscape(id) + "/public/basic.ics"}
+}
@@ -0,0 +1,74 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+func init() {
+	Register("http", openICSURL)
+	Register("https", openICSURL)
+	Register("webcal", openICSURL)
+	Register("file", func(spec string) (Source, error) {
+		u, err := url.Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		return ICSFile(u.Path), nil
+	})
+}
+
+// ICSFile reads an .ics file; "-" is stdin.
+type ICSFile string
+
+func (f ICSFile) Fetch(ctx context.Context) ([]icalplayers.Event, error) {
+	if f == "-" {
+		return icalplayers.FromReaderContext(ctx, os.Stdin, nil, icalplayers.SkipImages())
+	}
+	return icalplayers.FromFile(string(f), nil, icalplayers.SkipImages())
+}
+
+// ICSURL fetches an .ics feed over HTTP, conditionally on its ETag and
+// Last-Modified headers.
+type ICSURL struct {
+	URL    string
+	Client *http.Client // nil for httpclient.Default
+}
+
+func openICSURL(spec string) (Source, error) {
+	if rest, ok := strings.CutPrefix(spec, "webcal:"); ok {
+		spec = "https:" + rest
+	}
+	return &ICSURL{URL: spec}, nil
+}
+
+func (s *ICSURL) Fetch(ctx context.Context) ([]icalplayers.Event, error) {
+	return icalplayers.FromURL(ctx, s.URL, s.client(), nil, icalplayers.SkipImages())
+}
+
+// FetchSince's token carries the feed's ETag and Last-Modified.
+func (s *ICSURL) FetchSince(ctx context.Context, token string) ([]icalplayers.Event, string, error) {
+	etag, lastMod, _ := strings.Cut(token, "\n")
+	evs, next, err := icalplayers.FromURLConditional(ctx, s.URL, s.client(), nil, icalplayers.Validators{ETag: etag, LastModified: lastMod}, icalplayers.SkipImages())
+	if errors.Is(err, icalplayers.ErrNotModified) {
+		return nil, token, ErrNotModified
+	}
+	if err != nil {
+		return nil, token, err
+	}
+	return evs, next.ETag + "\n" + next.LastModified, nil
+}
+
+func (s *ICSURL) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return httpclient.Default
+}
@@ -0,0 +1,59 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/tsny/shopsync/pkg/eventbrite"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/squarespace"
+	"github.com/tsny/shopsync/pkg/wpevents"
+)
+
+// EventbriteToken authenticates eventbrite: sources.
+var EventbriteToken string
+
+func init() {
+	Register("wp+http", openWordPress)
+	Register("wp+https", openWordPress)
+	Register("squarespace+http", openSquarespace)
+	Register("squarespace+https", openSquarespace)
+	Register("eventbrite", func(spec string) (Source, error) {
+		org := strings.TrimPrefix(spec[len("eventbrite:"):], "//")
+		if org == "" {
+			return nil, errors.New("want eventbrite:<organizer ID>")
+		}
+		return Eventbrite(org), nil
+	})
+}
+
+// WordPress reads The Events Calendar's tribe/events/v1/events API.
+type WordPress string
+
+func openWordPress(spec string) (Source, error) {
+	return WordPress(strings.TrimPrefix(spec, "wp+")), nil
+}
+
+func (w WordPress) Fetch(ctx context.Context) ([]icalplayers.Event, error) {
+	return wpevents.FetchAll(ctx, string(w))
+}
+
+// Squarespace reads a Squarespace events page through its ?format=json view.
+type Squarespace string
+
+func openSquarespace(spec string) (Source, error) {
+	return Squarespace(strings.TrimPrefix(spec, "squarespace+")), nil
+}
+
+func (s Squarespace) Fetch(ctx context.Context) ([]icalplayers.Event, error) {
+	return squarespace.Fetch(ctx, string(s))
+}
+
+// Eventbrite reads an organizer's live events, authenticated with
+// EventbriteToken.
+type Eventbrite string
+
+func (e Eventbrite) Fetch(ctx context.Context) ([]icalplayers.Event, error) {
+	return eventbrite.FetchOrganizer(ctx, EventbriteToken, string(e))
+}
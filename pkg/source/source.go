@@ -0,0 +1,83 @@
+// Package source defines the interface every calendar backend implements
+// and a registry that picks one from a -src value by its URL scheme, so a
+// new backend only has to register itself.
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// Source is one feed of events.
+type Source interface {
+	// Fetch returns every event the source currently lists. Post images
+	// are left to the caller (icalplayers.EnrichImages).
+	Fetch(ctx context.Context) ([]icalplayers.Event, error)
+}
+
+// Conditional is implemented by sources that can tell cheaply whether
+// anything changed since an earlier fetch. The token is opaque to callers;
+// pass "" the first time.
+type Conditional interface {
+	Source
+	// FetchSince is Fetch, except that when nothing changed since the
+	// fetch that returned token it returns ErrNotModified and token.
+	FetchSince(ctx context.Context, token string) ([]icalplayers.Event, string, error)
+}
+
+// ErrNotModified is returned by FetchSince when the source is unchanged.
+var ErrNotModified = errors.New("source not modified")
+
+// Factory builds a source from a -src value whose scheme it was registered
+// for. spec is the whole value, scheme included.
+type Factory func(spec string) (Source, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+// Register makes Open use f for specs starting with scheme + ":". It panics
+// if the scheme is taken, like http.Handle does for a pattern.
+func Register(scheme string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	scheme = strings.ToLower(scheme)
+	if _, dup := factories[scheme]; dup {
+		panic("source: scheme " + scheme + " registered twice")
+	}
+	factories[scheme] = f
+}
+
+// Schemes lists the registered schemes, sorted.
+func Schemes() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return slices.Sorted(maps.Keys(factories))
+}
+
+// Open returns the source for spec. A spec without a registered scheme,
+// including "-" for stdin and Windows-style paths, is an ICS file.
+func Open(spec string) (Source, error) {
+	scheme, _, ok := strings.Cut(spec, ":")
+	if ok {
+		mu.RLock()
+		f := factories[strings.ToLower(scheme)]
+		mu.RUnlock()
+		if f != nil {
+			s, err := f(spec)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", spec, err)
+			}
+			return s, nil
+		}
+	}
+	return ICSFile(spec), nil
+}
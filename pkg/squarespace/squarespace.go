@@ -0,0 +1,172 @@
+// Package squarespace reads a Squarespace events collection through its
+// ?format=json view and converts the items to icalplayers.Event.
+package squarespace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// ErrDecode wraps errors from decoding a collection's JSON.
+var ErrDecode = errors.New("decode")
+
+// maxPages bounds pagination in case a site keeps handing out next pages.
+const maxPages = 20
+
+// item mirrors the fields of a Squarespace event item we use.
+type item struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Body       string   `json:"body"`
+	Excerpt    string   `json:"excerpt"`
+	FullURL    string   `json:"fullUrl"`
+	AssetURL   string   `json:"assetUrl"`
+	StartDate  int64    `json:"startDate"` // Unix milliseconds
+	EndDate    int64    `json:"endDate"`
+	Tags       []string `json:"tags"`
+	Categories []string `json:"categories"`
+	Location   struct {
+		AddressTitle string `json:"addressTitle"`
+		AddressLine1 string `json:"addressLine1"`
+		AddressLine2 string `json:"addressLine2"`
+	} `json:"location"`
+}
+
+type page struct {
+	Upcoming   []item `json:"upcoming"`
+	Items      []item `json:"items"`
+	Pagination struct {
+		NextPage    bool   `json:"nextPage"`
+		NextPageURL string `json:"nextPageUrl"`
+	} `json:"pagination"`
+}
+
+var (
+	htmlTagRe = regexp.MustCompile(`<[^>]+>`)
+	blockRe   = regexp.MustCompile(`(?i)</?(p|br|div|li|h[1-6])[^>]*>`)
+)
+
+// Fetch returns the upcoming events of the collection at pageURL (e.g.
+// https://example.org/shows), following pagination.
+func Fetch(ctx context.Context, pageURL string) ([]icalplayers.Event, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	var all []icalplayers.Event
+	next := base
+	for n := 1; next != nil && n <= maxPages; n++ {
+		q := next.Query()
+		q.Set("format", "json")
+		next.RawQuery = q.Encode()
+		slog.Debug("fetching Squarespace events page", "page", n, "url", next.String())
+		p, err := fetchPage(ctx, next.String())
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", n, err)
+		}
+		items := p.Upcoming
+		if items == nil {
+			items = p.Items
+		}
+		for _, it := range items {
+			all = append(all, convert(it, base))
+		}
+		next = nil
+		if p.Pagination.NextPage && p.Pagination.NextPageURL != "" {
+			if next, err = base.Parse(p.Pagination.NextPageURL); err != nil {
+				return nil, fmt.Errorf("next page: %w", err)
+			}
+		}
+	}
+	slog.Info("fetched Squarespace events", "url", pageURL, "count", len(all))
+	return all, nil
+}
+
+func fetchPage(ctx context.Context, u string) (*page, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpclient.Default.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("http status %d", resp.StatusCode)
+	}
+
+	var p page
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	return &p, nil
+}
+
+func convert(it item, base *url.URL) icalplayers.Event {
+	desc := stripHTML(it.Body)
+	if desc == "" {
+		desc = stripHTML(it.Excerpt)
+	}
+	ev := icalplayers.Event{
+		UID:          "sqsp-" + it.ID,
+		Summary:      html.UnescapeString(strings.TrimSpace(it.Title)),
+		Description:  desc,
+		PostImageURL: it.AssetURL,
+		Start:        millis(it.StartDate),
+		End:          millis(it.EndDate),
+		Players:      icalplayers.InferPlayerNames(desc, nil),
+		Categories:   append(append([]string(nil), it.Categories...), it.Tags...),
+	}
+	if it.FullURL != "" {
+		if u, err := base.Parse(it.FullURL); err == nil {
+			ev.URL = u.String()
+		}
+	}
+	var loc []string
+	for _, p := range []string{it.Location.AddressTitle, it.Location.AddressLine1, it.Location.AddressLine2} {
+		if p = strings.TrimSpace(p); p != "" {
+			loc = append(loc, p)
+		}
+	}
+	ev.Location = strings.Join(loc, ", ")
+	return ev
+}
+
+// stripHTML turns block tags into line breaks, so player cue lines survive,
+// then drops the remaining markup.
+func stripHTML(s string) string {
+	s = blockRe.ReplaceAllString(s, "\n")
+	s = htmlTagRe.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	var lines []string
+	for _, ln := range strings.Split(s, "\n") {
+		if ln = strings.TrimSpace(ln); ln != "" {
+			lines = append(lines, ln)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func millis(ms int64) *time.Time {
+	if ms == 0 {
+		return nil
+	}
+	t := time.UnixMilli(ms)
+	return &t
+}
@@ -0,0 +1,78 @@
+// Package webhooksig signs and verifies shopsync's outbound webhook
+// bodies. The signature is "sha256=" + hex(HMAC-SHA256(secret, timestamp +
+// "." + body)), sent in SignatureHeader, with the Unix timestamp in
+// TimestampHeader. Receivers written in Go can call Verify; others can
+// follow the same recipe.
+package webhooksig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The headers a signed delivery carries.
+const (
+	SignatureHeader = "X-Shopsync-Signature"
+	TimestampHeader = "X-Shopsync-Timestamp"
+)
+
+// DefaultWindow is how far a delivery's timestamp may be from the
+// receiver's clock before Verify treats it as a replay.
+const DefaultWindow = 5 * time.Minute
+
+var (
+	ErrUnsigned  = errors.New("webhooksig: delivery is not signed")
+	ErrTimestamp = errors.New("webhooksig: timestamp outside the replay window")
+	ErrSignature = errors.New("webhooksig: signature does not match")
+)
+
+// Sign returns the SignatureHeader value for body sent at ts.
+func Sign(secret string, ts time.Time, body []byte) string {
+	return "sha256=" + mac(secret, strconv.FormatInt(ts.Unix(), 10), body)
+}
+
+// SetHeaders signs body as sent now and sets both headers on h.
+func SetHeaders(h http.Header, secret string, now time.Time, body []byte) {
+	h.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	h.Set(SignatureHeader, Sign(secret, now, body))
+}
+
+// Verify checks a delivery's headers against body. The timestamp must be
+// within window of now (DefaultWindow if window is zero). A receiver that
+// must not act on a delivery twice should also remember signatures it has
+// accepted for window: a replay inside it carries the same one.
+func Verify(secret string, h http.Header, body []byte, now time.Time, window time.Duration) error {
+	sig, tsHeader := h.Get(SignatureHeader), h.Get(TimestampHeader)
+	if sig == "" || tsHeader == "" {
+		return ErrUnsigned
+	}
+	ts, err := strconv.ParseInt(tsHeader, 10, 64)
+	if err != nil {
+		return ErrTimestamp
+	}
+	if window == 0 {
+		window = DefaultWindow
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > window || d < -window {
+		return ErrTimestamp
+	}
+	want := mac(secret, tsHeader, body)
+	got, ok := strings.CutPrefix(sig, "sha256=")
+	if !ok || !hmac.Equal([]byte(got), []byte(want)) {
+		return ErrSignature
+	}
+	return nil
+}
+
+func mac(secret, ts string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(ts + "."))
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
@@ -5,29 +5,74 @@ package wpevents
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
+	"log/slog"
 	"net/http"
 	"os"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/tsny/shopsync/pkg/httpclient"
 	"github.com/tsny/shopsync/pkg/icalplayers"
 )
 
 // wpEvent mirrors the relevant fields from the tribe/events/v1/events API response.
 type wpEvent struct {
-	ID          int    `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	URL         string `json:"url"`
-	StartDate   string `json:"start_date"`
-	EndDate     string `json:"end_date"`
-	Timezone    string `json:"timezone"`
-	Image       struct {
-		URL string `json:"url"`
-	} `json:"image"`
+	ID          int               `json:"id"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	URL         string            `json:"url"`
+	StartDate   string            `json:"start_date"`
+	EndDate     string            `json:"end_date"`
+	Timezone    string            `json:"timezone"`
+	AllDay      bool              `json:"all_day"`
+	ModifiedUTC string            `json:"modified_utc"`
+	Cost        string            `json:"cost"`
+	Image       optional[wpImage] `json:"image"`
+	Venue       optional[wpVenue] `json:"venue"`
+	Organizer   []wpOrganizer     `json:"organizer"`
+	Categories  []wpCategory      `json:"categories"`
+}
+
+type wpImage struct {
+	URL string `json:"url"`
+}
+
+type wpVenue struct {
+	Venue   string `json:"venue"`
+	Address string `json:"address"`
+	City    string `json:"city"`
+	State   string `json:"state"`
+	Zip     string `json:"zip"`
+}
+
+type wpOrganizer struct {
+	Organizer string `json:"organizer"`
+}
+
+type wpCategory struct {
+	Name string `json:"name"`
+}
+
+// optional decodes a JSON object into V and ignores anything else. The
+// API sends false or [] instead of null for a missing image or venue.
+type optional[V any] struct {
+	V  V
+	OK bool
+}
+
+func (o *optional[V]) UnmarshalJSON(b []byte) error {
+	if len(b) == 0 || b[0] != '{' {
+		return nil
+	}
+	if err := json.Unmarshal(b, &o.V); err != nil {
+		return err
+	}
+	o.OK = true
+	return nil
 }
 
 type apiResponse struct {
@@ -36,6 +81,9 @@ type apiResponse struct {
 	Total       int       `json:"total"`
 }
 
+// ErrDecode wraps errors from decoding an API response body.
+var ErrDecode = errors.New("decode")
+
 var htmlTagRe = regexp.MustCompile(`<[^>]+>`)
 
 // cdnSizeRe matches the trailing /w=NNN,h=NNN (or h=NNN,w=NNN) segment on
@@ -65,7 +113,7 @@ func FetchAll(ctx context.Context, baseURL string) ([]icalplayers.Event, error)
 	page := 1
 
 	for nextURL != "" {
-		fmt.Printf("Fetching WP events page %d: %s\n", page, nextURL)
+		slog.Debug("fetching WP events page", "page", page, "url", nextURL)
 		resp, err := fetchPage(ctx, nextURL)
 		if err != nil {
 			return nil, fmt.Errorf("page %d: %w", page, err)
@@ -77,7 +125,7 @@ func FetchAll(ctx context.Context, baseURL string) ([]icalplayers.Event, error)
 		page++
 	}
 
-	fmt.Printf("Fetched %d WP events total.\n", len(all))
+	slog.Info("fetched WP events", "count", len(all))
 	return all, nil
 }
 
@@ -112,9 +160,8 @@ func fetchPage(ctx context.Context, url string) (*apiResponse, error) {
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", "shopsync/1.0")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Default.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +173,7 @@ func fetchPage(ctx context.Context, url string) (*apiResponse, error) {
 
 	var result apiResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("decode: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrDecode, err)
 	}
 	return &result, nil
 }
@@ -144,13 +191,50 @@ func convert(e wpEvent) icalplayers.Event {
 		}
 	}
 
-	return icalplayers.Event{
+	desc := stripHTML(e.Description)
+	ev := icalplayers.Event{
 		UID:          uid,
 		Summary:      html.UnescapeString(e.Title),
-		Description:  stripHTML(e.Description),
+		Description:  desc,
 		URL:          e.URL,
-		PostImageURL: e.Image.URL,
+		PostImageURL: e.Image.V.URL,
 		Start:        start,
 		End:          end,
+		AllDay:       e.AllDay,
+		Players:      icalplayers.InferPlayerNames(desc, nil),
+	}
+	if t, err := time.Parse("2006-01-02 15:04:05", e.ModifiedUTC); err == nil {
+		ev.Modified = &t
+	}
+	if cost := html.UnescapeString(strings.TrimSpace(e.Cost)); cost != "" {
+		ev.Details = &icalplayers.Details{Price: cost}
+	}
+	if e.Venue.OK {
+		ev.Location = venueString(e.Venue.V)
+	}
+	var orgs []string
+	for _, o := range e.Organizer {
+		if name := html.UnescapeString(strings.TrimSpace(o.Organizer)); name != "" {
+			orgs = append(orgs, name)
+		}
+	}
+	ev.Organizer = strings.Join(orgs, ", ")
+	for _, c := range e.Categories {
+		if name := html.UnescapeString(strings.TrimSpace(c.Name)); name != "" {
+			ev.Categories = append(ev.Categories, name)
+		}
+	}
+	return ev
+}
+
+// venueString formats a venue like the ICS export's LOCATION: name, then
+// whatever address parts are filled in.
+func venueString(v wpVenue) string {
+	var parts []string
+	for _, p := range []string{v.Venue, v.Address, v.City, strings.TrimSpace(v.State + " " + v.Zip)} {
+		if p = html.UnescapeString(strings.TrimSpace(p)); p != "" {
+			parts = append(parts, p)
+		}
 	}
+	return strings.Join(parts, ", ")
 }
@@ -0,0 +1,126 @@
+package wpimg
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// DetailFields are the page details Fetch looks for.
+var DetailFields = []string{"price", "doors", "ages", "lineup"}
+
+// DetailSelectors find page details by field (see DetailFields). The text
+// of every match is joined with newlines. Fields without a selector, or
+// whose selector matches nothing, fall back to spotting lines like
+// "Tickets: $10", "Doors 7:30pm", "18+" or "Lineup: …" in ContentSelector.
+var DetailSelectors = map[string]string{}
+
+// SetDetailSelector sets DetailSelectors[field] after checking the field
+// and the selector.
+func SetDetailSelector(field, sel string) error {
+	if !slices.Contains(DetailFields, field) {
+		return fmt.Errorf("unknown field %q (want %s)", field, strings.Join(DetailFields, ", "))
+	}
+	if _, err := cascadia.Compile(sel); err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+	DetailSelectors[field] = sel
+	return nil
+}
+
+// ContentSelector is the part of the page searched for details that have
+// no selector; the whole body if it matches nothing.
+var ContentSelector = ".entry-content, .tribe-events-single-event-description, article"
+
+var (
+	priceRe  = regexp.MustCompile(`(?i)(?:\bfree(?: admission| show)?\b|(?:\$|£|€)\s?\d+(?:\.\d{2})?(?:\s?[-–]\s?(?:\$|£|€)?\s?\d+(?:\.\d{2})?)?)`)
+	priceCue = regexp.MustCompile(`(?i)\b(?:tickets?|price|cost|admission|cover)\b`)
+	doorsRe  = regexp.MustCompile(`(?i)\bdoors?(?:\s+open)?(?:\s+at)?\s*[:@-]?\s*(\d{1,2}(?::\d{2})?\s*(?:[ap]\.?m\.?)?)`)
+	agesRe   = regexp.MustCompile(`(?i)\b(?:all[ -]ages|family[ -]friendly|(?:1[68]|21)\s?\+|(?:1[68]|21) and (?:over|up))`)
+	lineupRe = regexp.MustCompile(`(?i)^\s*(?:line-?up|featuring|starring|performers?|cast|with)\s*:\s*(.+)$`)
+)
+
+// details reads the DetailFields from doc. Only fields with a value are
+// returned.
+func details(doc *goquery.Document) map[string]string {
+	out := map[string]string{}
+	var lines []string
+	for _, f := range DetailFields {
+		if sel := DetailSelectors[f]; sel != "" {
+			var parts []string
+			doc.Find(sel).Each(func(_ int, s *goquery.Selection) {
+				if t := collapseSpace(s.Text()); t != "" {
+					parts = append(parts, t)
+				}
+			})
+			if len(parts) > 0 {
+				out[f] = strings.Join(parts, "\n")
+				continue
+			}
+		}
+		if lines == nil {
+			lines = contentLines(doc)
+		}
+		if v := guessDetail(f, lines); v != "" {
+			out[f] = v
+		}
+	}
+	return out
+}
+
+// guessDetail finds field in the page's text lines.
+func guessDetail(field string, lines []string) string {
+	for _, l := range lines {
+		switch field {
+		case "price":
+			// A bare "$10" could be anything; want a line about tickets,
+			// or one that is nothing but a price.
+			if m := priceRe.FindString(l); m != "" && (priceCue.MatchString(l) || len(l) <= len(m)+2) {
+				return strings.TrimSpace(m)
+			}
+		case "doors":
+			if m := doorsRe.FindStringSubmatch(l); m != nil {
+				return strings.TrimSpace(m[1])
+			}
+		case "ages":
+			if m := agesRe.FindString(l); m != "" {
+				return collapseSpace(m)
+			}
+		case "lineup":
+			if m := lineupRe.FindStringSubmatch(l); m != nil {
+				return strings.TrimSpace(m[1])
+			}
+		}
+	}
+	return ""
+}
+
+// contentLines is the text of ContentSelector (or the body), one entry
+// per block element or line break, whitespace collapsed.
+func contentLines(doc *goquery.Document) []string {
+	root := doc.Find(ContentSelector).First()
+	if root.Length() == 0 {
+		root = doc.Find("body")
+	}
+	root = root.Clone()
+	root.Find("script, style, noscript").Remove()
+	root.Find("br").ReplaceWithHtml("\n")
+	root.Find("p, li, div, h1, h2, h3, h4, h5, h6, tr, dt, dd").Each(func(_ int, s *goquery.Selection) {
+		s.AppendHtml("\n")
+	})
+	var out []string
+	for _, l := range strings.Split(root.Text(), "\n") {
+		if l = collapseSpace(l); l != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -1,4 +1,6 @@
-// Package wpimg downloads the first <img class="wp-post-image"> from a page.
+// Package wpimg downloads the first <img class="wp-post-image"> (or
+// PostImageSelector) from a page, and reads the ticketing and lineup
+// details around it (see DetailSelectors).
 package wpimg
 
 import (
@@ -14,18 +16,29 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/tsny/shopsync/pkg/httpclient"
 )
 
+// PostImageSelector finds a page's post image; the first match is used.
+// Sites not on the stock WordPress theme can point it elsewhere.
+var PostImageSelector = "img.wp-post-image"
+
+// ErrNoImage is returned (wrapped) by Fetch when the page has no post
+// image; the Result still carries the page's details.
+var ErrNoImage = errors.New("no post image")
+
 // Result describes the saved image.
 type Result struct {
 	ImageURL  string // absolute image URL
 	LocalPath string // file path where the image was saved
 	PageURL   *url.URL
+	// Details are the page's DetailFields that were found, by field.
+	Details map[string]string
 }
 
+// Fetch reads pageURL for its post image and details.
 func Fetch(ctx context.Context, pageURL string) (Result, error) {
 	var out Result
 
@@ -36,18 +49,12 @@ func Fetch(ctx context.Context, pageURL string) (Result, error) {
 
 	out.PageURL = u
 
-	client := &http.Client{
-		Timeout: 20 * time.Second,
-		// Follow redirects; default CheckRedirect is fine.
-	}
-
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return out, err
 	}
-	req.Header.Set("User-Agent", "wpimg/1.0 (+https://example.com)")
 
-	resp, err := client.Do(req)
+	resp, err := httpclient.Default.Do(req)
 	if err != nil {
 		return out, fmt.Errorf("get page: %w", err)
 	}
@@ -62,9 +69,11 @@ func Fetch(ctx context.Context, pageURL string) (Result, error) {
 		return out, fmt.Errorf("parse HTML: %w", err)
 	}
 
-	sel := doc.Find("img.wp-post-image").First()
+	out.Details = details(doc)
+
+	sel := doc.Find(PostImageSelector).First()
 	if sel.Length() == 0 {
-		return out, errors.New("no <img class=\"wp-post-image\"> found")
+		return out, fmt.Errorf("%w: no %s found", ErrNoImage, PostImageSelector)
 	}
 
 	// Try common attributes in order of preference.
@@ -76,7 +85,7 @@ func Fetch(ctx context.Context, pageURL string) (Result, error) {
 		imgSrc = firstNonEmptyAttr(sel, "data-src", "data-original", "data-lazy-src")
 	}
 	if imgSrc == "" {
-		return out, errors.New("wp-post-image has no usable src/srcset/data-src")
+		return out, errors.New("post image has no usable src/srcset/data-src")
 	}
 
 	imgURL, err := u.Parse(imgSrc)
@@ -100,12 +109,7 @@ func FetchAndSave(ctx context.Context, pageURL, destDir string) (Result, error)
 	if err != nil {
 		return out, err
 	}
-	imgReq.Header.Set("User-Agent", "wpimg/1.0 (+https://example.com)")
-	client := &http.Client{
-		Timeout: 20 * time.Second,
-		// Follow redirects; default CheckRedirect is fine.
-	}
-	imgResp, err := client.Do(imgReq)
+	imgResp, err := httpclient.Default.Do(imgReq)
 	if err != nil {
 		return out, fmt.Errorf("get image: %w", err)
 	}
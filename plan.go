@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// fieldChange is one column that a sync would rewrite.
+type fieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// plannedChange describes what a sync would do to one show.
+type plannedChange struct {
+	Action  string        `json:"action"` // insert, update or unchanged
+	UID     string        `json:"uid"`
+	Summary string        `json:"summary"`
+	Start   *time.Time    `json:"start,omitempty"`
+	Fields  []fieldChange `json:"fields,omitempty"`
+	// version is the stored row's version the diff was made against; the
+	// upsert only applies while the row is still at it. 0 for inserts.
+	version int64
+}
+
+// planChanges compares events with what is stored, using the same matching
+// rules the real write path uses: date+summary for WordPress sources (which
+// only ever add teams and set images), UID for ICS upserts.
+func (in *ingester) planChanges(ctx context.Context, events []icalplayers.Event) ([]plannedChange, error) {
+	merge := in.opts.wpURL != "" || in.opts.wpCache != ""
+	var out []plannedChange
+	for _, e := range events {
+		pc := plannedChange{UID: e.UID, Summary: e.Summary, Start: e.Start}
+		var (
+			existing *icalplayers.Event
+			err      error
+		)
+		if merge {
+			existing, err = in.store.FindByDateAndSummary(ctx, e.Start, e.Summary)
+		} else {
+			existing, err = in.store.GetShow(ctx, e.UID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			if e, err = in.resolveEdits(ctx, *existing, e); err != nil {
+				return nil, err
+			}
+		}
+		switch {
+		case existing == nil:
+			pc.Action = "insert"
+		case merge:
+			pc.UID = existing.UID
+			pc.Fields = mergeFieldChanges(*existing, e)
+		default:
+			pc.Fields = upsertFieldChanges(*existing, e)
+			pc.version = existing.Version
+		}
+		if pc.Action == "" {
+			pc.Action = "unchanged"
+			if len(pc.Fields) > 0 {
+				pc.Action = "update"
+			}
+		}
+		out = append(out, pc)
+	}
+	return out, nil
+}
+
+// mergeFieldChanges mirrors UpdateDescriptionAndTeams + UpdateShowImageURL:
+// teams are unioned and the image is only replaced by a non-empty one.
+func mergeFieldChanges(old, e icalplayers.Event) []fieldChange {
+	var fc []fieldChange
+	if old.Description != e.Description {
+		fc = append(fc, fieldChange{"description", old.Description, e.Description})
+	}
+	if !teamsEqualSorted(old.Teams, e.Teams) {
+		union := append([]string(nil), old.Teams...)
+		for _, t := range e.Teams {
+			if !slices.Contains(union, t) {
+				union = append(union, t)
+			}
+		}
+		if !teamsEqualSorted(old.Teams, union) {
+			fc = append(fc, fieldChange{"teams", joinList(old.Teams), joinList(union)})
+		}
+	}
+	if e.PostImageURL != "" && old.PostImageURL != e.PostImageURL {
+		fc = append(fc, fieldChange{"post_image_url", old.PostImageURL, e.PostImageURL})
+	}
+	if e.Details != nil && !icalplayers.SameDetails(old.Details, e.Details) {
+		fc = append(fc, fieldChange{"details", formatDetails(old.Details), formatDetails(e.Details)})
+	}
+	return fc
+}
+
+// upsertFieldChanges mirrors Store.Upsert, which overwrites every column
+// but keeps stored details when the event has none.
+func upsertFieldChanges(old, e icalplayers.Event) []fieldChange {
+	var fc []fieldChange
+	add := func(field, a, b string) {
+		if a != b {
+			fc = append(fc, fieldChange{field, a, b})
+		}
+	}
+	add("summary", old.Summary, e.Summary)
+	add("description", old.Description, e.Description)
+	add("url", old.URL, e.URL)
+	add("location", old.Location, e.Location)
+	add("post_image_url", old.PostImageURL, e.PostImageURL)
+	add("start", formatTime(old.Start), formatTime(e.Start))
+	if isShow(old) != isShow(e) || !isShow(e) {
+		add("event_type", old.EventType, e.EventType)
+	}
+	if e.Details != nil {
+		add("details", formatDetails(old.Details), formatDetails(e.Details))
+	}
+	if !teamsEqualSorted(nonEmpty(old.Players), nonEmpty(e.Players)) {
+		fc = append(fc, fieldChange{"players", joinList(old.Players), joinList(e.Players)})
+	}
+	if !teamsEqualSorted(nonEmpty(old.Teams), nonEmpty(e.Teams)) {
+		fc = append(fc, fieldChange{"teams", joinList(old.Teams), joinList(e.Teams)})
+	}
+	return fc
+}
+
+// printPlan writes a human-readable change list.
+func printPlan(w io.Writer, plan []plannedChange) {
+	var inserts, updates, unchanged int
+	for _, pc := range plan {
+		switch pc.Action {
+		case "insert":
+			inserts++
+		case "update":
+			updates++
+		default:
+			unchanged++
+			continue
+		}
+		printPlannedChange(w, pc)
+	}
+	fmt.Fprintf(w, "Plan: %d to insert, %d to update, %d unchanged. Ingest never deletes shows.\n", inserts, updates, unchanged)
+}
+
+// printPlannedChange writes one show's action and its old -> new fields.
+func printPlannedChange(w io.Writer, pc plannedChange) {
+	fmt.Fprintf(w, "%-7s %s  %s (%s)\n", strings.ToUpper(pc.Action), formatTime(pc.Start), pc.Summary, pc.UID)
+	for _, f := range pc.Fields {
+		fmt.Fprintf(w, "          %s: %q\n          %s  -> %q\n",
+			f.Field, truncateStr(f.Old, 80), strings.Repeat(" ", len(f.Field)), truncateStr(f.New, 80))
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return inOutputTZ(*t).Format("2006-01-02 15:04 MST")
+}
+
+func joinList(in []string) string {
+	return strings.Join(nonEmpty(in), ", ")
+}
+
+func nonEmpty(in []string) []string {
+	var out []string
+	for _, s := range in {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// formatDetails renders details as "price=$10, doors=7:30 PM", leaving out
+// empty fields.
+func formatDetails(d *icalplayers.Details) string {
+	if d == nil {
+		return ""
+	}
+	var parts []string
+	for _, f := range []struct{ k, v string }{{"price", d.Price}, {"doors", d.Doors}, {"ages", d.Ages}, {"lineup", d.Lineup}} {
+		if f.v != "" {
+			parts = append(parts, f.k+"="+f.v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+
+	"github.com/tsny/shopsync/pkg/httpclient"
+)
+
+// Poster serving limits: the largest ?w= honoured, the largest original
+// read, and how many resized posters are kept in memory.
+const (
+	maxPosterWidth   = 2400
+	maxPosterBytes   = 20 << 20
+	maxPosterEntries = 256
+)
+
+// posterMaxAge is how long browsers and CDNs may reuse a poster without
+// revalidating.
+const posterMaxAge = 24 * time.Hour
+
+// posters serves show posters from the images directory (downloaded
+// posters named like their URL's last path segment, as for cards) or,
+// failing that, from the venue's site through the shared HTTP client, whose cache
+// keeps them (on disk with -cache-dir) so the origin is fetched once.
+type posters struct {
+	dir    string
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]*poster // by ETag
+	order []string           // cache keys, oldest first
+}
+
+type poster struct {
+	contentType string
+	body        []byte
+	modTime     time.Time
+}
+
+func newPosters(dir string) *posters {
+	return &posters{dir: dir, client: httpclient.Default, cache: map[string]*poster{}}
+}
+
+// handlePoster streams a show's poster, scaled down to ?w= pixels wide
+// when given. Responses are public and cacheable; the ETag covers the
+// image URL and width, so a new poster gets a new tag.
+func (s *server) handlePoster(w http.ResponseWriter, r *http.Request) {
+	show, err := s.store.GetShow(r.Context(), r.PathValue("uid"))
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	if show == nil || show.PostImageURL == "" {
+		writeError(w, http.StatusNotFound, errors.New("image not found"))
+		return
+	}
+	width := 0
+	if v := r.URL.Query().Get("w"); v != "" {
+		width, err = strconv.Atoi(v)
+		if err != nil || width <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid w %q: want a positive number of pixels", v))
+			return
+		}
+		width = min(width, maxPosterWidth)
+	}
+
+	etag := posterETag(show.PostImageURL, width)
+	h := w.Header()
+	h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(posterMaxAge.Seconds())))
+	h.Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	p, err := s.posters.get(r.Context(), etag, show.PostImageURL, width)
+	if err != nil {
+		h.Del("Cache-Control")
+		h.Del("ETag")
+		s.posterError(w, r, err)
+		return
+	}
+	h.Set("Content-Type", p.contentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", p.modTime, bytes.NewReader(p.body))
+}
+
+// errPosterUnavailable is a poster the origin wouldn't give us or that
+// isn't an image; it's the origin's fault, not ours.
+var errPosterUnavailable = errors.New("image unavailable")
+
+func (s *server) posterError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errPosterUnavailable) {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	s.internalError(w, r, err)
+}
+
+func posterETag(u string, width int) string {
+	sum := sha256.Sum256([]byte(u + "\n" + strconv.Itoa(width)))
+	return `"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// get returns the poster at u scaled to width (0 for the original),
+// resizing at most once per ETag while it stays cached.
+func (ps *posters) get(ctx context.Context, etag, u string, width int) (*poster, error) {
+	ps.mu.Lock()
+	p := ps.cache[etag]
+	ps.mu.Unlock()
+	if p != nil {
+		return p, nil
+	}
+	p, err := ps.load(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if width > 0 {
+		if p, err = resizePoster(p, width); err != nil {
+			return nil, err
+		}
+		ps.remember(etag, p)
+	}
+	return p, nil
+}
+
+func (ps *posters) remember(key string, p *poster) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if _, ok := ps.cache[key]; !ok {
+		ps.order = append(ps.order, key)
+	}
+	ps.cache[key] = p
+	for len(ps.order) > maxPosterEntries {
+		delete(ps.cache, ps.order[0])
+		ps.order = ps.order[1:]
+	}
+}
+
+// load reads the original poster from the images directory, or fetches
+// it from u.
+func (ps *posters) load(ctx context.Context, u string) (*poster, error) {
+	if ps.dir != "" {
+		name := path.Base(strings.SplitN(u, "?", 2)[0])
+		if f, err := os.Open(filepath.Join(ps.dir, name)); err == nil {
+			defer f.Close()
+			if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+				if body, err := io.ReadAll(io.LimitReader(f, maxPosterBytes+1)); err == nil && len(body) <= maxPosterBytes {
+					if p, err := newPoster(body, fi.ModTime()); err == nil {
+						return p, nil
+					}
+				}
+			}
+		}
+	}
+	if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		return nil, fmt.Errorf("%w: %s is not an http(s) URL", errPosterUnavailable, u)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errPosterUnavailable, err)
+	}
+	resp, err := ps.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", errPosterUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s returned %s", errPosterUnavailable, u, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPosterBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading %s: %v", errPosterUnavailable, u, err)
+	}
+	if len(body) > maxPosterBytes {
+		return nil, fmt.Errorf("%w: %s is larger than %s", errPosterUnavailable, u, formatBytes(maxPosterBytes))
+	}
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	p, err := newPoster(body, modTime)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", errPosterUnavailable, u, err)
+	}
+	return p, nil
+}
+
+// newPoster sniffs body's type rather than trusting the origin, and
+// refuses anything that isn't an image.
+func newPoster(body []byte, modTime time.Time) (*poster, error) {
+	ct := http.DetectContentType(body)
+	if !strings.HasPrefix(ct, "image/") {
+		return nil, fmt.Errorf("not an image (%s)", ct)
+	}
+	return &poster{contentType: ct, body: body, modTime: modTime}, nil
+}
+
+// resizePoster scales p down to width, keeping its aspect ratio. Posters
+// already that narrow are returned as they are. PNGs and GIFs come out as
+// PNG, everything else as JPEG.
+func resizePoster(p *poster, width int) (*poster, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(p.body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errPosterUnavailable, err)
+	}
+	if cfg.Width <= width {
+		return p, nil
+	}
+	src, _, err := image.Decode(bytes.NewReader(p.body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errPosterUnavailable, err)
+	}
+	b := src.Bounds()
+	height := max(1, b.Dy()*width/b.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+
+	var buf bytes.Buffer
+	out := &poster{modTime: p.modTime}
+	switch p.contentType {
+	case "image/png", "image/gif":
+		out.contentType = "image/png"
+		err = png.Encode(&buf, dst)
+	default:
+		out.contentType = "image/jpeg"
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return nil, err
+	}
+	out.body = buf.Bytes()
+	return out, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"text/tabwriter"
+	"time"
+)
+
+// stageTiming is how long one pipeline stage took.
+type stageTiming struct {
+	name string
+	took time.Duration
+}
+
+// stageWork says what the stages ingest times spend their time on.
+var stageWork = map[string]string{
+	"source":    "fetch and parse feeds",
+	"unchanged": "hash events, DB",
+	"window":    "filter by date",
+	"enrich":    "image and detail fetches",
+	"match":     "player inference, team matching",
+	"teams":     "filter by team",
+	"summary":   "print summary",
+	"store":     "DB writes",
+	"record":    "DB sync history",
+	"sink":      "notifiers and sinks",
+	"batches":   "the stages above, a batch at a time (-batch-size)",
+}
+
+// startProfile starts a CPU profile in dir/cpu.pprof. The returned stop
+// ends it and writes dir/heap.pprof.
+func startProfile(dir string) (stop func() error, err error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	cpu, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		cpu.Close()
+		return nil, err
+	}
+	return func() error {
+		pprof.StopCPUProfile()
+		if err := cpu.Close(); err != nil {
+			return err
+		}
+		heap, err := os.Create(filepath.Join(dir, "heap.pprof"))
+		if err != nil {
+			return err
+		}
+		defer heap.Close()
+		runtime.GC() // up-to-date live objects
+		if err := pprof.WriteHeapProfile(heap); err != nil {
+			return err
+		}
+		return heap.Close()
+	}, nil
+}
+
+// printTimings writes a table of stage durations and their share of total.
+// Stages run once per batch are summed.
+func printTimings(w io.Writer, timings []stageTiming, total time.Duration) {
+	var summed []stageTiming
+	at := map[string]int{}
+	for _, t := range timings {
+		if i, ok := at[t.name]; ok {
+			summed[i].took += t.took
+			continue
+		}
+		at[t.name] = len(summed)
+		summed = append(summed, t)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tTIME\tSHARE\tWORK")
+	for _, t := range summed {
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(t.took) / float64(total)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.0f%%\t%s\n", t.name, t.took.Round(time.Millisecond), share, stageWork[t.name])
+	}
+	fmt.Fprintf(tw, "total\t%s\t\t\n", total.Round(time.Millisecond))
+	tw.Flush()
+}
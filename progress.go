@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// progress prints "phase n/total" lines to stderr during long runs. On a
+// terminal the line is redrawn in place; otherwise a line is written at most
+// once per progressInterval. A nil *progress is silent, which is what -quiet
+// gives you.
+type progress struct {
+	mu    sync.Mutex
+	w     io.Writer
+	tty   bool
+	phase string
+	total int
+	last  time.Time
+}
+
+const progressInterval = 2 * time.Second
+
+func newProgress(quiet bool) *progress {
+	if quiet {
+		return nil
+	}
+	tty := false
+	if fi, err := os.Stderr.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		tty = true
+	}
+	return &progress{w: os.Stderr, tty: tty}
+}
+
+// update reports that done of total items in phase are finished. Switching
+// to a new phase ends the previous line. total may be 0 when the size isn't
+// known yet.
+func (p *progress) update(phase string, done, total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if phase != p.phase {
+		if p.tty && p.phase != "" {
+			fmt.Fprintln(p.w)
+		}
+		p.phase, p.last = phase, time.Time{}
+	}
+	p.total = total
+	if done != 0 && done < total && !p.tty && time.Since(p.last) < progressInterval {
+		return
+	}
+	p.print(done)
+}
+
+// finish ends the current phase line.
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tty && p.phase != "" {
+		fmt.Fprintln(p.w)
+	}
+	p.phase = ""
+}
+
+func (p *progress) print(done int) {
+	p.last = time.Now()
+	line := fmt.Sprintf("[%s] %d/%d", p.phase, done, p.total)
+	if p.total == 0 {
+		line = fmt.Sprintf("[%s] ...", p.phase)
+	}
+	if p.tty {
+		fmt.Fprintf(p.w, "\r\033[K%s", line)
+		return
+	}
+	fmt.Fprintln(p.w, line)
+}
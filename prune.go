@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+func runPrune(args []string) {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", true, "If set, only report what would be pruned")
+	keepDays := fs.Int("keep-days", 30, "Keep shows that started within this many days; older ones are deleted")
+	imagesDir := fs.String("images-dir", "", "Directory of downloaded post images to sweep for files no show references (skipped if empty)")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if *keepDays < 0 {
+		fmt.Fprintln(os.Stderr, "-keep-days must not be negative")
+		os.Exit(exitUsage)
+	}
+
+	ctx := context.Background()
+	var opts []showstore.Option
+	if *dryRun {
+		opts = append(opts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, opts...)
+	defer store.Close()
+
+	cutoff := time.Now().AddDate(0, 0, -*keepDays)
+	if err := prunePastShows(ctx, store, cutoff, *dryRun); err != nil {
+		exitErr(dbErr(err))
+	}
+	if err := pruneOrphanedShowTeams(ctx, store, *dryRun); err != nil {
+		exitErr(dbErr(err))
+	}
+	if *imagesDir != "" {
+		if err := pruneImages(ctx, store, *imagesDir, *dryRun); err != nil {
+			exitErr(err)
+		}
+	}
+}
+
+func prunePastShows(ctx context.Context, store *showstore.Store, cutoff time.Time, dryRun bool) error {
+	if dryRun {
+		n, err := store.CountShowsBefore(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+		slog.Info("dry run; would delete past shows", "count", n, "before", cutoff.Format(time.DateOnly))
+		return nil
+	}
+	n, err := store.DeleteShowsBefore(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+	slog.Info("deleted past shows", "count", n, "before", cutoff.Format(time.DateOnly))
+	return nil
+}
+
+func pruneOrphanedShowTeams(ctx context.Context, store *showstore.Store, dryRun bool) error {
+	if dryRun {
+		n, err := store.CountOrphanedShowTeams(ctx)
+		if err != nil {
+			return err
+		}
+		slog.Info("dry run; would delete orphaned show_teams rows", "count", n)
+		return nil
+	}
+	n, err := store.DeleteOrphanedShowTeams(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("deleted orphaned show_teams rows", "count", n)
+	return nil
+}
+
+// pruneImages removes files in dir whose name isn't the last path segment of
+// any stored post_image_url.
+func pruneImages(ctx context.Context, store *showstore.Store, dir string, dryRun bool) error {
+	urls, err := store.GetImageURLs(ctx)
+	if err != nil {
+		return dbErr(err)
+	}
+	referenced := map[string]bool{}
+	for _, u := range urls {
+		for _, seg := range strings.Split(u, "/") {
+			// cdn-cgi URLs end in resize options, so any segment may be the file.
+			if i := strings.IndexAny(seg, "?#"); i >= 0 {
+				seg = seg[:i]
+			}
+			if seg != "" {
+				referenced[path.Base(seg)] = true
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var removed int
+	for _, ent := range entries {
+		if !ent.Type().IsRegular() || referenced[ent.Name()] {
+			continue
+		}
+		p := filepath.Join(dir, ent.Name())
+		if dryRun {
+			slog.Info("dry run; would remove unreferenced image", "path", p)
+			removed++
+			continue
+		}
+		if err := os.Remove(p); err != nil {
+			slog.Warn("could not remove image", "path", p, "err", err)
+			continue
+		}
+		slog.Debug("removed unreferenced image", "path", p)
+		removed++
+	}
+	if dryRun {
+		slog.Info("dry run; would remove unreferenced images", "count", removed, "dir", dir)
+	} else {
+		slog.Info("removed unreferenced images", "count", removed, "dir", dir)
+	}
+	return nil
+}
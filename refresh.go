@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"github.com/tsny/shopsync/pkg/showstore"
+	"github.com/tsny/shopsync/pkg/wpevents"
+	"github.com/tsny/shopsync/pkg/wpimg"
+)
+
+// imageCheck is the outcome for one stored post image.
+type imageCheck struct {
+	UID      string `json:"uid"`
+	Summary  string `json:"summary"`
+	OldImage string `json:"oldImage"`
+	NewImage string `json:"newImage,omitempty"`
+	Status   string `json:"status"` // ok, fixed, broken, unreachable
+	Reason   string `json:"reason,omitempty"`
+}
+
+// runRefreshImages HEAD-checks stored post images and re-scrapes the event
+// page for any that are gone.
+func runRefreshImages(args []string) {
+	fs := flag.NewFlagSet("refresh-images", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", true, "If set, only report replacements without writing them")
+	concurrency := fs.Int("concurrency", 4, "Number of images checked in parallel")
+	output := fs.String("output", "text", "Output format: text or json")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if !validOutput(*output) {
+		fmt.Fprintf(os.Stderr, "invalid -output %q (want text or json)\n", *output)
+		os.Exit(exitUsage)
+	}
+	if *concurrency < 1 {
+		fmt.Fprintf(os.Stderr, "-concurrency must be at least 1, got %d\n", *concurrency)
+		os.Exit(exitUsage)
+	}
+
+	ctx := context.Background()
+	var opts []showstore.Option
+	if *dryRun {
+		opts = append(opts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, opts...)
+	defer store.Close()
+
+	shows, err := store.GetShowsWithImageURL(ctx)
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	slog.Info("checking post images", "count", len(shows))
+	checks := checkImages(ctx, shows, *concurrency)
+
+	var failed int
+	for i := range checks {
+		c := &checks[i]
+		if c.Status != "fixed" || *dryRun {
+			continue
+		}
+		if err := store.UpdateShowImageURL(ctx, c.UID, c.NewImage); err != nil {
+			slog.Error("could not update post image", "uid", c.UID, "err", err)
+			c.Status, c.Reason = "broken", "update failed: "+err.Error()
+			failed++
+		}
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(checks); err != nil {
+			exitErr(err)
+		}
+	} else {
+		printImageChecks(checks, *dryRun)
+	}
+	if failed > 0 {
+		exitErr(withCode(exitPartial, fmt.Errorf("%d image updates failed", failed)))
+	}
+}
+
+func checkImages(ctx context.Context, shows []showstore.ShowWithImageURL, concurrency int) []imageCheck {
+	client := httpclient.Default
+	out := make([]imageCheck, len(shows))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, sh := range shows {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			out[i] = checkImage(ctx, client, sh)
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// checkImage probes one show's image. Only a 404 or 410 counts as gone; any
+// other failure might be transient and is reported as unreachable.
+func checkImage(ctx context.Context, client *http.Client, sh showstore.ShowWithImageURL) imageCheck {
+	c := imageCheck{UID: sh.UID, Summary: sh.Summary, OldImage: *sh.PostImageURL, Status: "ok"}
+	status, err := probeStatus(ctx, client, c.OldImage)
+	switch {
+	case err != nil:
+		c.Status, c.Reason = "unreachable", err.Error()
+		return c
+	case status == http.StatusNotFound || status == http.StatusGone:
+	case status >= 400:
+		c.Status, c.Reason = "unreachable", fmt.Sprintf("http status %d", status)
+		return c
+	default:
+		return c
+	}
+
+	c.Status = "broken"
+	if sh.URL == "" {
+		c.Reason = fmt.Sprintf("image returned %d and show has no event URL", status)
+		return c
+	}
+	res, err := wpimg.Fetch(ctx, sh.URL)
+	if err != nil {
+		c.Reason = "re-scrape failed: " + err.Error()
+		return c
+	}
+	img := wpevents.RewriteCdnCgiURL(res.ImageURL)
+	if img == c.OldImage {
+		c.Reason = "event page still points at the dead image"
+		return c
+	}
+	if msg := probeURL(ctx, client, img); msg != "" {
+		c.Reason = "replacement " + img + " is also dead: " + msg
+		return c
+	}
+	c.Status, c.NewImage, c.Reason = "fixed", img, ""
+	return c
+}
+
+func printImageChecks(checks []imageCheck, dryRun bool) {
+	counts := map[string]int{}
+	var problems []imageCheck
+	for _, c := range checks {
+		counts[c.Status]++
+		if c.Status != "ok" {
+			problems = append(problems, c)
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Status < problems[j].Status })
+	if len(problems) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "STATUS\tSUMMARY\tUID\tDETAIL")
+		for _, c := range problems {
+			detail := c.Reason
+			if c.Status == "fixed" {
+				detail = c.OldImage + " -> " + c.NewImage
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Status, truncateStr(c.Summary, 40), c.UID, detail)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+	fixed := "fixed"
+	if dryRun {
+		fixed = "fixable (dry run)"
+	}
+	fmt.Printf("%d checked: %d ok, %d %s, %d broken, %d unreachable\n",
+		len(checks), counts["ok"], counts["fixed"], fixed, counts["broken"], counts["unreachable"])
+}
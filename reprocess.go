@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// runReprocess re-runs player inference and team matching over stored
+// shows so heuristic, alias and override changes apply to shows already in
+// the database. Shows keep no copy of their source payload, so inference
+// runs on the stored description, the same text ingest infers from.
+func runReprocess(args []string) {
+	fs := flag.NewFlagSet("reprocess", flag.ExitOnError)
+	var opts ingestOptions
+	dryRun := fs.Bool("dry-run", true, "If set, only print what would change")
+	fs.StringVar(&opts.from, "from", "", "Only reprocess shows starting on or after this date (YYYY-MM-DD or a phrase, as in ingest)")
+	fs.StringVar(&opts.to, "to", "", "Only reprocess shows starting on or before this date (YYYY-MM-DD or a phrase, as in ingest)")
+	fs.StringVar(&opts.overridesFile, "overrides", "", "Overrides (YAML or CSV file or URL) applied after inference, as in ingest")
+	useRosters := fs.Bool("use-rosters", true, "Check inferred players against the matched teams' rosters (see 'shopsync rosters')")
+	keepTeams := fs.Bool("keep-teams", true, "Only add teams; never unlink a team a show already has (shows imported by showtool have teams their descriptions don't mention)")
+	output := fs.String("output", "text", "Report format: text or json")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if !validOutput(*output) {
+		exitErr(fmt.Errorf("invalid -output %q (want text or json)", *output))
+	}
+	from, to, err := opts.window()
+	if err != nil {
+		exitErr(withCode(exitUsage, err))
+	}
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		exitErr(err)
+	}
+	ctx := context.Background()
+	ovr, err := loadOverrides(ctx, opts.overridesFile, loc)
+	if err != nil {
+		exitErr(withCode(exitUsage, err))
+	}
+	var storeOpts []showstore.Option
+	if *dryRun {
+		storeOpts = append(storeOpts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, storeOpts...)
+	defer store.Close()
+
+	shows, _, err := store.ListShows(ctx, showstore.ShowFilter{From: from, To: to})
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	teams, err := store.GetAllTeams(ctx)
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	var rosters map[string][]string
+	if *useRosters {
+		if rosters, err = store.GetRosters(ctx); err != nil {
+			exitErr(dbErr(err))
+		}
+	}
+	slog.Info("reprocessing shows", "shows", len(shows), "teams", len(teams), "rosters", len(rosters))
+
+	var plan []plannedChange
+	var failed int
+	for _, old := range shows {
+		e, err := reprocessShow(old, teams, rosters, ovr, loc, *keepTeams)
+		if err != nil {
+			slog.Warn("skipping show", "uid", old.UID, "err", err)
+			failed++
+			continue
+		}
+		pc := plannedChange{Action: "unchanged", UID: old.UID, Summary: old.Summary, Start: old.Start, Fields: derivedFieldChanges(old, e)}
+		if len(pc.Fields) > 0 {
+			pc.Action = "update"
+		}
+		plan = append(plan, pc)
+		if *dryRun || pc.Action == "unchanged" {
+			continue
+		}
+		if err := store.UpdatePlayersAndTeams(ctx, e.UID, old.Version, e.Players, e.Teams, e.TeamIDs); err != nil {
+			if errors.Is(err, showstore.ErrVersionConflict) {
+				slog.Warn("show changed while reprocessing; skipped", "uid", e.UID)
+			} else {
+				slog.Error("update failed", "uid", e.UID, "err", err)
+			}
+			failed++
+		}
+	}
+	ovr.warnUnused()
+
+	var updates int
+	for _, pc := range plan {
+		if pc.Action == "update" {
+			updates++
+		}
+	}
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(map[string]any{"dryRun": *dryRun, "shows": len(shows), "updated": updates, "failed": failed, "changes": plan}); err != nil {
+			exitErr(err)
+		}
+	} else {
+		for _, pc := range plan {
+			if pc.Action == "update" {
+				printPlannedChange(os.Stdout, pc)
+			}
+		}
+		verb := "updated"
+		if *dryRun {
+			verb = "would update"
+		}
+		fmt.Printf("%d shows, %s %d, %d failed.\n", len(shows), verb, updates, failed)
+	}
+	if failed > 0 {
+		os.Exit(exitPartial)
+	}
+}
+
+// reprocessShow returns old with players and teams re-derived from its
+// description and any matching override. Players are checked against the
+// rosters of the show's teams, as in ingest.
+func reprocessShow(old icalplayers.Event, teams []showstore.Team, rosters map[string][]string, ovr overrides, loc *time.Location, keepTeams bool) (icalplayers.Event, error) {
+	e := old
+	e.Players = icalplayers.InferPlayerNames(e.Description, nil)
+	matched := findTeamsInEventDescription(e.Description, teams)
+	playersForced := false
+	if o := ovr.find(e, loc); o != nil {
+		forced, err := o.apply(&e, teams)
+		if err != nil {
+			return e, err
+		}
+		if forced != nil {
+			matched = forced
+		}
+		playersForced = o.Players != nil
+	}
+	e.Teams, e.TeamIDs = nil, nil
+	for _, t := range matched {
+		e.Teams = append(e.Teams, t.Name)
+		e.TeamIDs = append(e.TeamIDs, t.ID)
+	}
+	if keepTeams {
+		for _, name := range old.Teams {
+			if name != "" && !slices.Contains(e.Teams, name) {
+				e.Teams = append(e.Teams, name)
+			}
+		}
+		for _, id := range old.TeamIDs {
+			if !slices.Contains(e.TeamIDs, id) {
+				e.TeamIDs = append(e.TeamIDs, id)
+			}
+		}
+	}
+	if r := rosterOf(rosters, e.TeamIDs); len(r) > 0 && !playersForced {
+		e.Players = icalplayers.InferPlayerNamesWithRoster(e.Description, nil, r)
+	}
+	return e, nil
+}
+
+// derivedFieldChanges lists the inferred fields that differ between old
+// and e. post_image_url isn't one of them, so image overrides are left to
+// ingest.
+func derivedFieldChanges(old, e icalplayers.Event) []fieldChange {
+	var fc []fieldChange
+	if !teamsEqualSorted(nonEmpty(old.Players), nonEmpty(e.Players)) {
+		fc = append(fc, fieldChange{"players", joinList(old.Players), joinList(e.Players)})
+	}
+	if !teamsEqualSorted(nonEmpty(old.Teams), nonEmpty(e.Teams)) {
+		fc = append(fc, fieldChange{"teams", joinList(old.Teams), joinList(e.Teams)})
+	}
+	if !teamsEqualSorted(old.TeamIDs, e.TeamIDs) {
+		fc = append(fc, fieldChange{"team_ids", joinList(old.TeamIDs), joinList(e.TeamIDs)})
+	}
+	return fc
+}
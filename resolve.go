@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// maxSuggestions is how many fuzzy team suggestions are offered per event.
+const maxSuggestions = 5
+
+// minSuggestScore is the lowest similarity (0-1) worth suggesting.
+const minSuggestScore = 0.6
+
+// resolver asks the operator what to do with events that matched no team
+// during ingest -interactive. Choices are saved as aliases (or new teams)
+// so the next run matches on its own; dry runs only apply them in memory.
+type resolver struct {
+	store   *showstore.Store
+	in      *bufio.Reader
+	out     io.Writer
+	persist bool
+	done    bool // operator chose to skip everything left
+}
+
+func newResolver(store *showstore.Store, persist bool) *resolver {
+	return &resolver{store: store, in: bufio.NewReader(os.Stdin), out: os.Stderr, persist: persist}
+}
+
+// suggestion is a team that looks like it appears in an event description.
+type suggestion struct {
+	team  int    // index into the teams slice
+	text  string // the stretch of description that resembles the team
+	score float64
+}
+
+// resolve prompts for ev and returns the teams the operator chose, if any.
+// New teams and aliases are added to *teams so later events in the same run
+// match them too.
+func (r *resolver) resolve(ctx context.Context, ev icalplayers.Event, teams *[]showstore.Team) ([]showstore.Team, error) {
+	if r == nil || r.done {
+		return nil, nil
+	}
+	sugg := suggestTeams(ev.Description, *teams)
+	fmt.Fprintf(r.out, "\nNo team matched: %s", ev.Summary)
+	if ev.Start != nil {
+		fmt.Fprintf(r.out, " (%s)", ev.Start.Format("Mon Jan 2 3:04pm"))
+	}
+	fmt.Fprintf(r.out, "\n  %s\n", truncateStr(strings.Join(strings.Fields(ev.Description), " "), 300))
+	for i, s := range sugg {
+		fmt.Fprintf(r.out, "  %d) %s  (%q, %.0f%%)\n", i+1, (*teams)[s.team].Name, s.text, s.score*100)
+	}
+	for {
+		fmt.Fprint(r.out, "Pick a number, /search teams, c to create a team, s to skip, q to skip the rest: ")
+		line, err := r.readLine()
+		if err != nil {
+			r.done = true
+			return nil, nil
+		}
+		switch {
+		case line == "" || line == "s":
+			return nil, nil
+		case line == "q":
+			r.done = true
+			return nil, nil
+		case line == "c":
+			t, err := r.createTeam(ctx, ev, teams)
+			if err != nil || t == nil {
+				return nil, err
+			}
+			return []showstore.Team{*t}, nil
+		case strings.HasPrefix(line, "/"):
+			sugg = searchTeams(strings.TrimPrefix(line, "/"), *teams)
+			if len(sugg) == 0 {
+				fmt.Fprintln(r.out, "  no teams found")
+			}
+			for i, s := range sugg {
+				fmt.Fprintf(r.out, "  %d) %s\n", i+1, (*teams)[s.team].Name)
+			}
+		default:
+			n, err := strconv.Atoi(line)
+			if err != nil || n < 1 || n > len(sugg) {
+				fmt.Fprintln(r.out, "  not a choice")
+				continue
+			}
+			t, err := r.aliasTeam(ctx, ev, teams, sugg[n-1])
+			if err != nil || t == nil {
+				return nil, err
+			}
+			return []showstore.Team{*t}, nil
+		}
+	}
+}
+
+// aliasTeam asks which text in the description names the chosen team and
+// saves it as an alias.
+func (r *resolver) aliasTeam(ctx context.Context, ev icalplayers.Event, teams *[]showstore.Team, s suggestion) (*showstore.Team, error) {
+	t := &(*teams)[s.team]
+	alias, err := r.askAlias(ev.Description, s.text)
+	if err != nil {
+		return nil, err
+	}
+	if alias == "" || alias == t.Name {
+		return t, nil
+	}
+	if r.persist {
+		if err := r.store.AddTeamAlias(ctx, t.ID, alias); err != nil {
+			return nil, dbErr(fmt.Errorf("save alias %q for %s: %w", alias, t.Name, err))
+		}
+		slog.Info("saved team alias", "team", t.Name, "alias", alias)
+	}
+	t.Aliases = append(t.Aliases, alias)
+	return t, nil
+}
+
+// createTeam prompts for a new team name and, on real runs, inserts it.
+func (r *resolver) createTeam(ctx context.Context, ev icalplayers.Event, teams *[]showstore.Team) (*showstore.Team, error) {
+	fmt.Fprint(r.out, "New team name (blank to skip): ")
+	name, err := r.readLine()
+	if err != nil || name == "" {
+		return nil, nil
+	}
+	t := showstore.Team{Name: name, ID: "dry-run:" + name}
+	if r.persist {
+		if t, err = r.store.CreateTeam(ctx, name); err != nil {
+			return nil, dbErr(fmt.Errorf("create team %q: %w", name, err))
+		}
+		slog.Info("created team", "team", t.Name, "id", t.ID)
+	}
+	*teams = append(*teams, t)
+	if ok, _ := nameMatch(ev.Description, name); !ok {
+		s := suggestion{team: len(*teams) - 1}
+		return r.aliasTeam(ctx, ev, teams, s)
+	}
+	return &(*teams)[len(*teams)-1], nil
+}
+
+// askAlias reads the alias to save, defaulting to def. Aliases that the
+// matcher would ignore or that don't occur in desc are refused.
+func (r *resolver) askAlias(desc, def string) (string, error) {
+	for {
+		fmt.Fprintf(r.out, "Alias as written in the description [%s]: ", def)
+		line, err := r.readLine()
+		if err != nil {
+			return "", err
+		}
+		if line == "" {
+			line = def
+		}
+		if line == "" {
+			return "", nil
+		}
+		if ok, reason := nameMatch(desc, line); !ok {
+			fmt.Fprintf(r.out, "  %q would not match: %s\n", line, reason)
+			continue
+		}
+		return line, nil
+	}
+}
+
+func (r *resolver) readLine() (string, error) {
+	line, err := r.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// suggestTeams ranks teams by how closely some run of words in desc
+// resembles the team's name or one of its aliases.
+func suggestTeams(desc string, teams []showstore.Team) []suggestion {
+	words := strings.Fields(desc)
+	var out []suggestion
+	for i, t := range teams {
+		best := suggestion{team: i}
+		for _, name := range append([]string{t.Name}, t.Aliases...) {
+			n := len(strings.Fields(name))
+			if n == 0 {
+				continue
+			}
+			for j := 0; j+n <= len(words); j++ {
+				text := strings.TrimFunc(strings.Join(words[j:j+n], " "), func(r rune) bool {
+					return unicode.IsPunct(r) && r != '&' && r != '\''
+				})
+				if score := similarity(name, text); score > best.score {
+					best.text, best.score = text, score
+				}
+			}
+		}
+		if best.score >= minSuggestScore {
+			out = append(out, best)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].score > out[j].score })
+	if len(out) > maxSuggestions {
+		out = out[:maxSuggestions]
+	}
+	return out
+}
+
+// searchTeams lists up to ten teams whose name or an alias contains query.
+// They carry no description text to propose as an alias.
+func searchTeams(query string, teams []showstore.Team) []suggestion {
+	query = strings.ToLower(strings.TrimSpace(query))
+	var out []suggestion
+	for i, t := range teams {
+		for _, name := range append([]string{t.Name}, t.Aliases...) {
+			if query != "" && strings.Contains(strings.ToLower(name), query) {
+				out = append(out, suggestion{team: i})
+				break
+			}
+		}
+		if len(out) == 10 {
+			break
+		}
+	}
+	return out
+}
+
+// similarity is 1 minus the case-insensitive edit distance between a and b,
+// scaled by the longer length.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// checkInteractive reports why -interactive can't be used, if it can't.
+func checkInteractive(o *ingestOptions) error {
+	for _, src := range o.srcs {
+		if src == "-" {
+			return errors.New("-interactive can't be combined with -src -")
+		}
+	}
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return errors.New("-interactive needs stdin to be a terminal")
+	}
+	return nil
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// reviewCandidate is a show the match stage found no team for, with the
+// teams it resembles, waiting for the review stage to queue it once
+// stored.
+type reviewCandidate struct {
+	uid         string
+	confidence  float64
+	suggestions []showstore.ReviewSuggestion
+}
+
+// reviewSuggestions are the suggestTeams matches for desc, best first, and
+// the best score (0 with none).
+func reviewSuggestions(desc string, teams []showstore.Team) (float64, []showstore.ReviewSuggestion) {
+	var out []showstore.ReviewSuggestion
+	for _, s := range suggestTeams(desc, teams) {
+		t := teams[s.team]
+		out = append(out, showstore.ReviewSuggestion{TeamID: t.ID, Team: t.Name, Text: s.text, Score: s.score})
+	}
+	if len(out) == 0 {
+		return 0, out
+	}
+	return out[0].Score, out
+}
+
+// reviewStage queues the shows the match stage left without a team for
+// staff to review, and drops pending entries of shows that now match,
+// usually through an alias an earlier review saved. It runs after store,
+// on real runs only, and never stops the sync.
+func (in *ingester) reviewStage() stage {
+	return stage{name: "review", run: func(ctx context.Context, st *syncState) error {
+		report := st.report
+		queued := 0
+		for _, c := range report.review {
+			if err := in.store.QueueReview(ctx, c.uid, c.confidence, c.suggestions); err != nil {
+				slog.Warn("could not queue show for review", "uid", c.uid, "err", err)
+				continue
+			}
+			queued++
+		}
+		report.review = nil
+		report.QueuedForReview += queued
+		var matched []string
+		for _, e := range st.events {
+			if len(e.TeamIDs) > 0 {
+				matched = append(matched, e.UID)
+			}
+		}
+		cleared, err := in.store.ClearReview(ctx, matched)
+		if err != nil {
+			slog.Warn("could not clear matched shows from the review queue", "err", err)
+		}
+		if queued > 0 || cleared > 0 {
+			slog.Info("updated review queue", "queued", queued, "cleared", cleared)
+		}
+		return nil
+	}}
+}
+
+// reviewAction is how staff settle a queue entry: "approve" takes
+// suggested teams, "reassign" names the teams, "dismiss" marks the show as
+// no team's.
+type reviewAction struct {
+	Action string   `json:"action"`
+	Teams  []string `json:"teams"` // names, aliases or IDs; for approve, which suggestions (default the best)
+	Alias  string   `json:"alias"` // reassign: the description's name for the one team
+}
+
+// reviewResult is what resolveReview did.
+type reviewResult struct {
+	UID     string   `json:"uid"`
+	Status  string   `json:"status"`
+	Teams   []string `json:"teams,omitempty"`
+	Aliases []string `json:"aliases,omitempty"` // newly saved
+	// Pending counts other queued shows an alias now matches; the next
+	// sync gives them the team and clears them.
+	Pending int `json:"pending,omitempty"`
+}
+
+// errReview is a resolveReview request that can't be carried out as given.
+var errReview = errors.New("review")
+
+// resolveReview settles the queue entry of uid. Approving or reassigning
+// sets the show's teams (as a hand edit) and saves the text its
+// description names each team by as an alias, so later syncs match shows
+// like it on their own and the queue shrinks. It returns nil if uid has no
+// queue entry.
+func resolveReview(ctx context.Context, store *showstore.Store, uid string, act reviewAction) (*reviewResult, error) {
+	item, err := store.GetReview(ctx, uid)
+	if err != nil || item == nil {
+		return nil, err
+	}
+	res := &reviewResult{UID: uid}
+	if act.Action == "dismiss" {
+		res.Status = showstore.ReviewDismissed
+		if _, err := store.ResolveReview(ctx, uid, res.Status, nil, nil); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+	all, err := store.GetAllTeams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	type pick struct {
+		team  showstore.Team
+		alias string
+	}
+	var picks []pick
+	switch act.Action {
+	case "approve":
+		res.Status = showstore.ReviewApproved
+		if len(item.Suggestions) == 0 {
+			return nil, fmt.Errorf("%w: %s has no suggested team to approve; reassign it", errReview, uid)
+		}
+		want := act.Teams
+		if len(want) == 0 {
+			want = []string{item.Suggestions[0].TeamID}
+		}
+		for _, w := range want {
+			t := lookupTeam(w, all)
+			if t == nil {
+				return nil, fmt.Errorf("%w: unknown team %q", errReview, w)
+			}
+			i := slices.IndexFunc(item.Suggestions, func(s showstore.ReviewSuggestion) bool { return s.TeamID == t.ID })
+			if i < 0 {
+				return nil, fmt.Errorf("%w: %s wasn't suggested for %s; reassign it", errReview, t.Name, uid)
+			}
+			picks = append(picks, pick{*t, item.Suggestions[i].Text})
+		}
+	case "reassign":
+		res.Status = showstore.ReviewReassigned
+		if len(act.Teams) == 0 {
+			return nil, fmt.Errorf("%w: reassign needs a team", errReview)
+		}
+		if act.Alias != "" && len(act.Teams) > 1 {
+			return nil, fmt.Errorf("%w: an alias can only be given with one team", errReview)
+		}
+		for _, w := range act.Teams {
+			t := lookupTeam(w, all)
+			if t == nil {
+				return nil, fmt.Errorf("%w: unknown team %q", errReview, w)
+			}
+			p := pick{team: *t, alias: act.Alias}
+			if p.alias == "" {
+				if i := slices.IndexFunc(item.Suggestions, func(s showstore.ReviewSuggestion) bool { return s.TeamID == t.ID }); i >= 0 {
+					p.alias = item.Suggestions[i].Text
+				}
+			} else if ok, why := nameMatch(item.Description, p.alias); !ok {
+				return nil, fmt.Errorf("%w: alias %q would not match the description: %s", errReview, p.alias, why)
+			}
+			picks = append(picks, p)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown action %q (want approve, reassign or dismiss)", errReview, act.Action)
+	}
+
+	show, err := store.GetShow(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if show == nil {
+		return nil, nil
+	}
+	var names, ids []string
+	for _, p := range picks {
+		names, ids = append(names, p.team.Name), append(ids, p.team.ID)
+	}
+	if err := store.UpdatePlayersAndTeams(ctx, uid, show.Version, show.Players, names, ids); err != nil {
+		return nil, err
+	}
+	if err := store.MarkEdited(ctx, uid, showstore.FieldTeams); err != nil {
+		return nil, err
+	}
+	for _, p := range picks {
+		if p.alias == "" || strings.EqualFold(p.alias, p.team.Name) || slices.ContainsFunc(p.team.Aliases, func(a string) bool { return strings.EqualFold(a, p.alias) }) {
+			continue
+		}
+		if ok, _ := nameMatch(item.Description, p.alias); !ok {
+			slog.Warn("not saving alias the matcher would ignore", "team", p.team.Name, "alias", p.alias)
+			continue
+		}
+		if err := store.AddTeamAlias(ctx, p.team.ID, p.alias); err != nil {
+			return nil, fmt.Errorf("save alias %q for %s: %w", p.alias, p.team.Name, err)
+		}
+		slog.Info("saved team alias", "team", p.team.Name, "alias", p.alias)
+		res.Aliases = append(res.Aliases, p.alias)
+	}
+	if _, err := store.ResolveReview(ctx, uid, res.Status, ids, res.Aliases); err != nil {
+		return nil, err
+	}
+	res.Teams = names
+	if len(res.Aliases) > 0 {
+		pending, err := store.ListReview(ctx, showstore.ReviewPending)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range pending {
+			if slices.ContainsFunc(res.Aliases, func(a string) bool { ok, _ := nameMatch(it.Description, a); return ok }) {
+				res.Pending++
+			}
+		}
+	}
+	return res, nil
+}
+
+// runReview works through the team matching review queue:
+//
+//	shopsync review list [-status pending]
+//	shopsync review approve -show UID [-team T]...
+//	shopsync review reassign -show UID -team T [-alias TEXT]
+//	shopsync review dismiss -show UID
+func runReview(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: shopsync review list|approve|reassign|dismiss [flags]")
+		os.Exit(exitUsage)
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet("review "+cmd, flag.ExitOnError)
+	status := fs.String("status", showstore.ReviewPending, "Entries to list: "+strings.Join(showstore.ReviewStatuses, ", ")+" (list)")
+	output := fs.String("output", "text", "Output format: text or json")
+	uid := fs.String("show", "", "UID of the queued show (approve, reassign, dismiss)")
+	var teams stringList
+	fs.Var(&teams, "team", "Team name, alias or ID: which suggestion to approve (default the best), or the team to reassign to. Repeatable")
+	alias := fs.String("alias", "", "Text in the description that names the team, saved as its alias (reassign; default the suggested text for that team)")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args[1:])
+	logOpts.setup()
+
+	switch cmd {
+	case "list":
+		if !slices.Contains(showstore.ReviewStatuses, *status) {
+			exitErr(withCode(exitUsage, fmt.Errorf("invalid -status %q (want %s)", *status, strings.Join(showstore.ReviewStatuses, ", "))))
+		}
+	case "approve", "reassign", "dismiss":
+		if *uid == "" {
+			exitErr(withCode(exitUsage, fmt.Errorf("review %s needs -show", cmd)))
+		}
+		if cmd == "reassign" && len(teams) == 0 {
+			exitErr(withCode(exitUsage, errors.New("review reassign needs -team")))
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown review command %q\n", cmd)
+		os.Exit(exitUsage)
+	}
+	if !validOutput(*output) {
+		exitErr(withCode(exitUsage, fmt.Errorf("invalid -output %q (want text or json)", *output)))
+	}
+	ctx := context.Background()
+	var opts []showstore.Option
+	if cmd == "list" {
+		opts = append(opts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, opts...)
+	defer store.Close()
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if cmd == "list" {
+		items, err := store.ListReview(ctx, *status)
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		if *output == "json" {
+			if err := enc.Encode(append([]showstore.ReviewItem{}, items...)); err != nil {
+				exitErr(err)
+			}
+			return
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "DATE\tSHOW\tCONFIDENCE\tSUGGESTED\tUID")
+		for _, it := range items {
+			date := ""
+			if it.Start != nil {
+				date = inOutputTZ(*it.Start).Format("2006-01-02 15:04")
+			}
+			var sugg []string
+			for _, s := range it.Suggestions {
+				sugg = append(sugg, fmt.Sprintf("%s (%q %.0f%%)", s.Team, s.Text, s.Score*100))
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%.0f%%\t%s\t%s\n", date, truncateStr(it.Summary, 40), it.Confidence*100, strings.Join(sugg, ", "), it.UID)
+		}
+		tw.Flush()
+		return
+	}
+
+	res, err := resolveReview(ctx, store, *uid, reviewAction{Action: cmd, Teams: teams, Alias: *alias})
+	switch {
+	case errors.Is(err, errReview):
+		exitErr(withCode(exitUsage, err))
+	case errors.Is(err, showstore.ErrVersionConflict):
+		exitErr(fmt.Errorf("show %s changed while resolving; try again", *uid))
+	case err != nil:
+		exitErr(dbErr(err))
+	case res == nil:
+		exitErr(fmt.Errorf("show %q is not in the review queue", *uid))
+	}
+	slog.Info("resolved review", "uid", res.UID, "status", res.Status, "teams", res.Teams, "aliases", res.Aliases, "pending_now_matching", res.Pending)
+	if *output == "json" {
+		if err := enc.Encode(res); err != nil {
+			exitErr(err)
+		}
+	}
+}
+
+// handleReviewList serves the pending review queue (?status= for others):
+// a page with approve, reassign and dismiss buttons, or JSON.
+func (a *admin) handleReviewList(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = showstore.ReviewPending
+	}
+	if !slices.Contains(showstore.ReviewStatuses, status) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid status %q", status))
+		return
+	}
+	items, err := a.store.ListReview(r.Context(), status)
+	if err != nil {
+		a.fail(w, r, err)
+		return
+	}
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		writeJSON(w, http.StatusOK, append([]showstore.ReviewItem{}, items...))
+		return
+	}
+	a.render(w, "review", map[string]any{"Items": items, "Status": status, "Flash": r.URL.Query().Get("msg")})
+}
+
+// handleReview settles a queue entry from a JSON reviewAction or the
+// review page's form (teams one per line), answering with the
+// reviewResult; browsers go back to the queue.
+func (a *admin) handleReview(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
+	html := strings.Contains(r.Header.Get("Accept"), "text/html")
+	var act reviewAction
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&act); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %w", err))
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		act = reviewAction{Action: r.PostForm.Get("action"), Teams: formLines(r.PostForm.Get("teams")), Alias: strings.TrimSpace(r.PostForm.Get("alias"))}
+	}
+	res, err := resolveReview(r.Context(), a.store, uid, act)
+	switch {
+	case errors.Is(err, errReview) || errors.Is(err, showstore.ErrVersionConflict):
+		msg := strings.TrimPrefix(err.Error(), errReview.Error()+": ")
+		if html {
+			a.redirect(w, r, "/admin/review", msg+"; nothing saved.")
+			return
+		}
+		status := http.StatusBadRequest
+		if errors.Is(err, showstore.ErrVersionConflict) {
+			status = http.StatusConflict
+		}
+		writeError(w, status, errors.New(msg))
+		return
+	case err != nil:
+		a.fail(w, r, err)
+		return
+	case res == nil:
+		http.NotFound(w, r)
+		return
+	}
+	slog.Info("admin resolved review", "uid", uid, "status", res.Status, "teams", res.Teams, "aliases", res.Aliases)
+	if html {
+		msg := fmt.Sprintf("%s: %s.", uid, res.Status)
+		if len(res.Aliases) > 0 {
+			msg += fmt.Sprintf(" Saved alias %s; %d more queued shows will match it next sync.", strings.Join(res.Aliases, ", "), res.Pending)
+		}
+		a.redirect(w, r, "/admin/review", msg)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
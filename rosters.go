@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/roster"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// defaultTeamPageURL is where the venue site keeps team pages; {slug} is the
+// team name lower-cased with dashes.
+const defaultTeamPageURL = "https://theimprovshop.com/teams/{slug}/"
+
+var slugRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+func teamSlug(name string) string {
+	return strings.Trim(slugRe.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// runRosters scrapes each team's page for its performers and stores them
+// in players and team_rosters.
+func runRosters(args []string) {
+	fs := flag.NewFlagSet("rosters", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", true, "If set, print the scraped rosters instead of storing them")
+	pattern := fs.String("url-pattern", defaultTeamPageURL, "Team page URL; {slug} is replaced by the team name lower-cased with dashes")
+	var pages, only stringList
+	fs.Var(&pages, "page", "Team page for a team whose URL doesn't follow -url-pattern, as 'Team Name=URL'. Repeatable")
+	fs.Var(&only, "team", "Only scrape this team (name, alias or ID). Repeatable")
+	delay := fs.Duration("delay", 500*time.Millisecond, "Pause between page fetches")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if !strings.Contains(*pattern, "{slug}") {
+		exitErr(withCode(exitUsage, fmt.Errorf("-url-pattern %q has no {slug}", *pattern)))
+	}
+	pageFor := map[string]string{}
+	for _, p := range pages {
+		name, u, ok := strings.Cut(p, "=")
+		if !ok || !isURL(strings.TrimSpace(u)) {
+			exitErr(withCode(exitUsage, fmt.Errorf("invalid -page %q (want 'Team Name=URL')", p)))
+		}
+		pageFor[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(u)
+	}
+
+	ctx := context.Background()
+	var opts []showstore.Option
+	if *dryRun {
+		opts = append(opts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, opts...)
+	defer store.Close()
+
+	teams, err := store.GetAllTeams(ctx)
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	if len(only) > 0 {
+		var picked []showstore.Team
+		for _, want := range only {
+			t := lookupTeam(want, teams)
+			if t == nil {
+				exitErr(withCode(exitUsage, fmt.Errorf("unknown team %q", want)))
+			}
+			picked = append(picked, *t)
+		}
+		teams = picked
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if *dryRun {
+		fmt.Fprintln(tw, "TEAM\tMEMBERS\tNAMES")
+	}
+	var scraped, missing, failed int
+	for i, t := range teams {
+		if i > 0 {
+			time.Sleep(*delay)
+		}
+		pageURL := pageFor[strings.ToLower(t.Name)]
+		if pageURL == "" {
+			pageURL = strings.ReplaceAll(*pattern, "{slug}", teamSlug(t.Name))
+		}
+		log := slog.With("team", t.Name, "url", pageURL)
+		members, err := roster.Fetch(ctx, pageURL)
+		switch {
+		case errors.Is(err, roster.ErrNotFound):
+			log.Debug("no team page")
+			missing++
+			continue
+		case err != nil:
+			log.Warn("could not scrape team page", "err", err)
+			failed++
+			continue
+		case len(members) == 0:
+			// More likely a page layout the scraper doesn't know than an
+			// empty team, so leave any stored roster alone.
+			log.Warn("no members found on team page")
+			continue
+		}
+		scraped++
+		if *dryRun {
+			names := make([]string, len(members))
+			for j, m := range members {
+				names[j] = m.Name
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", t.Name, len(members), strings.Join(names, ", "))
+			continue
+		}
+		players := make([]showstore.Player, len(members))
+		for j, m := range members {
+			players[j] = showstore.Player{Name: m.Name, HeadshotURL: m.HeadshotURL, ProfileURL: m.ProfileURL}
+		}
+		added, removed, err := store.ReplaceRoster(ctx, t.ID, players)
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		log.Info("stored roster", "members", len(players), "joined", added, "left", removed)
+	}
+	tw.Flush()
+	slog.Info("rosters done", "teams", len(teams), "scraped", scraped, "no_page", missing, "failed", failed, "dry_run", *dryRun)
+	if failed > 0 {
+		os.Exit(exitPartial)
+	}
+}
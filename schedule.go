@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// scheduleWeek is a Monday-to-Sunday run of nights with shows.
+type scheduleWeek struct {
+	Start  time.Time
+	Nights []digestNight
+}
+
+// scheduleData is what render templates execute with.
+type scheduleData struct {
+	Title     string
+	Generated time.Time
+	Shows     []icalplayers.Event
+	Weeks     []scheduleWeek
+}
+
+// runRender writes upcoming shows as a static HTML site. Every *.html file
+// in -template not starting with "_" is executed into -out under the same
+// name (underscore files are partials); other files are copied as-is.
+// Without -template a built-in single-page schedule is used.
+func runRender(args []string) {
+	fset := flag.NewFlagSet("render", flag.ExitOnError)
+	tmplDir := fset.String("template", "", "Directory of Go html/template files and assets (default: built-in page)")
+	outDir := fset.String("out", "site", "Directory to write the site to")
+	days := fset.Int("days", 60, "Include shows starting within this many days")
+	title := fset.String("title", calendarName, "Page title passed to templates as .Title")
+	copyPosters := fset.Bool("copy-posters", false, "Download posters into <out>/posters and link those instead of the source URLs")
+	logOpts := addLogFlags(fset)
+	parseFlags(fset, args)
+	logOpts.setup()
+	if *days < 1 {
+		fmt.Fprintln(os.Stderr, "-days must be at least 1")
+		os.Exit(exitUsage)
+	}
+
+	tmpl, assets, err := loadSiteTemplates(*tmplDir)
+	if err != nil {
+		exitErr(withCode(exitUsage, err))
+	}
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		exitErr(err)
+	}
+	ctx := context.Background()
+	store := openStore(ctx, showstore.ReadOnly())
+	defer store.Close()
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	shows, _, err := store.ListShows(ctx, showstore.ShowFilter{From: now, To: today.AddDate(0, 0, *days)})
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		exitErr(err)
+	}
+	if *copyPosters {
+		copyPosterImages(ctx, shows, *outDir)
+	}
+	data := scheduleData{Title: *title, Generated: now, Shows: shows, Weeks: groupByWeek(groupByNight(shows, loc))}
+
+	pages := 0
+	for _, t := range tmpl.Templates() {
+		name := t.Name()
+		if !strings.HasSuffix(name, ".html") || strings.HasPrefix(name, "_") {
+			continue
+		}
+		if err := writeSitePage(filepath.Join(*outDir, name), tmpl, name, data); err != nil {
+			exitErr(err)
+		}
+		pages++
+	}
+	for _, a := range assets {
+		if err := copyFile(filepath.Join(*tmplDir, a), filepath.Join(*outDir, a)); err != nil {
+			exitErr(err)
+		}
+	}
+	slog.Info("rendered schedule", "shows", len(shows), "pages", pages, "assets", len(assets), "out", *outDir)
+}
+
+// loadSiteTemplates parses dir's *.html files and lists its other files,
+// relative to dir. An empty dir means the built-in template.
+func loadSiteTemplates(dir string) (*template.Template, []string, error) {
+	root := template.New("").Funcs(siteFuncs)
+	if dir == "" {
+		t, err := root.New("index.html").Parse(defaultSiteTemplate)
+		return t, nil, err
+	}
+	var assets []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if filepath.Ext(p) != ".html" || strings.Contains(rel, string(filepath.Separator)) {
+			assets = append(assets, rel)
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		_, err = root.New(rel).Parse(string(b))
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("-template: %w", err)
+	}
+	if root.Lookup("index.html") == nil {
+		return nil, nil, fmt.Errorf("-template: %s has no index.html", dir)
+	}
+	return root, assets, nil
+}
+
+// siteFuncs are the site templates' functions: join plus localeFuncs.
+var siteFuncs = func() template.FuncMap {
+	f := template.FuncMap(localeFuncs())
+	f["join"] = strings.Join
+	return f
+}()
+
+func writeSitePage(dst string, tmpl *template.Template, name string, data scheduleData) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.ExecuteTemplate(f, name, data); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return f.Close()
+}
+
+// groupByWeek buckets nights into Monday-start weeks.
+func groupByWeek(nights []digestNight) []scheduleWeek {
+	var out []scheduleWeek
+	for _, n := range nights {
+		offset := (int(n.Date.Weekday()) + 6) % 7 // days since Monday
+		monday := n.Date.AddDate(0, 0, -offset)
+		if len(out) == 0 || !out[len(out)-1].Start.Equal(monday) {
+			out = append(out, scheduleWeek{Start: monday})
+		}
+		out[len(out)-1].Nights = append(out[len(out)-1].Nights, n)
+	}
+	return out
+}
+
+// copyPosterImages downloads each show's poster into out/posters and points
+// PostImageURL at the local copy. Failures keep the remote URL.
+func copyPosterImages(ctx context.Context, shows []icalplayers.Event, out string) {
+	dir := filepath.Join(out, "posters")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		slog.Warn("could not create posters directory", "err", err)
+		return
+	}
+	client := httpclient.Default
+	for i, s := range shows {
+		if s.PostImageURL == "" {
+			continue
+		}
+		ext := path.Ext(strings.SplitN(s.PostImageURL, "?", 2)[0])
+		if ext == "" || len(ext) > 5 {
+			ext = ".jpg"
+		}
+		name := uidFileName(s.UID) + ext
+		if err := download(ctx, client, s.PostImageURL, filepath.Join(dir, name)); err != nil {
+			eventLogger(s).Warn("could not copy poster", "url", s.PostImageURL, "err", err)
+			continue
+		}
+		shows[i].PostImageURL = "posters/" + name
+	}
+}
+
+func download(ctx context.Context, client *http.Client, u, dst string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http status %d", resp.StatusCode)
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+const defaultSiteTemplate = `<!DOCTYPE html>
+<html lang="{{lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, Helvetica, Arial, sans-serif; max-width: 760px; margin: 0 auto; padding: 16px; color: #222; }
+h2 { margin-top: 40px; }
+h3 { border-bottom: 1px solid #ddd; padding-bottom: 4px; }
+.show { display: flex; gap: 16px; margin-bottom: 20px; }
+.show img { width: 160px; border-radius: 6px; }
+.time { color: #666; }
+footer { color: #888; font-size: 13px; margin-top: 40px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if not .Weeks}}<p>{{phrase "No upcoming shows."}}</p>{{end}}
+{{range .Weeks}}
+<h2>{{phrase "Week of"}} {{shortDate .Start}}</h2>
+{{range .Nights}}
+<h3>{{longDate .Date}}</h3>
+{{range .Shows}}
+<div class="show">
+{{if .PostImageURL}}<img src="{{.PostImageURL}}" alt="{{.Summary}} poster" loading="lazy">{{end}}
+<div>
+<div><strong>{{if .URL}}<a href="{{.URL}}">{{.Summary}}</a>{{else}}{{.Summary}}{{end}}</strong></div>
+<div class="time">{{time .Start}}</div>
+{{if .Teams}}<div>{{join .Teams ", "}}</div>{{end}}
+{{if .Players}}<div class="time">{{join .Players ", "}}</div>{{end}}
+</div>
+</div>
+{{end}}
+{{end}}
+{{end}}
+<footer>{{phrase "Updated"}} {{dateTime .Generated}}</footer>
+</body>
+</html>
+`
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// seasonRun is a season groupSeasons found, with its shows.
+type seasonRun struct {
+	showstore.Season
+	UIDs []string `json:"uids"`
+}
+
+// groupSeasons finds the runs of recurring series among shows: shows whose
+// summaries normalize alike (normalizeSummary), split wherever maxGap or
+// more passes between one and the next, keeping runs of at least minShows.
+// A run is named after its latest show's summary and dated in loc.
+func groupSeasons(shows []icalplayers.Event, minShows int, maxGap time.Duration, loc *time.Location) []seasonRun {
+	bySeries := map[string][]icalplayers.Event{}
+	for _, e := range shows {
+		if k := normalizeSummary(e.Summary); k != "" && e.Start != nil {
+			bySeries[k] = append(bySeries[k], e)
+		}
+	}
+	var out []seasonRun
+	flush := func(series string, run []icalplayers.Event) {
+		if len(run) < minShows {
+			return
+		}
+		last := run[len(run)-1]
+		r := seasonRun{Season: showstore.Season{
+			Name:   last.Summary,
+			Start:  run[0].Start.In(loc).Format(time.DateOnly),
+			End:    last.Start.In(loc).Format(time.DateOnly),
+			Series: series,
+			Shows:  len(run),
+		}}
+		r.ID = teamSlug(r.Name) + "-" + r.Start
+		for _, e := range run {
+			r.UIDs = append(r.UIDs, e.UID)
+		}
+		out = append(out, r)
+	}
+	for series, evs := range bySeries {
+		slices.SortFunc(evs, func(a, b icalplayers.Event) int {
+			return cmp.Or(a.Start.Compare(*b.Start), cmp.Compare(a.UID, b.UID))
+		})
+		start := 0
+		for i := 1; i < len(evs); i++ {
+			if evs[i].Start.Sub(*evs[i-1].Start) >= maxGap {
+				flush(series, evs[start:i])
+				start = i
+			}
+		}
+		flush(series, evs[start:])
+	}
+	slices.SortFunc(out, func(a, b seasonRun) int { return cmp.Or(cmp.Compare(a.Start, b.Start), cmp.Compare(a.ID, b.ID)) })
+	return out
+}
+
+// mergeSeasonRun keeps a grouped run in the season an earlier grouping made
+// for it: one of the same series whose dates meet or come within maxGap of
+// the run's. That season keeps its ID and name, which staff may have
+// changed, and its first date, which pruning may have removed the show of.
+// It returns nil if no stored season matches.
+func mergeSeasonRun(r *seasonRun, stored []showstore.Season, maxGap time.Duration) *showstore.Season {
+	days := int(maxGap.Hours() / 24)
+	for i, se := range stored {
+		if se.Series != r.Series {
+			continue
+		}
+		start, err1 := time.Parse(time.DateOnly, r.Start)
+		end, err2 := time.Parse(time.DateOnly, r.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if se.End < start.AddDate(0, 0, -days).Format(time.DateOnly) || se.Start > end.AddDate(0, 0, days).Format(time.DateOnly) {
+			continue
+		}
+		r.ID, r.Name = se.ID, se.Name
+		r.Start, r.End = min(r.Start, se.Start), max(r.End, se.End)
+		return &stored[i]
+	}
+	return nil
+}
+
+// runSeasons groups shows into named runs:
+//
+//	shopsync seasons group [-min-shows 3] [-max-gap 336h] [-dry-run=false]
+//	shopsync seasons set -name NAME [-id ID] [-from DATE -to DATE] [-match TEXT] [-show UID]...
+//	shopsync seasons list
+//	shopsync seasons delete -id ID
+func runSeasons(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: shopsync seasons group|set|list|delete [flags]")
+		os.Exit(exitUsage)
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet("seasons "+cmd, flag.ExitOnError)
+	minShows := fs.Int("min-shows", 3, "Fewest shows that make a run (group)")
+	maxGap := fs.Duration("max-gap", 14*24*time.Hour, "A gap this long between shows of a series ends a run (group)")
+	dryRun := fs.Bool("dry-run", true, "If set, only print the runs that would be saved (group)")
+	id := fs.String("id", "", "Season ID, used in /seasons/{id}; set derives one from -name (set, delete)")
+	name := fs.String("name", "", "Season name, e.g. 'Fall Mainstage Run' (set)")
+	from := fs.String("from", "", "First date, YYYY-MM-DD in venue time; default the first show's (set)")
+	to := fs.String("to", "", "Last date, inclusive; default the last show's (set)")
+	match := fs.String("match", "", "Add the shows between -from and -to whose summary contains this text, any case (set)")
+	var shows stringList
+	fs.Var(&shows, "show", "Add the show with this UID (set). Repeatable")
+	output := fs.String("output", "text", "Output format: text or json (group, list)")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args[1:])
+	logOpts.setup()
+
+	switch cmd {
+	case "group":
+		if *minShows < 2 {
+			exitErr(withCode(exitUsage, errors.New("-min-shows must be at least 2")))
+		}
+		if *maxGap <= 0 {
+			exitErr(withCode(exitUsage, errors.New("-max-gap must be positive")))
+		}
+	case "set":
+		if *name == "" {
+			exitErr(withCode(exitUsage, errors.New("seasons set needs -name")))
+		}
+		if *id == "" {
+			*id = teamSlug(*name)
+		}
+		if len(shows) == 0 && *match == "" && (*from == "" || *to == "") {
+			exitErr(withCode(exitUsage, errors.New("seasons set needs -show, -match, or both -from and -to")))
+		}
+		for _, d := range []string{*from, *to} {
+			if _, err := time.Parse(time.DateOnly, d); d != "" && err != nil {
+				exitErr(withCode(exitUsage, fmt.Errorf("invalid date %q: want YYYY-MM-DD", d)))
+			}
+		}
+	case "list":
+	case "delete":
+		if *id == "" {
+			exitErr(withCode(exitUsage, errors.New("seasons delete needs -id")))
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown seasons command %q\n", cmd)
+		os.Exit(exitUsage)
+	}
+	if !validOutput(*output) {
+		exitErr(withCode(exitUsage, fmt.Errorf("invalid -output %q (want text or json)", *output)))
+	}
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		exitErr(err)
+	}
+	ctx := context.Background()
+	var opts []showstore.Option
+	if cmd == "list" || cmd == "group" && *dryRun {
+		opts = append(opts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, opts...)
+	defer store.Close()
+
+	switch cmd {
+	case "group":
+		groupSeasonsCmd(ctx, store, *minShows, *maxGap, *dryRun, *output, loc)
+	case "set":
+		se := showstore.Season{ID: *id, Name: *name, Start: *from, End: *to}
+		uids := slices.Clone([]string(shows))
+		if *match != "" {
+			f := showstore.ShowFilter{Query: *match, Types: []string{icalplayers.TypeShow}}
+			if *from != "" {
+				f.From, _ = time.ParseInLocation(time.DateOnly, *from, loc)
+			}
+			if *to != "" {
+				t, _ := time.ParseInLocation(time.DateOnly, *to, loc)
+				f.To = t.AddDate(0, 0, 1)
+			}
+			found, _, err := store.ListShows(ctx, f)
+			if err != nil {
+				exitErr(dbErr(err))
+			}
+			for _, e := range found {
+				if strings.Contains(strings.ToLower(e.Summary), strings.ToLower(*match)) {
+					uids = append(uids, e.UID)
+				}
+			}
+		}
+		if se.Start == "" || se.End == "" {
+			for _, uid := range uids {
+				e, err := store.GetShow(ctx, uid)
+				if err != nil {
+					exitErr(dbErr(err))
+				}
+				if e == nil {
+					exitErr(fmt.Errorf("no show with uid %q", uid))
+				}
+				if e.Start == nil {
+					continue
+				}
+				d := e.Start.In(loc).Format(time.DateOnly)
+				if *from == "" && (se.Start == "" || d < se.Start) {
+					se.Start = d
+				}
+				if *to == "" && d > se.End {
+					se.End = d
+				}
+			}
+		}
+		if se.Start == "" || se.End == "" {
+			exitErr(withCode(exitUsage, errors.New("no dated shows to take the season's dates from; give -from and -to")))
+		}
+		if se.End < se.Start {
+			exitErr(withCode(exitUsage, fmt.Errorf("season ends (%s) before it starts (%s)", se.End, se.Start)))
+		}
+		slices.Sort(uids)
+		uids = slices.Compact(uids)
+		if err := store.SaveSeason(ctx, se, uids); err != nil {
+			exitErr(dbErr(err))
+		}
+		slog.Info("saved season", "id", se.ID, "name", se.Name, "start", se.Start, "end", se.End, "shows", len(uids))
+	case "list":
+		seasons, err := store.ListSeasons(ctx)
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		if *output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(append([]showstore.Season{}, seasons...)); err != nil {
+				exitErr(err)
+			}
+			return
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tSTART\tEND\tSHOWS\tGROUPED")
+		for _, se := range seasons {
+			grouped := "no"
+			if se.Series != "" {
+				grouped = "yes"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", se.ID, truncateStr(se.Name, 40), se.Start, se.End, se.Shows, grouped)
+		}
+		tw.Flush()
+	case "delete":
+		found, err := store.DeleteSeason(ctx, *id)
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		if !found {
+			exitErr(fmt.Errorf("no season %q", *id))
+		}
+		slog.Info("deleted season", "id", *id)
+	}
+}
+
+// groupSeasonsCmd saves the runs of stored shows' series as seasons,
+// extending those earlier groupings made. Shows already in a season staff
+// made by hand are left out of grouping.
+func groupSeasonsCmd(ctx context.Context, store *showstore.Store, minShows int, maxGap time.Duration, dryRun bool, output string, loc *time.Location) {
+	stored, err := store.ListSeasons(ctx)
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	shows, _, err := store.ListShows(ctx, showstore.ShowFilter{Types: []string{icalplayers.TypeShow}})
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	handMade := map[string]bool{}
+	for _, se := range stored {
+		if se.Series != "" {
+			continue
+		}
+		members, _, err := store.ListShows(ctx, showstore.ShowFilter{Season: se.ID})
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		for _, e := range members {
+			handMade[e.UID] = true
+		}
+	}
+	shows = slices.DeleteFunc(shows, func(e icalplayers.Event) bool { return handMade[e.UID] })
+
+	runs := groupSeasons(shows, minShows, maxGap, loc)
+	for i := range runs {
+		r := &runs[i]
+		if se := mergeSeasonRun(r, stored, maxGap); se != nil {
+			se.Series = "" // claimed; a second run of the series gets its own season
+			slog.Debug("extending season", "id", r.ID, "start", r.Start, "end", r.End)
+		}
+		if dryRun {
+			continue
+		}
+		if err := store.SaveSeason(ctx, r.Season, r.UIDs); err != nil {
+			exitErr(dbErr(err))
+		}
+		slog.Info("saved season", "id", r.ID, "name", r.Name, "start", r.Start, "end", r.End, "shows", len(r.UIDs))
+	}
+	if output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(map[string]any{"dryRun": dryRun, "seasons": append([]seasonRun{}, runs...)}); err != nil {
+			exitErr(err)
+		}
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSTART\tEND\tSHOWS")
+	for _, r := range runs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n", r.ID, truncateStr(r.Name, 40), r.Start, r.End, len(r.UIDs))
+	}
+	tw.Flush()
+	if dryRun {
+		fmt.Printf("%d runs; run with -dry-run=false to save them as seasons.\n", len(runs))
+	}
+}
+
+// seasonPage is GET /seasons/{id}: the season and every date of it.
+type seasonPage struct {
+	showstore.Season
+	Dates []icalplayers.Event `json:"dates"`
+}
+
+// handleSeasons lists the seasons, latest first.
+func (s *server) handleSeasons(w http.ResponseWriter, r *http.Request) {
+	seasons, err := s.store.ListSeasons(r.Context())
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	if seasons == nil {
+		seasons = []showstore.Season{}
+	}
+	writeJSON(w, http.StatusOK, seasons)
+}
+
+// handleSeason serves a season with all its shows, soonest first.
+func (s *server) handleSeason(w http.ResponseWriter, r *http.Request) {
+	se, err := s.store.GetSeason(r.Context(), r.PathValue("id"))
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	if se == nil {
+		writeError(w, http.StatusNotFound, errors.New("season not found"))
+		return
+	}
+	shows, _, err := s.store.ListShows(r.Context(), showstore.ShowFilter{Season: se.ID})
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	if shows == nil {
+		shows = []icalplayers.Event{}
+	}
+	writeJSON(w, http.StatusOK, seasonPage{Season: *se, Dates: localizeEvents(shows)})
+}
@@ -0,0 +1,623 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/daterange"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+
+	// calendarName is the X-WR-CALNAME of the published feed.
+	calendarName = "The Improv Shop"
+	// calendarHistory is how far back the published feed reaches, so
+	// subscribers still see last week's shows.
+	calendarHistory = 30 * 24 * time.Hour
+	// maxCalendarAlarms caps the reminders per show in the published feed.
+	maxCalendarAlarms = 3
+)
+
+// server exposes the store over HTTP: read-only, apart from the optional
+// /admin UI and POST /sync.
+type server struct {
+	store           *showstore.Store
+	loc             *time.Location
+	freshness       time.Duration
+	privateCalendar bool            // require a calendar_tokens token for /calendar.ics
+	calendarAlarms  []time.Duration // VALARMs per show in /calendar.ics; ?alarm= overrides
+	trigger         *syncTrigger    // POST /sync under -sync
+	admin           *admin
+	posters         *posters
+	keys            *apiKeys // nil unless -api-keys
+	cors            corsOrigins
+	cacheAges       map[string]time.Duration // by endpoint; see defaultCacheAges
+	etags           bool
+	swaggerUI       bool        // serve /docs
+	discord         *discordBot // nil unless -discord-public-key
+	captions        *template.Template
+}
+
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	freshness := fs.Duration("freshness", 6*time.Hour, "Report not ready when the last successful sync in sync_runs is older than this (0 disables)")
+	enableAdmin := fs.Bool("admin", false, "Serve the curation UI at /admin (basic auth as admin with $ADMIN_PASSWORD); needs write access to the database")
+	grpcAddr := fs.String("grpc-addr", "", "Also serve the gRPC Shows API (pkg/showpb) on this address, e.g. :9090 (disabled if empty)")
+	watchInterval := fs.Duration("watch-interval", 15*time.Second, "How often gRPC WatchShows streams poll the database for changes")
+	privateCalendar := fs.Bool("private-calendar", false, "Require a subscriber token (see 'shopsync tokens') for the ICS calendar, as ?token= or /subscribe/{token}/calendar.ics")
+	var calendarAlarms stringList
+	fs.Var(&calendarAlarms, "calendar-alarm", fmt.Sprintf("Remind calendar subscribers this long before each show, e.g. 2h or 30m, with a VALARM; up to %d, repeatable. Subscribers can override it with ?alarm=1h,15m or ?alarm=none", maxCalendarAlarms))
+	requireKeys := fs.Bool("api-keys", false, "Require an API key (see 'shopsync keys') for /shows, /teams and /players (read scope) and accept admin-scope keys for /admin")
+	var corsOrigin, cacheAges stringList
+	fs.Var(&corsOrigin, "cors-origin", "Let browser pages on this origin (e.g. https://theimprovshop.com, or * for any) call the API; repeatable")
+	fs.Var(&cacheAges, "cache", "Override an endpoint's Cache-Control max-age as ENDPOINT=DURATION, e.g. shows=5m (endpoints: shows, teams, players, calendar, feed, schema, qr; 0 means revalidate every time); repeatable")
+	etags := fs.Bool("etag", true, "Send ETags on cacheable responses and answer If-None-Match with 304 Not Modified")
+	swaggerUI := fs.Bool("swagger-ui", false, "Serve a Swagger UI for /openapi.json at /docs (loads its assets from unpkg.com)")
+	discordKey := fs.String("discord-public-key", "", "Answer Discord slash commands (/nextshow, /tonight) at /discord/interactions; the application's public key (see 'shopsync discord register')")
+	captionTemplate := fs.String("caption-template", "", "Go text/template file for /shows/{uid}/caption, as in 'shopsync captions -template' (default: built-in)")
+	enableSync := fs.Bool("sync", false, "Serve POST /sync, for an admin-scope API key, to run a sync in this process with the ingest flags below (usually set by -venue); needs -api-keys")
+	var syncOpts ingestOptions
+	syncOpts.register(fs)
+	imagesDir := fs.String("images-dir", "", "Directory of downloaded post images to serve /images/{uid} from before falling back to the venue's site")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		exitErr(err)
+	}
+	password := os.Getenv("ADMIN_PASSWORD")
+	if *grpcAddr != "" && *watchInterval <= 0 {
+		exitErr(withCode(exitUsage, errors.New("-watch-interval must be positive")))
+	}
+	if *enableAdmin && password == "" && !*requireKeys {
+		exitErr(withCode(exitUsage, errors.New("-admin needs ADMIN_PASSWORD or -api-keys")))
+	}
+	if *enableSync {
+		if !*requireKeys {
+			exitErr(withCode(exitUsage, errors.New("-sync needs -api-keys: POST /sync takes an admin-scope key")))
+		}
+		if err := syncOpts.validate(); err != nil {
+			exitErr(err)
+		}
+		if slices.Contains(syncOpts.srcs, "-") {
+			exitErr(withCode(exitUsage, errors.New("serve -sync cannot read -src from stdin")))
+		}
+	}
+	alarms, err := parseCalendarAlarms(calendarAlarms)
+	if err != nil {
+		exitErr(withCode(exitUsage, fmt.Errorf("-calendar-alarm: %w", err)))
+	}
+	ages, err := parseCacheAges(cacheAges)
+	if err != nil {
+		exitErr(withCode(exitUsage, err))
+	}
+	captions, err := loadCaptionTemplate(*captionTemplate)
+	if err != nil {
+		exitErr(withCode(exitUsage, fmt.Errorf("-caption-template: %w", err)))
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var storeOpts []showstore.Option
+	if !*enableAdmin && !*enableSync {
+		storeOpts = append(storeOpts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, storeOpts...)
+	defer store.Close()
+
+	s := &server{store: store, loc: loc, freshness: *freshness, privateCalendar: *privateCalendar, calendarAlarms: alarms, posters: newPosters(*imagesDir),
+		cors: corsOrigins(corsOrigin), cacheAges: ages, etags: *etags, swaggerUI: *swaggerUI, captions: captions}
+	if *requireKeys {
+		s.keys = newAPIKeys(store)
+	}
+	if *discordKey != "" {
+		if s.discord, err = newDiscordBot(*discordKey, store, loc); err != nil {
+			exitErr(withCode(exitUsage, err))
+		}
+	}
+	if *enableSync {
+		s.trigger = newSyncTrigger("", store)
+		in := &ingester{store: store, opts: syncOpts, feedCache: map[string]*feedCacheEntry{}, notifiers: syncOpts.notifiers(), sinks: configuredSinks()}
+		go runTriggered(ctx, in, s.trigger)
+	}
+	if *enableAdmin {
+		s.admin = &admin{store: store, loc: displayLoc(loc), password: password, keys: s.keys}
+	}
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if *grpcAddr != "" {
+		go func() {
+			if err := serveGRPC(ctx, *grpcAddr, &showsService{store: store, watchInterval: *watchInterval}); err != nil {
+				exitErr(err)
+			}
+		}()
+	}
+	slog.Info("serving", append(currentBuildInfo().logAttrs(), "addr", *addr)...)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		exitErr(err)
+	}
+}
+
+// apiRoutes are the endpoints described in /openapi.json.
+func (s *server) apiRoutes() []apiRoute {
+	routes := []apiRoute{
+		{"GET", "/shows", s.read(s.cached("shows", s.handleShows)), apiDoc{summary: "List shows", read: true, schema: "ShowPage",
+			query: append(slices.Clone(showQuery), apiParam{name: "team", typ: "string", desc: "Only shows by this team ID"})}},
+		{"GET", "/classes", s.read(s.cached("shows", s.handleClasses)), apiDoc{summary: "List classes and workshops, which /shows leaves out", read: true, schema: "ShowPage", query: showQuery}},
+		{"GET", "/shows/{uid}", s.read(s.cached("shows", s.handleShow)), apiDoc{summary: "Get a show", read: true, schema: "event", notFound: true}},
+		{"GET", "/shows/{uid}/caption", s.read(s.cached("shows", s.handleCaption)), apiDoc{summary: "A ready-to-post promo caption for a show", read: true, media: "text/plain", notFound: true}},
+		{"GET", "/shows/{uid}/qr.png", s.cached("qr", s.handleQR), apiDoc{summary: "A QR code of a show's short link, or of its ticket URL without one", media: "image/png", notFound: true,
+			query: []apiParam{{name: "size", typ: "integer", desc: fmt.Sprintf("Width in pixels, 21-%d (default %d)", maxQRSize, defaultQRSize)}}}},
+		{"GET", "/s/{code}", s.handleShortLink, apiDoc{summary: "Redirect a short link to its show's ticket URL", redirect: true, notFound: true}},
+		{"GET", "/images/{uid}", s.handlePoster, apiDoc{summary: "A show's poster image", media: "image/*", notFound: true,
+			query: []apiParam{{name: "w", typ: "integer", desc: fmt.Sprintf("Scale down to this width in pixels (at most %d)", maxPosterWidth)}}}},
+		{"GET", "/teams", s.read(s.cached("teams", s.handleTeams)), apiDoc{summary: "List teams", read: true, schema: "Team", array: true}},
+		{"GET", "/teams/{id}/shows", s.read(s.cached("teams", s.handleTeamShows)), apiDoc{summary: "List a team's shows", read: true, schema: "ShowPage", query: showQuery, notFound: true}},
+		{"GET", "/overlaps", s.read(s.cached("shows", s.handleOverlaps)), apiDoc{summary: "Pairs of shows double-booked on one stage, or too close for the turnaround", read: true, schema: "Overlap", array: true,
+			query: []apiParam{
+				{name: "from", typ: "string", desc: "Earliest start, as for /shows (default now)"},
+				{name: "to", typ: "string", desc: "Latest start, inclusive"},
+				{name: "team", typ: "string", desc: "Only shows by this team ID"},
+				{name: "length", typ: "string", desc: "How long a show runs, e.g. 90m; stored shows have no end time. Without it shows are taken to last an hour and only overlaps are reported"},
+				{name: "turnaround", typ: "string", desc: fmt.Sprintf("Shortest acceptable gap between shows, with length (default %s)", defaultMinTurnaround)},
+			}}},
+		{"GET", "/seasons", s.read(s.cached("shows", s.handleSeasons)), apiDoc{summary: "List seasons: named runs of shows, latest first", read: true, schema: "Season", array: true}},
+		{"GET", "/seasons/{id}", s.read(s.cached("shows", s.handleSeason)), apiDoc{summary: "A season with all its dates", read: true, schema: "SeasonPage", notFound: true}},
+		{"GET", "/players/{name}", s.read(s.cached("players", s.handlePlayer)), apiDoc{summary: "A performer's page: roster profile, teams, upcoming and recent shows", read: true, schema: "Player", notFound: true}},
+		{"GET", "/calendar.ics", s.cached("calendar", s.handleCalendar), apiDoc{summary: "Shows as an ICS calendar", media: "text/calendar",
+			query: []apiParam{{name: "token", typ: "string", desc: "Subscriber token; required under -private-calendar"}, calendarAlarmParam}}},
+		{"GET", "/subscribe/{token}/calendar.ics", s.cached("calendar", s.handleCalendar), apiDoc{summary: "Shows as an ICS calendar, for a subscriber token", media: "text/calendar",
+			query: []apiParam{calendarAlarmParam}}},
+		{"GET", "/feed.xml", s.cached("feed", s.handleFeed), apiDoc{summary: "Upcoming shows as an Atom feed", media: "application/atom+xml"}},
+		{"GET", "/board", s.handleBoard, apiDoc{summary: "Tonight's shows for the lobby TV: an auto-refreshing page, or JSON", media: "text/html",
+			query: []apiParam{{name: "format", typ: "string", desc: "json for the data behind the page", enum: []string{"html", "json"}}}}},
+		{"GET", "/about", s.handleAbout, apiDoc{summary: "Build and schema versions", schema: "BuildInfo"}},
+		{"GET", "/schema/event.json", s.cached("schema", s.handleEventSchema), apiDoc{summary: "JSON Schema of a show", media: "application/schema+json"}},
+	}
+	if s.trigger != nil {
+		routes = append(routes, apiRoute{"POST", "/sync", s.keys.require(showstore.ScopeAdmin, s.trigger.handleSync), apiDoc{summary: "Start a sync now; syncs already queued absorb it", admin: true, accepted: true,
+			query: []apiParam{{name: "idempotency_key", typ: "string", desc: "Acknowledge without syncing again if this key was already seen; or send an Idempotency-Key header"}}}})
+	}
+	return routes
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	routes := s.apiRoutes()
+	for _, rt := range routes {
+		mux.HandleFunc(rt.method+" "+rt.path, rt.handler)
+	}
+	mux.HandleFunc("GET /openapi.json", s.handleOpenAPI(routes))
+	if s.swaggerUI {
+		mux.HandleFunc("GET /docs", s.handleSwaggerUI)
+	}
+	mux.Handle("GET /metrics", metricsHandler())
+	(&healthChecks{store: s.store, freshness: s.freshness, lastSync: s.store.LastSuccessfulSync}).register(mux)
+	if s.admin != nil {
+		s.admin.register(mux)
+	}
+	if s.discord != nil {
+		s.discord.register(mux)
+	}
+	return logRequests(s.cors.handler(mux))
+}
+
+// read guards a JSON API endpoint with a read-scope key under -api-keys.
+// The calendar, feed, posters, health and metrics stay public.
+func (s *server) read(h http.HandlerFunc) http.HandlerFunc {
+	if s.keys == nil {
+		return h
+	}
+	return s.keys.require(showstore.ScopeRead, h)
+}
+
+// showPage is the body of the show listing endpoints. Shows holds
+// icalplayers.Events, or just the ?fields= of each.
+type showPage struct {
+	Shows      any    `json:"shows"`
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// handleShows lists shows. Query parameters: from and to (YYYY-MM-DD or a
+// phrase like "this weekend" in venue time, to inclusive; see daterange),
+// upcoming=true, team (team ID), q (text search), sort (start or
+// updated_at), fields (comma-separated event fields to return), limit, and
+// offset or cursor (a previous page's nextCursor).
+func (s *server) handleShows(w http.ResponseWriter, r *http.Request) {
+	f, err := s.showFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.writeShows(w, r, f)
+}
+
+// handleClasses lists classes and workshops, which /shows leaves out. It
+// takes the same parameters; ?type narrows it to one of them.
+func (s *server) handleClasses(w http.ResponseWriter, r *http.Request) {
+	f, err := s.showFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if r.URL.Query().Get("type") == "" {
+		f.Types = []string{icalplayers.TypeClass, icalplayers.TypeWorkshop}
+	}
+	s.writeShows(w, r, f)
+}
+
+func (s *server) handleShow(w http.ResponseWriter, r *http.Request) {
+	e, err := s.store.GetShow(r.Context(), r.PathValue("uid"))
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	if e == nil {
+		writeError(w, http.StatusNotFound, errors.New("show not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, localizeEvent(*e))
+}
+
+// teamJSON is a team as served by the API.
+type teamJSON struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+func (s *server) handleTeams(w http.ResponseWriter, r *http.Request) {
+	teams, err := s.store.GetAllTeams(r.Context())
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Name < teams[j].Name })
+	out := make([]teamJSON, 0, len(teams))
+	for _, t := range teams {
+		out = append(out, teamJSON{ID: t.ID, Name: t.Name, Aliases: t.Aliases})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *server) handleTeamShows(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	t, err := s.store.GetTeam(r.Context(), id)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	if t == nil {
+		writeError(w, http.StatusNotFound, errors.New("team not found"))
+		return
+	}
+	f, err := s.showFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	f.TeamID = t.ID
+	s.writeShows(w, r, f)
+}
+
+const (
+	// playerUpcoming caps the upcoming shows on a player's page.
+	playerUpcoming = 20
+	// playerRecent is how far back a player's recent shows go.
+	playerRecent = 90 * 24 * time.Hour
+)
+
+// playerProfile is a performer's page: their roster entry, when rosters
+// list them, plus the shows that name them.
+type playerProfile struct {
+	showstore.Player
+	Teams    []teamJSON          `json:"teams"`    // from rosters and from their shows
+	Upcoming []icalplayers.Event `json:"upcoming"` // soonest first
+	Recent   []icalplayers.Event `json:"recent"`   // past playerRecent, latest first
+}
+
+// handlePlayer returns a performer's page for a name as it appears in a
+// show's players (any case). Names no roster lists work too, as long as
+// some show names them.
+func (s *server) handlePlayer(w http.ResponseWriter, r *http.Request) {
+	ctx, name := r.Context(), r.PathValue("name")
+	p, err := s.store.GetPlayer(ctx, name)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	now := time.Now()
+	upcoming, _, err := s.store.ListShows(ctx, showstore.ShowFilter{Player: name, From: now, Limit: playerUpcoming})
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	recent, _, err := s.store.ListShows(ctx, showstore.ShowFilter{Player: name, From: now.Add(-playerRecent), To: now})
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	if p == nil && len(upcoming) == 0 && len(recent) == 0 {
+		writeError(w, http.StatusNotFound, errors.New("player not found"))
+		return
+	}
+	slices.Reverse(recent)
+	out := playerProfile{Upcoming: localizeEvents(upcoming), Recent: localizeEvents(recent), Teams: []teamJSON{}}
+	if p != nil {
+		out.Player = *p
+	} else {
+		// Spelled as the latest show has it.
+		out.Name = name
+		key := func(n string) string { return strings.ToLower(strings.Join(strings.Fields(n), " ")) }
+		for _, n := range slices.Concat(recent, upcoming)[0].Players {
+			if key(n) == key(name) {
+				out.Name = n
+				break
+			}
+		}
+	}
+	ids := slices.Clone(out.TeamIDs)
+	for _, e := range slices.Concat(upcoming, recent) {
+		ids = append(ids, e.TeamIDs...)
+	}
+	slices.Sort(ids)
+	if ids = slices.Compact(ids); len(ids) > 0 {
+		all, err := s.store.GetAllTeams(ctx)
+		if err != nil {
+			s.internalError(w, r, err)
+			return
+		}
+		for _, t := range all {
+			if _, ok := slices.BinarySearch(ids, t.ID); ok {
+				out.Teams = append(out.Teams, teamJSON{ID: t.ID, Name: t.Name})
+			}
+		}
+		slices.SortFunc(out.Teams, func(a, b teamJSON) int { return strings.Compare(a.Name, b.Name) })
+	}
+	if out.Upcoming == nil {
+		out.Upcoming = []icalplayers.Event{}
+	}
+	if out.Recent == nil {
+		out.Recent = []icalplayers.Event{}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleCalendar publishes stored shows, already deduplicated and enriched
+// with teams and images, as an ICS feed. ?alarm= replaces -calendar-alarm
+// for the subscriber, since the upstream feed carries no reminders.
+func (s *server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	if status, err := s.checkCalendarToken(r); err != nil {
+		if status == http.StatusInternalServerError {
+			s.internalError(w, r, err)
+		} else {
+			writeError(w, status, err)
+		}
+		return
+	}
+	shows, _, err := s.store.ListShows(r.Context(), showstore.ShowFilter{From: time.Now().Add(-calendarHistory), Types: []string{icalplayers.TypeShow}})
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	var buf bytes.Buffer
+	alarms := s.calendarAlarms
+	if v := r.URL.Query().Get("alarm"); v != "" {
+		if alarms, err = parseCalendarAlarms([]string{v}); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid alarm: %w", err))
+			return
+		}
+	}
+	if err := icalplayers.WriteICS(&buf, shows, calendarName, alarms...); err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	if s.privateCalendar {
+		w.Header().Set("Cache-Control", "private") // see cached
+	}
+	_, _ = buf.WriteTo(w)
+}
+
+// calendarAlarmParam documents ?alarm= on the calendar routes.
+var calendarAlarmParam = apiParam{name: "alarm", typ: "string", desc: "Reminders before each show, e.g. 2h,15m, or none; replaces -calendar-alarm"}
+
+// parseCalendarAlarms reads -calendar-alarm and ?alarm= values:
+// comma-separated durations before a show, or "none".
+func parseCalendarAlarms(values []string) ([]time.Duration, error) {
+	var out []time.Duration
+	for _, v := range values {
+		for _, f := range strings.Split(v, ",") {
+			f = strings.TrimSpace(f)
+			if f == "" || f == "none" {
+				continue
+			}
+			d, err := time.ParseDuration(f)
+			if err != nil || d <= 0 || d > 7*24*time.Hour {
+				return nil, fmt.Errorf("%q: want a duration before the show like 2h or 30m, at most 168h", f)
+			}
+			if !slices.Contains(out, d) {
+				out = append(out, d)
+			}
+		}
+	}
+	if len(out) > maxCalendarAlarms {
+		return nil, fmt.Errorf("at most %d alarms", maxCalendarAlarms)
+	}
+	return out, nil
+}
+
+func (s *server) writeShows(w http.ResponseWriter, r *http.Request, f showstore.ShowFilter) {
+	fields, err := parseFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// One extra row says whether there is a next page.
+	limit := f.Limit
+	f.Limit++
+	shows, total, err := s.store.ListShows(r.Context(), f)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	page := showPage{Total: total, Limit: limit, Offset: f.Offset}
+	if len(shows) > limit {
+		shows = shows[:limit]
+		page.NextCursor = encodeCursor(f.Sort, shows[limit-1])
+	}
+	if shows == nil {
+		shows = []icalplayers.Event{}
+	}
+	shows = localizeEvents(shows)
+	page.Shows = shows
+	if fields != nil {
+		if page.Shows, err = projectEvents(shows, fields); err != nil {
+			s.internalError(w, r, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// showFilter reads the listing query parameters.
+func (s *server) showFilter(r *http.Request) (showstore.ShowFilter, error) {
+	q := r.URL.Query()
+	f := showstore.ShowFilter{
+		TeamID: q.Get("team"),
+		Query:  q.Get("q"),
+		Limit:  defaultPageSize,
+		Types:  []string{icalplayers.TypeShow},
+	}
+	var err error
+	if v := q.Get("type"); v != "" {
+		if f.Types, err = parseEventTypes([]string{v}); err != nil {
+			return f, err
+		}
+	}
+	if f.From, f.To, err = daterange.Window(q.Get("from"), q.Get("to"), time.Now().In(s.loc)); err != nil {
+		return f, err
+	}
+	if v := q.Get("upcoming"); v != "" {
+		upcoming, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid upcoming %q", v)
+		}
+		if upcoming && (f.From.IsZero() || f.From.Before(time.Now())) {
+			f.From = time.Now()
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 1 || f.Limit > maxPageSize {
+			return f, fmt.Errorf("invalid limit %q: want 1-%d", v, maxPageSize)
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if f.Offset, err = strconv.Atoi(v); err != nil || f.Offset < 0 {
+			return f, fmt.Errorf("invalid offset %q", v)
+		}
+	}
+	if f.Sort, err = showstore.ParseShowSort(q.Get("sort")); err != nil {
+		return f, err
+	}
+	if v := q.Get("cursor"); v != "" {
+		if f.Offset > 0 {
+			return f, errors.New("use cursor or offset, not both")
+		}
+		if f.After, err = decodeCursor(v, f.Sort); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+func (s *server) handleAbout(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, currentBuildInfo())
+}
+
+// handleEventSchema publishes the JSON Schema the show endpoints' events
+// follow.
+func (s *server) handleEventSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	_, _ = w.Write(icalplayers.EventSchema())
+}
+
+func (s *server) internalError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.Error("request failed", "method", r.Method, "path", r.URL.Path, "err", err)
+	writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// statusRecorder captures the response status for request logging.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		metricHTTPRequests.WithLabelValues(route, strconv.Itoa(rec.status)).Inc()
+		path := r.URL.Path
+		if r.PathValue("token") != "" {
+			path = route // keep subscriber tokens out of logs
+		}
+		slog.Debug("request", "method", r.Method, "path", path, "status", rec.status, "duration", time.Since(start))
+	})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// GET /shows/{uid} answers with the same event a GET /shows page holds.
+func TestShowShape(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+	team, err := store.CreateTeam(ctx, "Shape Ensemble "+time.Now().Format("150405.000000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2030, 1, 4, 20, 0, 0, 0, time.UTC)
+	err = store.Upsert(ctx, icalplayers.Event{
+		UID:         "shape-1",
+		Summary:     "Friday Night Harold",
+		Description: "Cast: Maya Ortiz, Devon Clarke",
+		URL:         "https://example.com/show/shape-1/",
+		Start:       &start,
+		Location:    "Main Stage",
+		Players:     []string{"Maya Ortiz", "Devon Clarke"},
+		Teams:       []string{team.Name},
+		TeamIDs:     []string{team.ID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := (&server{store: store, loc: time.UTC}).routes()
+	get := func(path string, v any) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d: %s", path, w.Code, w.Body)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+	}
+	var page struct {
+		Shows []map[string]any `json:"shows"`
+	}
+	get("/shows", &page)
+	if len(page.Shows) != 1 {
+		t.Fatalf("GET /shows has %d shows, want 1", len(page.Shows))
+	}
+	var one map[string]any
+	get("/shows/shape-1", &one)
+
+	if !reflect.DeepEqual(one, page.Shows[0]) {
+		t.Errorf("GET /shows/shape-1 = %v\nGET /shows has %v", one, page.Shows[0])
+	}
+	if ids, _ := one["teamIds"].([]any); len(ids) != 1 || ids[0] != team.ID {
+		t.Errorf("teamIds = %v, want [%s]", one["teamIds"], team.ID)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// showCursor is where a /shows page ended. It goes to clients as an
+// opaque base64 token (nextCursor) that they pass back as ?cursor=.
+type showCursor struct {
+	Sort showstore.ShowSort `json:"s"`
+	At   *time.Time         `json:"t,omitempty"`
+	UID  string             `json:"u"`
+}
+
+func encodeCursor(sort showstore.ShowSort, e icalplayers.Event) string {
+	k := sort.Key(e)
+	b, _ := json.Marshal(showCursor{Sort: sort, At: k.At, UID: k.UID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor reads a ?cursor= for a listing in sort order.
+func decodeCursor(v string, sort showstore.ShowSort) (*showstore.ShowKey, error) {
+	var c showCursor
+	b, err := base64.RawURLEncoding.DecodeString(v)
+	if err == nil {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil || c.UID == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	if c.Sort != sort {
+		return nil, fmt.Errorf("cursor is for sort=%s, not %s", c.Sort, sort)
+	}
+	return &showstore.ShowKey{At: c.At, UID: c.UID}, nil
+}
+
+// eventFields are the JSON names of icalplayers.Event, what ?fields= may
+// pick from.
+var eventFields = func() []string {
+	var names []string
+	t := reflect.TypeFor[icalplayers.Event]()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}()
+
+// parseFields reads a comma-separated ?fields= list; nil means all fields.
+func parseFields(v string) ([]string, error) {
+	if v == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, f := range strings.Split(v, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !slices.Contains(eventFields, f) {
+			return nil, fmt.Errorf("unknown field %q (want some of %s)", f, strings.Join(eventFields, ", "))
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// projectEvents keeps only fields of each event. Fields an event leaves
+// out (empty omitempty ones) stay out.
+func projectEvents(events []icalplayers.Event, fields []string) ([]map[string]json.RawMessage, error) {
+	out := make([]map[string]json.RawMessage, 0, len(events))
+	for _, e := range events {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(b, &all); err != nil {
+			return nil, err
+		}
+		m := make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			if v, ok := all[f]; ok {
+				m[f] = v
+			}
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
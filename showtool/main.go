@@ -448,7 +448,7 @@ func main() {
 					fmt.Printf("    teams: %v -> %v\n", existing.Teams, icalEvent.Teams)
 				}
 				if !*dryRun {
-					if err := store.UpdateDescriptionAndTeams(ctx, existing.UID, icalEvent.Description, icalEvent.Teams, icalEvent.TeamIDs); err != nil {
+					if err := store.UpdateDescriptionAndTeams(ctx, existing.UID, existing.Version, icalEvent.Description, icalEvent.Teams, icalEvent.TeamIDs); err != nil {
 						fmt.Printf("    ERROR: %v\n", err)
 						errs++
 						continue
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/gcal"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// sink is a destination a real sync's change set is applied to after the
+// primary database has it. Sinks are declared under "sinks" in the config
+// file, so one run can fan out to several places.
+type sink interface {
+	Name() string
+	Apply(ctx context.Context, cs changeSet) error
+}
+
+// sinkConfig declares one sink. Which fields apply depends on Type; values
+// may reference environment variables as $VAR so secrets stay out of the
+// file.
+type sinkConfig struct {
+	// Type is postgres, json, webhook or gcal.
+	Type string `yaml:"type"`
+	// URL is the database URL (postgres) or endpoint (webhook).
+	URL string `yaml:"url"`
+	// Schema is the Postgres schema to write to (postgres).
+	Schema string `yaml:"schema"`
+	// Path is the file kept up to date (json).
+	Path string `yaml:"path"`
+	// Secret signs webhook bodies as -webhook does with WEBHOOK_SECRET.
+	Secret string `yaml:"secret"`
+	// Calendar and Credentials are as for publish-gcal (gcal).
+	Calendar    string `yaml:"calendar"`
+	Credentials string `yaml:"credentials"`
+}
+
+// sinkTypes builds each kind of sink from its config.
+var sinkTypes = map[string]func(c sinkConfig) (sink, error){
+	"postgres": newPostgresSink,
+	"json":     newJSONSink,
+	"webhook":  newWebhookSink,
+	"gcal":     newGCalSink,
+}
+
+// configSinks are the active config's sinks, set by applyVenue.
+var configSinks []sinkConfig
+
+// newSinks builds the sinks in cfgs, failing on the first bad entry.
+func newSinks(cfgs []sinkConfig) ([]sink, error) {
+	var out []sink
+	for i, c := range cfgs {
+		c.URL, c.Secret, c.Credentials = os.ExpandEnv(c.URL), os.ExpandEnv(c.Secret), os.ExpandEnv(c.Credentials)
+		build := sinkTypes[c.Type]
+		if build == nil {
+			types := make([]string, 0, len(sinkTypes))
+			for t := range sinkTypes {
+				types = append(types, t)
+			}
+			slices.Sort(types)
+			return nil, fmt.Errorf("sink %d: unknown type %q (want %s)", i+1, c.Type, strings.Join(types, ", "))
+		}
+		s, err := build(c)
+		if err != nil {
+			return nil, fmt.Errorf("sink %d (%s): %w", i+1, c.Type, err)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// configuredSinks builds the config file's sinks, exiting with a usage
+// error if one is invalid.
+func configuredSinks() []sink {
+	sinks, err := newSinks(configSinks)
+	if err != nil {
+		exitErr(withCode(exitUsage, err))
+	}
+	return sinks
+}
+
+// applySinks hands the run's changes to every sink. Unlike notifiers a
+// sink is a copy of the data, so failures are reported as warnings and
+// returned.
+func (in *ingester) applySinks(ctx context.Context, report *syncReport) error {
+	if len(in.sinks) == 0 {
+		return nil
+	}
+	cs := report.changeSet()
+	if cs.empty() {
+		return nil
+	}
+	var errs []error
+	for _, s := range in.sinks {
+		if err := s.Apply(ctx, cs); err != nil {
+			slog.Error("sink failed", "sink", s.Name(), "err", err)
+			report.warn("sink %s: %v", s.Name(), err)
+			errs = append(errs, fmt.Errorf("sink %s: %w", s.Name(), err))
+			continue
+		}
+		slog.Info("applied changes to sink", "sink", s.Name(), "added", len(cs.Added), "changed", len(cs.Changed), "removed", len(cs.Removed))
+	}
+	return errors.Join(errs...)
+}
+
+// postgresSink mirrors changes into another shopsync database. The target
+// must be migrated and have the same teams, since show_teams links to them.
+type postgresSink struct {
+	url, schema string
+}
+
+func newPostgresSink(c sinkConfig) (sink, error) {
+	if c.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	return &postgresSink{url: c.URL, schema: c.Schema}, nil
+}
+
+func (p *postgresSink) Name() string {
+	if p.schema != "" {
+		return "postgres " + p.schema
+	}
+	return "postgres"
+}
+
+func (p *postgresSink) Apply(ctx context.Context, cs changeSet) error {
+	var opts []showstore.Option
+	if p.schema != "" {
+		opts = append(opts, showstore.Schema(p.schema))
+	}
+	store, err := showstore.Open(ctx, p.url, opts...)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	var errs []error
+	for _, e := range changedShows(cs) {
+		e.Version = 0 // the mirror's versions are its own
+		if err := store.Upsert(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.UID, err))
+		}
+	}
+	if uids := removedUIDs(cs); len(uids) > 0 {
+		if _, err := store.DeleteShows(ctx, uids); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// jsonSink keeps a file of every show it has been told about, in export's
+// format, sorted by start.
+type jsonSink struct {
+	path string
+}
+
+func newJSONSink(c sinkConfig) (sink, error) {
+	if c.Path == "" {
+		return nil, errors.New("path is required")
+	}
+	return &jsonSink{path: c.Path}, nil
+}
+
+func (j *jsonSink) Name() string { return "json " + j.path }
+
+func (j *jsonSink) Apply(_ context.Context, cs changeSet) error {
+	var shows []icalplayers.Event
+	b, err := os.ReadFile(j.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(b, &shows); err != nil {
+			return fmt.Errorf("%s: %w", j.path, err)
+		}
+	}
+	drop := map[string]bool{}
+	for _, e := range changedShows(cs) {
+		drop[e.UID] = true
+	}
+	for _, uid := range removedUIDs(cs) {
+		drop[uid] = true
+	}
+	shows = slices.DeleteFunc(shows, func(e icalplayers.Event) bool { return drop[e.UID] })
+	shows = append(shows, changedShows(cs)...)
+	slices.SortStableFunc(shows, func(a, b icalplayers.Event) int {
+		switch {
+		case a.Start == nil && b.Start == nil:
+			return 0
+		case a.Start == nil:
+			return 1
+		case b.Start == nil:
+			return -1
+		}
+		return a.Start.Compare(*b.Start)
+	})
+
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), ".shopsync-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(icalplayers.JSON(shows), '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), j.path)
+}
+
+// webhookSink posts the change set like -webhook does.
+type webhookSink struct {
+	*webhookNotifier
+}
+
+func newWebhookSink(c sinkConfig) (sink, error) {
+	if !isURL(c.URL) {
+		return nil, fmt.Errorf("invalid url %q", c.URL)
+	}
+	return webhookSink{newWebhookNotifier(c.URL, c.Secret)}, nil
+}
+
+func (w webhookSink) Name() string { return w.name() }
+
+func (w webhookSink) Apply(ctx context.Context, cs changeSet) error { return w.notify(ctx, cs) }
+
+// gcalSink writes changed shows into a Google Calendar as publish-gcal
+// does, but only the shows in the change set.
+type gcalSink struct {
+	calendar, credentials string
+}
+
+func newGCalSink(c sinkConfig) (sink, error) {
+	if c.Calendar == "" || c.Credentials == "" {
+		return nil, errors.New("calendar and credentials are required")
+	}
+	return &gcalSink{calendar: c.Calendar, credentials: c.Credentials}, nil
+}
+
+func (g *gcalSink) Name() string { return "gcal " + g.calendar }
+
+func (g *gcalSink) Apply(ctx context.Context, cs changeSet) error {
+	key, err := os.ReadFile(g.credentials)
+	if err != nil {
+		return err
+	}
+	client, err := gcal.New(ctx, key, g.calendar)
+	if err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, e := range changedShows(cs) {
+		if e.Start == nil {
+			continue
+		}
+		if _, err := client.Put(ctx, gcalEvent(e, loc)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.UID, err))
+		}
+	}
+	for _, uid := range removedUIDs(cs) {
+		if err := client.Delete(ctx, gcalEventID(uid)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", uid, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// changedShows returns the added and changed shows of cs.
+func changedShows(cs changeSet) []icalplayers.Event {
+	out := slices.Clone(cs.Added)
+	for _, c := range cs.Changed {
+		out = append(out, c.Show)
+	}
+	return out
+}
+
+func removedUIDs(cs changeSet) []string {
+	out := make([]string, 0, len(cs.Removed))
+	for _, e := range cs.Removed {
+		out = append(out, e.UID)
+	}
+	return out
+}
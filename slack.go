@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// Slack change types selectable with -slack-notify. time covers start and
+// venue moves, which go out as their own SCHEDULE CHANGE message.
+const (
+	slackNew    = "new"
+	slackTime   = "time"
+	slackLineup = "lineup"
+)
+
+var slackChangeTypes = []string{slackNew, slackTime, slackLineup}
+
+// slackNotifier posts schedule changes, then a summary of new shows and
+// lineup changes, to a Slack incoming webhook.
+type slackNotifier struct {
+	url    string
+	kinds  []string
+	loc    *time.Location
+	client *http.Client
+}
+
+// parseSlackKinds splits a comma-separated -slack-notify value.
+func parseSlackKinds(s string) ([]string, error) {
+	var out []string
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if !slices.Contains(slackChangeTypes, k) {
+			return nil, fmt.Errorf("unknown -slack-notify type %q (want %s)", k, strings.Join(slackChangeTypes, ", "))
+		}
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+func newSlackNotifier(u string, kinds []string) *slackNotifier {
+	loc, err := time.LoadLocation(venueTimezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return &slackNotifier{url: u, kinds: kinds, loc: loc, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (s *slackNotifier) name() string { return "slack" }
+
+func (s *slackNotifier) notify(ctx context.Context, cs changeSet) error {
+	if slices.Contains(s.kinds, slackTime) {
+		if err := s.post(ctx, s.scheduleMessage(cs.scheduleChanges())); err != nil {
+			return err
+		}
+	}
+	return s.post(ctx, s.message(cs))
+}
+
+// post sends text as one message; empty text sends nothing.
+func (s *slackNotifier) post(ctx context.Context, text string) error {
+	if text == "" {
+		return nil
+	}
+	body, err := json.Marshal(map[string]any{"text": text, "unfurl_links": false})
+	if err != nil {
+		return err
+	}
+	return postWithRetry(ctx, s.client, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+}
+
+// message renders the change types s cares about as Slack mrkdwn, or ""
+// when there's nothing to say.
+func (s *slackNotifier) message(cs changeSet) string {
+	var b strings.Builder
+	if slices.Contains(s.kinds, slackNew) && len(cs.Added) > 0 {
+		fmt.Fprintf(&b, "*%d new %s*\n", len(cs.Added), plural(len(cs.Added), "show", "shows"))
+		for _, e := range cs.Added {
+			fmt.Fprintf(&b, "• %s\n", s.showLine(e))
+		}
+	}
+	var lineupChanges []string
+	for _, c := range cs.Changed {
+		for _, f := range c.Fields {
+			if (f.Field == "teams" || f.Field == "players") && slices.Contains(s.kinds, slackLineup) {
+				lineupChanges = append(lineupChanges, fmt.Sprintf("• %s: %s %s → %s", s.showLine(c.Show), f.Field, orNone(f.Old), orNone(f.New)))
+			}
+		}
+	}
+	if len(lineupChanges) > 0 {
+		fmt.Fprintf(&b, "*Lineup changes*\n%s\n", strings.Join(lineupChanges, "\n"))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// scheduleMessage renders start and venue moves, or "" if there are none.
+func (s *slackNotifier) scheduleMessage(changes []changedShow) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, ":rotating_light: *SCHEDULE CHANGE* (%d %s)\n", len(changes), plural(len(changes), "show", "shows"))
+	for _, c := range changes {
+		fmt.Fprintf(&b, "• %s", s.showLine(c.Show))
+		for _, f := range c.Fields {
+			switch f.Field {
+			case "start":
+				fmt.Fprintf(&b, "\n    time: was %s", s.formatStored(f.Old))
+			case "location":
+				fmt.Fprintf(&b, "\n    venue: %s → %s", slackEscape(orNone(f.Old)), slackEscape(orNone(f.New)))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// showLine is "<url|Summary> – Fri Oct 17, 7:30 PM (Team A, Team B)".
+func (s *slackNotifier) showLine(e icalplayers.Event) string {
+	title := slackEscape(e.Summary)
+	if e.URL != "" {
+		title = "<" + e.URL + "|" + title + ">"
+	}
+	line := title
+	if e.Start != nil {
+		line += " – " + e.Start.In(s.loc).Format("Mon Jan 2, 3:04 PM")
+	}
+	if len(e.Teams) > 0 {
+		line += " (" + slackEscape(strings.Join(e.Teams, ", ")) + ")"
+	}
+	return line
+}
+
+// formatStored reformats a fieldChange time (see formatTime) in venue time,
+// or returns it as-is if it doesn't parse.
+func (s *slackNotifier) formatStored(v string) string {
+	t, err := time.Parse("2006-01-02 15:04 MST", v)
+	if err != nil {
+		return orNone(v)
+	}
+	return t.In(s.loc).Format("Mon Jan 2, 3:04 PM")
+}
+
+// slackEscape escapes the three characters Slack treats as markup.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
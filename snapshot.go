@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// snapshotter uploads the full show list to S3 after each successful
+// daemon sync: once under a timestamped key as a point-in-time backup and
+// once as latest.json for the static site.
+type snapshotter struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// newSnapshotter parses dest (s3://bucket/optional/prefix) and loads AWS
+// credentials and region the usual way (environment, shared config, instance
+// role). AWS_ENDPOINT_URL_S3 points it at S3-compatible storage.
+func newSnapshotter(ctx context.Context, dest string) (*snapshotter, error) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("invalid -s3-snapshot %q: want s3://bucket/prefix", dest)
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("aws config: %w", err)
+	}
+	return &snapshotter{
+		client: s3.NewFromConfig(cfg),
+		bucket: u.Host,
+		prefix: strings.Trim(u.Path, "/"),
+	}, nil
+}
+
+// upload writes the current show list. The timestamped object goes first so
+// latest.json never points at data we failed to back up.
+func (s *snapshotter) upload(ctx context.Context, store *showstore.Store, at time.Time) error {
+	shows, err := store.GetAllShows(ctx)
+	if err != nil {
+		return dbErr(err)
+	}
+	body := append(icalplayers.JSON(shows), '\n')
+	key := path.Join(s.prefix, "shows-"+at.UTC().Format("20060102T150405Z")+".json")
+	if err := s.put(ctx, key, body, "max-age=31536000, immutable"); err != nil {
+		return err
+	}
+	if err := s.put(ctx, path.Join(s.prefix, "latest.json"), body, "max-age=60"); err != nil {
+		return err
+	}
+	slog.Info("uploaded show snapshot", "bucket", s.bucket, "key", key, "shows", len(shows), "bytes", len(body))
+	return nil
+}
+
+func (s *snapshotter) put(ctx context.Context, key string, body []byte, cacheControl string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(key),
+		Body:         bytes.NewReader(body),
+		ContentType:  aws.String("application/json"),
+		CacheControl: aws.String(cacheControl),
+	})
+	if err != nil {
+		return withCode(exitNetwork, fmt.Errorf("s3 put %s: %w", key, err))
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+type statsReport struct {
+	Upcoming         showstore.UpcomingCounts `json:"upcoming"`
+	IdleTeams        []string                 `json:"teamsWithoutUpcomingShows"`
+	MissingImages    []string                 `json:"upcomingShowsMissingImages"`
+	RecentSyncRuns   []showstore.SyncRun      `json:"recentSyncRuns"`
+	LastSuccessfulAt *time.Time               `json:"lastSuccessfulSync,omitempty"`
+	// Attendance totals the house counts recorded with 'attendance set'
+	// over the last -attendance-days; nil when that is 0.
+	Attendance *attendanceStats `json:"attendance,omitempty"`
+}
+
+type attendanceStats struct {
+	Days     int               `json:"days"`
+	Shows    int               `json:"shows"` // with a count recorded
+	ByTeam   []attendanceTotal `json:"byTeam"`
+	BySeries []attendanceTotal `json:"bySeries"`
+}
+
+func runStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	output := fs.String("output", "text", "Output format: text or json")
+	runs := fs.Int("runs", 10, "Number of recent sync runs to show")
+	attendanceDays := fs.Int("attendance-days", 90, "Total recorded attendance by team and series over this many past days (0 leaves it out)")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if !validOutput(*output) {
+		exitErr(fmt.Errorf("invalid -output %q (want text or json)", *output))
+	}
+	if *attendanceDays < 0 {
+		exitErr(withCode(exitUsage, errors.New("-attendance-days must not be negative")))
+	}
+
+	ctx := context.Background()
+	store := openStore(ctx, showstore.ReadOnly())
+	defer store.Close()
+
+	rep, err := collectStats(ctx, store, *runs, *attendanceDays)
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rep); err != nil {
+			exitErr(err)
+		}
+		return
+	}
+	printStats(rep)
+}
+
+func collectStats(ctx context.Context, store *showstore.Store, runs, attendanceDays int) (*statsReport, error) {
+	var rep statsReport
+	var err error
+	if rep.Upcoming, err = store.CountUpcoming(ctx); err != nil {
+		return nil, err
+	}
+	idle, err := store.TeamsWithoutUpcomingShows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rep.IdleTeams = []string{}
+	for _, t := range idle {
+		rep.IdleTeams = append(rep.IdleTeams, t.Name)
+	}
+	missing, err := store.GetShowsWithoutImageURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	upcoming, err := store.GetAllShows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	future := map[string]bool{}
+	now := time.Now()
+	for _, e := range upcoming {
+		if e.Start != nil && !e.Start.Before(now) {
+			future[e.UID] = true
+		}
+	}
+	rep.MissingImages = []string{}
+	for _, m := range missing {
+		if future[m.UID] {
+			rep.MissingImages = append(rep.MissingImages, m.Summary)
+		}
+	}
+	if rep.RecentSyncRuns, err = store.RecentSyncRuns(ctx, runs); err != nil {
+		return nil, err
+	}
+	for _, r := range rep.RecentSyncRuns {
+		if r.Error == "" {
+			t := r.FinishedAt
+			rep.LastSuccessfulAt = &t
+			break
+		}
+	}
+	if attendanceDays > 0 {
+		recs, err := store.ListAttendance(ctx, now.AddDate(0, 0, -attendanceDays), now)
+		if err != nil {
+			return nil, err
+		}
+		rep.Attendance = &attendanceStats{
+			Days: attendanceDays, Shows: len(recs),
+			ByTeam: attendanceBy(recs, attendanceTeams), BySeries: attendanceBy(recs, attendanceSeries(recs)),
+		}
+	}
+	return &rep, nil
+}
+
+func printStats(rep *statsReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	u := rep.Upcoming
+	fmt.Fprintf(w, "Upcoming shows:\t%d\n", u.Total)
+	fmt.Fprintf(w, "  next 7 days:\t%d\n", u.Next7Days)
+	fmt.Fprintf(w, "  next 30 days:\t%d\n", u.Next30Days)
+	fmt.Fprintf(w, "  missing images:\t%d\n", u.MissingImage)
+	fmt.Fprintf(w, "  without teams:\t%d\n", u.WithoutTeams)
+	if rep.LastSuccessfulAt != nil {
+		fmt.Fprintf(w, "Last successful sync:\t%s (%s ago)\n", inOutputTZ(*rep.LastSuccessfulAt).Format(time.RFC3339), time.Since(*rep.LastSuccessfulAt).Round(time.Minute))
+	} else {
+		fmt.Fprintf(w, "Last successful sync:\tnever recorded\n")
+	}
+	w.Flush()
+
+	fmt.Printf("\nTeams without upcoming shows (%d):\n", len(rep.IdleTeams))
+	for _, t := range rep.IdleTeams {
+		fmt.Printf("  %s\n", t)
+	}
+	fmt.Printf("\nUpcoming shows missing images (%d):\n", len(rep.MissingImages))
+	for _, s := range rep.MissingImages {
+		fmt.Printf("  %s\n", s)
+	}
+
+	fmt.Printf("\nRecent sync runs:\n")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tDURATION\tPARSED\tINSERTED\tUPDATED\tFAILED\tSOURCES\tERROR")
+	for _, r := range rep.RecentSyncRuns {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			inOutputTZ(r.StartedAt).Format("2006-01-02 15:04"), r.FinishedAt.Sub(r.StartedAt).Round(time.Second),
+			r.EventsParsed, r.Inserted, r.Updated, r.Failed, strings.Join(r.Sources, ","), truncateStr(r.Error, 60))
+	}
+	w.Flush()
+
+	if a := rep.Attendance; a != nil {
+		fmt.Printf("\nAttendance, last %d days (%d shows counted):\n", a.Days, a.Shows)
+		for i, group := range []struct {
+			title  string
+			totals []attendanceTotal
+		}{{"TEAM", a.ByTeam}, {"SERIES", a.BySeries}} {
+			if len(group.totals) == 0 {
+				continue
+			}
+			if i > 0 {
+				fmt.Println()
+			}
+			w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "%s\tSHOWS\tTOTAL\tAVERAGE\tFILL\n", group.title)
+			for _, t := range group.totals {
+				fill := "-"
+				if t.FillRate != nil {
+					fill = fmt.Sprintf("%d%%", *t.FillRate)
+				}
+				fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", truncateStr(t.Name, 40), t.Shows, t.Attendance, t.Average, fill)
+			}
+			w.Flush()
+		}
+	}
+}
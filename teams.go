@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// defaultTeamsFile is read by ingest -use-teams-file.
+const defaultTeamsFile = "teams.txt"
+
+func runTeams(args []string) {
+	if len(args) > 0 && args[0] == "export" {
+		runTeamsExport(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "list" {
+		args = args[1:]
+	}
+	fs := flag.NewFlagSet("teams", flag.ExitOnError)
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+
+	ctx := context.Background()
+	store := openStore(ctx, showstore.ReadOnly())
+	defer store.Close()
+
+	teams, err := store.GetAllTeams(ctx)
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	for _, t := range teams {
+		fmt.Printf("%s\t%s\n", t.ID, t.Name)
+	}
+}
+
+func runTeamsExport(args []string) {
+	fs := flag.NewFlagSet("teams export", flag.ExitOnError)
+	out := fs.String("out", "", "Write to this file instead of stdout (e.g. "+defaultTeamsFile+")")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+
+	ctx := context.Background()
+	store := openStore(ctx, showstore.ReadOnly())
+	defer store.Close()
+
+	teams, err := store.GetAllTeams(ctx)
+	if err != nil {
+		exitErr(dbErr(err))
+	}
+	if *out == "" {
+		writeTeamsFile(os.Stdout, teams)
+		return
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		exitErr(err)
+	}
+	writeTeamsFile(f, teams)
+	if err := f.Close(); err != nil {
+		exitErr(err)
+	}
+	slog.Info("wrote teams file", "count", len(teams), "path", *out)
+}
+
+// writeTeamsFile writes one team per line as name<TAB>id<TAB>aliases, with
+// aliases comma-separated. readTeamsFile reads it back.
+func writeTeamsFile(w io.Writer, teams []showstore.Team) {
+	fmt.Fprintln(w, "# name\tid\taliases (comma-separated)")
+	for _, t := range teams {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, t.ID, strings.Join(t.Aliases, ", "))
+	}
+}
+
+// readTeamsFile parses a teams file. Besides the full name<TAB>id<TAB>aliases
+// form it accepts the older one-name-per-line format; blank lines and lines
+// starting with # are ignored.
+func readTeamsFile(path string) ([]showstore.Team, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var teams []showstore.Team
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		t := showstore.Team{Name: strings.TrimSpace(fields[0])}
+		if len(fields) > 1 {
+			t.ID = strings.TrimSpace(fields[1])
+		}
+		if len(fields) > 2 {
+			for _, a := range strings.Split(fields[2], ",") {
+				if a = strings.TrimSpace(a); a != "" {
+					t.Aliases = append(t.Aliases, a)
+				}
+			}
+		}
+		teams = append(teams, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
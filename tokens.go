@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// runTokens manages private calendar subscriber tokens:
+//
+//	shopsync tokens add -name NAME
+//	shopsync tokens list
+//	shopsync tokens revoke -name NAME
+func runTokens(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: shopsync tokens add|list|revoke [flags]")
+		os.Exit(exitUsage)
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet("tokens "+cmd, flag.ExitOnError)
+	name := fs.String("name", "", "Subscriber name (add, revoke)")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args[1:])
+	logOpts.setup()
+
+	ctx := context.Background()
+	switch cmd {
+	case "add", "revoke":
+		if *name == "" {
+			fmt.Fprintf(os.Stderr, "tokens %s needs -name\n", cmd)
+			os.Exit(exitUsage)
+		}
+	case "list":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown tokens command %q\n", cmd)
+		os.Exit(exitUsage)
+	}
+	var opts []showstore.Option
+	if cmd == "list" {
+		opts = append(opts, showstore.ReadOnly())
+	}
+	store := openStore(ctx, opts...)
+	defer store.Close()
+
+	switch cmd {
+	case "add":
+		token, err := store.CreateCalendarToken(ctx, *name)
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		slog.Info("issued calendar token", "name", *name)
+		fmt.Println(token)
+	case "revoke":
+		n, err := store.RevokeCalendarTokens(ctx, *name)
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		if n == 0 {
+			exitErr(fmt.Errorf("no live tokens for %q", *name))
+		}
+		slog.Info("revoked calendar tokens", "name", *name, "count", n)
+	case "list":
+		tokens, err := store.ListCalendarTokens(ctx)
+		if err != nil {
+			exitErr(dbErr(err))
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tCREATED\tREVOKED")
+		for _, t := range tokens {
+			revoked := "-"
+			if t.RevokedAt != nil {
+				revoked = t.RevokedAt.Format(time.DateTime)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", t.Name, t.CreatedAt.Format(time.DateTime), revoked)
+		}
+		tw.Flush()
+	}
+}
+
+var errBadToken = errors.New("invalid or revoked calendar token")
+
+// calendarToken returns the subscriber token on r, from the
+// /subscribe/{token}/calendar.ics path or a ?token= query parameter.
+func calendarToken(r *http.Request) string {
+	if t := r.PathValue("token"); t != "" {
+		return t
+	}
+	return r.URL.Query().Get("token")
+}
+
+// checkCalendarToken enforces -private-calendar. Without it any request is
+// allowed, but a token that is given must still be valid so revoked
+// subscribers notice.
+func (s *server) checkCalendarToken(r *http.Request) (int, error) {
+	token := calendarToken(r)
+	if token == "" {
+		if s.privateCalendar {
+			return http.StatusUnauthorized, errors.New("calendar token required")
+		}
+		return 0, nil
+	}
+	ok, err := s.store.ValidCalendarToken(r.Context(), token)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !ok {
+		return http.StatusForbidden, errBadToken
+	}
+	return 0, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// maxIdempotencyKey is the longest Idempotency-Key POST /sync accepts.
+const maxIdempotencyKey = 255
+
+// syncTrigger lets POST /sync wake the daemon loop early. Requests that
+// arrive while a sync is already queued are folded into it, so a burst of
+// WordPress save_post hooks causes one extra sync, not one each.
+//
+// A request may carry an Idempotency-Key header (or ?idempotency_key=
+// for hooks that can't set headers). A key already queued, running, or
+// stored in sync_runs by a finished run is acknowledged without starting
+// anything, so a webhook delivered twice syncs once.
+type syncTrigger struct {
+	token string
+	store *showstore.Store // looks up keys of finished runs; may be nil
+	ch    chan struct{}
+
+	mu      sync.Mutex
+	queued  []string // keys of the queued sync
+	running []string // keys of the sync in progress, until it's recorded
+}
+
+func newSyncTrigger(token string, store *showstore.Store) *syncTrigger {
+	return &syncTrigger{token: token, store: store, ch: make(chan struct{}, 1)}
+}
+
+func (t *syncTrigger) register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /sync", t.handleSync)
+}
+
+// handleSync authenticates with "Authorization: Bearer <token>" or, for
+// hooks that can't set headers, a ?token= query parameter. serve's trigger
+// has no token; an admin-scope API key guards it instead.
+func (t *syncTrigger) handleSync(w http.ResponseWriter, r *http.Request) {
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if given == "" {
+		given = r.URL.Query().Get("token")
+	}
+	if t.token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(t.token)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid sync token"})
+		return
+	}
+	key := strings.TrimSpace(cmp.Or(r.Header.Get("Idempotency-Key"), r.URL.Query().Get("idempotency_key")))
+	if len(key) > maxIdempotencyKey {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("idempotency key is longer than %d bytes", maxIdempotencyKey)})
+		return
+	}
+
+	// Held across the sync_runs lookup: the daemon records a run's keys
+	// before it drops them from running (see finish), so a key is always
+	// in one place or the other.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if key != "" {
+		if dup, body, err := t.duplicate(r.Context(), key); err != nil {
+			slog.Error("looking up idempotency key", "err", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		} else if dup {
+			slog.Info("duplicate sync request", "idempotency_key", key, "remote", r.RemoteAddr)
+			writeJSON(w, http.StatusOK, body)
+			return
+		}
+	}
+	select {
+	case t.ch <- struct{}{}:
+		slog.Info("sync requested", "remote", r.RemoteAddr, "idempotency_key", key)
+		t.queue(key)
+		writeJSON(w, http.StatusAccepted, map[string]any{"queued": true})
+	default:
+		t.queue(key)
+		writeJSON(w, http.StatusAccepted, map[string]any{"queued": false, "reason": "a sync is already queued"})
+	}
+}
+
+// duplicate reports whether key belongs to a queued, running or finished
+// sync, with the response to give. t.mu must be held.
+func (t *syncTrigger) duplicate(ctx context.Context, key string) (bool, map[string]any, error) {
+	body := map[string]any{"queued": false, "duplicate": true}
+	switch {
+	case slices.Contains(t.queued, key):
+		body["reason"] = "a sync with this idempotency key is already queued"
+		return true, body, nil
+	case slices.Contains(t.running, key):
+		body["reason"] = "a sync with this idempotency key is running"
+		return true, body, nil
+	}
+	if t.store == nil {
+		return false, nil, nil
+	}
+	id, err := t.store.SyncRunForIdempotencyKey(ctx, key)
+	if err != nil || id == "" {
+		return false, nil, err
+	}
+	body["reason"] = "a sync with this idempotency key already ran"
+	body["runId"] = id
+	return true, body, nil
+}
+
+func (t *syncTrigger) queue(key string) {
+	if key != "" {
+		t.queued = append(t.queued, key)
+	}
+}
+
+// start marks the queued sync as running and returns its keys, for the
+// run to record in sync_runs.
+func (t *syncTrigger) start() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running, t.queued = t.queued, nil
+	return t.running
+}
+
+// finish forgets the keys of the sync that just ended. Call it after the
+// run is recorded.
+func (t *syncTrigger) finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running = nil
+}
+
+// runTriggered runs a sync with in each time t is triggered, until ctx
+// ends. It is serve -sync's loop; unlike the daemon it has no schedule.
+func runTriggered(ctx context.Context, in *ingester, t *syncTrigger) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.ch:
+		}
+		in.resync, in.idempotencyKeys = true, t.start()
+		slog.Info("starting requested sync", "idempotency_keys", in.idempotencyKeys)
+		syncOnce(ctx, in)
+		in.resync, in.idempotencyKeys = false, nil
+		t.finish()
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// postSync sends POST /sync to t, with key as the Idempotency-Key unless
+// it's empty, and returns the status and decoded body.
+func postSync(t *testing.T, trig *syncTrigger, target, key string) (int, map[string]any) {
+	t.Helper()
+	r := httptest.NewRequest("POST", target, nil)
+	if key != "" {
+		r.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	trig.handleSync(w, r)
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("POST %s: %v", target, err)
+	}
+	return w.Code, body
+}
+
+func TestSyncTriggerToken(t *testing.T) {
+	trig := newSyncTrigger("s3cret", nil)
+	for _, tt := range []struct {
+		auth, target string
+		want         int
+	}{
+		{"", "/sync", http.StatusUnauthorized},
+		{"Bearer wrong", "/sync", http.StatusUnauthorized},
+		{"", "/sync?token=wrong", http.StatusUnauthorized},
+		{"Bearer s3cret", "/sync", http.StatusAccepted},
+		{"", "/sync?token=s3cret", http.StatusAccepted},
+	} {
+		r := httptest.NewRequest("POST", tt.target, nil)
+		if tt.auth != "" {
+			r.Header.Set("Authorization", tt.auth)
+		}
+		w := httptest.NewRecorder()
+		trig.handleSync(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%q %s: status %d, want %d", tt.auth, tt.target, w.Code, tt.want)
+		}
+	}
+}
+
+func TestSyncTriggerIdempotency(t *testing.T) {
+	trig := newSyncTrigger("", nil)
+	steps := []struct {
+		name, target, key string
+		status            int
+		queued, duplicate bool
+		reason            string
+	}{
+		{"first request queues a sync", "/sync", "k1", http.StatusAccepted, true, false, ""},
+		{"same key while queued", "/sync", "k1", http.StatusOK, false, true, "already queued"},
+		{"same key as a query parameter", "/sync?idempotency_key=k1", "", http.StatusOK, false, true, "already queued"},
+		{"new key joins the queued sync", "/sync", "k2", http.StatusAccepted, false, false, "a sync is already queued"},
+		{"no key joins it too", "/sync", "", http.StatusAccepted, false, false, "a sync is already queued"},
+		{"key too long", "/sync", strings.Repeat("k", maxIdempotencyKey+1), http.StatusBadRequest, false, false, ""},
+	}
+	for _, s := range steps {
+		status, body := postSync(t, trig, s.target, s.key)
+		if status != s.status {
+			t.Fatalf("%s: status %d, want %d (%v)", s.name, status, s.status, body)
+		}
+		if status == http.StatusBadRequest {
+			continue
+		}
+		if body["queued"] != s.queued || (body["duplicate"] == true) != s.duplicate || !strings.Contains(str(body["reason"]), s.reason) {
+			t.Errorf("%s: %v", s.name, body)
+		}
+	}
+
+	// The loop picks the sync up: its keys are now running.
+	<-trig.ch
+	if keys := trig.start(); !slices.Equal(keys, []string{"k1", "k2"}) {
+		t.Errorf("start() = %v, want [k1 k2]", keys)
+	}
+	if status, body := postSync(t, trig, "/sync", "k2"); status != http.StatusOK || !strings.Contains(str(body["reason"]), "running") {
+		t.Errorf("key of the running sync: %d %v", status, body)
+	}
+	if status, body := postSync(t, trig, "/sync", "k3"); status != http.StatusAccepted || body["queued"] != true {
+		t.Errorf("new key during a run: %d %v, want a new sync queued", status, body)
+	}
+
+	// Without a store, a finished run's keys are forgotten.
+	trig.finish()
+	if status, body := postSync(t, trig, "/sync", "k1"); status != http.StatusAccepted || body["duplicate"] == true {
+		t.Errorf("key of a finished run, no store: %d %v", status, body)
+	}
+}
+
+// A key recorded in sync_runs is answered with that run's ID.
+func TestSyncTriggerReplaysFinishedRun(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	id, err := store.RecordSyncRun(ctx, showstore.SyncRun{StartedAt: now.Add(-time.Minute), FinishedAt: now, IdempotencyKeys: []string{"k1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trig := newSyncTrigger("", store)
+	status, body := postSync(t, trig, "/sync", "k1")
+	if status != http.StatusOK || body["duplicate"] != true || body["runId"] != id {
+		t.Errorf("replay: %d %v, want run %s", status, body, id)
+	}
+	if status, _ := postSync(t, trig, "/sync", "k2"); status != http.StatusAccepted {
+		t.Errorf("unknown key: status %d, want 202", status)
+	}
+}
+
+func str(v any) string {
+	s, _ := v.(string)
+	return s
+}
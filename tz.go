@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+)
+
+// outputTZ is the zone -tz asked for times to be shown in; nil leaves each
+// command's usual zone. It only affects rendering: stored and compared
+// times are instants and don't care.
+var outputTZ *time.Location
+
+// setOutputTZ parses a -tz value. "" keeps the default.
+func setOutputTZ(name string) error {
+	if name == "" {
+		outputTZ = nil
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid -tz %q: %w", name, err)
+	}
+	outputTZ = loc
+	return nil
+}
+
+// displayLoc returns the -tz zone, or def when -tz wasn't given.
+func displayLoc(def *time.Location) *time.Location {
+	if outputTZ != nil {
+		return outputTZ
+	}
+	return def
+}
+
+// inOutputTZ returns t in the -tz zone, or unchanged without -tz.
+func inOutputTZ(t time.Time) time.Time {
+	if outputTZ == nil {
+		return t
+	}
+	return t.In(outputTZ)
+}
+
+// localizeEvents returns a copy of events with their times in the -tz zone,
+// for printing or encoding. Without -tz it returns events as they are.
+func localizeEvents(events []icalplayers.Event) []icalplayers.Event {
+	if outputTZ == nil {
+		return events
+	}
+	out := make([]icalplayers.Event, len(events))
+	for i, e := range events {
+		out[i] = localizeEvent(e)
+	}
+	return out
+}
+
+func localizeEvent(e icalplayers.Event) icalplayers.Event {
+	for _, t := range []**time.Time{&e.Start, &e.End, &e.UpdatedAt, &e.Modified} {
+		if *t != nil {
+			lt := inOutputTZ(**t)
+			*t = &lt
+		}
+	}
+	return e
+}
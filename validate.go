@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"github.com/tsny/shopsync/pkg/httpclient"
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// lintFinding is one problem spotted in a feed.
+type lintFinding struct {
+	Severity string `json:"severity"` // error or warning
+	Check    string `json:"check"`
+	UID      string `json:"uid,omitempty"`
+	Summary  string `json:"summary,omitempty"`
+	Message  string `json:"message"`
+}
+
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	var opts ingestOptions
+	fs.Var(&opts.srcs, "src", "Path or URL to an .ics file. Use '-' to read from stdin. Repeatable")
+	fs.StringVar(&opts.wpURL, "wp", "", "URL to WordPress tribe/events API")
+	fs.StringVar(&opts.wpCache, "wp-cache", "", "Path to cached WP events JSON")
+	fs.BoolVar(&opts.skipImageSearch, "skip-image-search", true, "If set, do not fetch post images while parsing")
+	teamsFile := fs.String("teams", defaultTeamsFile, "Teams file used for the no-teams check (see 'teams export'); skipped if missing")
+	minTurnaround := fs.Duration("min-turnaround", defaultMinTurnaround, "Flag shows on the same stage less than this apart (end to start); overlapping shows are errors")
+	var blackoutSpecs stringList
+	fs.Var(&blackoutSpecs, "blackout", "Warn about shows on days the venue is dark: a date or range with a reason (2026-11-26=Thanksgiving), or an .ics file or URL of holidays and closures. Repeatable")
+	checkURLs := fs.Bool("check-urls", true, "Request every event URL and flag dead links")
+	output := fs.String("output", "text", "Report format: text or json")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args)
+	logOpts.setup()
+	if *minTurnaround < 0 {
+		exitErr(withCode(exitUsage, fmt.Errorf("-min-turnaround must not be negative, got %s", *minTurnaround)))
+	}
+	if !validOutput(*output) {
+		exitErr(fmt.Errorf("invalid -output %q (want text or json)", *output))
+	}
+
+	ctx := context.Background()
+	in := &ingester{opts: opts}
+	st := &syncState{report: newSyncReport()}
+	enrich := enrichOptions{skip: opts.skipImageSearch, details: opts.details, workers: icalplayers.DefaultImageWorkers, rate: icalplayers.DefaultImageRate}
+	if err := (pipeline{in.sourceStage(), in.enrichStage(enrich)}).run(ctx, st); err != nil {
+		exitErr(err)
+	}
+	events := st.events
+
+	var teams []showstore.Team
+	if t, err := readTeamsFile(*teamsFile); err == nil {
+		teams = t
+	} else {
+		slog.Warn("skipping team checks", "teams_file", *teamsFile, "err", err)
+	}
+
+	findings := append(lintEvents(events, teams), overlapFindings(findOverlaps(events, *minTurnaround, 0))...)
+	if len(blackoutSpecs) > 0 {
+		loc, err := time.LoadLocation(venueTimezone)
+		if err != nil {
+			exitErr(err)
+		}
+		b, err := loadBlackouts(ctx, blackoutSpecs, loc)
+		if err != nil {
+			exitErr(withCode(exitUsage, err))
+		}
+		findings = append(findings, blackoutFindings(showsOnBlackouts(events, b, loc), loc)...)
+	}
+	if *checkURLs {
+		findings = append(findings, checkEventURLs(ctx, events)...)
+	}
+	sortFindings(findings)
+
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(map[string]any{"events": len(events), "findings": findings}); err != nil {
+			exitErr(err)
+		}
+	} else {
+		printFindings(os.Stdout, len(events), findings)
+	}
+	for _, f := range findings {
+		if f.Severity == "error" {
+			os.Exit(exitPartial)
+		}
+	}
+}
+
+// lintEvents runs the offline checks over a parsed feed.
+func lintEvents(events []icalplayers.Event, teams []showstore.Team) []lintFinding {
+	var out []lintFinding
+	add := func(sev, check string, e icalplayers.Event, format string, args ...any) {
+		out = append(out, lintFinding{Severity: sev, Check: check, UID: e.UID, Summary: e.Summary, Message: fmt.Sprintf(format, args...)})
+	}
+
+	seen := map[string]int{}
+	for _, e := range events {
+		if e.UID == "" {
+			add("error", "missing-uid", e, "event has no UID")
+		} else {
+			seen[e.UID]++
+			if seen[e.UID] == 2 {
+				add("error", "duplicate-uid", e, "UID appears more than once")
+			}
+		}
+		if e.Start == nil {
+			add("error", "missing-start", e, "event has no start time")
+		}
+		if strings.TrimSpace(e.Summary) == "" {
+			add("error", "missing-summary", e, "event has no summary")
+		}
+		if e.URL == "" {
+			add("warning", "missing-url", e, "event has no URL")
+		}
+		if len(teams) > 0 && len(findTeamsInEventDescription(e.Description, teams)) == 0 {
+			add("warning", "no-teams", e, "no known team appears in the description")
+		}
+		for _, p := range icalplayers.InferPlayerNames(e.Description, nil) {
+			if reason := suspiciousPlayer(p, teams); reason != "" {
+				add("warning", "suspicious-player", e, "inferred player %q %s", p, reason)
+			}
+		}
+	}
+	return out
+}
+
+var digitRe = regexp.MustCompile(`\d`)
+
+// suspiciousPlayer explains why an inferred name doesn't look like a person,
+// or returns "" if it does.
+func suspiciousPlayer(name string, teams []showstore.Team) string {
+	switch {
+	case digitRe.MatchString(name):
+		return "contains digits"
+	case len(strings.Fields(name)) > 3:
+		return "has more than three words"
+	case len(name) > 4 && strings.ToUpper(name) == name && strings.IndexFunc(name, unicode.IsLetter) >= 0:
+		return "is all caps"
+	}
+	for _, t := range teams {
+		if strings.EqualFold(t.Name, name) {
+			return "is a team name"
+		}
+	}
+	return ""
+}
+
+// checkEventURLs requests every distinct event URL and reports failures.
+func checkEventURLs(ctx context.Context, events []icalplayers.Event) []lintFinding {
+	byURL := map[string][]icalplayers.Event{}
+	for _, e := range events {
+		if e.URL != "" {
+			byURL[e.URL] = append(byURL[e.URL], e)
+		}
+	}
+	client := httpclient.Default
+	var (
+		mu  sync.Mutex
+		out []lintFinding
+		wg  sync.WaitGroup
+	)
+	sem := make(chan struct{}, 4)
+	for u, evs := range byURL {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if msg := probeURL(ctx, client, u); msg != "" {
+				mu.Lock()
+				for _, e := range evs {
+					out = append(out, lintFinding{Severity: "error", Check: "dead-url", UID: e.UID, Summary: e.Summary, Message: u + ": " + msg})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// probeURL returns "" if u answers with a non-error status.
+func probeURL(ctx context.Context, client *http.Client, u string) string {
+	status, err := probeStatus(ctx, client, u)
+	if err != nil {
+		return err.Error()
+	}
+	if status >= 400 {
+		return fmt.Sprintf("http status %d", status)
+	}
+	return ""
+}
+
+// probeStatus returns the status u answers a HEAD with. Servers that reject
+// HEAD get a GET.
+func probeStatus(ctx context.Context, client *http.Client, u string) (int, error) {
+	status := 0
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, u, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("User-Agent", "shopsync/1.0")
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		status = resp.StatusCode
+		if status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented {
+			break
+		}
+	}
+	return status, nil
+}
+
+func sortFindings(f []lintFinding) {
+	sort.SliceStable(f, func(i, j int) bool {
+		if f[i].Severity != f[j].Severity {
+			return f[i].Severity == "error"
+		}
+		return f[i].Check < f[j].Check
+	})
+}
+
+func printFindings(w io.Writer, events int, findings []lintFinding) {
+	if len(findings) == 0 {
+		fmt.Fprintf(w, "%d events, no problems found.\n", events)
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tCHECK\tEVENT\tMESSAGE")
+	var errs, warns int
+	for _, f := range findings {
+		if f.Severity == "error" {
+			errs++
+		} else {
+			warns++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Severity, f.Check, truncateStr(f.Summary, 40), f.Message)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d events, %d errors, %d warnings.\n", events, errs, warns)
+}
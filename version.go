@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/tsny/shopsync/pkg/icalplayers"
+	"github.com/tsny/shopsync/pkg/showstore"
+)
+
+// Set at build time with
+//
+//	-ldflags "-X main.version=v1.2.3 -X main.commit=abc123 -X main.buildDate=2025-01-01T00:00:00Z"
+//
+// commit and buildDate fall back to the VCS stamp Go embeds when building
+// from a checkout.
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// buildInfo is what version, /about and startup logs report.
+type buildInfo struct {
+	Version       string `json:"version"`
+	Commit        string `json:"commit,omitempty"`
+	BuildDate     string `json:"buildDate,omitempty"`
+	GoVersion     string `json:"goVersion"`
+	SchemaVersion int    `json:"schemaVersion"`
+	// EventSchemaVersion is the version of the export JSON contract
+	// (GET /schema/event.json).
+	EventSchemaVersion int `json:"eventSchemaVersion"`
+}
+
+func currentBuildInfo() buildInfo {
+	bi := buildInfo{
+		Version:       version,
+		Commit:        commit,
+		BuildDate:     buildDate,
+		GoVersion:     runtime.Version(),
+		SchemaVersion: showstore.SchemaVersion,
+
+		EventSchemaVersion: icalplayers.EventSchemaVersion,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && bi.Commit == "":
+				bi.Commit = s.Value
+			case s.Key == "vcs.time" && bi.BuildDate == "":
+				bi.BuildDate = s.Value
+			case s.Key == "vcs.modified" && s.Value == "true" && bi.Commit != "" && commit == "":
+				bi.Commit += "-dirty"
+			}
+		}
+	}
+	return bi
+}
+
+// logAttrs returns the build info as slog key/value pairs.
+func (bi buildInfo) logAttrs() []any {
+	return []any{"version", bi.Version, "commit", bi.Commit, "built", bi.BuildDate, "schema", bi.SchemaVersion}
+}
+
+func runVersion(args []string) {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	output := fs.String("output", "text", "Output format: text or json")
+	parseFlags(fs, args)
+	if !validOutput(*output) {
+		fmt.Fprintf(os.Stderr, "invalid -output %q (want text or json)\n", *output)
+		os.Exit(exitUsage)
+	}
+	bi := currentBuildInfo()
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(bi)
+		return
+	}
+	fmt.Printf("shopsync %s\n", bi.Version)
+	fmt.Printf("  commit:  %s\n", orNone(bi.Commit))
+	fmt.Printf("  built:   %s\n", orNone(bi.BuildDate))
+	fmt.Printf("  go:      %s\n", bi.GoVersion)
+	fmt.Printf("  schema:  %d\n", bi.SchemaVersion)
+	fmt.Printf("  events:  v%d\n", bi.EventSchemaVersion)
+}
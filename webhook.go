@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/tsny/shopsync/pkg/webhooksig"
+)
+
+const (
+	webhookAttempts = 3
+	webhookBackoff  = 2 * time.Second
+)
+
+// webhookNotifier POSTs the change set as JSON (X-Shopsync-Event
+// shows.changed), then, if any show's start or venue moved, a
+// scheduleChangeSet (shows.schedule_changed). When secret is set each body
+// is signed with webhooksig, afresh for every attempt, so receivers can
+// check it came from us and reject replays.
+type webhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func newWebhookNotifier(u, secret string) *webhookNotifier {
+	if secret == "" {
+		slog.Warn("webhook deliveries will be unsigned; set WEBHOOK_SECRET (or the sink's secret) so receivers can verify them", "url", u)
+	}
+	return &webhookNotifier{url: u, secret: secret, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (w *webhookNotifier) name() string {
+	if u, err := url.Parse(w.url); err == nil {
+		return "webhook " + u.Host
+	}
+	return "webhook"
+}
+
+// scheduleChangeSet is the shows.schedule_changed payload.
+type scheduleChangeSet struct {
+	RunID    string        `json:"runId,omitempty"`
+	SyncedAt time.Time     `json:"syncedAt"`
+	Changes  []changedShow `json:"changes"`
+}
+
+func (w *webhookNotifier) notify(ctx context.Context, cs changeSet) error {
+	if err := w.post(ctx, "shows.changed", cs); err != nil {
+		return err
+	}
+	if sc := cs.scheduleChanges(); len(sc) > 0 {
+		return w.post(ctx, "shows.schedule_changed", scheduleChangeSet{RunID: cs.RunID, SyncedAt: cs.SyncedAt, Changes: sc})
+	}
+	return nil
+}
+
+func (w *webhookNotifier) post(ctx context.Context, event string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return postWithRetry(ctx, w.client, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "shopsync/1.0")
+		req.Header.Set("X-Shopsync-Event", event)
+		if w.secret != "" {
+			webhooksig.SetHeaders(req.Header, w.secret, time.Now(), body)
+		}
+		return req, nil
+	})
+}
+
+// postWithRetry sends the request built by newReq, retrying network errors,
+// 429s and 5xx responses with exponential backoff.
+func postWithRetry(ctx context.Context, client *http.Client, newReq func() (*http.Request, error)) error {
+	var lastErr error
+	for attempt := range webhookAttempts {
+		if attempt > 0 {
+			t := time.NewTimer(webhookBackoff << (attempt - 1))
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			}
+		}
+		req, err := newReq()
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		if resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("status %s: %s", resp.Status, bytes.TrimSpace(snippet))
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return lastErr
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", webhookAttempts, lastErr)
+}
+
+// runWebhook checks deliveries on the receiving end, e.g. from a shell
+// hook: "shopsync webhook verify -timestamp $TS -signature $SIG < body".
+func runWebhook(args []string) {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprintln(os.Stderr, "usage: shopsync webhook verify [flags] < body")
+		os.Exit(exitUsage)
+	}
+	fs := flag.NewFlagSet("webhook verify", flag.ExitOnError)
+	secret := fs.String("secret", os.Getenv("WEBHOOK_SECRET"), "Shared secret (default $WEBHOOK_SECRET)")
+	ts := fs.String("timestamp", "", "The delivery's "+webhooksig.TimestampHeader+" header")
+	sig := fs.String("signature", "", "The delivery's "+webhooksig.SignatureHeader+" header")
+	window := fs.Duration("window", webhooksig.DefaultWindow, "Reject deliveries whose timestamp is further than this from now")
+	logOpts := addLogFlags(fs)
+	parseFlags(fs, args[1:])
+	logOpts.setup()
+	if *secret == "" {
+		exitErr(withCode(exitUsage, errors.New("-secret or WEBHOOK_SECRET is required")))
+	}
+	body, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		exitErr(err)
+	}
+	h := http.Header{}
+	h.Set(webhooksig.TimestampHeader, *ts)
+	h.Set(webhooksig.SignatureHeader, *sig)
+	if err := webhooksig.Verify(*secret, h, body, time.Now(), *window); err != nil {
+		exitErr(err)
+	}
+	slog.Info("signature ok")
+}